@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultOSVURL is the OSV API queried by audit in addition to GitHub
+	defaultOSVURL = "https://api.osv.dev"
+
+	// osvEcosystem names GitHub Actions in OSV records
+	osvEcosystem = "GitHub Actions"
+
+	// osvTimeout bounds a single OSV request
+	osvTimeout = 30 * time.Second
+
+	// maxOSVResponseSize bounds an OSV query response
+	maxOSVResponseSize = 8 << 20
+
+	// Sources of an advisory
+	advisorySourceGitHub = "github"
+	advisorySourceOSV    = "osv"
+)
+
+// Advisory is a published vulnerability affecting the version of an action
+// a workflow runs
+type Advisory struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Summary  string   `json:"summary"`
+	Severity string   `json:"severity,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	// Patched is the first version that fixes the vulnerability, empty when
+	// there is no fix yet
+	Patched string `json:"patched,omitempty"`
+	// Source is "github" or "osv", the database that reported it first
+	Source string `json:"source"`
+}
+
+// advisoryKey identifies a version of an action repository
+type advisoryKey struct {
+	repo    string
+	version string
+}
+
+// osvClient queries the OSV vulnerability database
+type osvClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// newOSVClient returns an OSV client for the API at baseURL
+func newOSVClient(baseURL string) *osvClient {
+	return &osvClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Transport: loggingTransport{}, Timeout: osvTimeout},
+	}
+}
+
+// query lists the OSV records affecting version of repo
+func (c *osvClient) query(repo, version string) ([]Advisory, error) {
+	body, err := json.Marshal(map[string]any{
+		"version": version,
+		"package": map[string]string{"name": repo, "ecosystem": osvEcosystem},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Post(c.baseURL+"/v1/query", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, &ResolveError{Kind: ErrorKindNetwork, Err: fmt.Errorf("OSV could not be reached: %w", err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OSV query for %s@%s failed with status %d", repo, version, resp.StatusCode)
+	}
+
+	var result struct {
+		Vulns []struct {
+			ID               string   `json:"id"`
+			Aliases          []string `json:"aliases"`
+			Summary          string   `json:"summary"`
+			DatabaseSpecific struct {
+				Severity string `json:"severity"`
+			} `json:"database_specific"`
+			Affected []struct {
+				Package struct {
+					Name string `json:"name"`
+				} `json:"package"`
+				Ranges []struct {
+					Events []struct {
+						Fixed string `json:"fixed"`
+					} `json:"events"`
+				} `json:"ranges"`
+			} `json:"affected"`
+		} `json:"vulns"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOSVResponseSize)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid OSV response: %w", err)
+	}
+
+	advisories := make([]Advisory, 0, len(result.Vulns))
+	for _, vuln := range result.Vulns {
+		advisory := Advisory{
+			ID:       vuln.ID,
+			Aliases:  vuln.Aliases,
+			Summary:  vuln.Summary,
+			Severity: strings.ToLower(vuln.DatabaseSpecific.Severity),
+			URL:      "https://osv.dev/vulnerability/" + vuln.ID,
+			Source:   advisorySourceOSV,
+		}
+		for _, affected := range vuln.Affected {
+			if !strings.EqualFold(affected.Package.Name, repo) {
+				continue
+			}
+			for _, r := range affected.Ranges {
+				for _, event := range r.Events {
+					if event.Fixed != "" {
+						advisory.Patched = event.Fixed
+					}
+				}
+			}
+		}
+		advisories = append(advisories, advisory)
+	}
+	return advisories, nil
+}
+
+// advisoryNames returns the id and aliases of an advisory
+func (a Advisory) advisoryNames() []string {
+	return append([]string{a.ID}, a.Aliases...)
+}
+
+// mergeAdvisories adds the OSV records that GitHub did not report. OSV
+// imports the GitHub database, so most records share a GHSA id or alias.
+func mergeAdvisories(github, osv []Advisory) []Advisory {
+	merged := github
+	for _, advisory := range osv {
+		duplicate := slices.ContainsFunc(merged, func(known Advisory) bool {
+			return slices.ContainsFunc(advisory.advisoryNames(), func(name string) bool {
+				return slices.Contains(known.advisoryNames(), name)
+			})
+		})
+		if !duplicate {
+			merged = append(merged, advisory)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].ID < merged[j].ID })
+	return merged
+}
+
+// advisoryVersion returns the version advisories are matched against,
+// without its v prefix. Major tags such as v4 move, so the most specific
+// version tag of the commit they resolved to is looked up; a commit pin
+// without a version comment is matched by its tags the same way.
+func advisoryVersion(forge Forge, action ActionInfo) (string, error) {
+	version := currentVersion(action)
+	if strings.Count(version, ".") < 2 && action.CurrentSHA != "" {
+		switch {
+		case action.CurrentSHA == action.LatestSHA && versionTagRegex.MatchString(action.LatestTag):
+			version = action.LatestTag
+		default:
+			owner, repo, _ := splitActionPath(action.Repo)
+			tags, err := forge.TagsAt(owner, repo, action.CurrentSHA)
+			if err != nil {
+				return "", err
+			}
+			for _, tag := range tags {
+				if versionTagRegex.MatchString(tag) && (version == "" || compareVersionTags(tag, version) > 0) {
+					version = tag
+				}
+			}
+		}
+	}
+	return strings.TrimPrefix(version, "v"), nil
+}
+
+// describeAdvisory summarizes an advisory and its fix
+func describeAdvisory(advisory Advisory) string {
+	description := advisory.ID
+	if advisory.Severity != "" {
+		description += " (" + advisory.Severity + ")"
+	}
+	description += ": " + advisory.Summary
+	if advisory.Patched != "" {
+		description += "; fixed in " + advisory.Patched
+	} else {
+		description += "; no fixed version yet"
+	}
+	return description
+}
+
+// checkAdvisories looks up the GitHub Security Advisories, and the OSV
+// records unless osv is nil, affecting the version of every action, stores
+// them in the actions and returns the no-known-advisories policy rule.
+// Versions that could not be checked fail the rule too.
+func checkAdvisories(gc *GitHubClient, forge Forge, osv *osvClient, actions WorkflowActions) PolicyResult {
+	fmt.Fprintln(console, "\n🛡️  Checking action versions against security advisories...")
+	result := PolicyResult{Rule: "no-known-advisories", Description: "No action runs a version with a published security advisory", Required: true}
+
+	type lookup struct {
+		advisories []Advisory
+		err        error
+	}
+	checked := make(map[advisoryKey]lookup)
+	unknown := 0
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker || action.Error != nil {
+				continue
+			}
+			owner, repo, _ := splitActionPath(action.Repo)
+			version, err := advisoryVersion(forge, *action)
+			if err == nil && version == "" {
+				logger.Debug("no version to check advisories for", "repo", action.Repo, "ref", action.CurrentRef)
+				unknown++
+				continue
+			}
+			key := advisoryKey{repo: owner + "/" + repo, version: version}
+
+			found, ok := checked[key]
+			if !ok && err == nil {
+				found.advisories, found.err = gc.api.Advisories(gc.ctx, key.repo, version)
+				if found.err == nil && osv != nil {
+					var records []Advisory
+					records, found.err = osv.query(key.repo, version)
+					found.advisories = mergeAdvisories(found.advisories, records)
+				}
+				checked[key] = found
+			}
+			if err != nil {
+				found.err = err
+			}
+			if found.err != nil {
+				fmt.Fprintf(console, "  %s %s:%d %s: could not check advisories: %v %s\n", severityStatus(ruleAdvisory), action.WorkflowFile, action.Line, action.Repo, found.err, ruleTag(ruleAdvisory))
+				result.Violations = append(result.Violations, violationFor(*action, fmt.Sprintf("could not check advisories: %v", found.err)))
+				continue
+			}
+			action.Advisories = found.advisories
+		}
+	}
+
+	for _, action := range sortedActions(actions) {
+		for _, advisory := range action.Advisories {
+			fmt.Fprintf(console, "  %s %s:%d %s: %s %s\n", severityStatus(ruleAdvisory), action.WorkflowFile, action.Line, action.reference(), describeAdvisory(advisory), ruleTag(ruleAdvisory))
+			if advisory.URL != "" {
+				fmt.Fprintf(console, "      %s\n", advisory.URL)
+			}
+			result.Violations = append(result.Violations, violationFor(action, describeAdvisory(advisory)))
+		}
+	}
+	if unknown > 0 {
+		fmt.Fprintf(console, "  %s %d actions are not pinned to a version tag and were not checked\n", theme.Warning, unknown)
+	}
+	if len(result.Violations) == 0 {
+		fmt.Fprintf(console, "%s No known advisories affect the pinned versions\n", theme.OK)
+	}
+	return result
+}
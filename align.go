@@ -0,0 +1,271 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SkewVersion is one of the versions an action is pinned to
+type SkewVersion struct {
+	// Ref is the pinned SHA, digest or tag
+	Ref string `json:"ref"`
+	// Tag is the tag named in the pin comment, when there is one
+	Tag string `json:"tag,omitempty"`
+	// Locations lists the references using this version
+	Locations []SkewLocation `json:"locations"`
+}
+
+// SkewLocation is a reference to a skewed action
+type SkewLocation struct {
+	Workflow string `json:"workflow"`
+	Line     int    `json:"line"`
+}
+
+// String formats the location as file:line
+func (l SkewLocation) String() string {
+	return fmt.Sprintf("%s:%d", l.Workflow, l.Line)
+}
+
+// VersionSkew is an action pinned to different versions across workflows
+type VersionSkew struct {
+	Repo     string        `json:"repo"`
+	Versions []SkewVersion `json:"versions"`
+}
+
+// skewKey identifies the repository of an action, so that sub-actions such
+// as actions/cache/save are expected to share the version of actions/cache
+func skewKey(action ActionInfo) string {
+	if action.Docker {
+		return action.Repo
+	}
+	owner, repo, _ := splitActionPath(action.Repo)
+	return owner + "/" + repo
+}
+
+// pinnedVersion returns the commit or digest an action is pinned to, or its
+// ref when it was not resolved
+func pinnedVersion(action ActionInfo) string {
+	if action.CurrentSHA != "" {
+		return action.CurrentSHA
+	}
+	return action.CurrentRef
+}
+
+// findVersionSkew returns the actions pinned to more than one version,
+// ordered by repository with the most used version first
+func findVersionSkew(actions WorkflowActions) []VersionSkew {
+	versions := make(map[string]map[string]*SkewVersion)
+	for _, action := range sortedActions(actions) {
+		key := skewKey(action)
+		if versions[key] == nil {
+			versions[key] = make(map[string]*SkewVersion)
+		}
+		pin := pinnedVersion(action)
+		version, ok := versions[key][pin]
+		if !ok {
+			version = &SkewVersion{Ref: action.CurrentRef}
+			if action.Pinned() {
+				version.Tag = commentTag(action.OriginalLine, action.Repo)
+			}
+			versions[key][pin] = version
+		}
+		version.Locations = append(version.Locations, SkewLocation{Workflow: action.WorkflowFile, Line: action.Line})
+	}
+
+	var skews []VersionSkew
+	for repo, byPin := range versions {
+		if len(byPin) < 2 {
+			continue
+		}
+		skew := VersionSkew{Repo: repo}
+		for _, version := range byPin {
+			skew.Versions = append(skew.Versions, *version)
+		}
+		sort.Slice(skew.Versions, func(i, j int) bool {
+			if len(skew.Versions[i].Locations) != len(skew.Versions[j].Locations) {
+				return len(skew.Versions[i].Locations) > len(skew.Versions[j].Locations)
+			}
+			return skew.Versions[i].Ref < skew.Versions[j].Ref
+		})
+		skews = append(skews, skew)
+	}
+	sort.Slice(skews, func(i, j int) bool {
+		return skews[i].Repo < skews[j].Repo
+	})
+	return skews
+}
+
+// printVersionSkew reports the actions pinned to different versions
+func printVersionSkew(skews []VersionSkew) {
+	if len(skews) == 0 || !ruleEnabled(ruleVersionSkew) {
+		return
+	}
+
+	fmt.Fprintf(console, "\n%s Actions pinned to different versions (%d) %s:\n", severityStatus(ruleVersionSkew), len(skews), ruleTag(ruleVersionSkew))
+	for _, skew := range skews {
+		fmt.Fprintf(console, "  %s:\n", skew.Repo)
+		for _, version := range skew.Versions {
+			label := version.Ref
+			if shaRegex.MatchString(label) || digestRegex.MatchString(label) {
+				label = shortPin(label)
+			}
+			if version.Tag != "" {
+				label += " (" + version.Tag + ")"
+			}
+			locations := make([]string, 0, len(version.Locations))
+			for _, location := range version.Locations {
+				locations = append(locations, location.String())
+			}
+			fmt.Fprintf(console, "    %s: %s\n", label, strings.Join(locations, ", "))
+		}
+	}
+	fmt.Fprintf(console, "  💡 Run %s align to pin every occurrence to the same version\n", programName)
+}
+
+// alignTarget points action at the commit or digest ref resolves to
+func alignTarget(forge Forge, registry *registryClient, action *ActionInfo, ref string) error {
+	var target string
+	var err error
+	if action.Docker {
+		target, err = registry.Digest(strings.TrimPrefix(action.Repo, dockerPrefix), ref)
+	} else {
+		owner, repo, _ := splitActionPath(action.Repo)
+		target, err = forge.ResolveRef(owner, repo, ref)
+	}
+	if err != nil {
+		return err
+	}
+	action.LatestTag = ref
+	action.LatestSHA = target
+	action.ReleaseDate = ""
+	return nil
+}
+
+// setupAlign registers the flags of the align command and returns its runner
+func setupAlign(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	to := fs.String("to", "", "Align to this `tag` instead of the latest release")
+	fs.BoolVar(&autoApprove, "yes", false, "Apply without prompting")
+
+	return func(args []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(nil, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Without arguments every action pinned to several versions is aligned
+		skewed := make(map[string]bool)
+		for _, skew := range findVersionSkew(actions) {
+			skewed[skew.Repo] = true
+		}
+		selected := make(WorkflowActions)
+		for workflow, actionList := range actions {
+			for _, action := range actionList {
+				match := len(args) == 0 && skewed[skewKey(action)]
+				for _, pattern := range args {
+					match = match || matchesActionPattern(pattern, action) || pattern == skewKey(action)
+				}
+				if match {
+					selected[workflow] = append(selected[workflow], action)
+				}
+			}
+		}
+		if len(selected) == 0 {
+			fmt.Fprintf(console, "%s Every action is pinned to a single version\n", theme.OK)
+			return
+		}
+
+		forge := forgeFor(NewGitHubClient(), cfg, selected)
+		if *to == "" {
+			checkForUpdates(forge, selected, cfg)
+		} else {
+			fmt.Fprintf(console, "Resolving %s...\n", *to)
+			var registry *registryClient
+			for workflow, actionList := range selected {
+				for i := range actionList {
+					action := &actionList[i]
+					if action.Docker && registry == nil {
+						registry = newRegistryClient()
+					}
+					if err := alignTarget(forge, registry, action, *to); err != nil {
+						action.Error = classifyError(err)
+						fmt.Fprintf(console, "  %s %s: %v\n", theme.Error, action.Repo, action.Error)
+					}
+				}
+				selected[workflow] = actionList
+			}
+		}
+
+		if err := alignActions(selected); err != nil {
+			fmt.Fprintf(console, "Error aligning actions: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// alignActions rewrites every selected reference that is not pinned to its
+// target, after one confirmation for all files. Like a group update, either
+// every file is aligned or all are restored from their backups.
+func alignActions(actions WorkflowActions) error {
+	workflows := make([]string, 0, len(actions))
+	changes := 0
+	for workflow, actionList := range actions {
+		pending := false
+		for i := range actionList {
+			action := &actionList[i]
+			action.NeedsUpdate = action.Error == nil && action.LatestSHA != "" && action.CurrentRef != action.LatestSHA
+			pending = pending || action.NeedsUpdate
+			if action.NeedsUpdate {
+				changes++
+			}
+		}
+		if pending {
+			workflows = append(workflows, workflow)
+		}
+	}
+	sort.Strings(workflows)
+
+	if changes == 0 {
+		fmt.Fprintf(console, "\n%s Every selected reference is already aligned\n", theme.OK)
+		return nil
+	}
+
+	fmt.Fprintln(console, "\n🎯 Aligning references:")
+	for _, workflow := range workflows {
+		for _, action := range actions[workflow] {
+			if action.NeedsUpdate {
+				fmt.Fprintf(console, "  %s %s:%d %s: %s → %s (%s)\n", theme.Update, workflow, action.Line, action.Repo,
+					action.CurrentRef, action.LatestTag, shortPin(action.LatestSHA))
+			}
+		}
+	}
+	if !promptForConfirmation(fmt.Sprintf("Align %d reference(s) in %d file(s)?", changes, len(workflows))) {
+		fmt.Fprintf(console, "%s No files were changed\n", theme.Skipped)
+		return nil
+	}
+
+	backupFiles, err := createBackups(workflows)
+	if err != nil {
+		return err
+	}
+	for i, workflow := range workflows {
+		if err := updateWorkflowFile(workflow, actions[workflow]); err != nil {
+			for _, restore := range workflows[:i+1] {
+				if restoreErr := copyFile(backupFiles[restore], restore); restoreErr != nil {
+					fmt.Fprintf(console, "  %s Failed to restore backup of %s: %v\n", theme.Error, restore, restoreErr)
+				}
+			}
+			return fmt.Errorf("failed to update %s, all files restored: %w", workflow, err)
+		}
+		fmt.Fprintf(console, "  %s Updated %s\n", theme.OK, workflow)
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Scopes of the patterns allowed-actions derives
+const (
+	// patternScopeOwner allows every action of the owners in use
+	patternScopeOwner = "owner"
+	// patternScopeAction allows every version of the actions in use
+	patternScopeAction = "action"
+	// patternScopeRef allows the refs in use only
+	patternScopeRef = "ref"
+)
+
+// patternScopes lists the valid --scope values
+var patternScopes = []string{patternScopeOwner, patternScopeAction, patternScopeRef}
+
+// allowedActionsPolicy is the body of the selected actions API, which
+// organization owners apply with PUT orgs/{org}/actions/permissions/selected-actions
+type allowedActionsPolicy struct {
+	GitHubOwnedAllowed bool     `json:"github_owned_allowed"`
+	VerifiedAllowed    bool     `json:"verified_allowed"`
+	PatternsAllowed    []string `json:"patterns_allowed"`
+}
+
+// derivePatterns returns the allowed actions policy admitting every action
+// in use. GitHub-owned actions are admitted by github_owned_allowed, and
+// the actions of org, which GitHub always allows, are left out.
+func derivePatterns(actions WorkflowActions, scope, org string) allowedActionsPolicy {
+	policy := allowedActionsPolicy{PatternsAllowed: []string{}}
+	seen := make(map[string]bool)
+	for _, action := range sortedByRepo(actions) {
+		if action.Docker {
+			continue
+		}
+		owner, _, _ := splitActionPath(action.Repo)
+		if slices.Contains(firstPartyOwners, strings.ToLower(owner)) {
+			policy.GitHubOwnedAllowed = true
+			continue
+		}
+		if org != "" && strings.EqualFold(owner, org) {
+			continue
+		}
+		var pattern string
+		switch scope {
+		case patternScopeOwner:
+			pattern = owner + "/*"
+		case patternScopeRef:
+			pattern = action.Repo + "@" + action.CurrentRef
+		default:
+			pattern = action.Repo + "@*"
+		}
+		if !seen[strings.ToLower(pattern)] {
+			seen[strings.ToLower(pattern)] = true
+			policy.PatternsAllowed = append(policy.PatternsAllowed, pattern)
+		}
+	}
+	sort.Strings(policy.PatternsAllowed)
+	return policy
+}
+
+// policyPatternRegex compiles a pattern of the allowed actions policy, in
+// which * matches any characters, including slashes. Patterns are case
+// insensitive.
+func policyPatternRegex(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile(`(?i)^` + strings.Join(parts, ".*") + `$`)
+}
+
+// policyMatch returns the first pattern matching owner/repo@ref. Patterns
+// starting with ! block the actions they match instead.
+func policyMatch(patterns []string, uses string, blocking bool) string {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		negated := strings.HasPrefix(pattern, "!")
+		if negated != blocking {
+			continue
+		}
+		if policyPatternRegex(strings.TrimPrefix(pattern, "!")).MatchString(uses) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// policyBlockReason returns why the allowed actions policy of org blocks an
+// action, or "" when it is allowed. Actions of org itself are local and
+// always allowed; verified creators are looked up once per owner.
+func policyBlockReason(gc *GitHubClient, policy *githubActionsPolicy, org string, action ActionInfo, verified map[string]bool) string {
+	owner, _, _ := splitActionPath(action.Repo)
+	uses := action.Repo + "@" + action.CurrentRef
+	local := strings.EqualFold(owner, org)
+
+	if policy.Disabled {
+		return "GitHub Actions is disabled"
+	}
+	if policy.SHAPinningRequired && !action.Pinned() {
+		return "the policy requires actions to be pinned to a full-length commit SHA"
+	}
+	if pattern := policyMatch(policy.Patterns, uses, true); pattern != "" && !local {
+		return "blocked by the pattern " + pattern
+	}
+	switch policy.AllowedActions {
+	case "local_only":
+		if !local {
+			return "only actions of the organization are allowed"
+		}
+	case "selected":
+		if local || policyMatch(policy.Patterns, uses, false) != "" {
+			return ""
+		}
+		firstParty := slices.Contains(firstPartyOwners, strings.ToLower(owner))
+		if firstParty {
+			if policy.GitHubOwnedAllowed {
+				return ""
+			}
+			return "GitHub-owned actions are not allowed and no pattern matches"
+		}
+		if policy.VerifiedAllowed {
+			isVerified, ok := verified[strings.ToLower(owner)]
+			if !ok {
+				isVerified = publisherStatus(gc, owner) == PublisherVerified
+				verified[strings.ToLower(owner)] = isVerified
+			}
+			if isVerified {
+				return ""
+			}
+		}
+		return "no pattern of the policy matches"
+	}
+	return ""
+}
+
+// describePolicy summarizes an allowed actions policy for the console
+func describePolicy(policy *githubActionsPolicy) string {
+	if policy.Disabled {
+		return "GitHub Actions is disabled"
+	}
+	var description string
+	switch policy.AllowedActions {
+	case "all":
+		description = "all actions allowed"
+	case "local_only":
+		description = "only local actions allowed"
+	case "selected":
+		var allowed []string
+		if policy.GitHubOwnedAllowed {
+			allowed = append(allowed, "GitHub-owned")
+		}
+		if policy.VerifiedAllowed {
+			allowed = append(allowed, "verified creators")
+		}
+		allowed = append(allowed, fmt.Sprintf("%d pattern(s)", len(policy.Patterns)))
+		description = "selected actions allowed: " + strings.Join(allowed, ", ")
+	default:
+		description = "allowed actions " + policy.AllowedActions
+	}
+	if policy.SHAPinningRequired {
+		description += ", SHA pinning required"
+	}
+	return description
+}
+
+// policyOwner returns the organization whose policy applies, from --org,
+// GITHUB_REPOSITORY_OWNER or the origin remote
+func policyOwner(org string) string {
+	if org != "" {
+		return org
+	}
+	if owner := os.Getenv("GITHUB_REPOSITORY_OWNER"); owner != "" {
+		return owner
+	}
+	owner, _, _ := strings.Cut(originRepository(), "/")
+	return owner
+}
+
+// setupAllowedActions registers the flags of the allowed-actions command
+// and returns its runner
+func setupAllowedActions(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	scope := fs.String("scope", patternScopeAction, "Allow each `scope` in use: "+strings.Join(patternScopes, ", "))
+	org := fs.String("org", "", "Leave out the actions of this `org`, which its policy always allows")
+	jsonOutput := fs.Bool("json", false, "Write the policy as the JSON body of the selected actions API to stdout")
+
+	return func(args []string) {
+		if !slices.Contains(patternScopes, *scope) {
+			fmt.Fprintf(console, "Error: --scope must be one of %s, got %q\n", strings.Join(patternScopes, ", "), *scope)
+			os.Exit(2)
+		}
+		if *jsonOutput {
+			console = os.Stderr
+		}
+		_, scanOpts := mustLoadScanConfig(opts)
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+		policy := derivePatterns(actions, *scope, *org)
+
+		if *jsonOutput {
+			data, err := marshalIndented(policy)
+			if err != nil {
+				fmt.Fprintf(console, "Error encoding the policy: %v\n", err)
+				os.Exit(1)
+			}
+			_, _ = os.Stdout.Write(data)
+			return
+		}
+		fmt.Fprintf(console, "\n🛂 Allowed actions policy for the actions in use (%d pattern(s)):\n", len(policy.PatternsAllowed))
+		if policy.GitHubOwnedAllowed {
+			fmt.Fprintln(console, "  Allow actions created by GitHub")
+		}
+		for _, pattern := range policy.PatternsAllowed {
+			fmt.Fprintf(console, "  %s\n", pattern)
+		}
+		fmt.Fprintln(console, "\n  💡 Paste the patterns, separated by commas, under Settings → Actions → General → Policies,")
+		fmt.Fprintf(console, "     or apply the --json output with gh api -X PUT orgs/<org>/actions/permissions/selected-actions --input <file>\n")
+	}
+}
+
+// setupAllowedActionsCheck registers the flags of the allowed-actions
+// check command and returns its runner
+func setupAllowedActionsCheck(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	org := fs.String("org", "", "Check against the policy of this `org`, by default GITHUB_REPOSITORY_OWNER or the owner of the origin remote")
+	repoFlag := fs.String("repo", "", "Check against the policy of this `owner/repo` instead, which can only be stricter than its organization's")
+
+	return func(args []string) {
+		owner, repo := policyOwner(*org), ""
+		if *repoFlag != "" {
+			var ok bool
+			owner, repo, ok = strings.Cut(*repoFlag, "/")
+			if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+				fmt.Fprintf(console, "Error: --repo must be owner/repo, got %q\n", *repoFlag)
+				os.Exit(2)
+			}
+		}
+		if owner == "" {
+			fmt.Fprintln(console, "Error: the organization is unknown; set --org")
+			os.Exit(2)
+		}
+		_, scanOpts := mustLoadScanConfig(opts)
+		target := owner
+		if repo != "" {
+			target = owner + "/" + repo
+		}
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		gc := NewGitHubClient()
+		fmt.Fprintf(console, "\n🛂 Checking the actions against the allowed actions policy of %s...\n", target)
+		policy, err := gc.api.ActionsPolicy(gc.ctx, owner, repo)
+		if err != nil {
+			fmt.Fprintf(console, "Error: failed to read the allowed actions policy of %s: %v\n", target, err)
+			if kind := errorKindOf(err); kind == ErrorKindNotFound || kind == ErrorKindPermissionDenied {
+				fmt.Fprintln(console, "     💡 Reading the policy requires an organization owner's token, or admin on the repository for --repo")
+			}
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "  Policy: %s\n", describePolicy(policy))
+
+		if !ruleEnabled(ruleBlockedByPolicy) {
+			return
+		}
+		failed, blocked := 0, 0
+		verified := make(map[string]bool)
+		for _, action := range sortedActions(actions) {
+			// Container images are not governed by the policy
+			if action.Docker {
+				continue
+			}
+			reason := policyBlockReason(gc, policy, owner, action, verified)
+			if reason == "" {
+				continue
+			}
+			blocked++
+			if ruleFails(ruleBlockedByPolicy) {
+				failed++
+			}
+			fmt.Fprintf(console, "  %s %s:%d %s: %s %s\n", severityStatus(ruleBlockedByPolicy), action.WorkflowFile, action.Line, action.reference(), reason, ruleTag(ruleBlockedByPolicy))
+		}
+
+		if blocked == 0 {
+			fmt.Fprintf(console, "%s The policy of %s allows every action in use\n", theme.OK, target)
+			return
+		}
+		fmt.Fprintf(console, "     💡 Runs using these actions fail before their jobs start. Ask an owner of %s to allow them, e.g. with the patterns of %s allowed-actions\n", target, programName)
+		if failed > 0 {
+			fmt.Fprintf(console, "Error: %d action(s) are blocked by the allowed actions policy of %s\n", blocked, target)
+			os.Exit(1)
+		}
+	}
+}
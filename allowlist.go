@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AllowlistConfig restricts the actions workflows may use to trusted owners,
+// such as "actions/*", "github/*" and "ourorg/*"
+type AllowlistConfig struct {
+	// Actions are globs of the trusted action paths; an empty list disables
+	// the allowlist
+	Actions []string `yaml:"actions,omitempty"`
+	// Exceptions allow individual actions outside the list
+	Exceptions []AllowException `yaml:"exceptions,omitempty"`
+}
+
+// AllowException allows actions outside the allowlist, e.g. while a review
+// of the publisher is pending
+type AllowException struct {
+	// Uses is a glob of the allowed action paths
+	Uses string `yaml:"uses"`
+	// Reason records why the action is allowed
+	Reason string `yaml:"reason"`
+	// Expires is the last day the exception applies, as YYYY-MM-DD; empty
+	// for exceptions that do not expire
+	Expires string `yaml:"expires,omitempty"`
+}
+
+// enabled reports whether an allowlist is configured
+func (a AllowlistConfig) enabled() bool {
+	return len(a.Actions) > 0
+}
+
+// validate checks the patterns and exceptions of the allowlist
+func (a AllowlistConfig) validate() error {
+	if err := validateGlobs(a.Actions); err != nil {
+		return err
+	}
+	if len(a.Exceptions) > 0 && !a.enabled() {
+		return errors.New("exceptions are listed without allowed actions")
+	}
+	for i, exception := range a.Exceptions {
+		if exception.Uses == "" {
+			return fmt.Errorf("exception %d has no uses pattern", i+1)
+		}
+		if err := validateGlobs([]string{exception.Uses}); err != nil {
+			return err
+		}
+		if exception.Reason == "" {
+			return fmt.Errorf("exception for %s has no reason", exception.Uses)
+		}
+		if exception.Expires != "" {
+			if _, err := time.Parse(time.DateOnly, exception.Expires); err != nil {
+				return fmt.Errorf("exception for %s expires on %q, expected YYYY-MM-DD", exception.Uses, exception.Expires)
+			}
+		}
+	}
+	return nil
+}
+
+// expired reports whether the exception no longer applies at now
+func (e AllowException) expired(now time.Time) bool {
+	if e.Expires == "" {
+		return false
+	}
+	last, err := time.Parse(time.DateOnly, e.Expires)
+	return err == nil && !now.UTC().Before(last.AddDate(0, 0, 1))
+}
+
+// trusts reports whether the allowlist accepts an action path, and returns
+// the exception accepting it when it is outside the list. Owners are case
+// insensitive, so paths and patterns are compared in lower case.
+func (a AllowlistConfig) trusts(repo string, now time.Time) (bool, *AllowException) {
+	repo = strings.ToLower(repo)
+	patterns := make([]string, len(a.Actions))
+	for i, pattern := range a.Actions {
+		patterns[i] = strings.ToLower(pattern)
+	}
+	if matchesActionGlobs(patterns, repo) {
+		return true, nil
+	}
+	for i, exception := range a.Exceptions {
+		if !exception.expired(now) && matchesActionGlobs([]string{strings.ToLower(exception.Uses)}, repo) {
+			return true, &a.Exceptions[i]
+		}
+	}
+	return false, nil
+}
+
+// markUntrusted sets Untrusted on the actions outside the allowlist and
+// returns their number. Container images and local actions are not
+// governed by the allowlist.
+func markUntrusted(cfg *Config, actions WorkflowActions) int {
+	if !cfg.Allowlist.enabled() {
+		return 0
+	}
+	now := time.Now()
+	untrusted := 0
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker {
+				continue
+			}
+			if trusted, _ := cfg.Allowlist.trusts(action.Repo, now); !trusted {
+				action.Untrusted = true
+				untrusted++
+			}
+		}
+	}
+	return untrusted
+}
+
+// checkAllowlist reports the actions outside the allowlist and the
+// exceptions in use, and returns an error when any action is not trusted.
+// Nothing is checked without an allowlist or when the rule is disabled.
+func checkAllowlist(cfg *Config, actions WorkflowActions) error {
+	if !cfg.Allowlist.enabled() || !ruleEnabled(ruleUntrusted) {
+		return nil
+	}
+	fmt.Fprintln(console, "\n🏷️  Verifying actions come from trusted owners...")
+
+	untrusted := markUntrusted(cfg, actions)
+	now := time.Now()
+	excepted := make(map[string]bool)
+	for _, action := range sortedActions(actions) {
+		if action.Docker {
+			continue
+		}
+		if action.Untrusted {
+			fmt.Fprintf(console, "  %s %s:%d %s is not on the allowlist %s\n", severityStatus(ruleUntrusted), action.WorkflowFile, action.Line, action.Repo, ruleTag(ruleUntrusted))
+			continue
+		}
+		if _, exception := cfg.Allowlist.trusts(action.Repo, now); exception != nil && !excepted[action.Repo] {
+			excepted[action.Repo] = true
+			description := exception.Reason
+			if exception.Expires != "" {
+				description += ", until " + exception.Expires
+			}
+			fmt.Fprintf(console, "  %s %s is allowed by an exception: %s\n", theme.Skipped, action.Repo, description)
+		}
+	}
+	for _, exception := range cfg.Allowlist.Exceptions {
+		if exception.expired(now) {
+			fmt.Fprintf(console, "  %s The exception for %s expired on %s\n", theme.Warning, exception.Uses, exception.Expires)
+		}
+	}
+
+	if untrusted > 0 {
+		fmt.Fprintln(console, "     💡 Replace the actions, or list them under allowlist.exceptions with a reason once reviewed")
+		return fmt.Errorf("%d action(s) are not on the allowlist of trusted owners", untrusted)
+	}
+	fmt.Fprintf(console, "%s All actions come from trusted owners\n", theme.OK)
+	return nil
+}
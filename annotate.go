@@ -0,0 +1,187 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// bestTag picks the tag that names a commit most precisely: version tags
+// before others, then the one with the most components, so v4.2.1 is
+// preferred over the moving v4 tag pointing at the same commit
+func bestTag(tags []string) string {
+	best := ""
+	for _, tag := range tags {
+		if best == "" {
+			best = tag
+			continue
+		}
+		tagVersion, bestVersion := versionTagRegex.MatchString(tag), versionTagRegex.MatchString(best)
+		switch {
+		case tagVersion != bestVersion:
+			if tagVersion {
+				best = tag
+			}
+		case strings.Count(tag, ".") != strings.Count(best, "."):
+			if strings.Count(tag, ".") > strings.Count(best, ".") {
+				best = tag
+			}
+		case compareVersionTags(tag, best) > 0:
+			best = tag
+		}
+	}
+	return best
+}
+
+// tagResolver looks up the tags of pinned commits, once per repository and commit
+type tagResolver struct {
+	forge Forge
+	cache map[string]string
+}
+
+// newTagResolver returns a resolver looking tags up on forge
+func newTagResolver(forge Forge) *tagResolver {
+	return &tagResolver{forge: forge, cache: make(map[string]string)}
+}
+
+// tagFor returns the most precise tag pointing at the commit an action is
+// pinned to, or "" when no tag does
+func (r *tagResolver) tagFor(action ActionInfo) (string, error) {
+	owner, repo, _ := splitActionPath(action.Repo)
+	key := owner + "/" + repo + "@" + action.CurrentRef
+	if tag, ok := r.cache[key]; ok {
+		return tag, nil
+	}
+	tags, err := r.forge.TagsAt(owner, repo, action.CurrentRef)
+	if err != nil {
+		return "", err
+	}
+	// CodeQL bundle tags mirror the action versions
+	var usable []string
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "codeql-bundle-") {
+			usable = append(usable, tag)
+		}
+	}
+	tag := bestTag(usable)
+	r.cache[key] = tag
+	return tag, nil
+}
+
+// resolvesTo reports whether tag still points at the commit an action is
+// pinned to
+func (r *tagResolver) resolvesTo(action ActionInfo, tag string) (bool, error) {
+	owner, repo, _ := splitActionPath(action.Repo)
+	sha, err := r.forge.ResolveRef(owner, repo, tag)
+	if errorKindOf(err) == ErrorKindNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(sha, action.CurrentRef), nil
+}
+
+// needsAnnotation reports whether an action is pinned to a commit SHA
+// without a comment naming its version
+func needsAnnotation(action ActionInfo) bool {
+	if action.Docker || !action.Pinned() {
+		return false
+	}
+	_, comment := splitTrailingComment(action.OriginalLine)
+	return strings.TrimSpace(strings.TrimLeft(comment, "#!")) == ""
+}
+
+// setupAnnotate registers the flags of the annotate command and returns its runner
+func setupAnnotate(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	fs.BoolVar(&autoApprove, "yes", false, "Write the comments without prompting")
+
+	return func(args []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		bare := make(WorkflowActions)
+		for workflow, actionList := range actions {
+			for _, action := range actionList {
+				if needsAnnotation(action) {
+					bare[workflow] = append(bare[workflow], action)
+				}
+			}
+		}
+		if len(bare) == 0 {
+			fmt.Fprintf(console, "%s Every SHA pin has a version comment\n", theme.OK)
+			return
+		}
+		if !pinComment.enabled() {
+			fmt.Fprintf(console, "Error: pin_comment is empty, so no comments are written\n")
+			os.Exit(1)
+		}
+
+		if err := annotateActions(newTagResolver(forgeFor(NewGitHubClient(), cfg, bare)), bare); err != nil {
+			fmt.Fprintf(console, "Error annotating actions: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// annotateActions looks up the tag of every bare SHA pin and, after one
+// confirmation, appends it as a comment. The SHAs are left unchanged.
+func annotateActions(resolver *tagResolver, actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n🏷️  Looking up the tags of bare SHA pins...")
+
+	workflows := sortedWorkflows(actions)
+
+	annotated := 0
+	var changed []string
+	for _, workflow := range workflows {
+		before := annotated
+		actionList := actions[workflow]
+		for i := range actionList {
+			action := &actionList[i]
+			tag, err := resolver.tagFor(*action)
+			switch {
+			case err != nil:
+				action.Error = classifyError(err)
+				fmt.Fprintf(console, "  %s %s:%d %s: %v\n", theme.Error, workflow, action.Line, action.Repo, action.Error)
+			case tag == "":
+				fmt.Fprintf(console, "  %s %s:%d %s@%s: no tag points at this commit\n", theme.Skipped, workflow, action.Line, action.Repo, shortPin(action.CurrentRef))
+			default:
+				// Rewriting to the same SHA only adds the comment
+				action.LatestTag = tag
+				action.LatestSHA = action.CurrentRef
+				action.NeedsUpdate = true
+				annotated++
+				fmt.Fprintf(console, "  %s %s:%d %s@%s is %s\n", theme.Update, workflow, action.Line, action.Repo, shortPin(action.CurrentRef), tag)
+			}
+		}
+		if annotated > before {
+			changed = append(changed, workflow)
+		}
+	}
+
+	if annotated == 0 {
+		fmt.Fprintf(console, "\n%s No comments to add\n", theme.OK)
+		return nil
+	}
+	if !promptForConfirmation(fmt.Sprintf("Add %d version comment(s)?", annotated)) {
+		fmt.Fprintf(console, "%s No files were changed\n", theme.Skipped)
+		return nil
+	}
+
+	for _, workflow := range changed {
+		if err := updateWorkflowFile(workflow, actions[workflow]); err != nil {
+			return fmt.Errorf("failed to update %s: %w", workflow, err)
+		}
+	}
+	fmt.Fprintf(console, "\n%s Annotated %d SHA pin(s)\n", theme.OK, annotated)
+	return nil
+}
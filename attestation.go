@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+const (
+	// inTotoPayloadType is the DSSE payload type of in-toto statements
+	inTotoPayloadType = "application/vnd.in-toto+json"
+
+	// Predicate types named in the output
+	slsaProvenancePrefix = "https://slsa.dev/provenance/"
+	releasePredicateType = "https://in-toto.io/attestation/release/v0.1"
+)
+
+// Attestation is the verified attestation of the commit an action is
+// pinned to
+type Attestation struct {
+	// PredicateType is the kind of attestation, e.g. SLSA provenance
+	PredicateType string `json:"predicate_type"`
+	// Signer is the workflow identity of the signing certificate
+	Signer string `json:"signer"`
+}
+
+// describe names the kind of attestation for the console
+func (a *Attestation) describe() string {
+	switch {
+	case strings.HasPrefix(a.PredicateType, slsaProvenancePrefix):
+		return "SLSA provenance"
+	case a.PredicateType == releasePredicateType:
+		return "release attestation"
+	}
+	return a.PredicateType
+}
+
+// sigstoreBundle is the part of a Sigstore bundle needed to check its
+// signature
+type sigstoreBundle struct {
+	VerificationMaterial struct {
+		Certificate *struct {
+			RawBytes string `json:"rawBytes"`
+		} `json:"certificate"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes string `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain"`
+	} `json:"verificationMaterial"`
+	DSSEEnvelope *struct {
+		Payload     string `json:"payload"`
+		PayloadType string `json:"payloadType"`
+		Signatures  []struct {
+			Sig string `json:"sig"`
+		} `json:"signatures"`
+	} `json:"dsseEnvelope"`
+}
+
+// inTotoStatement is the part of an in-toto statement naming its subjects
+type inTotoStatement struct {
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// bundleCertificate returns the leaf signing certificate of a bundle
+func bundleCertificate(bundle sigstoreBundle) (*x509.Certificate, error) {
+	var raw string
+	switch material := bundle.VerificationMaterial; {
+	case material.Certificate != nil:
+		raw = material.Certificate.RawBytes
+	case material.X509CertificateChain != nil && len(material.X509CertificateChain.Certificates) > 0:
+		raw = material.X509CertificateChain.Certificates[0].RawBytes
+	default:
+		return nil, errors.New("bundle has no signing certificate")
+	}
+	der, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate encoding: %w", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// dssePAE returns the pre-authentication encoding DSSE signatures are made
+// over
+func dssePAE(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// verifySignature checks a signature over message with the public key of
+// cert
+func verifySignature(cert *x509.Certificate, message, signature []byte) error {
+	return verifyKeySignature(cert.PublicKey, message, signature)
+}
+
+// verifyKeySignature checks a signature over message with a public key.
+// Ed25519 signs the message itself, the other algorithms its digest.
+func verifyKeySignature(publicKey crypto.PublicKey, message, signature []byte) error {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		var h hash.Hash = sha256.New()
+		if key.Curve.Params().BitSize > 256 {
+			h = sha512.New384()
+		}
+		h.Write(message)
+		if !ecdsa.VerifyASN1(key, h.Sum(nil), signature) {
+			return errors.New("signature does not match the key")
+		}
+		return nil
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, message, signature) {
+			return errors.New("signature does not match the key")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported signing key %T", publicKey)
+}
+
+// workflowIdentity returns the workflow URI a signing certificate was
+// issued to
+func workflowIdentity(cert *x509.Certificate) string {
+	if len(cert.URIs) == 0 {
+		return ""
+	}
+	return cert.URIs[0].String()
+}
+
+// verifyBundle checks a Sigstore bundle attests commit and is signed by a
+// workflow of owner/repo. The signature is checked against the certificate
+// in the bundle; the certificate chain and transparency log entry are left to
+// gh attestation verify.
+func verifyBundle(raw json.RawMessage, owner, repo, commit string) (*Attestation, error) {
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(raw, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	envelope := bundle.DSSEEnvelope
+	if envelope == nil || len(envelope.Signatures) == 0 {
+		return nil, errors.New("bundle has no signed envelope")
+	}
+	if envelope.PayloadType != inTotoPayloadType {
+		return nil, fmt.Errorf("unexpected payload type %s", envelope.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload encoding: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(envelope.Signatures[0].Sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	cert, err := bundleCertificate(bundle)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(cert, dssePAE(envelope.PayloadType, payload), signature); err != nil {
+		return nil, err
+	}
+
+	signer := workflowIdentity(cert)
+	if !strings.HasPrefix(strings.ToLower(signer), strings.ToLower("https://github.com/"+owner+"/"+repo+"/")) {
+		return nil, fmt.Errorf("signed by %q, not a workflow of %s/%s", signer, owner, repo)
+	}
+
+	var statement inTotoStatement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("invalid in-toto statement: %w", err)
+	}
+	for _, subject := range statement.Subject {
+		if strings.EqualFold(subject.Digest["sha1"], commit) {
+			return &Attestation{PredicateType: statement.PredicateType, Signer: signer}, nil
+		}
+	}
+	return nil, fmt.Errorf("statement does not name commit %s", shortPin(commit))
+}
+
+// invalidAttestationError reports attestations that exist but do not
+// verify, as opposed to failed lookups
+type invalidAttestationError struct {
+	err error
+}
+
+func (e *invalidAttestationError) Error() string { return e.err.Error() }
+func (e *invalidAttestationError) Unwrap() error { return e.err }
+
+// lookupAttestation returns the first attestation of the commit that
+// verifies, nil when the commit has none, and an invalidAttestationError
+// when every attestation fails to verify
+func lookupAttestation(gc *GitHubClient, owner, repo, commit string) (*Attestation, error) {
+	bundles, err := gc.api.Attestations(gc.ctx, owner, repo, "sha1:"+commit)
+	if errorKindOf(err) == ErrorKindNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, bundle := range bundles {
+		attestation, err := verifyBundle(bundle, owner, repo, commit)
+		if err == nil {
+			return attestation, nil
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return nil, &invalidAttestationError{err: lastErr}
+	}
+	return nil, nil
+}
+
+// checkAttestations looks up the attestations of the commit every action is
+// pinned to and reports which have verifiable provenance. Attestations that
+// exist but do not verify are stored as findings. It returns the number of
+// pinned commits with verified attestations.
+func checkAttestations(gc *GitHubClient, actions WorkflowActions) int {
+	fmt.Fprintln(console, "\n🔏 Verifying attestations of pinned commits...")
+
+	type lookup struct {
+		attestation *Attestation
+		err         error
+	}
+	looked := make(map[string]lookup)
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker || !action.Pinned() {
+				continue
+			}
+			owner, repo, _ := splitActionPath(action.Repo)
+			key := strings.ToLower(owner+"/"+repo) + "@" + action.CurrentSHA
+			found, ok := looked[key]
+			if !ok {
+				found.attestation, found.err = lookupAttestation(gc, owner, repo, action.CurrentSHA)
+				looked[key] = found
+			}
+			action.Attestation = found.attestation
+			var invalid *invalidAttestationError
+			if errors.As(found.err, &invalid) {
+				action.AttestationError = invalid.Error()
+			}
+		}
+	}
+
+	verified := 0
+	reported := make(map[string]bool)
+	for _, action := range sortedByRepo(actions) {
+		owner, repo, _ := splitActionPath(action.Repo)
+		key := strings.ToLower(owner+"/"+repo) + "@" + action.CurrentSHA
+		found, ok := looked[key]
+		if !ok || reported[key] {
+			continue
+		}
+		reported[key] = true
+		label := owner + "/" + repo + "@" + shortPin(action.CurrentSHA)
+		switch {
+		case action.AttestationError != "":
+			fmt.Fprintf(console, "  %s %s: attestation does not verify: %s %s\n", severityStatus(ruleAttestation), label, action.AttestationError, ruleTag(ruleAttestation))
+		case found.err != nil:
+			fmt.Fprintf(console, "  %s %s: could not look up attestations: %v\n", theme.Warning, label, found.err)
+		case found.attestation == nil:
+			fmt.Fprintf(console, "  %s %s: no attestations\n", theme.Skipped, label)
+		default:
+			verified++
+			fmt.Fprintf(console, "  %s %s: %s signed by %s\n", theme.OK, label, found.attestation.describe(), found.attestation.Signer)
+		}
+	}
+	fmt.Fprintf(console, "🔏 %d of %d pinned commits have verifiable provenance\n", verified, len(looked))
+	return verified
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Bundle entries written by audit --attest
+	attestManifestFile  = "manifest.json"
+	attestScanFile      = "scan-results.json"
+	attestMetadataFile  = "resolved-metadata.json"
+	attestLockFile      = "actions.lock.json"
+	attestPolicyFile    = "policy-evaluation.json"
+	attestConfigFile    = "config/" + defaultConfigFile
+	attestWorkflowsDir  = "workflows/"
+	attestFormatVersion = 1
+)
+
+// PolicyViolation is an action or workflow that fails a policy rule
+type PolicyViolation struct {
+	Workflow string `json:"workflow"`
+	Line     int    `json:"line,omitempty"`
+	Repo     string `json:"repo,omitempty"`
+	Ref      string `json:"ref,omitempty"`
+	Message  string `json:"message"`
+}
+
+// PolicyResult is the outcome of a single policy rule
+type PolicyResult struct {
+	Rule        string            `json:"rule"`
+	Description string            `json:"description"`
+	Required    bool              `json:"required"`
+	Passed      bool              `json:"passed"`
+	Violations  []PolicyViolation `json:"violations"`
+}
+
+// PolicyEvaluation is the outcome of every policy rule. It passes when all
+// required rules pass; advisory rules are reported but do not fail the audit.
+type PolicyEvaluation struct {
+	Passed bool           `json:"passed"`
+	Rules  []PolicyResult `json:"rules"`
+}
+
+// LockEntry records the commit a workflow reference resolved to
+type LockEntry struct {
+	Repo      string   `json:"repo"`
+	Ref       string   `json:"ref"`
+	SHA       string   `json:"sha"`
+	Workflows []string `json:"workflows"`
+	// Provenance records why the reference is pinned to SHA
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// ResolvedMetadata describes what an action resolved to at audit time
+type ResolvedMetadata struct {
+	Repo       string   `json:"repo"`
+	Refs       []string `json:"refs"`
+	LatestTag  string   `json:"latest_tag,omitempty"`
+	LatestSHA  string   `json:"latest_sha,omitempty"`
+	ReleaseURL string   `json:"release_url,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// fileDigest is the SHA-256 of a file
+type fileDigest struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// attestManifest identifies the tool, repository and inputs of an evidence bundle
+type attestManifest struct {
+	FormatVersion int          `json:"format_version"`
+	GeneratedAt   time.Time    `json:"generated_at"`
+	Tool          toolInfo     `json:"tool"`
+	Run           RunMetadata  `json:"run"`
+	Commit        string       `json:"repository_commit,omitempty"`
+	Config        *fileDigest  `json:"config,omitempty"`
+	PolicyPassed  bool         `json:"policy_passed"`
+	Workflows     []fileDigest `json:"workflows"`
+	Files         []fileDigest `json:"files"`
+}
+
+// toolInfo identifies the binary that produced a bundle
+type toolInfo struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// sortedActions returns every action ordered by workflow file and line
+func sortedActions(actions WorkflowActions) []ActionInfo {
+	var all []ActionInfo
+	for _, actionList := range actions {
+		all = append(all, actionList...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].WorkflowFile != all[j].WorkflowFile {
+			return all[i].WorkflowFile < all[j].WorkflowFile
+		}
+		return all[i].Line < all[j].Line
+	})
+	return all
+}
+
+// violationFor describes an action that fails a rule
+func violationFor(action ActionInfo, message string) PolicyViolation {
+	return PolicyViolation{
+		Workflow: action.WorkflowFile,
+		Line:     action.Line,
+		Repo:     action.Repo,
+		Ref:      action.CurrentRef,
+		Message:  message,
+	}
+}
+
+// evaluatePolicy checks the scanned actions against the pinning controls,
+// along with the extra rules evaluated by other checks
+func evaluatePolicy(actions WorkflowActions, extra ...PolicyResult) PolicyEvaluation {
+	pinned := PolicyResult{Rule: "sha-pinned", Description: "Every action is pinned to a full commit SHA", Required: true}
+	resolved := PolicyResult{Rule: "resolvable", Description: "Every action reference resolves to a commit", Required: true}
+	current := PolicyResult{Rule: "up-to-date", Description: "Every action is pinned to its latest release"}
+	permissions := PolicyResult{Rule: "workflow-permissions", Description: "Every workflow declares a top-level permissions block"}
+	consistent := PolicyResult{Rule: "consistent-versions", Description: "Every action is pinned to the same version in all workflows"}
+	forkSafe := PolicyResult{Rule: "fork-pull-requests", Description: "Workflows triggered by pull requests only run pinned third-party actions"}
+
+	for _, action := range sortedActions(actions) {
+		if !action.Pinned() {
+			pinned.Violations = append(pinned.Violations, violationFor(action, "not pinned to "+action.pinKind()))
+		}
+		switch {
+		case action.Error != nil:
+			resolved.Violations = append(resolved.Violations, violationFor(action, action.Error.Error()))
+		case action.NeedsUpdate:
+			current.Violations = append(current.Violations, violationFor(action, "latest release is "+action.LatestTag))
+		}
+	}
+
+	workflows := make([]string, 0, len(actions))
+	for workflow := range actions {
+		// Composite actions have no permissions of their own
+		if !isActionFile(workflow) && isYAMLFile(workflow) {
+			workflows = append(workflows, workflow)
+		}
+	}
+	sort.Strings(workflows)
+	for _, workflow := range workflows {
+		content, err := os.ReadFile(filepath.Clean(workflow))
+		if err != nil || !hasTopLevelPermissions(string(content)) {
+			permissions.Violations = append(permissions.Violations, PolicyViolation{Workflow: workflow, Message: "no top-level permissions block"})
+		}
+	}
+
+	for _, skew := range findVersionSkew(actions) {
+		for _, version := range skew.Versions[1:] {
+			for _, location := range version.Locations {
+				consistent.Violations = append(consistent.Violations, PolicyViolation{
+					Workflow: location.Workflow,
+					Line:     location.Line,
+					Repo:     skew.Repo,
+					Ref:      version.Ref,
+					Message:  "most workflows pin " + skew.Versions[0].Ref,
+				})
+			}
+		}
+	}
+
+	for _, exposure := range findForkExposures(actions) {
+		forkSafe.Violations = append(forkSafe.Violations, PolicyViolation{
+			Workflow: exposure.Workflow,
+			Line:     exposure.Line,
+			Repo:     exposure.Repo,
+			Ref:      exposure.Ref,
+			Message:  "unpinned in a workflow triggered by " + strings.Join(exposure.Triggers, ", "),
+		})
+	}
+
+	evaluation := PolicyEvaluation{Passed: true}
+	for _, result := range append([]PolicyResult{pinned, resolved, current, permissions, consistent, forkSafe}, extra...) {
+		result.Passed = len(result.Violations) == 0
+		if result.Violations == nil {
+			result.Violations = []PolicyViolation{}
+		}
+		if result.Required && !result.Passed {
+			evaluation.Passed = false
+		}
+		evaluation.Rules = append(evaluation.Rules, result)
+	}
+	return evaluation
+}
+
+// buildLockEntries lists the commit every distinct reference resolved to
+func buildLockEntries(actions WorkflowActions) []LockEntry {
+	entries := make(map[string]*LockEntry)
+	for _, action := range sortedActions(actions) {
+		key := action.Repo + "@" + action.CurrentRef
+		entry, ok := entries[key]
+		if !ok {
+			entry = &LockEntry{Repo: action.Repo, Ref: action.CurrentRef, SHA: action.CurrentSHA, Provenance: action.pinProvenance()}
+			entries[key] = entry
+		}
+		if !containsString(entry.Workflows, action.WorkflowFile) {
+			entry.Workflows = append(entry.Workflows, action.WorkflowFile)
+		}
+	}
+
+	lock := make([]LockEntry, 0, len(entries))
+	for _, entry := range entries {
+		lock = append(lock, *entry)
+	}
+	sort.Slice(lock, func(i, j int) bool {
+		if lock[i].Repo != lock[j].Repo {
+			return lock[i].Repo < lock[j].Repo
+		}
+		return lock[i].Ref < lock[j].Ref
+	})
+	return lock
+}
+
+// buildResolvedMetadata summarizes what each action repository resolved to
+func buildResolvedMetadata(actions WorkflowActions) []ResolvedMetadata {
+	byRepo := make(map[string]*ResolvedMetadata)
+	for _, action := range sortedActions(actions) {
+		meta, ok := byRepo[action.Repo]
+		if !ok {
+			meta = &ResolvedMetadata{Repo: action.Repo}
+			byRepo[action.Repo] = meta
+		}
+		if !containsString(meta.Refs, action.CurrentRef) {
+			meta.Refs = append(meta.Refs, action.CurrentRef)
+		}
+		if action.Error != nil {
+			meta.Error = action.Error.Error()
+			continue
+		}
+		meta.LatestTag = action.LatestTag
+		meta.LatestSHA = action.LatestSHA
+		meta.ReleaseURL = action.ReleaseURL
+	}
+
+	metadata := make([]ResolvedMetadata, 0, len(byRepo))
+	for _, meta := range byRepo {
+		metadata = append(metadata, *meta)
+	}
+	sort.Slice(metadata, func(i, j int) bool {
+		return metadata[i].Repo < metadata[j].Repo
+	})
+	return metadata
+}
+
+// digestOf returns the hex encoded SHA-256 of data
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// marshalIndented encodes v as indented JSON with a trailing newline
+func marshalIndented(v any) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeAttestation writes the evidence bundle for an audit run to bundlePath
+func writeAttestation(bundlePath string, actions WorkflowActions, skipped []SkippedFile, cfg *Config, configPath string, evaluation PolicyEvaluation) error {
+	generatedAt := time.Now().UTC()
+	entries := make(map[string][]byte)
+
+	var scan bytes.Buffer
+	if err := writeJSONReport(&scan, actions, skipped, collectExpressionUses(actions, skipped), nil, cfg); err != nil {
+		return fmt.Errorf("failed to encode scan results: %w", err)
+	}
+	entries[attestScanFile] = scan.Bytes()
+
+	for name, v := range map[string]any{
+		attestMetadataFile: buildResolvedMetadata(actions),
+		attestLockFile:     map[string]any{"version": attestFormatVersion, "run": runMetadata(cfg), "actions": buildLockEntries(actions)},
+		attestPolicyFile:   evaluation,
+	} {
+		data, err := marshalIndented(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		entries[name] = data
+	}
+
+	manifest := attestManifest{
+		FormatVersion: attestFormatVersion,
+		GeneratedAt:   generatedAt,
+		Tool:          currentTool(),
+		Run:           runMetadata(cfg),
+		PolicyPassed:  evaluation.Passed,
+		Workflows:     []fileDigest{},
+	}
+	if lines, err := gitLines("rev-parse", "HEAD"); err == nil && len(lines) > 0 {
+		manifest.Commit = lines[0]
+	}
+
+	if content, err := os.ReadFile(filepath.Clean(configPath)); err == nil {
+		entries[attestConfigFile] = content
+		manifest.Config = &fileDigest{Path: configPath, SHA256: digestOf(content)}
+	}
+
+	workflows := sortedWorkflows(actions)
+	for _, workflow := range workflows {
+		content, err := os.ReadFile(filepath.Clean(workflow))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", workflow, err)
+		}
+		entries[attestWorkflowsDir+filepath.ToSlash(workflow)] = content
+		manifest.Workflows = append(manifest.Workflows, fileDigest{Path: workflow, SHA256: digestOf(content)})
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		manifest.Files = append(manifest.Files, fileDigest{Path: name, SHA256: digestOf(entries[name])})
+	}
+	manifestData, err := marshalIndented(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Clean(bundlePath), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create evidence bundle: %w", err)
+	}
+	archive := zip.NewWriter(file)
+
+	write := func(name string, data []byte) error {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: path.Clean(name), Method: zip.Deflate, Modified: generatedAt})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	err = write(attestManifestFile, manifestData)
+	for _, name := range names {
+		if err != nil {
+			break
+		}
+		err = write(name, entries[name])
+	}
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write evidence bundle: %w", err)
+	}
+	return nil
+}
+
+// printPolicyEvaluation prints the outcome of every policy rule
+func printPolicyEvaluation(evaluation PolicyEvaluation) {
+	fmt.Fprintln(console, "\n📋 Policy evaluation:")
+	for _, result := range evaluation.Rules {
+		status := theme.OK
+		switch {
+		case result.Passed:
+		case result.Required:
+			status = theme.Error
+		default:
+			status = theme.Warning
+		}
+		fmt.Fprintf(console, "  %s %s: %s\n", status, result.Rule, result.Description)
+		for _, violation := range result.Violations {
+			printViolation(violation)
+		}
+	}
+}
+
+// printViolation prints the location and message of a policy violation.
+// Violations of custom policies may lack a location.
+func printViolation(violation PolicyViolation) {
+	location := violation.Workflow
+	if violation.Line > 0 {
+		location = fmt.Sprintf("%s:%d", violation.Workflow, violation.Line)
+	}
+	switch {
+	case violation.Repo != "" && violation.Ref != "":
+		location += fmt.Sprintf(" %s@%s", violation.Repo, violation.Ref)
+	case violation.Repo != "":
+		location += " " + violation.Repo
+	}
+	location = strings.TrimSpace(location)
+	if location == "" {
+		fmt.Fprintf(console, "      %s\n", violation.Message)
+		return
+	}
+	fmt.Fprintf(console, "      %s: %s\n", location, violation.Message)
+}
+
+// setupAudit registers the flags of the audit command and returns its runner
+func setupAudit(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	attest := fs.String("attest", "", "Write an evidence bundle (zip) for auditors to `file`")
+	advisories := fs.Bool("advisories", true, "Check action versions against GitHub Security Advisories and OSV")
+	osvURL := fs.String("osv-url", defaultOSVURL, "OSV API `url` queried with --advisories, empty to only query GitHub")
+	scorecard := registerScorecardFlags(fs)
+
+	return func(args []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+
+		gc := NewGitHubClient()
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, skipped, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		forge := forgeFor(gc, cfg, actions)
+		checkForUpdates(forge, actions, cfg)
+
+		var extra []PolicyResult
+		if *advisories && ruleEnabled(ruleAdvisory) {
+			var osv *osvClient
+			if *osvURL != "" {
+				osv = newOSVClient(*osvURL)
+			}
+			extra = append(extra, checkAdvisories(gc, forge, osv, actions))
+		}
+
+		if result := scorecard.scorecards(cfg, actions); result != nil {
+			extra = append(extra, *result)
+		}
+		if len(cfg.Policies) > 0 && ruleEnabled(ruleCustomPolicy) {
+			results, err := evaluatePolicies(cfg.Policies, buildPolicyInput(cfg, actions, skipped, collectExpressionUses(actions, skipped)))
+			if err != nil {
+				fmt.Fprintf(console, "%s %v\n", theme.Warning, err)
+			}
+			extra = append(extra, results...)
+		}
+
+		evaluation := evaluatePolicy(actions, extra...)
+		printPolicyEvaluation(evaluation)
+
+		if *attest != "" {
+			if err := writeAttestation(*attest, actions, skipped, cfg, opts.configPath, evaluation); err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(console, "\n📦 Evidence bundle written to %s\n", *attest)
+		}
+
+		if !evaluation.Passed {
+			fmt.Fprintf(console, "\n%s Audit failed: required policy rules did not pass\n", theme.Error)
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "\n%s Audit passed\n", theme.OK)
+		exitIfBudgetExhausted(actions)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxBreakingLines limits how many marked release note lines are kept
+	maxBreakingLines = 5
+)
+
+// breakingMarkerRegex matches release note lines that announce an
+// incompatible change, such as "BREAKING CHANGE:", "Migration guide" or
+// "Removed input `node-version-file`"
+var breakingMarkerRegex = regexp.MustCompile(`(?i)\bbreaking\b|\bmigrat(e|es|ed|ing|ion)\b|\bremoved?\b.*\binputs?\b|\binputs?\b.*\b(was|were|has been|have been) removed\b`)
+
+// findBreakingMarkers returns the lines of release notes that mention
+// breaking changes, trimmed of Markdown list and heading markers
+func findBreakingMarkers(notes string) []string {
+	var marked []string
+	for _, line := range strings.Split(notes, "\n") {
+		if !breakingMarkerRegex.MatchString(line) {
+			continue
+		}
+		line = strings.TrimLeft(strings.TrimSpace(line), "#-+> ")
+		line = strings.TrimSpace(strings.TrimPrefix(line, "* "))
+		if line == "" {
+			continue
+		}
+		if len(marked) == maxBreakingLines {
+			break
+		}
+		marked = append(marked, line)
+	}
+	return marked
+}
+
+// ReviewRequired reports whether the update of an action should be reviewed
+// by hand because its release notes mention breaking changes
+func (a ActionInfo) ReviewRequired() bool {
+	return a.NeedsUpdate && len(a.BreakingChanges) > 0
+}
+
+// printBreakingChanges prints the marked release note lines of an update
+func printBreakingChanges(action ActionInfo, indent string) {
+	fmt.Fprintf(console, "%s%s Review required: the release notes of %s mention breaking changes\n", indent, theme.Warning, action.LatestTag)
+	for _, line := range action.BreakingChanges {
+		fmt.Fprintf(console, "%s  %s\n", indent, line)
+	}
+}
+
+// heldForReview reports whether --yes holds back the update of an action
+// because it needs review, and prints why
+func heldForReview(action ActionInfo, indent string) bool {
+	if !autoApprove || allowBreaking || !action.ReviewRequired() {
+		return false
+	}
+	fmt.Fprintf(console, "%s%s Held %s at %s for review, pass --allow-breaking to apply it with --yes\n", indent, theme.Skipped, action.Repo, action.CurrentRef)
+	return true
+}
+
+// renderPRBody returns a Markdown pull request description of the applied
+// updates, with the ones that need review listed first and the details of
+// the run last
+func renderPRBody(applied []ActionInfo, run RunMetadata) string {
+	var review, routine []ActionInfo
+	for _, action := range applied {
+		if action.ReviewRequired() {
+			review = append(review, action)
+		} else {
+			routine = append(routine, action)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("Pins GitHub Actions to the commit SHAs of their latest releases. Generated by github-ci-hash.\n")
+	if len(review) > 0 {
+		b.WriteString("\n## ⚠️ Review required\n\nThe release notes of these updates mention breaking changes:\n\n")
+		for _, action := range review {
+			writePRBodyLine(&b, action)
+			for _, line := range action.BreakingChanges {
+				fmt.Fprintf(&b, "  > %s\n", line)
+			}
+		}
+	}
+	if len(routine) > 0 {
+		b.WriteString("\n## Updates\n\n")
+		for _, action := range routine {
+			writePRBodyLine(&b, action)
+		}
+	}
+	writeRunDetails(&b, run)
+	return b.String()
+}
+
+// writePRBodyLine writes the list item for one applied update
+func writePRBodyLine(b *strings.Builder, action ActionInfo) {
+	fmt.Fprintf(b, "- `%s` in `%s`: %s → %s", action.Repo, action.WorkflowFile, action.CurrentRef, action.LatestTag)
+	if action.Rename {
+		fmt.Fprintf(b, ", moved to `%s`", action.MovedTo)
+	}
+	if action.ReleaseURL != "" {
+		fmt.Fprintf(b, " ([release notes](%s))", action.ReleaseURL)
+	}
+	b.WriteString("\n")
+}
+
+// writePRBodyFile writes the pull request description of the applied
+// updates. Like reports, it may go outside the repository, such as to
+// $RUNNER_TEMP.
+func writePRBodyFile(path string, applied []ActionInfo, cfg *Config) error {
+	return os.WriteFile(filepath.Clean(path), []byte(renderPRBody(applied, runMetadata(cfg))), 0600)
+}
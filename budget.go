@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// exitBudgetExhausted is the exit code of runs that skipped actions after
+// using up the --max-api-calls budget, so pipelines sharing a token can tell
+// an incomplete run from failed checks
+const exitBudgetExhausted = 3
+
+// maxAPICalls caps the GitHub API requests of a run; 0 means no cap. It is
+// set by --max-api-calls.
+var maxAPICalls int
+
+// apiCalls counts the GitHub API requests sent against the budget
+var apiCalls atomic.Int64
+
+// budgetExhausted reports whether the next API request would exceed the budget
+func budgetExhausted() bool {
+	return maxAPICalls > 0 && apiCalls.Load() >= int64(maxAPICalls)
+}
+
+// errBudgetExhausted is returned for requests refused once the budget is used up
+func errBudgetExhausted() error {
+	return newResolveError(ErrorKindBudgetExhausted, "API budget of %d requests exhausted", maxAPICalls)
+}
+
+// budgetTransport refuses GitHub API requests beyond --max-api-calls. Rate
+// limit lookups do not count against the quota, so they are not counted.
+type budgetTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/rate_limit") {
+		if apiCalls.Add(1) > int64(maxAPICalls) {
+			return nil, errBudgetExhausted()
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// exitIfBudgetExhausted lists the actions skipped because the API budget was
+// used up and exits with exitBudgetExhausted when there are any
+func exitIfBudgetExhausted(actions WorkflowActions) {
+	var skipped []ActionInfo
+	for _, action := range sortedActions(actions) {
+		if action.Error != nil && action.Error.Kind == ErrorKindBudgetExhausted {
+			skipped = append(skipped, action)
+		}
+	}
+	if len(skipped) == 0 {
+		return
+	}
+
+	fmt.Fprintf(console, "\n%s Skipped %d action(s) after %d API requests (--max-api-calls):\n", theme.Skipped, len(skipped), maxAPICalls)
+	for _, action := range skipped {
+		fmt.Fprintf(console, "  %s:%d %s\n", action.WorkflowFile, action.Line, action.reference())
+	}
+	os.Exit(exitBudgetExhausted)
+}
@@ -0,0 +1,781 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// programName is the name of the executable used in help and man pages
+	programName = "github-ci-hash"
+
+	// defaultManDir is where docs man writes pages by default
+	defaultManDir = "man"
+)
+
+// commandExample is a sample invocation shown in help and man pages
+type commandExample struct {
+	Command     string
+	Description string
+}
+
+// command describes a subcommand. Dispatch, help output, and man pages are
+// all generated from this metadata so that they cannot drift apart.
+type command struct {
+	// Name is the command as typed, e.g. "check" or "config show"
+	Name string
+	// Args describes the positional arguments, e.g. "[files...]"
+	Args string
+	// Summary is a one-line description shown in the command list
+	Summary string
+	// Description is the long help text
+	Description string
+	// Examples are sample invocations
+	Examples []commandExample
+	// Setup registers the command's flags and returns the function that runs
+	// it with the remaining positional arguments
+	Setup func(fs *flag.FlagSet) func(args []string)
+}
+
+// commands lists every command in the order shown in help output. It is
+// populated in init because help and docs refer back to it.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			Name:    "check",
+			Args:    "[files...]",
+			Summary: "Check for updates without applying",
+			Description: "Scans the workflow files, resolves the latest release of every action, and " +
+				"reports which actions have updates available. It fails on findings at error " +
+				"severity, such as an action version on the denylist of compromised actions. Files given as arguments are scanned " +
+				"instead of the workflow directory.",
+			Examples: []commandExample{
+				{"github-ci-hash check", "Check every workflow"},
+				{"github-ci-hash check --json > report.json", "Write machine readable results"},
+				{"github-ci-hash check --changed-only", "Only check workflows changed relative to origin/main"},
+				{"github-ci-hash check --verify-dist", "Also compare JavaScript bundles at the pinned SHA with their tags"},
+				{"github-ci-hash check --min-scorecard 5", "Show OpenSSF Scorecard scores and fail below 5"},
+				{"github-ci-hash check --publishers", "Show verified creators and Marketplace listings"},
+				{"github-ci-hash check --attestations", "Show which pinned commits have verifiable provenance"},
+				{"github-ci-hash check --immutable", "Show which actions are pinned to immutable releases"},
+				{"github-ci-hash check --deep", "Also report unpinned actions called by pinned composite actions"},
+				{"github-ci-hash check --runtimes", "Flag actions pinned to releases on node12 or node16"},
+				{"github-ci-hash check --deprecated", "Flag archived, deprecated and stale actions with their successors"},
+				{"github-ci-hash check --since 7d --quiet", "Check at most weekly with a short summary, as the pre-push hook does"},
+				{"github-ci-hash check --reporter slack", "Pipe the results to the ci-hash-slack plugin"},
+			},
+			Setup: setupCheck,
+		},
+		{
+			Name:    "update",
+			Args:    "[workflow-files...]",
+			Summary: "Update workflows to the latest SHAs (with confirmation)",
+			Description: "Checks for updates like check, then asks for confirmation before pinning each " +
+				"workflow to the latest release SHAs. Backups are written before any file is changed " +
+				"and restored when an update fails.",
+			Examples: []commandExample{
+				{"github-ci-hash update", "Update all workflows"},
+				{"github-ci-hash update ci.yml", "Update a single workflow"},
+				{"github-ci-hash update \"deploy-*.yml\"", "Update the workflows matching a glob"},
+				{"github-ci-hash update --job build", "Update only the actions of the build job"},
+				{"github-ci-hash update --yes --strategy patch", "Apply patch releases unattended, leaving minor and major updates for review"},
+				{"github-ci-hash update --tui", "Review and apply updates in a full-screen UI"},
+				{"github-ci-hash update --stdin < ci.yml > pinned.yml", "Pin a workflow read from stdin"},
+				{"github-ci-hash update --expand-short", "Expand abbreviated SHAs without changing versions"},
+				{"github-ci-hash update --to actions/checkout@v4.1.7", "Pin an action to a known-good tag instead of the latest release"},
+				{"github-ci-hash update --yes --pr-body pr.md", "Apply updates unattended and describe them for a pull request"},
+			},
+			Setup: setupUpdate,
+		},
+		{
+			Name:    "verify",
+			Args:    "[files...]",
+			Summary: "Verify all actions are pinned to SHAs",
+			Description: "Exits with a non-zero status when any action reference is not pinned to a " +
+				"full commit SHA, uses a version on the denylist of compromised actions, or is outside " +
+				"the allowlist of trusted owners when one is configured, and when a configured Rego or " +
+				"CUE policy reports a violation. No API " +
+				"calls are made, which makes it suitable for hooks and CI. " +
+				"With --require-2fa, the publishers of third-party actions must also be organizations " +
+				"that enforce two-factor authentication, which is looked up through the API. " +
+				"With --require-verified, they must come from verified creators unless they match " +
+				"allow_unverified. " +
+				"With --require-org-owned, every action must come from a repository owned by an " +
+				"organization that is not a fork, unless it matches allow_personal. " +
+				"With --reachable, every pinned commit must be reachable from a tag or the default " +
+				"branch of its repository, which catches impostor commits that only exist in a fork. " +
+				"With --comments, the tag in the comment of every SHA pin is resolved, and pins whose " +
+				"tag was moved or whose comment is stale are reported. " +
+				"With --ratchet, unpinned actions are tolerated up to the count stored in a baseline " +
+				"file, so large repositories can adopt enforcement before cleaning up.",
+			Examples: []commandExample{
+				{"github-ci-hash verify", "Verify every workflow"},
+				{"github-ci-hash verify --ratchet", "Fail only when unpinned actions were added"},
+				{"github-ci-hash verify --stdin < ci.yml", "Verify a workflow read from stdin"},
+				{"github-ci-hash verify --require-2fa", "Also require 2FA-enforcing publishers"},
+				{"github-ci-hash verify --comments", "Also check that pin comments name the pinned commits"},
+				{"github-ci-hash verify --require-verified", "Also require verified creators for third-party actions"},
+				{"github-ci-hash verify --require-org-owned", "Also reject actions from personal accounts and forks"},
+				{"github-ci-hash verify --reachable", "Also reject pinned commits that only exist in a fork"},
+				{"github-ci-hash verify --changed --offline", "Verify staged and uncommitted workflows, as the pre-commit hook does"},
+			},
+			Setup: setupVerify,
+		},
+		{
+			Name:    "baseline tighten",
+			Summary: "Lower the verify --ratchet baseline to the current count",
+			Description: "Counts the unpinned actions and lowers the baseline of verify --ratchet to that " +
+				"count, so fixed violations cannot come back. It fails when the count is above the " +
+				"baseline and records a new baseline when there is none.",
+			Examples: []commandExample{
+				{"github-ci-hash baseline tighten", "Lock in the actions pinned since the last baseline"},
+			},
+			Setup: setupBaselineTighten,
+		},
+		{
+			Name:    "audit",
+			Args:    "[files...]",
+			Summary: "Evaluate pinning policy and produce audit evidence",
+			Description: "Resolves every action like check and evaluates the pinning policy: actions must be " +
+				"pinned to commit SHAs that resolve, should be up to date in workflows declaring " +
+				"permissions, and must not run versions with a known vulnerability in GitHub " +
+				"Security Advisories or OSV. With --attest, the scan results, resolved metadata, " +
+				"lockfile, policy evaluation, and tool version and input hashes are written to a zip " +
+				"for auditors.",
+			Examples: []commandExample{
+				{"github-ci-hash audit", "Evaluate the policy"},
+				{"github-ci-hash audit --attest evidence.zip", "Write an evidence bundle"},
+				{"github-ci-hash audit --osv-url ''", "Only check GitHub Security Advisories"},
+				{"github-ci-hash audit --scorecard", "Also record the OpenSSF Scorecard of every action repository"},
+			},
+			Setup: setupAudit,
+		},
+		{
+			Name:    "lint",
+			Args:    "[files...]",
+			Summary: "Detect risky workflow patterns beyond pinning",
+			Description: "Reads the workflow files for patterns that hand secrets or the token to " +
+				"untrusted code: pull_request_target and workflow_run workflows checking out the " +
+				"head of a pull request, checkouts keeping persisted credentials, untrusted " +
+				"${{ github.event.* }} fields interpolated into run: and github-script scripts, " +
+				"and secrets exposed to workflows run for pull requests from forks. It fails on " +
+				"findings at error severity, which the rules section of the configuration sets.",
+			Examples: []commandExample{
+				{"github-ci-hash lint", "Lint every workflow"},
+				{"github-ci-hash lint --sarif lint.sarif", "Also write the findings for code scanning"},
+				{"github-ci-hash lint --json ci.yml", "Write the findings of one workflow as JSON"},
+			},
+			Setup: setupLint,
+		},
+		{
+			Name:    "why",
+			Args:    "action...",
+			Summary: "Explain why actions are pinned where they are",
+			Description: "Resolves the given actions like check and explains each pin: the rule that " +
+				"selects its version (latest release, the newest tag matching a tag_filter, the " +
+				"highest tag over git, or an image tag's digest) and whether the pin is behind it. " +
+				"The same provenance is recorded in check --json and the audit --attest lockfile.",
+			Examples: []commandExample{
+				{"github-ci-hash why actions/checkout", "Explain the pins of one action"},
+				{"github-ci-hash why 'github/*'", "Explain every action of an owner"},
+			},
+			Setup: setupWhy,
+		},
+		{
+			Name:    "search-usage",
+			Args:    "owner/repo",
+			Summary: "Find unpinned usage of an action across an organization",
+			Description: "Uses the GitHub code search API to find the workflows and composite actions " +
+				"of every repository in the organization that reference the action, including " +
+				"repositories that do not run this tool yet, and lists the references that are not " +
+				"pinned to a commit SHA or, with --below, are older than a version. Code search " +
+				"requires a token and allows 10 requests per minute, so requests are paced.",
+			Examples: []commandExample{
+				{"github-ci-hash search-usage --org my-org actions/checkout", "Find unpinned references to actions/checkout"},
+				{"github-ci-hash search-usage --org my-org --below v4 actions/checkout", "Also find pins older than v4"},
+				{"github-ci-hash search-usage --org my-org --json tj-actions/changed-files > usage.json", "Write the findings as JSON"},
+			},
+			Setup: setupSearchUsage,
+		},
+		{
+			Name:    "allowed-actions",
+			Args:    "[files...]",
+			Summary: "Derive an allowed actions policy from the actions in use",
+			Description: "Lists the patterns of GitHub's allowed actions setting that admit every action " +
+				"the workflows use, for organization owners to apply: one pattern per action by " +
+				"default, or per owner or ref with --scope. GitHub-owned actions are covered by " +
+				"allowing actions created by GitHub, and the actions of --org need no pattern. " +
+				"--json writes the body of the selected actions API.",
+			Examples: []commandExample{
+				{"github-ci-hash allowed-actions --org my-org", "List the patterns for the actions in use"},
+				{"github-ci-hash allowed-actions --scope owner --json > selected-actions.json", "Allow the owners in use, as an API body"},
+			},
+			Setup: setupAllowedActions,
+		},
+		{
+			Name:    "allowed-actions check",
+			Args:    "[files...]",
+			Summary: "Flag actions the allowed actions policy of the organization blocks",
+			Description: "Reads the allowed actions policy of the organization, or of a repository with " +
+				"--repo, and reports every uses: that it would block: actions matching no pattern, " +
+				"blocked with a ! pattern, GitHub-owned or from unverified creators when those are " +
+				"not allowed, and references not pinned to a SHA when pinning is required. Reading " +
+				"the policy requires an organization owner's token.",
+			Examples: []commandExample{
+				{"github-ci-hash allowed-actions check --org my-org", "Check the workflows against the organization policy"},
+				{"github-ci-hash allowed-actions check --repo my-org/app", "Check against the policy of one repository"},
+			},
+			Setup: setupAllowedActionsCheck,
+		},
+		{
+			Name:    "rate-limit",
+			Args:    "[files...]",
+			Summary: "Show the remaining GitHub API budget",
+			Description: "Prints the user the token belongs to, the requests left in the REST, GraphQL " +
+				"and code search rate limits with the time they reset, and an estimate of the " +
+				"requests a full check of the workflows needs, to run before large scans. Reading " +
+				"the rate limit does not count against it.",
+			Examples: []commandExample{
+				{"github-ci-hash rate-limit", "Show the budget and what a check of this repository costs"},
+				{"github-ci-hash rate-limit --json", "Write the budget as JSON"},
+			},
+			Setup: setupRateLimit,
+		},
+		{
+			Name:    "licenses",
+			Args:    "[files...]",
+			Summary: "Report the licenses of the actions in use",
+			Description: "Scans the workflow files and looks up the license GitHub detects in the " +
+				"repository of every action, listing the workflows that use it. The report is " +
+				"printed as a table or written as JSON or CSV for compliance reviews. It fails when " +
+				"a repository uses a license listed under deny_licenses or with --deny.",
+			Examples: []commandExample{
+				{"github-ci-hash licenses", "List the license of every action repository"},
+				{"github-ci-hash licenses --format csv > licenses.csv", "Write the report as CSV"},
+				{"github-ci-hash licenses --deny AGPL-3.0 --deny NONE", "Fail for AGPL-3.0 and unlicensed actions"},
+			},
+			Setup: setupLicenses,
+		},
+		{
+			Name:    "sbom",
+			Args:    "[files...]",
+			Summary: "Generate a CycloneDX or SPDX SBOM of the actions in use",
+			Description: "Scans the workflow files and writes a software bill of materials in which every " +
+				"action version is a component with a package URL such as " +
+				"pkg:github/actions/checkout@<sha> and the workflows depend on the actions they use. " +
+				"Pins carry the version of their pin comment; with --resolve, the refs of unpinned " +
+				"actions are resolved to commit SHAs so their package URLs name a commit too.",
+			Examples: []commandExample{
+				{"github-ci-hash sbom > actions.cdx.json", "Write a CycloneDX SBOM"},
+				{"github-ci-hash sbom --format spdx --output actions.spdx.json", "Write an SPDX SBOM to a file"},
+				{"github-ci-hash sbom --resolve", "Resolve unpinned actions to commit SHAs first"},
+			},
+			Setup: setupSBOM,
+		},
+		{
+			Name:    "submit-deps",
+			Args:    "[files...]",
+			Summary: "Submit the actions in use to the GitHub dependency graph",
+			Description: "Scans the workflow files and submits every workflow as a manifest of its actions " +
+				"to the dependency submission API of the repository, so the dependency graph and " +
+				"Dependabot alerts cover pinned actions. Pins are submitted at the version of their " +
+				"pin comment so advisories match. In a workflow, the repository, commit and ref come " +
+				"from the GitHub environment; the token needs contents: write.",
+			Examples: []commandExample{
+				{"github-ci-hash submit-deps", "Submit a snapshot for the current commit"},
+				{"github-ci-hash submit-deps --dry-run", "Write the snapshot without submitting it"},
+				{"github-ci-hash submit-deps --repo my-org/app --ref refs/heads/main --sha $(git rev-parse HEAD)", "Submit for an explicit repository and commit"},
+			},
+			Setup: setupSubmitDeps,
+		},
+		{
+			Name:    "policy eval",
+			Args:    "[policy files...]",
+			Summary: "Evaluate custom Rego or CUE policies",
+			Description: "Scans the workflow files and evaluates the policies listed under policies in the " +
+				"configuration, or the given files, against the action inventory and workflow " +
+				"metadata. Rego policies are evaluated with opa and CUE policies with cue, which must " +
+				"be on PATH. It fails when any policy reports a violation; verify and audit evaluate " +
+				"the configured policies as well. With --input, the document the policies evaluate is " +
+				"written instead.",
+			Examples: []commandExample{
+				{"github-ci-hash policy eval", "Evaluate the configured policies"},
+				{"github-ci-hash policy eval policy/actions.rego", "Evaluate one policy file"},
+				{"github-ci-hash policy eval --input > input.json", "Write the policy input to develop a policy"},
+				{"github-ci-hash policy eval --resolve", "Evaluate with the latest release of every action resolved"},
+			},
+			Setup: setupPolicyEval,
+		},
+		{
+			Name:    "pin",
+			Args:    "[files...]",
+			Summary: "Pin references to the commit their current ref names",
+			Description: "Resolves tags, branches and image tags exactly as written, e.g. @v4 or @main, " +
+				"and after confirmation rewrites them to the commit SHA or digest with the ref as " +
+				"their comment. Unlike update no newer version is looked up, so pinning and " +
+				"upgrading can be reviewed separately.",
+			Examples: []commandExample{
+				{"github-ci-hash pin", "Pin every unpinned reference in place"},
+				{"github-ci-hash pin --yes ci.yml", "Pin one workflow without prompting"},
+			},
+			Setup: setupPin,
+		},
+		{
+			Name:    "annotate",
+			Args:    "[files...]",
+			Summary: "Add version comments to bare SHA pins",
+			Description: "Finds actions pinned to a commit SHA without a comment, looks up the tags " +
+				"pointing at each commit and, after confirmation, appends the most precise one as a " +
+				"comment in the pin_comment format. The SHAs are not changed.",
+			Examples: []commandExample{
+				{"github-ci-hash annotate", "Annotate every workflow"},
+				{"github-ci-hash annotate --yes ci.yml", "Annotate one workflow without prompting"},
+			},
+			Setup: setupAnnotate,
+		},
+		{
+			Name:    "unpin",
+			Args:    "[files...]",
+			Summary: "Convert SHA pins back to the tags they name",
+			Description: "The reverse of update: rewrites every action pinned to a commit SHA to the tag " +
+				"named in its comment, or, for bare pins, to the most precise tag pointing at the " +
+				"commit. Useful for debugging and for diffing against upstream examples; the " +
+				"result is no longer pinned, so the rewrite needs confirmation.",
+			Examples: []commandExample{
+				{"github-ci-hash unpin ci.yml", "Turn the pins of one workflow back into tags"},
+				{"github-ci-hash unpin --yes", "Unpin every workflow without prompting"},
+			},
+			Setup: setupUnpin,
+		},
+		{
+			Name:    "align",
+			Args:    "[action...]",
+			Summary: "Pin every occurrence of an action to the same version",
+			Description: "Finds actions pinned to different versions across workflows, as reported by " +
+				"check, and rewrites every occurrence to one version in a single pass: the latest " +
+				"release, or the tag given with --to. Without arguments every skewed action is " +
+				"aligned; named actions are aligned even when they are consistent.",
+			Examples: []commandExample{
+				{"github-ci-hash align", "Align every action pinned to several versions"},
+				{"github-ci-hash align actions/checkout --to v4.2.2", "Pin every checkout step to v4.2.2"},
+			},
+			Setup: setupAlign,
+		},
+		{
+			Name:    "lock",
+			Summary: "Write gha.lock from the pins of the workflows",
+			Description: "Records every action in the workflows with the commit it is pinned to, the " +
+				"version its pin comment or ref names, when it was resolved, the workflows using it " +
+				"and the provenance of the pin, with the run that wrote it. Unpinned references are " +
+				"resolved and new pins looked up through the API. Commit the lockfile to review pin " +
+				"changes as diffs; entries that did not change keep their resolution time and provenance.",
+			Examples: []commandExample{
+				{"github-ci-hash lock", "Write or refresh gha.lock"},
+				{"github-ci-hash lock --check", "Fail in CI when gha.lock is out of date"},
+			},
+			Setup: setupLock,
+		},
+		{
+			Name:    "lock sign",
+			Summary: "Sign gha.lock with Sigstore",
+			Description: "Signs the lockfile and writes a Sigstore bundle next to it, gha.lock.sigstore.json " +
+				"by default. In a GitHub Actions job with id-token: write the signature is keyless: an " +
+				"ephemeral key is certified by Fulcio for the identity of the workflow and the signature " +
+				"is recorded in the Rekor transparency log. Locally, sign with a PEM private key via --key.",
+			Examples: []commandExample{
+				{"github-ci-hash lock sign", "Sign gha.lock keyless in a workflow"},
+				{"github-ci-hash lock sign --key lock.key", "Sign gha.lock with a local key"},
+			},
+			Setup: setupLockSign,
+		},
+		{
+			Name:    "lock verify",
+			Summary: "Verify the Sigstore signature of gha.lock",
+			Description: "Checks that the lockfile is unchanged since it was signed and that the signature " +
+				"was made by the expected signer: the public key given with --key, or for keyless " +
+				"signatures an identity matching --identity, optionally of the --issuer. Keyless " +
+				"certificates must chain to Fulcio and the signature must be recorded in Rekor, as the " +
+				"embedded Sigstore public-good trusted root or --trusted-root has them. sync runs the " +
+				"same verification before applying a signed lockfile.",
+			Examples: []commandExample{
+				{"github-ci-hash lock verify --key lock.pub", "Verify a signature made with a local key"},
+				{"github-ci-hash lock verify --identity '^https://github.com/acme/app/' --issuer https://token.actions.githubusercontent.com", "Verify a keyless signature made by a workflow of acme/app"},
+			},
+			Setup: setupLockVerify,
+		},
+		{
+			Name:    "sync",
+			Args:    "[files...]",
+			Summary: "Pin the workflows to the commits in gha.lock",
+			Description: "Rewrites every reference whose commit differs from the lockfile to the locked " +
+				"commit and version, after one confirmation, so the same pins can be reproduced on " +
+				"other branches. A reference is matched to the entry of its version, or to the only " +
+				"entry of its action. A signed lockfile is verified first, with the flags of lock " +
+				"verify. Run lock for the other direction.",
+			Examples: []commandExample{
+				{"github-ci-hash sync", "Pin the workflows to gha.lock"},
+				{"github-ci-hash sync --check", "Fail when a workflow differs from gha.lock"},
+			},
+			Setup: setupSync,
+		},
+		{
+			Name:    "report diff",
+			Args:    "old.json new.json",
+			Summary: "Compare two exported check results",
+			Description: "Compares two reports written by check --json and lists the regressions, newly available " +
+				"updates, fixed violations, and added or removed actions. References are matched by workflow " +
+				"file and repository, so unrelated edits do not show up as changes.",
+			Examples: []commandExample{
+				{"github-ci-hash report diff last-week.json report.json", "Show what changed since last week"},
+				{"github-ci-hash report diff --fail-on-regression old.json new.json", "Fail when a pin regressed"},
+			},
+			Setup: setupReportDiff,
+		},
+		{
+			Name:    "serve",
+			Summary: "Serve a read-only /verify endpoint for merge gating",
+			Description: "Starts an HTTP server answering GET /verify?repo=owner/name&ref=sha with the " +
+				"pinning status of the repository's workflows at that ref.",
+			Examples: []commandExample{
+				{"github-ci-hash serve --addr :9090", "Listen on port 9090"},
+			},
+			Setup: setupServe,
+		},
+		{
+			Name:    "init",
+			Summary: "Generate config, hooks, and a scheduled workflow",
+			Description: "Inspects the repository's workflows and writes a starter configuration, " +
+				"installs git hooks, and adds a weekly scheduled workflow, asking before each step.",
+			Examples: []commandExample{
+				{"github-ci-hash init --yes", "Accept every suggestion without prompting"},
+			},
+			Setup: setupInit,
+		},
+		{
+			Name:    "init workflow",
+			Summary: "Generate the scheduled companion workflow",
+			Description: "Writes " + scheduledWorkflowFile + ", a SHA-pinned workflow that runs weekly checks, " +
+				"uploads the results as SARIF for code scanning, and opens a pull request with the updates. " +
+				"The schedule, pull request branch, labels, reviewers and token secret come from the " +
+				"workflow section of the configuration.",
+			Examples: []commandExample{
+				{"github-ci-hash init workflow", "Write the workflow"},
+				{"github-ci-hash init workflow --stdout", "Preview the workflow"},
+			},
+			Setup: setupInitWorkflow,
+		},
+		{
+			Name:        "config validate",
+			Summary:     "Validate the configuration file",
+			Description: "Checks the configuration file against its JSON Schema and reports every problem with its location.",
+			Setup:       setupConfigValidate,
+		},
+		{
+			Name:        "config show",
+			Summary:     "Print the (effective) configuration",
+			Description: "Prints the loaded configuration. With --effective, command line flags and defaults are merged in.",
+			Examples: []commandExample{
+				{"github-ci-hash config show --effective --theme mono", "Show the configuration a run would use"},
+			},
+			Setup: setupConfigShow,
+		},
+		{
+			Name:        "config schema",
+			Summary:     "Print the configuration JSON Schema",
+			Description: "Writes the JSON Schema of the configuration file to stdout, e.g. for editor integration.",
+			Setup:       setupConfigSchema,
+		},
+		{
+			Name:    "install-hooks",
+			Summary: "Install pre-commit hooks",
+			Description: "Installs pre-commit and pre-push hooks into the directory git runs hooks from. " +
+				"When lefthook or husky manages the hooks, the stanza to add to its configuration is printed instead.",
+			Setup: setupInstallHooks,
+		},
+		{
+			Name:        "uninstall-hooks",
+			Summary:     "Remove hooks installed by install-hooks",
+			Description: "Removes the hooks written by install-hooks and restores any hooks they replaced.",
+			Setup:       setupUninstallHooks,
+		},
+		{
+			Name:    "self-update",
+			Summary: "Install the latest verified release binary",
+			Description: "Downloads the latest release binary for this platform, verifies the signed " +
+				"checksums, and replaces the running executable.",
+			Examples: []commandExample{
+				{"github-ci-hash self-update --check", "Only report whether an update is available"},
+			},
+			Setup: setupSelfUpdate,
+		},
+		{
+			Name:        "docs man",
+			Summary:     "Generate man pages",
+			Description: "Writes a man page for the program and one for every command.",
+			Examples: []commandExample{
+				{"github-ci-hash docs man --dir /usr/local/share/man/man1", "Install the man pages"},
+			},
+			Setup: setupDocsMan,
+		},
+		{
+			Name:        "help",
+			Args:        "[command]",
+			Summary:     "Show help for a command",
+			Description: "Shows the list of commands, or the options and examples of a single command.",
+			Examples: []commandExample{
+				{"github-ci-hash help update", "Show help for the update command"},
+			},
+			Setup: setupHelp,
+		},
+		{
+			Name:        "version",
+			Summary:     "Show version information",
+			Description: "Prints the version, commit, and build time of the binary.",
+			Setup:       setupVersion,
+		},
+	}
+}
+
+// findCommand returns the command named by the leading arguments and the
+// arguments that follow it. The longest matching name wins.
+func findCommand(args []string) (*command, []string) {
+	var best *command
+	bestWords := 0
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.Name)
+		if len(words) <= bestWords || len(words) > len(args) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == cmd.Name {
+			best, bestWords = cmd, len(words)
+		}
+	}
+	if best == nil {
+		return nil, args
+	}
+	return best, args[bestWords:]
+}
+
+// flagSet creates the command's flag set with its flags registered
+func (c *command) flagSet() (*flag.FlagSet, func(args []string)) {
+	fs := flag.NewFlagSet(c.Name, flag.ExitOnError)
+	fs.SetOutput(console)
+	logOpts := &logOptions{}
+	logOpts.register(fs)
+	run := c.Setup(fs)
+	fs.Usage = func() {
+		printCommandHelp(console, c)
+	}
+	return fs, func(args []string) {
+		if err := configureLogging(logOpts); err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Debug("running command", "command", c.Name, "version", Version)
+		run(args)
+	}
+}
+
+// run parses the command's flags and runs it
+func (c *command) run(args []string) {
+	fs, run := c.flagSet()
+	_ = fs.Parse(args)
+	recordRun(c.Name, fs)
+	run(fs.Args())
+}
+
+// commandFlags returns the flags of a command sorted by name
+func commandFlags(c *command) []*flag.Flag {
+	fs, _ := c.flagSet()
+	var flags []*flag.Flag
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+	return flags
+}
+
+// flagSynopsis returns "--name <arg>" for a flag
+func flagSynopsis(f *flag.Flag) string {
+	name, _ := flag.UnquoteUsage(f)
+	if name == "" {
+		return "--" + f.Name
+	}
+	return "--" + f.Name + " <" + strings.ToLower(name) + ">"
+}
+
+// flagDescription returns a flag's usage text with its default value, which
+// is left out when it is a zero value
+func flagDescription(f *flag.Flag) string {
+	_, usage := flag.UnquoteUsage(f)
+	if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "[]" && f.DefValue != "0" {
+		usage += " (default: " + f.DefValue + ")"
+	}
+	return usage
+}
+
+// printUsage prints the list of commands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "GitHub CI Hash Updater")
+	fmt.Fprintf(w, "Version: %s (commit: %s, built: %s)\n", Version, GitCommit, BuildTime)
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Usage:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-42s - %s\n", strings.TrimSpace(programName+" "+cmd.Name+" "+cmd.Args), cmd.Summary)
+	}
+	if plugins := discoverPlugins(); len(plugins) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Plugins:")
+		for _, name := range plugins {
+			fmt.Fprintf(w, "  %-42s - %s%s on PATH\n", programName+" "+name, pluginPrefix, name)
+		}
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Run '%s help <command>' for the options and examples of a command.\n", programName)
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Environment variables:")
+	fmt.Fprintln(w, "  GITHUB_TOKEN or GH_TOKEN - GitHub API token for higher rate limits")
+	fmt.Fprintln(w, "  (or authenticate with 'gh auth login' to use gh CLI token)")
+	fmt.Fprintln(w, "  NO_COLOR                 - Use the mono theme unless a theme is selected")
+}
+
+// printCommandHelp prints the long help of a command
+func printCommandHelp(w io.Writer, c *command) {
+	fmt.Fprintf(w, "Usage: %s\n\n", strings.TrimSpace(programName+" "+c.Name+" [options] "+c.Args))
+	fmt.Fprintf(w, "%s\n", c.Description)
+
+	if flags := commandFlags(c); len(flags) > 0 {
+		fmt.Fprintln(w, "\nOptions:")
+		for _, f := range flags {
+			fmt.Fprintf(w, "  %-24s %s\n", flagSynopsis(f), flagDescription(f))
+		}
+	}
+
+	if len(c.Examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, example := range c.Examples {
+			fmt.Fprintf(w, "  # %s\n  %s\n\n", example.Description, example.Command)
+		}
+	}
+}
+
+// setupHelp registers the flags of the help command and returns its runner
+func setupHelp(_ *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) == 0 {
+			printUsage(console)
+			return
+		}
+		cmd, rest := findCommand(args)
+		if cmd == nil || len(rest) > 0 {
+			fmt.Fprintf(console, "Unknown command: %s\n", strings.Join(args, " "))
+			os.Exit(1)
+		}
+		printCommandHelp(console, cmd)
+	}
+}
+
+// roffEscape escapes text for use in a man page
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// manPageName returns the file name of a command's man page
+func manPageName(c *command) string {
+	if c == nil {
+		return programName + ".1"
+	}
+	return programName + "-" + strings.ReplaceAll(c.Name, " ", "-") + ".1"
+}
+
+// renderManPage renders the man page of a command, or of the program when c is nil
+func renderManPage(c *command, date string) string {
+	var b strings.Builder
+	title := strings.TrimSuffix(manPageName(c), ".1")
+
+	fmt.Fprintf(&b, ".TH %s 1 %q %q %q\n", strings.ToUpper(title), date, programName+" "+Version, "GitHub CI Hash Updater")
+	b.WriteString(".SH NAME\n")
+
+	if c == nil {
+		fmt.Fprintf(&b, "%s \\- keep GitHub Actions pinned to commit SHAs\n", programName)
+		b.WriteString(".SH SYNOPSIS\n")
+		fmt.Fprintf(&b, ".B %s\n.I command\n[options]\n", programName)
+		b.WriteString(".SH DESCRIPTION\n")
+		b.WriteString("Checks, updates, and verifies the SHA pinning of the actions used by GitHub workflow files.\n")
+		b.WriteString(".SH COMMANDS\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape(cmd.Name), roffEscape(cmd.Summary))
+		}
+		b.WriteString(".SH ENVIRONMENT\n")
+		b.WriteString(".TP\n.B GITHUB_TOKEN, GH_TOKEN\nGitHub API token for higher rate limits.\n")
+		b.WriteString(".TP\n.B NO_COLOR\nUse the mono theme unless a theme is selected.\n")
+		b.WriteString(".SH SEE ALSO\n")
+		pages := make([]string, 0, len(commands))
+		for _, cmd := range commands {
+			pages = append(pages, roffEscape(strings.TrimSuffix(manPageName(cmd), ".1"))+"(1)")
+		}
+		b.WriteString(strings.Join(pages, ", ") + "\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%s \\- %s\n", roffEscape(title), roffEscape(c.Summary))
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %s %s\n[options] %s\n", programName, roffEscape(c.Name), roffEscape(c.Args))
+	b.WriteString(".SH DESCRIPTION\n")
+	b.WriteString(roffEscape(c.Description) + "\n")
+
+	if flags := commandFlags(c); len(flags) > 0 {
+		b.WriteString(".SH OPTIONS\n")
+		for _, f := range flags {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape(flagSynopsis(f)), roffEscape(flagDescription(f)))
+		}
+	}
+
+	if len(c.Examples) > 0 {
+		b.WriteString(".SH EXAMPLES\n")
+		for _, example := range c.Examples {
+			fmt.Fprintf(&b, ".TP\n%s\n.nf\n%s\n.fi\n", roffEscape(example.Description), roffEscape(example.Command))
+		}
+	}
+
+	fmt.Fprintf(&b, ".SH SEE ALSO\n%s(1)\n", programName)
+	return b.String()
+}
+
+// setupDocsMan registers the flags of the docs man command and returns its runner
+func setupDocsMan(fs *flag.FlagSet) func(args []string) {
+	dir := fs.String("dir", defaultManDir, "`Directory` to write the man pages to")
+
+	return func(_ []string) {
+		if err := os.MkdirAll(*dir, 0750); err != nil {
+			fmt.Fprintf(console, "Error: failed to create %s: %v\n", *dir, err)
+			os.Exit(1)
+		}
+
+		date := time.Now().UTC().Format("2006-01-02")
+		pages := append([]*command{nil}, commands...)
+		for _, cmd := range pages {
+			pagePath := filepath.Join(*dir, manPageName(cmd))
+			if err := os.WriteFile(pagePath, []byte(renderManPage(cmd, date)), 0600); err != nil {
+				fmt.Fprintf(console, "Error: failed to write %s: %v\n", pagePath, err)
+				os.Exit(1)
+			}
+		}
+		fmt.Fprintf(console, "%s Wrote %d man pages to %s\n", theme.OK, len(pages), *dir)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commentDrift explains why the pin comment of a commit SHA pin no longer
+// names the pinned commit, or returns "" when it does. When the tag points
+// elsewhere, the tags at the pinned commit tell a stale comment from a tag
+// that was moved after pinning.
+func commentDrift(forge Forge, owner, repo, sha, tag string) (string, error) {
+	target, err := forge.ResolveRef(owner, repo, tag)
+	if errorKindOf(err) == ErrorKindNotFound {
+		return fmt.Sprintf("the pin comment names %s, which %s/%s does not have", tag, owner, repo), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.EqualFold(target, sha) {
+		return "", nil
+	}
+
+	tags, err := forge.TagsAt(owner, repo, sha)
+	if err != nil {
+		logger.Debug("could not list the tags of the pinned commit", "repo", owner+"/"+repo, "sha", sha, "error", err)
+	}
+	if len(tags) > 0 {
+		return fmt.Sprintf("the pin comment says %s, but the pinned commit %s is %s; the comment is stale", tag, shortPin(sha), strings.Join(tags, ", ")), nil
+	}
+	return fmt.Sprintf("the pin comment says %s, which now points at %s instead of the pinned commit %s; the tag may have been moved", tag, shortPin(target), shortPin(sha)), nil
+}
+
+// verifyPinComments resolves the tag in the comment of every commit SHA pin
+// and reports the pins whose tag points at another commit or no longer
+// exists. Pins without a comment are not checked.
+func verifyPinComments(forge Forge, actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n💬 Verifying pin comments name the pinned commits...")
+
+	checked := make(map[string]string)
+	failed, unverifiable := 0, 0
+	for _, action := range sortedActions(actions) {
+		if action.Docker || !action.Pinned() {
+			continue
+		}
+		tag := commentTag(action.OriginalLine, action.Repo)
+		if tag == "" {
+			continue
+		}
+		owner, repo, _ := splitActionPath(action.Repo)
+		key := strings.ToLower(owner+"/"+repo+"@"+action.CurrentRef) + "\x00" + tag
+
+		drift, ok := checked[key]
+		if !ok {
+			var err error
+			drift, err = commentDrift(forge, owner, repo, action.CurrentRef, tag)
+			if err != nil {
+				fmt.Fprintf(console, "  %s %s:%d %s: could not resolve %s: %v %s\n", severityStatus(ruleCommentMismatch), action.WorkflowFile, action.Line, action.Repo, tag, err, ruleTag(ruleCommentMismatch))
+				unverifiable++
+				continue
+			}
+			checked[key] = drift
+		}
+		if drift != "" {
+			fmt.Fprintf(console, "  %s %s:%d %s: %s %s\n", severityStatus(ruleCommentMismatch), action.WorkflowFile, action.Line, action.Repo, drift, ruleTag(ruleCommentMismatch))
+			failed++
+		}
+	}
+
+	if failed+unverifiable > 0 {
+		fmt.Fprintln(console, "     💡 Fix stale comments by hand, or re-pin the action with update; a moved tag deserves a look at what changed")
+		return fmt.Errorf("%d pin comment(s) do not match their pinned commits and %d could not be checked", failed, unverifiable)
+	}
+	fmt.Fprintf(console, "%s All pin comments name the pinned commits\n", theme.OK)
+	return nil
+}
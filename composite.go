@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// defaultActionDir is where repositories keep their local composite actions
+	defaultActionDir = ".github/actions"
+)
+
+// isActionFile reports whether a path names an action metadata file
+func isActionFile(file string) bool {
+	name := filepath.Base(file)
+	return name == "action.yml" || name == "action.yaml"
+}
+
+// rootActionFiles returns the action manifest at the repository root, for
+// repositories that are themselves an action
+func rootActionFiles() []string {
+	var files []string
+	for _, name := range []string{"action.yml", "action.yaml"} {
+		if info, err := os.Stat(name); err == nil && !info.IsDir() {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// isRootActionFile reports whether a path names the root action manifest
+func isRootActionFile(file string) bool {
+	return filepath.Dir(file) == "." && isActionFile(file)
+}
+
+// actionPaths returns the directories searched for local composite actions
+func (c *Config) actionPaths() []string {
+	if c != nil && len(c.ActionPaths) > 0 {
+		return c.ActionPaths
+	}
+	return []string{defaultActionDir}
+}
+
+// findActionFiles returns the action.yml and action.yaml files below the
+// given directories, sorted by path. Missing directories contribute nothing.
+func findActionFiles(roots []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, root := range roots {
+		_ = filepath.WalkDir(filepath.Clean(root), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !isActionFile(path) || seen[path] {
+				return nil
+			}
+			seen[path] = true
+			files = append(files, path)
+			return nil
+		})
+	}
+	sort.Strings(files)
+	return files
+}
+
+// underActionPath reports whether a path is an action file below one of roots
+func underActionPath(file string, roots []string) bool {
+	if !isActionFile(file) {
+		return false
+	}
+	for _, root := range roots {
+		if rel, err := filepath.Rel(filepath.Clean(root), file); err == nil && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
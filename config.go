@@ -0,0 +1,485 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultConfigFile is the configuration file looked up in the current directory
+	defaultConfigFile = ".github-ci-hash.yml"
+)
+
+// Config represents the contents of the .github-ci-hash.yml configuration file
+type Config struct {
+	// Include restricts scanning to workflow files matching at least one glob
+	Include []string `yaml:"include,omitempty"`
+	// Exclude skips workflow files matching any of the globs
+	Exclude []string `yaml:"exclude,omitempty"`
+	// ActionPaths lists the directories searched for local composite actions
+	// (action.yml or action.yaml); defaults to .github/actions
+	ActionPaths []string `yaml:"action_paths,omitempty"`
+	// TemplatePaths lists files and directories holding template sources that
+	// generate workflows (Jinja, cookiecutter, Helm, ytt, jsonnet); their
+	// literal uses: references are scanned and pinned
+	TemplatePaths []string `yaml:"template_paths,omitempty"`
+	// Actions holds per-action settings keyed by "owner/repo" (or a full sub-action path)
+	Actions map[string]ActionConfig `yaml:"actions,omitempty"`
+	// Groups lists actions that must be updated together. The first group
+	// matching an action wins.
+	Groups []GroupConfig `yaml:"groups,omitempty"`
+	// Theme selects the output theme; the --theme flag takes precedence
+	Theme string `yaml:"theme,omitempty"`
+	// Hooks overrides the scripts written by install-hooks, keyed by hook
+	// name. Values are Go templates; an empty value disables the hook.
+	Hooks map[string]string `yaml:"hooks,omitempty"`
+	// HookArgs overrides the arguments the default hooks pass to the tool,
+	// keyed by hook name, e.g. "verify --changed" for pre-commit
+	HookArgs map[string]string `yaml:"hook_args,omitempty"`
+	// GitFallback resolves actions with git ls-remote once the REST API quota
+	// is exhausted; the --git-fallback flag enables it as well
+	GitFallback bool `yaml:"git_fallback,omitempty"`
+	// Workflow customizes the companion workflow written by init workflow
+	Workflow WorkflowConfig `yaml:"workflow,omitempty"`
+	// Limits bounds the workflows that are parsed, protecting against
+	// pathological files in scanned third-party repositories
+	Limits LimitsConfig `yaml:"limits,omitempty"`
+	// PinComment is a Go template for the comment written after pinned
+	// references, with .Tag and .Date; an empty value writes no comment
+	PinComment *string `yaml:"pin_comment,omitempty"`
+	// TwoFactorOrgs lists organizations known to enforce two-factor
+	// authentication. Only owners can read the setting through the API, so
+	// this curated list vouches for the publishers of third-party actions.
+	TwoFactorOrgs []string `yaml:"two_factor_orgs,omitempty"`
+	// AllowUnverified are globs of the actions verify --require-verified
+	// accepts from publishers that are not verified creators
+	AllowUnverified []string `yaml:"allow_unverified,omitempty"`
+	// AllowPersonal are globs of the actions verify --require-org-owned
+	// accepts from personal accounts and forks
+	AllowPersonal []string `yaml:"allow_personal,omitempty"`
+	// Denylist lists organization-specific compromised or banned actions,
+	// checked along with the built-in denylist
+	Denylist []DenyEntry `yaml:"denylist,omitempty"`
+	// DenylistURL is fetched for an updated denylist in the format of the
+	// built-in one
+	DenylistURL string `yaml:"denylist_url,omitempty"`
+	// Allowlist restricts the actions verify accepts to trusted owners
+	Allowlist AllowlistConfig `yaml:"allowlist,omitempty"`
+	// Policies lists Rego or CUE policies evaluated against the scanned
+	// actions by verify, audit and policy eval
+	Policies []PolicyConfig `yaml:"policies,omitempty"`
+	// Rules remaps the severity of findings by rule ID or name, e.g.
+	// GCH003: error; off disables a rule
+	Rules map[string]string `yaml:"rules,omitempty"`
+	// TagFallback is a regular expression selecting the tags considered for
+	// repositories that publish no releases; the highest match is used.
+	// Defaults to version tags such as v1.2.3.
+	TagFallback string `yaml:"tag_fallback,omitempty"`
+	// IncludePrereleases makes release candidates, betas and other
+	// pre-releases eligible as the latest version; the
+	// --include-prereleases flag enables it as well
+	IncludePrereleases bool `yaml:"include_prereleases,omitempty"`
+	// Strategy limits updates to the same major and minor version (patch),
+	// the same major version (minor) or none (major, the default); the
+	// --strategy flag overrides it
+	Strategy string `yaml:"strategy,omitempty"`
+	// Resolver names the ci-hash-<name> plugin that resolves every action
+	// without a resolver of its own, instead of the GitHub API
+	Resolver string `yaml:"resolver,omitempty"`
+	// MinReleaseAge holds back releases younger than this age, e.g. 7d, so
+	// freshly compromised or yanked releases are not adopted; the
+	// --min-release-age flag overrides it
+	MinReleaseAge string `yaml:"min_release_age,omitempty"`
+	// MinScorecard fails check and audit for action repositories scoring
+	// below it in OpenSSF Scorecard, out of 10; the --min-scorecard flag
+	// overrides it
+	MinScorecard float64 `yaml:"min_scorecard,omitempty"`
+	// StaleAfter is the age of a latest release after which check
+	// --deprecated reports the action as possibly unmaintained, 730d by
+	// default; 0d disables it
+	StaleAfter string `yaml:"stale_after,omitempty"`
+	// PreferImmutable updates actions to their newest immutable release
+	// when the latest release is mutable
+	PreferImmutable bool `yaml:"prefer_immutable,omitempty"`
+	// RequireImmutable are globs of critical actions that must be pinned to
+	// immutable releases; updates of them prefer immutable releases too
+	RequireImmutable []string `yaml:"require_immutable,omitempty"`
+	// DenyLicenses lists the SPDX identifiers of licenses the licenses
+	// command fails for, e.g. AGPL-3.0; NONE matches repositories without
+	// a license and NOASSERTION licenses GitHub does not recognize
+	DenyLicenses []string `yaml:"deny_licenses,omitempty"`
+	// PinDatabase is the committed file recording the commit every release
+	// tag resolved to when first seen, e.g. .github-ci-hash-pins.json; tags
+	// resolving to another commit later are reported instead of updated
+	PinDatabase string `yaml:"pin_database,omitempty"`
+
+	tagFallback *regexp.Regexp
+	// source identifies the file the configuration was read from
+	source *fileDigest
+}
+
+// includePrereleases reports whether pre-releases are eligible as the latest
+// version of an action. A per-action setting wins over the top-level one.
+func (c *Config) includePrereleases(repo string) bool {
+	if c == nil {
+		return false
+	}
+	if setting := c.actionConfig(repo).IncludePrereleases; setting != nil {
+		return *setting
+	}
+	return c.IncludePrereleases
+}
+
+// tagFallbackFilter returns the filter selecting the tags of repositories
+// without releases
+func (c *Config) tagFallbackFilter() *regexp.Regexp {
+	if c != nil && c.tagFallback != nil {
+		return c.tagFallback
+	}
+	return versionTagRegex
+}
+
+// LimitsConfig holds the parse limits; zero values use the defaults
+type LimitsConfig struct {
+	// MaxFileSize is the largest file parsed, in bytes
+	MaxFileSize int64 `yaml:"max_file_size,omitempty"`
+	// MaxLineLength is the longest line accepted, in characters
+	MaxLineLength int `yaml:"max_line_length,omitempty"`
+	// MaxActions is the largest number of uses: references accepted per file
+	MaxActions int `yaml:"max_actions,omitempty"`
+}
+
+// WorkflowConfig holds the inputs of the generated companion workflow
+type WorkflowConfig struct {
+	// Schedule is the cron expression the checks run on
+	Schedule string `yaml:"schedule,omitempty"`
+	// Branch is the branch update pull requests are pushed to
+	Branch string `yaml:"branch,omitempty"`
+	// Labels are added to update pull requests
+	Labels []string `yaml:"labels,omitempty"`
+	// Reviewers are requested on update pull requests
+	Reviewers []string `yaml:"reviewers,omitempty"`
+	// TokenSecret names the secret holding a token allowed to push workflow changes
+	TokenSecret string `yaml:"token_secret,omitempty"`
+}
+
+// GroupConfig defines a logical dependency group
+type GroupConfig struct {
+	// Name identifies the group in output and prompts
+	Name string `yaml:"name"`
+	// Actions are globs matched against the action path, e.g. "docker/*"
+	Actions []string `yaml:"actions"`
+}
+
+// matches reports whether an action path belongs to the group
+func (g GroupConfig) matches(repo string) bool {
+	return matchesActionGlobs(g.Actions, repo)
+}
+
+// matchesActionGlobs reports whether any glob matches an action path.
+// Patterns are matched against the full path and the owner/repo part of
+// sub-actions, so "docker/*" also matches "docker/setup/action".
+func matchesActionGlobs(patterns []string, repo string) bool {
+	candidates := []string{repo}
+	if parts := strings.SplitN(repo, "/", 3); len(parts) == 3 {
+		candidates = append(candidates, parts[0]+"/"+parts[1])
+	}
+	for _, pattern := range patterns {
+		for _, candidate := range candidates {
+			if matched, err := path.Match(pattern, candidate); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// actionGroup returns the name of the logical group an action belongs to, or
+// an empty string when it is not grouped. Configured groups take precedence
+// over the built-in grouping of sub-actions.
+func (c *Config) actionGroup(repo string) string {
+	if c != nil {
+		for _, group := range c.Groups {
+			if group.matches(repo) {
+				return group.Name
+			}
+		}
+	}
+	return builtinActionGroup(repo)
+}
+
+// ActionConfig holds settings that apply to a single action
+type ActionConfig struct {
+	// TagFilter is a regular expression that candidate release tags must match
+	TagFilter string `yaml:"tag_filter,omitempty"`
+	// IncludePrereleases overrides the top-level include_prereleases for
+	// this action
+	IncludePrereleases *bool `yaml:"include_prereleases,omitempty"`
+	// Resolver names the ci-hash-<name> plugin that resolves this action
+	Resolver string `yaml:"resolver,omitempty"`
+	// MinReleaseAge overrides the top-level min_release_age for this
+	// action; 0d disables the cool-off
+	MinReleaseAge string `yaml:"min_release_age,omitempty"`
+
+	tagFilter *regexp.Regexp
+}
+
+// resolver returns the resolver plugin of an action repository, or "" when
+// it is resolved by the built-in forges
+func (c *Config) resolver(repo string) string {
+	if c == nil {
+		return ""
+	}
+	if name := c.actionConfig(repo).Resolver; name != "" {
+		return name
+	}
+	return c.Resolver
+}
+
+// usesResolvers reports whether any action is resolved by a plugin
+func (c *Config) usesResolvers() bool {
+	if c == nil {
+		return false
+	}
+	for _, ac := range c.Actions {
+		if ac.Resolver != "" {
+			return true
+		}
+	}
+	return c.Resolver != ""
+}
+
+// actionConfig returns the settings for an action repository. An exact match
+// on the full path (e.g. "github/codeql-action/analyze") wins over the
+// owner/repo entry.
+func (c *Config) actionConfig(repo string) ActionConfig {
+	if c == nil || c.Actions == nil {
+		return ActionConfig{}
+	}
+	if ac, ok := c.Actions[repo]; ok {
+		return ac
+	}
+	if parts := strings.SplitN(repo, "/", 3); len(parts) == 3 {
+		if ac, ok := c.Actions[parts[0]+"/"+parts[1]]; ok {
+			return ac
+		}
+	}
+	return ActionConfig{}
+}
+
+// loadConfig reads the configuration file at configPath. A missing file yields an
+// empty configuration unless the path was given explicitly.
+func loadConfig(configPath string, explicit bool) (*Config, error) {
+	cfg := &Config{}
+
+	content, err := os.ReadFile(filepath.Clean(configPath))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	if err := interpolateConfigDocument(&doc); err != nil {
+		return nil, fmt.Errorf("invalid config file %s:\n  %s", configPath, strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+	if err := validateConfigDocument(&doc); err != nil {
+		return nil, fmt.Errorf("invalid config file %s:\n  %s", configPath, strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+	if err := doc.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	cfg.source = &fileDigest{Path: configPath, SHA256: digestOf(content)}
+
+	if err := validateGlobs(cfg.Include); err != nil {
+		return nil, fmt.Errorf("invalid include pattern in %s: %w", configPath, err)
+	}
+	if err := validateGlobs(cfg.Exclude); err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern in %s: %w", configPath, err)
+	}
+	if err := validateGlobs(cfg.AllowUnverified); err != nil {
+		return nil, fmt.Errorf("invalid allow_unverified pattern in %s: %w", configPath, err)
+	}
+	if err := validateGlobs(cfg.AllowPersonal); err != nil {
+		return nil, fmt.Errorf("invalid allow_personal pattern in %s: %w", configPath, err)
+	}
+	if err := validateGlobs(cfg.RequireImmutable); err != nil {
+		return nil, fmt.Errorf("invalid require_immutable pattern in %s: %w", configPath, err)
+	}
+
+	if cfg.Theme != "" {
+		if _, err := lookupTheme(cfg.Theme); err != nil {
+			return nil, fmt.Errorf("invalid theme in %s: %w", configPath, err)
+		}
+	}
+
+	for name := range cfg.HookArgs {
+		if !isManagedHookName(name) {
+			return nil, fmt.Errorf("unknown hook %q in hook_args of %s", name, configPath)
+		}
+	}
+
+	for name, text := range cfg.Hooks {
+		if !isManagedHookName(name) {
+			return nil, fmt.Errorf("unknown hook %q in %s", name, configPath)
+		}
+		if _, err := template.New(name).Parse(text); err != nil {
+			return nil, fmt.Errorf("invalid template for %s hook in %s: %w", name, configPath, err)
+		}
+	}
+
+	if _, err := newPinCommentFormat(cfg.pinCommentFormat()); err != nil {
+		return nil, fmt.Errorf("invalid pin_comment in %s: %w", configPath, err)
+	}
+
+	seenGroups := make(map[string]bool)
+	for i, group := range cfg.Groups {
+		if group.Name == "" {
+			return nil, fmt.Errorf("groups[%d] in %s has no name", i, configPath)
+		}
+		if seenGroups[group.Name] {
+			return nil, fmt.Errorf("duplicate group %q in %s", group.Name, configPath)
+		}
+		seenGroups[group.Name] = true
+		if len(group.Actions) == 0 {
+			return nil, fmt.Errorf("group %q in %s lists no actions", group.Name, configPath)
+		}
+		if err := validateGlobs(group.Actions); err != nil {
+			return nil, fmt.Errorf("invalid action pattern in group %q in %s: %w", group.Name, configPath, err)
+		}
+	}
+
+	if err := cfg.Allowlist.validate(); err != nil {
+		return nil, fmt.Errorf("invalid allowlist in %s: %w", configPath, err)
+	}
+
+	if err := validatePolicies(cfg.Policies); err != nil {
+		return nil, fmt.Errorf("invalid policies in %s: %w", configPath, err)
+	}
+
+	if _, err := resolveRuleSeverities(cfg.Rules); err != nil {
+		return nil, fmt.Errorf("invalid rules in %s: %w", configPath, err)
+	}
+
+	if cfg.Resolver != "" && !pluginNameRegex.MatchString(cfg.Resolver) {
+		return nil, fmt.Errorf("invalid resolver %q in %s", cfg.Resolver, configPath)
+	}
+
+	if _, err := parseUpdateStrategy(cfg.Strategy); err != nil {
+		return nil, fmt.Errorf("invalid strategy in %s: %w", configPath, err)
+	}
+
+	if cfg.MinScorecard < 0 || cfg.MinScorecard > 10 {
+		return nil, fmt.Errorf("invalid min_scorecard %g in %s: scores range from 0 to 10", cfg.MinScorecard, configPath)
+	}
+
+	if cfg.MinReleaseAge != "" {
+		if _, err := parseAge(cfg.MinReleaseAge); err != nil {
+			return nil, fmt.Errorf("invalid min_release_age in %s: %w", configPath, err)
+		}
+	}
+	if cfg.StaleAfter != "" {
+		if _, err := parseAge(cfg.StaleAfter); err != nil {
+			return nil, fmt.Errorf("invalid stale_after in %s: %w", configPath, err)
+		}
+	}
+
+	if cfg.TagFallback != "" {
+		re, err := regexp.Compile(cfg.TagFallback)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag_fallback in %s: %w", configPath, err)
+		}
+		cfg.tagFallback = re
+	}
+
+	for name, ac := range cfg.Actions {
+		if ac.Resolver != "" && !pluginNameRegex.MatchString(ac.Resolver) {
+			return nil, fmt.Errorf("invalid resolver %q for %s in %s", ac.Resolver, name, configPath)
+		}
+		if ac.MinReleaseAge != "" {
+			if _, err := parseAge(ac.MinReleaseAge); err != nil {
+				return nil, fmt.Errorf("invalid min_release_age for %s in %s: %w", name, configPath, err)
+			}
+		}
+		if ac.TagFilter != "" {
+			re, err := regexp.Compile(ac.TagFilter)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tag_filter for %s in %s: %w", name, configPath, err)
+			}
+			ac.tagFilter = re
+			cfg.Actions[name] = ac
+		}
+	}
+
+	return cfg, nil
+}
+
+// setupConfigValidate registers the flags of the config validate command and returns its runner
+func setupConfigValidate(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+
+	return func(_ []string) {
+		// Unlike other commands, validating a missing default file is an error
+		if _, err := loadConfig(opts.configPath, true); err != nil {
+			fmt.Fprintf(console, "%s %v\n", theme.Error, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "%s %s is valid\n", theme.OK, opts.configPath)
+	}
+}
+
+// setupConfigShow registers the flags of the config show command and returns its runner
+func setupConfigShow(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	effective := fs.Bool("effective", false, "Merge command line flags and defaults into the output")
+
+	return func(_ []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+		header := []string{"Configuration loaded from " + opts.configPath}
+		if *effective {
+			merged := *cfg
+			merged.Include = scanOpts.Include
+			merged.Exclude = scanOpts.Exclude
+			merged.Theme = theme.Name
+			merged.ActionPaths = scanOpts.ActionPaths
+			merged.Limits = cfg.Limits.effective()
+			cfg = &merged
+			header = []string{"Effective configuration: " + opts.configPath + " merged with command line flags and defaults"}
+		}
+
+		content, err := renderConfig(cfg, header)
+		if err != nil {
+			fmt.Fprintf(console, "Error rendering config: %v\n", err)
+			os.Exit(1)
+		}
+		if _, err := os.Stdout.Write(content); err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// setupConfigSchema registers the flags of the config schema command and returns its runner
+func setupConfigSchema(_ *flag.FlagSet) func(args []string) {
+	return func(_ []string) {
+		if _, err := os.Stdout.Write(configSchemaJSON); err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// PendingRelease is a release held back because it is younger than the
+// minimum release age; it is offered once the cool-off period ends
+type PendingRelease struct {
+	Tag string `json:"tag"`
+	SHA string `json:"sha"`
+	// Date is the publication date as YYYY-MM-DD
+	Date string `json:"date"`
+	// Eligible is the first day the release is offered, as YYYY-MM-DD
+	Eligible string `json:"eligible"`
+}
+
+// minReleaseAge returns the age a release must reach before it is offered
+// as an update of repo, and the setting it comes from. A per-action setting
+// wins over the top-level one; zero disables the cool-off.
+func (c *Config) minReleaseAge(repo string) (time.Duration, string) {
+	if c == nil {
+		return 0, ""
+	}
+	setting, value := "min_release_age", c.MinReleaseAge
+	if age := c.actionConfig(repo).MinReleaseAge; age != "" {
+		setting, value = "actions."+repo+".min_release_age", age
+	}
+	if value == "" {
+		return 0, ""
+	}
+	// Validated when the configuration was loaded
+	age, _ := parseAge(value)
+	return age, setting + " " + value
+}
+
+// holdForCoolOff keeps an action at its current version when the release
+// it would be updated to was published less than minAge before now.
+// Releases without a publication date, such as bare tags, are not held.
+func holdForCoolOff(action *ActionInfo, date string, minAge time.Duration, setting, source string, now time.Time) {
+	if !action.NeedsUpdate || minAge <= 0 || date == "" {
+		return
+	}
+	published, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		logger.Debug("release date is not a date", "repo", action.Repo, "date", date)
+		return
+	}
+	// Only the day is known, so the age counts from the end of it
+	eligible := published.AddDate(0, 0, 1).Add(minAge)
+	if !now.Before(eligible) {
+		return
+	}
+
+	action.CoolingOff = &PendingRelease{
+		Tag:      action.LatestTag,
+		SHA:      action.LatestSHA,
+		Date:     date,
+		Eligible: eligible.Format(time.DateOnly),
+	}
+	action.Provenance = &Provenance{Reason: PinCoolOff, Tag: action.LatestTag, Setting: setting, Source: source}
+
+	current := currentVersion(*action)
+	if current == "" {
+		current = action.CurrentRef
+	}
+	action.LatestTag = current
+	action.LatestSHA = action.CurrentSHA
+	action.NeedsUpdate = false
+	action.BreakingChanges = nil
+	action.ReleaseURL, action.ReleaseNotes, action.ReleaseDate = "", "", ""
+}
+
+// describeCoolOff summarizes a held release for the console
+func describeCoolOff(pending *PendingRelease) string {
+	return fmt.Sprintf("%s released %s, offered from %s", pending.Tag, pending.Date, pending.Eligible)
+}
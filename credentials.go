@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// credentialService is the keychain service (or credential target) the
+	// token is looked up under
+	credentialService = "github-ci-hash"
+
+	// credentialHost is the host git credential helpers are asked about
+	credentialHost = "github.com"
+
+	// credentialTimeout bounds each external credential lookup
+	credentialTimeout = 5 * time.Second
+)
+
+// getTokenFromGitCredential asks the configured git credential helpers for a
+// github.com credential. Prompting is disabled so that a missing credential
+// never blocks the tool.
+func getTokenFromGitCredential() string {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=" + credentialHost + "\n\n")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never", "GIT_ASKPASS=")
+	output, err := cmd.Output()
+	if err != nil {
+		// git not available or no helper has a credential
+		return ""
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		if password, ok := strings.CutPrefix(scanner.Text(), "password="); ok {
+			return strings.TrimSpace(password)
+		}
+	}
+	return ""
+}
+
+// commandOutput runs a credential lookup command and returns its trimmed output
+func commandOutput(name string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialTimeout)
+	defer cancel()
+
+	// #nosec G204 - the commands and arguments are fixed by the caller
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
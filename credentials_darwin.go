@@ -0,0 +1,14 @@
+//go:build darwin
+
+package main
+
+// osCredentialSource names the credential store read by getTokenFromOSStore
+const osCredentialSource = "macOS Keychain"
+
+// getTokenFromOSStore reads the token from a generic password item in the
+// login keychain, created with:
+//
+//	security add-generic-password -s github-ci-hash -a "$USER" -w <token>
+func getTokenFromOSStore() string {
+	return commandOutput("security", "find-generic-password", "-s", credentialService, "-w")
+}
@@ -0,0 +1,14 @@
+//go:build !darwin && !windows
+
+package main
+
+// osCredentialSource names the credential store read by getTokenFromOSStore
+const osCredentialSource = "Secret Service"
+
+// getTokenFromOSStore reads the token from the freedesktop Secret Service
+// (GNOME Keyring, KWallet), stored with:
+//
+//	secret-tool store --label=github-ci-hash service github-ci-hash
+func getTokenFromOSStore() string {
+	return commandOutput("secret-tool", "lookup", "service", credentialService)
+}
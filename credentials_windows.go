@@ -0,0 +1,78 @@
+//go:build windows
+
+package main
+
+import (
+	"strings"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// osCredentialSource names the credential store read by getTokenFromOSStore
+const osCredentialSource = "Windows Credential Manager"
+
+// credTypeGeneric is CRED_TYPE_GENERIC
+const credTypeGeneric = 1
+
+var (
+	advapi32      = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW = advapi32.NewProc("CredReadW")
+	procCredFree  = advapi32.NewProc("CredFree")
+)
+
+// winCredential mirrors the CREDENTIALW structure
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// getTokenFromOSStore reads the token from a generic credential, created with:
+//
+//	cmdkey /generic:github-ci-hash /user:token /pass:<token>
+func getTokenFromOSStore() string {
+	target, err := syscall.UTF16PtrFromString(credentialService)
+	if err != nil {
+		return ""
+	}
+
+	var cred *winCredential
+	// #nosec G103 - CredReadW returns the credential through a pointer
+	ret, _, _ := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 || cred == nil {
+		return ""
+	}
+	defer func() {
+		// #nosec G103 - the credential must be released with CredFree
+		_, _, _ = procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	}()
+
+	if cred.CredentialBlobSize == 0 || cred.CredentialBlob == nil {
+		return ""
+	}
+	// #nosec G103 - the blob is CredentialBlobSize bytes long
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	return strings.TrimSpace(decodeCredentialBlob(blob))
+}
+
+// decodeCredentialBlob decodes a credential blob, which cmdkey stores as UTF-16
+func decodeCredentialBlob(blob []byte) string {
+	if len(blob)%2 != 0 || blob[1] != 0 {
+		return string(blob)
+	}
+	units := make([]uint16, len(blob)/2)
+	for i := range units {
+		units[i] = uint16(blob[2*i]) | uint16(blob[2*i+1])<<8
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// denylistFormatVersion is the version of the denylist file format
+	denylistFormatVersion = 1
+
+	// denylistTimeout bounds the download of a remote denylist
+	denylistTimeout = 30 * time.Second
+
+	// maxDenylistSize bounds a downloaded denylist
+	maxDenylistSize = 4 << 20
+
+	// Sources of a denylist entry
+	denySourceBuiltin = "builtin"
+	denySourceRemote  = "remote"
+	denySourceConfig  = "config"
+)
+
+// builtinDenylistJSON lists the action versions known to be compromised
+// when the binary was built
+//
+//go:embed denylist/actions.json
+var builtinDenylistJSON []byte
+
+// DenyEntry is a compromised or banned action. It denies the commit SHA,
+// the tag or branch Ref, or every version of Repo when neither is given.
+type DenyEntry struct {
+	Repo   string `yaml:"repo" json:"repo"`
+	SHA    string `yaml:"sha,omitempty" json:"sha,omitempty"`
+	Ref    string `yaml:"ref,omitempty" json:"ref,omitempty"`
+	Reason string `yaml:"reason,omitempty" json:"reason,omitempty"`
+	URL    string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Source is "builtin", "remote" or "config"
+	Source string `yaml:"-" json:"source"`
+}
+
+// denylistFile is the format of the built-in and remote denylists
+type denylistFile struct {
+	Version   int         `json:"version"`
+	UpdatedAt string      `json:"updated_at,omitempty"`
+	Entries   []DenyEntry `json:"entries"`
+}
+
+// parseDenylist reads the entries of a denylist file
+func parseDenylist(data []byte, source string) ([]DenyEntry, error) {
+	var list denylistFile
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid denylist: %w", err)
+	}
+	if list.Version != denylistFormatVersion {
+		return nil, fmt.Errorf("unsupported denylist version %d", list.Version)
+	}
+	for i := range list.Entries {
+		if list.Entries[i].Repo == "" {
+			return nil, fmt.Errorf("denylist entry %d has no repo", i+1)
+		}
+		list.Entries[i].Source = source
+	}
+	return list.Entries, nil
+}
+
+// fetchDenylist downloads the denylist at url
+func fetchDenylist(url string) ([]DenyEntry, error) {
+	client := &http.Client{Transport: loggingTransport{}, Timeout: denylistTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, &ResolveError{Kind: ErrorKindNetwork, Err: fmt.Errorf("denylist %s could not be reached: %w", url, err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching denylist %s failed with status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDenylistSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read denylist %s: %w", url, err)
+	}
+	return parseDenylist(data, denySourceRemote)
+}
+
+// loadDenylist returns the built-in denylist, the one at denylist_url unless
+// offline, and the entries of the configuration. A remote list that cannot
+// be fetched only loses its new entries.
+func loadDenylist(cfg *Config, offline bool) ([]DenyEntry, error) {
+	entries, err := parseDenylist(builtinDenylistJSON, denySourceBuiltin)
+	if err != nil {
+		return nil, fmt.Errorf("built-in %w", err)
+	}
+
+	switch {
+	case cfg.DenylistURL == "":
+	case offline:
+		logger.Debug("skipping the remote denylist in offline mode", "url", cfg.DenylistURL)
+	default:
+		remote, err := fetchDenylist(cfg.DenylistURL)
+		if err != nil {
+			fmt.Fprintf(console, "%s Using the built-in denylist: %v\n", theme.Warning, err)
+		}
+		entries = append(entries, remote...)
+	}
+
+	for _, entry := range cfg.Denylist {
+		entry.Source = denySourceConfig
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// matches reports whether the entry denies the version of action in use.
+// Commits are matched by the pinned SHA or the commit a tag resolved to, so
+// unpinned references are only matched by SHA after resolution.
+func (e DenyEntry) matches(action ActionInfo) bool {
+	owner, repo, _ := splitActionPath(action.Repo)
+	if !strings.EqualFold(e.Repo, action.Repo) && !strings.EqualFold(e.Repo, owner+"/"+repo) {
+		return false
+	}
+	if e.SHA == "" && e.Ref == "" {
+		return true
+	}
+
+	if e.SHA != "" {
+		sha := strings.ToLower(e.SHA)
+		ref := strings.ToLower(action.CurrentRef)
+		if strings.ToLower(action.CurrentSHA) == sha || ref == sha || (shortSHARegex.MatchString(ref) && strings.HasPrefix(sha, ref)) {
+			return true
+		}
+	}
+	if e.Ref != "" {
+		if action.CurrentRef == e.Ref || (action.Pinned() && commentTag(action.OriginalLine, action.Repo) == e.Ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe explains why an entry denies an action
+func (e DenyEntry) describe() string {
+	description := "denylisted"
+	if e.Reason != "" {
+		description += ": " + e.Reason
+	}
+	if e.Source == denySourceConfig {
+		description += " (from the configuration)"
+	}
+	return description
+}
+
+// markCompromised records in action.Compromised the first entry denying the
+// version of every action and returns the number of actions denied
+func markCompromised(entries []DenyEntry, actions WorkflowActions) int {
+	denied := 0
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker {
+				continue
+			}
+			for _, entry := range entries {
+				if entry.matches(*action) {
+					matched := entry
+					action.Compromised = &matched
+					denied++
+					break
+				}
+			}
+		}
+	}
+	return denied
+}
+
+// checkDenylist reports the actions whose version in use is compromised and
+// returns an error when there is any. Nothing is checked when the rule is
+// disabled.
+func checkDenylist(entries []DenyEntry, actions WorkflowActions) error {
+	if !ruleEnabled(ruleDenylisted) {
+		return nil
+	}
+	fmt.Fprintln(console, "\n🚫 Checking actions against the denylist of compromised versions...")
+
+	denied := markCompromised(entries, actions)
+	for _, action := range sortedActions(actions) {
+		if action.Compromised == nil {
+			continue
+		}
+		fmt.Fprintf(console, "  %s %s:%d %s: %s %s\n", severityStatus(ruleDenylisted), action.WorkflowFile, action.Line, action.reference(), action.Compromised.describe(), ruleTag(ruleDenylisted))
+		if action.Compromised.URL != "" {
+			fmt.Fprintf(console, "      %s\n", action.Compromised.URL)
+		}
+	}
+
+	if denied > 0 {
+		return fmt.Errorf("%d action(s) use a denylisted version; pin them to a safe commit and rotate the secrets their workflows could read", denied)
+	}
+	fmt.Fprintf(console, "%s No action uses a denylisted version\n", theme.OK)
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultStaleAfter is the age of a latest release after which an
+	// action is reported as possibly unmaintained
+	defaultStaleAfter = "730d"
+
+	// readmeNoticeWindow is how much of a README is searched for a
+	// deprecation notice; notices are put at the top
+	readmeNoticeWindow = 4096
+
+	// maxNoticeLength bounds the notice quoted in the output
+	maxNoticeLength = 120
+)
+
+// knownSuccessors maps well-known deprecated actions to their replacement
+var knownSuccessors = map[string]string{
+	"actions/create-release":       "softprops/action-gh-release",
+	"actions/upload-release-asset": "softprops/action-gh-release",
+	"actions/setup-ruby":           "ruby/setup-ruby",
+	"actions/setup-elixir":         "erlef/setup-beam",
+	"actions/setup-haskell":        "haskell-actions/setup",
+	"actions-rs/toolchain":         "dtolnay/rust-toolchain",
+}
+
+// deprecationNoticeRegex matches statements that an action is deprecated,
+// such as "This action is deprecated" or a "DEPRECATED" heading
+var deprecationNoticeRegex = regexp.MustCompile(`(?im)\b(?:this|the)\s+(?:action|repository|repo|project)\s+(?:is|has\s+been)\s+(?:now\s+)?(?:deprecated|archived|unmaintained|no\s+longer\s+(?:maintained|supported))|^[\s#>*_]*(?:\[!\w+\]\s*)?(?:⚠️\s*)?deprecated(?:[\s*_:!.]|$)`)
+
+// successorRegex matches the replacement a deprecation notice points at,
+// e.g. "use ruby/setup-ruby instead" or "in favor of [owner/repo](...)"
+var successorRegex = regexp.MustCompile("(?i)\\b(?:use|in\\s+favou?r\\s+of|replaced\\s+by|superseded\\s+by|successor(?:\\s+is)?:?|migrate\\s+to|switch\\s+to)\\s+\\[?`?(?:https://github\\.com/)?([a-z0-9][a-z0-9-]*/[a-z0-9._-]*[a-z0-9_-])")
+
+// Deprecation records why an action repository looks dead
+type Deprecation struct {
+	Archived bool `json:"archived,omitempty"`
+	// Notice is the deprecation statement of the repository description or
+	// README
+	Notice string `json:"notice,omitempty"`
+	// LastRelease is the date of a latest release older than stale_after,
+	// as YYYY-MM-DD
+	LastRelease string `json:"last_release,omitempty"`
+	// Successor is the action suggested as a replacement, when known
+	Successor string `json:"successor,omitempty"`
+}
+
+// describe summarizes the deprecation for the console
+func (d *Deprecation) describe() string {
+	var reasons []string
+	if d.Archived {
+		reasons = append(reasons, "archived")
+	}
+	if d.Notice != "" {
+		reasons = append(reasons, fmt.Sprintf("deprecated (%q)", d.Notice))
+	}
+	if d.LastRelease != "" {
+		reasons = append(reasons, "no release since "+d.LastRelease)
+	}
+	description := strings.Join(reasons, ", ")
+	if d.Successor != "" {
+		description += "; use " + d.Successor + " instead"
+	}
+	return description
+}
+
+// staleAfter returns the configured release age after which an action is
+// reported, or zero when the check is disabled
+func (c *Config) staleAfter() time.Duration {
+	value := defaultStaleAfter
+	if c != nil && c.StaleAfter != "" {
+		value = c.StaleAfter
+	}
+	// Validated when the configuration was loaded
+	age, _ := parseAge(value)
+	return age
+}
+
+// findDeprecationNotice returns the line of text declaring a deprecation,
+// stripped of markdown, and the successor it names other than repo
+func findDeprecationNotice(text, repo string) (string, string) {
+	loc := deprecationNoticeRegex.FindStringIndex(text)
+	if loc == nil {
+		return "", ""
+	}
+	start := strings.LastIndex(text[:loc[0]], "\n") + 1
+	end := len(text)
+	if i := strings.Index(text[loc[0]:], "\n"); i >= 0 {
+		end = loc[0] + i
+	}
+	notice := strings.TrimSpace(strings.Trim(strings.TrimSpace(text[start:end]), "#>*_[]!"))
+	if len(notice) > maxNoticeLength {
+		notice = strings.TrimSpace(notice[:maxNoticeLength]) + "…"
+	}
+
+	for _, match := range successorRegex.FindAllStringSubmatch(text[start:], -1) {
+		successor := strings.TrimSuffix(strings.TrimSuffix(match[1], ".git"), ".")
+		if !strings.EqualFold(successor, repo) {
+			return notice, successor
+		}
+	}
+	return notice, ""
+}
+
+// lookupDeprecation reads whether owner/repo is archived or declares a
+// deprecation in its description or README
+func lookupDeprecation(gc *GitHubClient, owner, repo string) (*Deprecation, error) {
+	name := owner + "/" + repo
+	repository, err := gc.api.Repository(gc.ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	deprecation := &Deprecation{Archived: repository.Archived}
+	deprecation.Notice, deprecation.Successor = findDeprecationNotice(repository.Description, name)
+	if deprecation.Successor != "" {
+		return deprecation, nil
+	}
+
+	readme, err := gc.api.Readme(gc.ctx, owner, repo)
+	switch {
+	case errorKindOf(err) == ErrorKindNotFound:
+	case err != nil:
+		return nil, err
+	default:
+		if len(readme) > readmeNoticeWindow {
+			readme = readme[:readmeNoticeWindow]
+		}
+		notice, successor := findDeprecationNotice(string(readme), name)
+		if deprecation.Notice == "" {
+			deprecation.Notice = notice
+		}
+		deprecation.Successor = successor
+	}
+	return deprecation, nil
+}
+
+// checkDeprecations flags actions whose repository is archived or
+// deprecated, or whose latest release is older than stale_after, and stores
+// the findings in the actions. Each repository is looked up once.
+func checkDeprecations(gc *GitHubClient, cfg *Config, actions WorkflowActions) {
+	fmt.Fprintln(console, "\n🪦 Checking for archived or deprecated actions...")
+
+	type lookup struct {
+		deprecation *Deprecation
+		err         error
+	}
+	staleAfter := cfg.staleAfter()
+	now := time.Now()
+	looked := make(map[string]lookup)
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker {
+				continue
+			}
+			owner, repo, _ := splitActionPath(action.Repo)
+			key := strings.ToLower(owner + "/" + repo)
+			found, ok := looked[key]
+			if !ok {
+				found.deprecation, found.err = lookupDeprecation(gc, owner, repo)
+				looked[key] = found
+			}
+			if found.err != nil {
+				continue
+			}
+
+			// Copied, since the release date differs between versions
+			deprecation := *found.deprecation
+			if released, err := time.Parse(time.DateOnly, action.ReleaseDate); err == nil && staleAfter > 0 && now.Sub(released) > staleAfter {
+				deprecation.LastRelease = action.ReleaseDate
+			}
+			if deprecation.Successor == "" {
+				deprecation.Successor = knownSuccessors[key]
+			}
+			if deprecation.Archived || deprecation.Notice != "" || deprecation.LastRelease != "" {
+				action.Deprecation = &deprecation
+			}
+		}
+	}
+
+	reported := make(map[string]bool)
+	flagged := 0
+	for _, action := range sortedByRepo(actions) {
+		owner, repo, _ := splitActionPath(action.Repo)
+		if found := looked[strings.ToLower(owner+"/"+repo)]; found.err != nil && !reported[action.Repo] {
+			reported[action.Repo] = true
+			fmt.Fprintf(console, "  %s %s: could not look up the repository: %v\n", theme.Warning, action.Repo, found.err)
+		}
+		if action.Deprecation == nil || reported[action.Repo] {
+			continue
+		}
+		reported[action.Repo] = true
+		flagged++
+		fmt.Fprintf(console, "  %s %s: %s %s\n", severityStatus(ruleDeprecated), action.Repo, action.Deprecation.describe(), ruleTag(ruleDeprecated))
+	}
+	if flagged == 0 {
+		fmt.Fprintf(console, "%s No action repository is archived, deprecated or stale\n", theme.OK)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ChangeKind classifies a difference between two check runs
+type ChangeKind string
+
+const (
+	// ChangeNewUpdate means an update became available, or a newer one than before
+	ChangeNewUpdate ChangeKind = "new_update"
+	// ChangeFixed means a violation of the old run is gone
+	ChangeFixed ChangeKind = "fixed"
+	// ChangeRegression means the new run has a violation the old run did not
+	ChangeRegression ChangeKind = "regression"
+	// ChangeAdded means a pinned action reference was added
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved means an action reference was removed
+	ChangeRemoved ChangeKind = "removed"
+)
+
+// changeKindOrder is the order in which change kinds are reported
+var changeKindOrder = []ChangeKind{ChangeRegression, ChangeNewUpdate, ChangeFixed, ChangeAdded, ChangeRemoved}
+
+// ActionChange is a single difference between two check runs
+type ActionChange struct {
+	Kind     ChangeKind `json:"kind"`
+	Workflow string     `json:"workflow"`
+	Repo     string     `json:"repo"`
+	Line     int        `json:"line"`
+	Message  string     `json:"message"`
+}
+
+// ReportDiff is the machine readable result of report diff
+type ReportDiff struct {
+	Run        RunMetadata    `json:"run"`
+	OldSummary Summary        `json:"old_summary"`
+	NewSummary Summary        `json:"new_summary"`
+	Changes    []ActionChange `json:"changes"`
+}
+
+// HasRegressions reports whether the new run introduced a violation
+func (d ReportDiff) HasRegressions() bool {
+	for _, change := range d.Changes {
+		if change.Kind == ChangeRegression {
+			return true
+		}
+	}
+	return false
+}
+
+// readReport loads a report written by check --json
+func readReport(path string) (*Report, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(content, &report); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// actionKey identifies a reference across runs. Line numbers shift as
+// workflows are edited, so the n-th reference to a repository in a workflow
+// is matched with the n-th one of the other run.
+type actionKey struct {
+	workflow string
+	repo     string
+	index    int
+}
+
+// indexActions maps every action of a run to its key
+func indexActions(actions WorkflowActions) (map[actionKey]ActionInfo, []actionKey) {
+	indexed := make(map[actionKey]ActionInfo)
+	var keys []actionKey
+	for _, action := range sortedActions(actions) {
+		key := actionKey{workflow: action.WorkflowFile, repo: action.Repo}
+		for {
+			if _, ok := indexed[key]; !ok {
+				break
+			}
+			key.index++
+		}
+		indexed[key] = action
+		keys = append(keys, key)
+	}
+	return indexed, keys
+}
+
+// diffReports compares two check runs. Actions are matched by workflow file
+// and repository, so edits elsewhere in a workflow do not show up as changes.
+func diffReports(oldReport, newReport *Report) ReportDiff {
+	diff := ReportDiff{
+		OldSummary: summarize(oldReport.Workflows),
+		NewSummary: summarize(newReport.Workflows),
+		Changes:    []ActionChange{},
+	}
+
+	oldActions, oldKeys := indexActions(oldReport.Workflows)
+	newActions, newKeys := indexActions(newReport.Workflows)
+
+	add := func(kind ChangeKind, action ActionInfo, format string, args ...any) {
+		diff.Changes = append(diff.Changes, ActionChange{
+			Kind:     kind,
+			Workflow: action.WorkflowFile,
+			Repo:     action.Repo,
+			Line:     action.Line,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, key := range newKeys {
+		current := newActions[key]
+		previous, ok := oldActions[key]
+		if !ok {
+			if current.Pinned() {
+				add(ChangeAdded, current, "added at %s", current.CurrentRef)
+			} else {
+				add(ChangeRegression, current, "added at %s, not pinned to %s", current.CurrentRef, current.pinKind())
+			}
+			continue
+		}
+		diffAction(previous, current, add)
+	}
+
+	for _, key := range oldKeys {
+		if _, ok := newActions[key]; !ok {
+			previous := oldActions[key]
+			add(ChangeRemoved, previous, "removed (was %s)", previous.CurrentRef)
+		}
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		a, b := diff.Changes[i], diff.Changes[j]
+		if a.Kind != b.Kind {
+			return changeKindRank(a.Kind) < changeKindRank(b.Kind)
+		}
+		if a.Workflow != b.Workflow {
+			return a.Workflow < b.Workflow
+		}
+		return a.Line < b.Line
+	})
+	return diff
+}
+
+// diffAction records the changes between two runs of the same reference
+func diffAction(previous, current ActionInfo, add func(ChangeKind, ActionInfo, string, ...any)) {
+	wasPinned := previous.Pinned()
+	isPinned := current.Pinned()
+	switch {
+	case wasPinned && !isPinned:
+		add(ChangeRegression, current, "no longer pinned to a commit SHA (%s)", current.CurrentRef)
+	case !wasPinned && isPinned:
+		add(ChangeFixed, current, "now pinned to a commit SHA")
+	}
+
+	switch {
+	case previous.Error == nil && current.Error != nil:
+		add(ChangeRegression, current, "could not be resolved: %v", current.Error)
+		return
+	case previous.Error != nil && current.Error == nil:
+		add(ChangeFixed, current, "resolves again")
+	case current.Error != nil:
+		return
+	}
+
+	switch {
+	case current.NeedsUpdate && !previous.NeedsUpdate:
+		add(ChangeNewUpdate, current, "update available: %s → %s", current.CurrentRef, current.LatestTag)
+	case current.NeedsUpdate && previous.LatestTag != current.LatestTag:
+		add(ChangeNewUpdate, current, "newer release %s available (was %s)", current.LatestTag, previous.LatestTag)
+	case !current.NeedsUpdate && previous.NeedsUpdate && previous.Error == nil:
+		add(ChangeFixed, current, "updated to %s", current.LatestTag)
+	}
+}
+
+// changeKindRank returns the position of kind in the report order
+func changeKindRank(kind ChangeKind) int {
+	for i, k := range changeKindOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(changeKindOrder)
+}
+
+// changeKindTitle returns the section heading for a change kind
+func changeKindTitle(kind ChangeKind) string {
+	switch kind {
+	case ChangeRegression:
+		return theme.Error + " Regressions"
+	case ChangeNewUpdate:
+		return theme.Update + " New updates available"
+	case ChangeFixed:
+		return theme.OK + " Fixed"
+	case ChangeAdded:
+		return "➕ Added"
+	default:
+		return "➖ Removed"
+	}
+}
+
+// printReportDiff prints the changes grouped by kind followed by the summaries
+func printReportDiff(w io.Writer, diff ReportDiff) {
+	if len(diff.Changes) == 0 {
+		fmt.Fprintf(w, "%s No changes\n", theme.OK)
+	}
+
+	for _, kind := range changeKindOrder {
+		var changes []ActionChange
+		for _, change := range diff.Changes {
+			if change.Kind == kind {
+				changes = append(changes, change)
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%d):\n", changeKindTitle(kind), len(changes))
+		for _, change := range changes {
+			fmt.Fprintf(w, "  %s:%d %s: %s\n", change.Workflow, change.Line, change.Repo, change.Message)
+		}
+	}
+
+	fmt.Fprintln(w, "\n📈 Summary (old → new):")
+	fmt.Fprintf(w, "  Total: %d → %d\n", diff.OldSummary.Total, diff.NewSummary.Total)
+	fmt.Fprintf(w, "  %s Up to date: %d → %d\n", theme.OK, diff.OldSummary.UpToDate, diff.NewSummary.UpToDate)
+	fmt.Fprintf(w, "  %s Need updates: %d → %d\n", theme.Update, diff.OldSummary.NeedsUpdate, diff.NewSummary.NeedsUpdate)
+	fmt.Fprintf(w, "  %s Errors: %d → %d\n", theme.Error, diff.OldSummary.Errors, diff.NewSummary.Errors)
+}
+
+// setupReportDiff registers the flags of the report diff command and returns its runner
+func setupReportDiff(fs *flag.FlagSet) func(args []string) {
+	jsonOutput := fs.Bool("json", false, "Write the changes as JSON to stdout")
+	failOnRegression := fs.Bool("fail-on-regression", false, "Exit with status 1 when the new run has regressions")
+
+	return func(args []string) {
+		if len(args) != 2 {
+			fmt.Fprintf(console, "Usage: %s report diff [options] old.json new.json\n", programName)
+			os.Exit(2)
+		}
+
+		oldReport, err := readReport(args[0])
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		newReport, err := readReport(args[1])
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		diff := diffReports(oldReport, newReport)
+		diff.Run = runMetadata(nil)
+		if *jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(diff); err != nil {
+				fmt.Fprintf(console, "Error writing JSON report: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			fmt.Fprintf(console, "📊 Comparing %s → %s\n", args[0], args[1])
+			printReportDiff(console, diff)
+		}
+
+		if *failOnRegression && diff.HasRegressions() {
+			os.Exit(1)
+		}
+	}
+}
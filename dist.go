@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// distManifest is the part of an action manifest naming the action and its
+// entry points
+type distManifest struct {
+	Name string `yaml:"name"`
+	Runs struct {
+		Using string `yaml:"using"`
+		Main  string `yaml:"main"`
+		Pre   string `yaml:"pre"`
+		Post  string `yaml:"post"`
+	} `yaml:"runs"`
+}
+
+// nodeEntryPoints returns the JavaScript files a node action runs, or
+// nothing when the manifest describes a composite or container action
+func nodeEntryPoints(manifest []byte) ([]string, error) {
+	var m distManifest
+	if err := yaml.Unmarshal(manifest, &m); err != nil {
+		return nil, fmt.Errorf("invalid action manifest: %w", err)
+	}
+	if !strings.HasPrefix(m.Runs.Using, "node") {
+		return nil, nil
+	}
+	var files []string
+	for _, file := range []string{m.Runs.Main, m.Runs.Pre, m.Runs.Post} {
+		if file != "" {
+			files = append(files, path.Clean(file))
+		}
+	}
+	return files, nil
+}
+
+// splitActionPath splits a uses: repository into owner, repo and the
+// directory of the action inside the repository
+func splitActionPath(action string) (string, string, string) {
+	parts := strings.SplitN(action, "/", 3)
+	if len(parts) < 2 {
+		return action, "", ""
+	}
+	if len(parts) == 2 {
+		return parts[0], parts[1], ""
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// fetchManifest reads action.yml, or action.yaml, from dir at ref
+func fetchManifest(forge Forge, owner, repo, dir, ref string) ([]byte, error) {
+	var lastErr error
+	for _, name := range []string{"action.yml", "action.yaml"} {
+		content, err := forge.FileContents(owner, repo, path.Join(dir, name), ref)
+		if err == nil {
+			return content, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// verifyDist compares the entry points of a JavaScript action at its pinned
+// SHA with the same files at the tag named in its comment. A tag whose
+// bundle differs from the pinned commit has been moved or its release was
+// rebuilt, which is how compromised actions have been distributed. It
+// returns the differing files.
+func verifyDist(forge Forge, action ActionInfo) ([]string, error) {
+	tag := commentTag(action.OriginalLine, action.Repo)
+	if tag == "" || action.Docker || action.ReusableWorkflow || !action.Pinned() {
+		return nil, nil
+	}
+
+	owner, repo, dir := splitActionPath(action.Repo)
+	manifest, err := fetchManifest(forge, owner, repo, dir, action.CurrentRef)
+	if err != nil {
+		return nil, err
+	}
+	files, err := nodeEntryPoints(manifest)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	var mismatched []string
+	for _, file := range files {
+		filePath := path.Join(dir, file)
+		pinned, err := forge.FileContents(owner, repo, filePath, action.CurrentRef)
+		if err != nil {
+			return nil, err
+		}
+		tagged, err := forge.FileContents(owner, repo, filePath, tag)
+		if err != nil {
+			return nil, err
+		}
+		pinnedSum, taggedSum := sha256.Sum256(pinned), sha256.Sum256(tagged)
+		if !bytes.Equal(pinnedSum[:], taggedSum[:]) {
+			mismatched = append(mismatched, filePath)
+		}
+	}
+	return mismatched, nil
+}
+
+// verifyDistBundles checks the bundles of every pinned JavaScript action and
+// returns the number of actions whose bundle at the tag differs
+func verifyDistBundles(forge Forge, actions WorkflowActions) int {
+	fmt.Fprintln(console, "\n📦 Verifying JavaScript bundles against their tags...")
+
+	// The same pin is usually shared by many workflows
+	type result struct {
+		files []string
+		err   error
+	}
+	results := make(map[string]result)
+
+	mismatches := 0
+	for _, workflow := range sortedWorkflows(actions) {
+		actionList := actions[workflow]
+		for i := range actionList {
+			action := &actionList[i]
+			key := action.Repo + "@" + action.CurrentRef + " " + commentTag(action.OriginalLine, action.Repo)
+			r, ok := results[key]
+			if !ok {
+				r.files, r.err = verifyDist(forge, *action)
+				results[key] = r
+			}
+			files, err := r.files, r.err
+			switch {
+			case err != nil:
+				fmt.Fprintf(console, "  %s %s:%d %s: could not verify bundle: %v\n", theme.Warning, action.WorkflowFile, action.Line, action.Repo, err)
+				logger.Info("failed to verify bundle", "workflow", workflow, "repo", action.Repo, "error", err)
+			case len(files) > 0:
+				mismatches++
+				action.DistMismatch = files
+				fmt.Fprintf(console, "  %s %s:%d %s@%s: %s differs from tag %s %s\n", severityStatus(ruleDistMismatch), action.WorkflowFile, action.Line,
+					action.Repo, shortPin(action.CurrentRef), strings.Join(files, ", "), commentTag(action.OriginalLine, action.Repo), ruleTag(ruleDistMismatch))
+			}
+		}
+		actions[workflow] = actionList
+	}
+
+	if mismatches == 0 {
+		fmt.Fprintf(console, "%s Bundles match their tags\n", theme.OK)
+	}
+	return mismatches
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// ErrorKind classifies resolver failures so users and bots can react to them
+type ErrorKind string
+
+const (
+	// ErrorKindRateLimited means the GitHub API rate limit was exceeded
+	ErrorKindRateLimited ErrorKind = "rate_limited"
+	// ErrorKindNotFound means the repository, release, or ref does not exist
+	ErrorKindNotFound ErrorKind = "not_found"
+	// ErrorKindPermissionDenied means the token lacks access to the resource
+	ErrorKindPermissionDenied ErrorKind = "permission_denied"
+	// ErrorKindNetwork means the API could not be reached
+	ErrorKindNetwork ErrorKind = "network"
+	// ErrorKindTagFormat means no tag matched the expected format
+	ErrorKindTagFormat ErrorKind = "tag_format_mismatch"
+	// ErrorKindBudgetExhausted means the action was skipped once the
+	// --max-api-calls budget was used up
+	ErrorKindBudgetExhausted ErrorKind = "budget_exhausted"
+	// ErrorKindUnknown is used for errors that fit no other category
+	ErrorKindUnknown ErrorKind = "unknown"
+)
+
+// ResolveError is a classified error raised while resolving an action
+type ResolveError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// Error implements the error interface
+func (e *ResolveError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// Label returns a short human readable name for the error kind
+func (e *ResolveError) Label() string {
+	switch e.Kind {
+	case ErrorKindRateLimited:
+		return "Rate limited"
+	case ErrorKindNotFound:
+		return "Not found"
+	case ErrorKindPermissionDenied:
+		return "Permission denied"
+	case ErrorKindNetwork:
+		return "Network error"
+	case ErrorKindTagFormat:
+		return "Tag format mismatch"
+	case ErrorKindBudgetExhausted:
+		return "API budget exhausted"
+	default:
+		return "Error"
+	}
+}
+
+// Hint returns an actionable suggestion for resolving the error
+func (e *ResolveError) Hint() string {
+	switch e.Kind {
+	case ErrorKindRateLimited:
+		return "Authenticate with GITHUB_TOKEN, GH_TOKEN, or 'gh auth login' for higher rate limits, or retry after the limit resets"
+	case ErrorKindNotFound:
+		return "The action repository, release, or ref no longer exists; check the uses: reference"
+	case ErrorKindPermissionDenied:
+		return "The token cannot access this repository; check its scopes or authenticate"
+	case ErrorKindNetwork:
+		return "Check your network connection and retry"
+	case ErrorKindTagFormat:
+		return "Adjust the tag_filter for this action in the config file"
+	case ErrorKindBudgetExhausted:
+		return "Raise --max-api-calls or resolve the remaining actions in a later run"
+	default:
+		return ""
+	}
+}
+
+// MarshalJSON encodes the error with its classification and hint
+func (e *ResolveError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind    ErrorKind `json:"kind"`
+		Message string    `json:"message"`
+		Hint    string    `json:"hint,omitempty"`
+	}{
+		Kind:    e.Kind,
+		Message: e.Error(),
+		Hint:    e.Hint(),
+	})
+}
+
+// UnmarshalJSON decodes an error written by MarshalJSON, e.g. when reading an
+// exported report back in
+func (e *ResolveError) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		Kind    ErrorKind `json:"kind"`
+		Message string    `json:"message"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	e.Kind = decoded.Kind
+	e.Err = errors.New(decoded.Message)
+	return nil
+}
+
+// classifyError wraps err in a ResolveError with the most specific kind that applies
+func classifyError(err error) *ResolveError {
+	var resolveErr *ResolveError
+	if errors.As(err, &resolveErr) {
+		return &ResolveError{Kind: resolveErr.Kind, Err: err}
+	}
+
+	return &ResolveError{Kind: errorKindOf(err), Err: err}
+}
+
+// errorKindOf inspects GitHub API and network errors to determine their kind
+func errorKindOf(err error) ErrorKind {
+	if kind, ok := githubErrorKind(err); ok {
+		return kind
+	}
+	var resolveErr *ResolveError
+	if errors.As(err, &resolveErr) {
+		return resolveErr.Kind
+	}
+
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.As(err, &netErr) || errors.As(err, &urlErr) {
+		return ErrorKindNetwork
+	}
+
+	return ErrorKindUnknown
+}
+
+// newResolveError creates a classified error with a formatted message
+func newResolveError(kind ErrorKind, format string, args ...any) *ResolveError {
+	return &ResolveError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of progress event
+type EventType string
+
+const (
+	// EventActionResolved is emitted once the latest version of an action is known
+	EventActionResolved EventType = "action_resolved"
+	// EventUpdatePlanned is emitted for each uses: line rewritten in memory
+	EventUpdatePlanned EventType = "update_planned"
+	// EventFileUpdated is emitted after a workflow file has been written
+	EventFileUpdated EventType = "file_updated"
+	// EventError is emitted when resolving an action or writing a file fails
+	EventError EventType = "error"
+)
+
+// Event reports progress of a scan or update. Only the fields relevant to
+// the event type are set.
+type Event struct {
+	Type        EventType     `json:"type"`
+	Time        time.Time     `json:"time"`
+	Workflow    string        `json:"workflow,omitempty"`
+	Line        int           `json:"line,omitempty"`
+	Repo        string        `json:"repo,omitempty"`
+	From        string        `json:"from,omitempty"`
+	To          string        `json:"to,omitempty"`
+	SHA         string        `json:"sha,omitempty"`
+	NeedsUpdate bool          `json:"needs_update,omitempty"`
+	Changes     int           `json:"changes,omitempty"`
+	Error       *ResolveError `json:"error,omitempty"`
+}
+
+// EventHandler receives progress events. Handlers are called synchronously
+// from the goroutine doing the work and should return quickly.
+type EventHandler func(Event)
+
+// eventHandler is the registered handler; nil discards events
+var eventHandler EventHandler
+
+// emitEvent timestamps an event and passes it to the registered handler
+func emitEvent(event Event) {
+	if eventHandler == nil {
+		return
+	}
+	event.Time = time.Now().UTC()
+	eventHandler(event)
+}
+
+// actionEvent builds an event describing a single action reference
+func actionEvent(eventType EventType, action *ActionInfo) Event {
+	return Event{
+		Type:        eventType,
+		Workflow:    action.WorkflowFile,
+		Line:        action.Line,
+		Repo:        action.Repo,
+		From:        action.CurrentRef,
+		To:          action.LatestTag,
+		SHA:         action.LatestSHA,
+		NeedsUpdate: action.NeedsUpdate,
+		Error:       action.Error,
+	}
+}
+
+// newJSONEventHandler returns a handler that writes one JSON object per line
+func newJSONEventHandler(w io.Writer) EventHandler {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := encoder.Encode(event); err != nil {
+			logger.Warn("failed to write event", "error", err)
+		}
+	}
+}
+
+// openEventStream registers a JSON lines handler writing to path, or to
+// stderr when path is "-". The file stays open until the process exits.
+func openEventStream(path string) error {
+	if path == "-" {
+		eventHandler = newJSONEventHandler(os.Stderr)
+		return nil
+	}
+
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open event stream: %w", err)
+	}
+	eventHandler = newJSONEventHandler(file)
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expressionMarker opens a workflow expression
+const expressionMarker = "${{"
+
+// ExpressionUse records a uses: value built from an expression, such as
+// ${{ matrix.action }}. The reference is only known at run time, so it
+// cannot be pinned and its line is never rewritten.
+type ExpressionUse struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Value  string `json:"value"`
+}
+
+// isExpression reports whether a uses: value contains a workflow expression
+func isExpression(value string) bool {
+	return strings.Contains(value, expressionMarker)
+}
+
+// findExpressionUses returns the uses: values of a workflow that contain
+// expressions. Sources that are not valid YAML are scanned line by line
+// like templates.
+func findExpressionUses(filename, content string) []ExpressionUse {
+	var uses []ExpressionUse
+	record := func(value string, line, column int) {
+		if isExpression(value) {
+			uses = append(uses, ExpressionUse{File: filename, Line: line, Column: column, Value: value})
+		}
+	}
+
+	content, _ = splitBOM(content)
+	docs, err := parseDocuments(content)
+	if err != nil {
+		walkTemplateUses(content, func(value, _ string, lineNo, column int) {
+			record(value, lineNo, column)
+		})
+		return uses
+	}
+	for _, doc := range docs {
+		walkUses(doc, func(node *yaml.Node) {
+			record(node.Value, node.Line, node.Column)
+		})
+	}
+	return uses
+}
+
+// collectExpressionUses finds the expression references in the scanned
+// workflows, including files skipped because they had no other references
+func collectExpressionUses(actions WorkflowActions, skipped []SkippedFile) []ExpressionUse {
+	files := make([]string, 0, len(actions))
+	for workflow := range actions {
+		files = append(files, workflow)
+	}
+	for _, file := range skipped {
+		if file.Reason == reasonNoActions {
+			files = append(files, file.Path)
+		}
+	}
+
+	var uses []ExpressionUse
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Clean(file))
+		if err != nil {
+			logger.Debug("could not read workflow for expressions", "file", file, "error", err)
+			continue
+		}
+		uses = append(uses, findExpressionUses(file, string(content))...)
+	}
+
+	sort.Slice(uses, func(i, j int) bool {
+		if uses[i].File != uses[j].File {
+			return uses[i].File < uses[j].File
+		}
+		return uses[i].Line < uses[j].Line
+	})
+	return uses
+}
+
+// printExpressionUses warns about uses: values that cannot be pinned
+func printExpressionUses(uses []ExpressionUse) {
+	if len(uses) == 0 || !ruleEnabled(ruleExpression) {
+		return
+	}
+
+	fmt.Fprintf(console, "\n%s uses: values built from expressions cannot be pinned (%d) %s:\n", severityStatus(ruleExpression), len(uses), ruleTag(ruleExpression))
+	for _, use := range uses {
+		fmt.Fprintf(console, "  %s:%d %s\n", use.File, use.Line, use.Value)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lastCheckFile is the file in the git directory recording when check last
+// completed without errors, for check --since
+const lastCheckFile = "github-ci-hash-last-check"
+
+// parseAge parses a --since age: a Go duration such as 12h, or a number of
+// days such as 7d
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return age, nil
+}
+
+// lastCheckPath returns where the time of the last check is recorded. It is
+// kept in the git directory so it is never committed.
+func lastCheckPath() (string, error) {
+	lines, err := gitLines("rev-parse", "--git-path", lastCheckFile)
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("git did not report a path for %s", lastCheckFile)
+	}
+	return filepath.FromSlash(lines[0]), nil
+}
+
+// lastCheckTime returns when check last completed without errors
+func lastCheckTime() (time.Time, bool) {
+	path, err := lastCheckPath()
+	if err != nil {
+		return time.Time{}, false
+	}
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return time.Time{}, false
+	}
+	last, err := time.Parse(time.RFC3339, strings.TrimSpace(string(content)))
+	return last, err == nil
+}
+
+// recordCheck records that check completed now
+func recordCheck() {
+	path, err := lastCheckPath()
+	if err == nil {
+		err = os.WriteFile(path, []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0600)
+	}
+	if err != nil {
+		logger.Debug("could not record the check time", "error", err)
+	}
+}
+
+// printGroupedSummary writes the short summary check --quiet prints for
+// hooks: one line of counts, then each update and error grouped by action
+func printGroupedSummary(w io.Writer, actions WorkflowActions) {
+	summary := summarize(actions)
+	fmt.Fprintf(w, "%s: %d actions, %d with updates, %d errors\n", programName, summary.Total, summary.NeedsUpdate, summary.Errors)
+
+	type group struct {
+		line      string
+		workflows []string
+	}
+	groups := make(map[string]*group)
+	var keys []string
+	for _, action := range sortedActions(actions) {
+		var line string
+		switch {
+		case action.Error != nil:
+			line = fmt.Sprintf("  %s %s: %s", theme.Error, action.Repo, action.Error.Label())
+		case action.NeedsUpdate:
+			line = fmt.Sprintf("  %s %s %s → %s", theme.Update, action.Repo, action.CurrentRef, action.LatestTag)
+			if action.Pinned() {
+				line = fmt.Sprintf("  %s %s %s → %s", theme.Update, action.Repo, shortPin(action.CurrentRef), action.LatestTag)
+			}
+		default:
+			continue
+		}
+		g, ok := groups[line]
+		if !ok {
+			g = &group{line: line}
+			groups[line] = g
+			keys = append(keys, line)
+		}
+		workflow := filepath.Base(action.WorkflowFile)
+		if len(g.workflows) == 0 || g.workflows[len(g.workflows)-1] != workflow {
+			g.workflows = append(g.workflows, workflow)
+		}
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s (%s)\n", key, strings.Join(groups[key].workflows, ", "))
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Release is a published release of an action repository. Forges without
+// release metadata return releases that only carry a tag.
+type Release struct {
+	Tag   string
+	URL   string
+	Notes string
+	// Date is the publication date as YYYY-MM-DD, empty when unknown
+	Date string
+	// Immutable is set for releases whose tag and assets GitHub locks
+	// against changes
+	Immutable bool
+}
+
+// Comparison summarizes the commits between two refs
+type Comparison struct {
+	// Status is "ahead", "behind", "identical" or "diverged"
+	Status   string
+	AheadBy  int
+	BehindBy int
+	// Commits lists the first line of each commit message from base to head
+	Commits []string
+}
+
+// Forge is the code host the resolver talks to. Implementations map the
+// operations onto their own API; owner and repo identify the repository on
+// that host.
+type Forge interface {
+	// Name identifies the forge in output, e.g. "github" or "git"
+	Name() string
+	// LatestRelease returns the latest published release; pre-releases are
+	// only eligible when prereleases is set
+	LatestRelease(owner, repo string, prereleases bool) (*Release, error)
+	// LatestImmutableRelease returns the newest eligible release that is
+	// locked against changes
+	LatestImmutableRelease(owner, repo string, prereleases bool) (*Release, error)
+	// ReleaseAt returns the release published for a tag
+	ReleaseAt(owner, repo, tag string) (*Release, error)
+	// LatestMatchingTag returns the most recent tag matching filter, with
+	// its release when one was published for it
+	LatestMatchingTag(owner, repo string, filter *regexp.Regexp, prereleases bool) (string, *Release, error)
+	// HighestTag returns the highest version tag matching filter, for
+	// repositories that publish no releases
+	HighestTag(owner, repo string, filter *regexp.Regexp) (string, error)
+	// ResolveRef resolves a tag or branch to the commit SHA it points at
+	ResolveRef(owner, repo, ref string) (string, error)
+	// ExpandSHA returns the full SHA of the commit an abbreviated SHA names
+	ExpandSHA(owner, repo, prefix string) (string, error)
+	// TagsAt returns the tags pointing at a commit
+	TagsAt(owner, repo, sha string) ([]string, error)
+	// Compare summarizes the commits between base and head
+	Compare(owner, repo, base, head string) (*Comparison, error)
+	// FileContents returns the contents of a file at ref
+	FileContents(owner, repo, path, ref string) ([]byte, error)
+	// CanonicalName returns the current owner/repo of a repository, which
+	// differs from the given one after a rename or transfer
+	CanonicalName(owner, repo string) (string, error)
+}
+
+// Name implements Forge
+func (gc *GitHubClient) Name() string {
+	return "github"
+}
+
+// LatestRelease implements Forge. The latest release endpoint skips releases
+// flagged as pre-releases, but not those only named like one, so the list
+// of releases is searched when the latest has a pre-release tag or
+// pre-releases are eligible.
+func (gc *GitHubClient) LatestRelease(owner, repo string, prereleases bool) (*Release, error) {
+	if !prereleases {
+		release, err := gc.GetLatestRelease(owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		if !isPrereleaseTag(release.Tag) {
+			return &release.Release, nil
+		}
+	}
+	release, err := gc.GetNewestRelease(owner, repo, prereleases)
+	if err != nil {
+		return nil, err
+	}
+	return &release.Release, nil
+}
+
+// LatestImmutableRelease implements Forge
+func (gc *GitHubClient) LatestImmutableRelease(owner, repo string, prereleases bool) (*Release, error) {
+	for page := 1; page != 0; {
+		releases, next, err := gc.api.ListReleases(gc.ctx, owner, repo, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases for %s/%s: %w", owner, repo, err)
+		}
+		for i := range releases {
+			if releases[i].Immutable && eligibleRelease(&releases[i], prereleases) {
+				return &releases[i].Release, nil
+			}
+		}
+		page = next
+	}
+	return nil, newResolveError(ErrorKindNotFound, "no immutable release of %s/%s", owner, repo)
+}
+
+// ReleaseAt implements Forge
+func (gc *GitHubClient) ReleaseAt(owner, repo, tag string) (*Release, error) {
+	release, err := gc.api.ReleaseByTag(gc.ctx, owner, repo, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %s of %s/%s: %w", tag, owner, repo, err)
+	}
+	return &release.Release, nil
+}
+
+// LatestMatchingTag implements Forge
+func (gc *GitHubClient) LatestMatchingTag(owner, repo string, filter *regexp.Regexp, prereleases bool) (string, *Release, error) {
+	tag, release, err := gc.GetLatestMatchingTag(owner, repo, filter, prereleases)
+	if err != nil || release == nil {
+		return tag, nil, err
+	}
+	return tag, &release.Release, nil
+}
+
+// HighestTag implements Forge. Tags are not listed in version order, so
+// every page of tags is compared.
+func (gc *GitHubClient) HighestTag(owner, repo string, filter *regexp.Regexp) (string, error) {
+	best := ""
+	for page := 1; page != 0; {
+		tags, next, err := gc.api.ListTags(gc.ctx, owner, repo, page)
+		if err != nil {
+			return "", fmt.Errorf("failed to list tags for %s/%s: %w", owner, repo, err)
+		}
+		for _, tag := range tags {
+			if filter.MatchString(tag.Name) && (best == "" || compareVersionTags(tag.Name, best) > 0) {
+				best = tag.Name
+			}
+		}
+		page = next
+	}
+	if best == "" {
+		return "", newResolveError(ErrorKindNotFound, "no tag of %s/%s matches %q", owner, repo, filter.String())
+	}
+	return best, nil
+}
+
+// ResolveRef implements Forge
+func (gc *GitHubClient) ResolveRef(owner, repo, ref string) (string, error) {
+	return gc.ResolveSHA(owner, repo, ref)
+}
+
+// ExpandSHA implements Forge
+func (gc *GitHubClient) ExpandSHA(owner, repo, prefix string) (string, error) {
+	sha, err := gc.api.GetCommitSHA(gc.ctx, owner, repo, prefix)
+	if kind := errorKindOf(err); err != nil && kind != ErrorKindNotFound && kind != ErrorKindUnknown {
+		return "", &ResolveError{Kind: kind, Err: fmt.Errorf("could not expand %s for %s/%s: %w", prefix, owner, repo, err)}
+	}
+	// The commits endpoint also accepts tag and branch names
+	if err != nil || !strings.HasPrefix(sha, prefix) {
+		return "", newResolveError(ErrorKindNotFound, "%s is not an abbreviated commit SHA of %s/%s", prefix, owner, repo)
+	}
+	return sha, nil
+}
+
+// TagsAt implements Forge. The tags API has no reverse lookup, so every page
+// of tags is listed.
+func (gc *GitHubClient) TagsAt(owner, repo, sha string) ([]string, error) {
+	var tags []string
+	for page := 1; page != 0; {
+		listed, next, err := gc.api.ListTags(gc.ctx, owner, repo, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags for %s/%s: %w", owner, repo, err)
+		}
+		for _, tag := range listed {
+			if tag.SHA == sha {
+				tags = append(tags, tag.Name)
+			}
+		}
+		page = next
+	}
+	return tags, nil
+}
+
+// Compare implements Forge
+func (gc *GitHubClient) Compare(owner, repo, base, head string) (*Comparison, error) {
+	comparison, err := gc.api.Compare(gc.ctx, owner, repo, base, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s in %s/%s: %w", base, head, owner, repo, err)
+	}
+	return comparison, nil
+}
+
+// FileContents implements Forge
+func (gc *GitHubClient) FileContents(owner, repo, path, ref string) ([]byte, error) {
+	return gc.fileContents(gc.ctx, owner, repo, path, ref)
+}
+
+// fileContents is FileContents bounded by ctx, such as the timeout of a
+// server request
+func (gc *GitHubClient) fileContents(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	content, err := gc.api.FileContents(ctx, owner, repo, path, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from %s/%s at %s: %w", path, owner, repo, ref, err)
+	}
+	return content, nil
+}
+
+// CanonicalName implements Forge. Only repositories whose requests were
+// redirected are looked up, once each, so the check costs no requests
+// otherwise.
+func (gc *GitHubClient) CanonicalName(owner, repo string) (string, error) {
+	if gc.redirects == nil {
+		return owner + "/" + repo, nil
+	}
+	name, redirected := gc.redirects.lookup(owner, repo)
+	if !redirected {
+		return owner + "/" + repo, nil
+	}
+	if name != "" {
+		return name, nil
+	}
+	repository, err := gc.api.Repository(gc.ctx, owner, repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the new location of %s/%s: %w", owner, repo, err)
+	}
+	gc.redirects.setName(owner, repo, repository.FullName)
+	return repository.FullName, nil
+}
+
+// firstLine returns the first line of a commit message
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return strings.TrimSpace(line)
+}
+
+// fallbackForge uses the primary forge until it reports an exhausted rate
+// limit and the fallback forge for every call after that
+type fallbackForge struct {
+	primary  Forge
+	fallback Forge
+	switched bool
+}
+
+// newFallbackForge returns a forge that switches from primary to fallback
+// once the primary's rate limit is exhausted
+func newFallbackForge(primary, fallback Forge) *fallbackForge {
+	return &fallbackForge{primary: primary, fallback: fallback}
+}
+
+// forgeFor returns the forge that resolves actions for a run. The git
+// fallback is enabled by the configuration, or automatically when the
+// actions need more requests than the token's quota has left. Actions with
+// a configured resolver plugin are sent to it instead. Repeated lookups
+// are answered from memory, after latest releases and refs are prefetched
+// in GraphQL batches.
+func forgeFor(gc *GitHubClient, cfg *Config, actions WorkflowActions) Forge {
+	forge := builtinForgeFor(gc, cfg, actions)
+	if cfg.usesResolvers() {
+		forge = newResolverForge(cfg, forge)
+	}
+	memo := newMemoForge(forge)
+	prefetchGraphQL(gc, cfg, memo, actions)
+	return memo
+}
+
+// builtinForgeFor returns the GitHub forge, with the git fallback when it
+// is enabled or needed
+func builtinForgeFor(gc *GitHubClient, cfg *Config, actions WorkflowActions) Forge {
+	if cfg != nil && cfg.GitFallback {
+		return newFallbackForge(gc, newGitForge())
+	}
+	if budgetRequests(gc, actions) {
+		fmt.Fprintln(console, "   Resolving with git ls-remote once the quota is exhausted.")
+		return newFallbackForge(gc, newGitForge())
+	}
+	return gc
+}
+
+// current returns the forge that handles the next call
+func (f *fallbackForge) current() Forge {
+	if f.switched {
+		return f.fallback
+	}
+	return f.primary
+}
+
+// retry reports whether err exhausted the primary's rate limit, switching to
+// the fallback forge if so
+func (f *fallbackForge) retry(err error) bool {
+	if err == nil || f.switched || classifyError(err).Kind != ErrorKindRateLimited {
+		return false
+	}
+	f.switched = true
+	fmt.Fprintf(console, " %s API rate limit exceeded, falling back to %s...", theme.Warning, f.fallback.Name())
+	logger.Info("rate limit exhausted, switching forge", "from", f.primary.Name(), "to", f.fallback.Name())
+	return true
+}
+
+// Name implements Forge
+func (f *fallbackForge) Name() string {
+	return f.current().Name()
+}
+
+// LatestRelease implements Forge
+func (f *fallbackForge) LatestRelease(owner, repo string, prereleases bool) (*Release, error) {
+	release, err := f.current().LatestRelease(owner, repo, prereleases)
+	if f.retry(err) {
+		return f.fallback.LatestRelease(owner, repo, prereleases)
+	}
+	return release, err
+}
+
+// LatestImmutableRelease implements Forge
+func (f *fallbackForge) LatestImmutableRelease(owner, repo string, prereleases bool) (*Release, error) {
+	release, err := f.current().LatestImmutableRelease(owner, repo, prereleases)
+	if f.retry(err) {
+		return f.fallback.LatestImmutableRelease(owner, repo, prereleases)
+	}
+	return release, err
+}
+
+// ReleaseAt implements Forge
+func (f *fallbackForge) ReleaseAt(owner, repo, tag string) (*Release, error) {
+	release, err := f.current().ReleaseAt(owner, repo, tag)
+	if f.retry(err) {
+		return f.fallback.ReleaseAt(owner, repo, tag)
+	}
+	return release, err
+}
+
+// LatestMatchingTag implements Forge
+func (f *fallbackForge) LatestMatchingTag(owner, repo string, filter *regexp.Regexp, prereleases bool) (string, *Release, error) {
+	tag, release, err := f.current().LatestMatchingTag(owner, repo, filter, prereleases)
+	if f.retry(err) {
+		return f.fallback.LatestMatchingTag(owner, repo, filter, prereleases)
+	}
+	return tag, release, err
+}
+
+// HighestTag implements Forge
+func (f *fallbackForge) HighestTag(owner, repo string, filter *regexp.Regexp) (string, error) {
+	tag, err := f.current().HighestTag(owner, repo, filter)
+	if f.retry(err) {
+		return f.fallback.HighestTag(owner, repo, filter)
+	}
+	return tag, err
+}
+
+// ResolveRef implements Forge
+func (f *fallbackForge) ResolveRef(owner, repo, ref string) (string, error) {
+	sha, err := f.current().ResolveRef(owner, repo, ref)
+	if f.retry(err) {
+		return f.fallback.ResolveRef(owner, repo, ref)
+	}
+	return sha, err
+}
+
+// ExpandSHA implements Forge
+func (f *fallbackForge) ExpandSHA(owner, repo, prefix string) (string, error) {
+	sha, err := f.current().ExpandSHA(owner, repo, prefix)
+	if f.retry(err) {
+		return f.fallback.ExpandSHA(owner, repo, prefix)
+	}
+	return sha, err
+}
+
+// TagsAt implements Forge
+func (f *fallbackForge) TagsAt(owner, repo, sha string) ([]string, error) {
+	tags, err := f.current().TagsAt(owner, repo, sha)
+	if f.retry(err) {
+		return f.fallback.TagsAt(owner, repo, sha)
+	}
+	return tags, err
+}
+
+// Compare implements Forge
+func (f *fallbackForge) Compare(owner, repo, base, head string) (*Comparison, error) {
+	comparison, err := f.current().Compare(owner, repo, base, head)
+	if f.retry(err) {
+		return f.fallback.Compare(owner, repo, base, head)
+	}
+	return comparison, err
+}
+
+// FileContents implements Forge
+func (f *fallbackForge) FileContents(owner, repo, path, ref string) ([]byte, error) {
+	content, err := f.current().FileContents(owner, repo, path, ref)
+	if f.retry(err) {
+		return f.fallback.FileContents(owner, repo, path, ref)
+	}
+	return content, err
+}
+
+// CanonicalName implements Forge
+func (f *fallbackForge) CanonicalName(owner, repo string) (string, error) {
+	name, err := f.current().CanonicalName(owner, repo)
+	if f.retry(err) {
+		return f.fallback.CanonicalName(owner, repo)
+	}
+	return name, err
+}
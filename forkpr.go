@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// forkTriggers are the events that run workflows for pull requests opened
+// from forks. pull_request_target runs with the base repository's secrets
+// and a write token, so an unpinned action there is the most exposed.
+var forkTriggers = []string{"pull_request_target", "pull_request"}
+
+// ForkExposure records an unpinned third-party action in a workflow that
+// pull requests from forks can trigger
+type ForkExposure struct {
+	Workflow string   `json:"workflow"`
+	Line     int      `json:"line"`
+	Repo     string   `json:"repo"`
+	Ref      string   `json:"ref"`
+	Triggers []string `json:"triggers"`
+}
+
+// reference spells the exposed action as its uses: value does, like
+// ActionInfo.reference
+func (e ForkExposure) reference() string {
+	return ActionInfo{Repo: e.Repo, CurrentRef: e.Ref, Docker: strings.HasPrefix(e.Repo, dockerPrefix)}.reference()
+}
+
+// workflowFileEvents returns the events a workflow file is triggered by
+func workflowFileEvents(workflow string) []string {
+	content, err := os.ReadFile(filepath.Clean(workflow))
+	if err != nil {
+		logger.Debug("could not read workflow for triggers", "file", workflow, "error", err)
+		return nil
+	}
+	body, _ := splitBOM(string(content))
+	docs, err := parseDocuments(body)
+	if err != nil || len(docs) == 0 {
+		return nil
+	}
+	return triggerEvents(docs[0])
+}
+
+// workflowForkTriggers returns the fork pull request events a workflow file
+// is triggered by, most exposed first
+func workflowForkTriggers(workflow string) []string {
+	events := workflowFileEvents(workflow)
+	var triggers []string
+	for _, trigger := range forkTriggers {
+		if slices.Contains(events, trigger) {
+			triggers = append(triggers, trigger)
+		}
+	}
+	return triggers
+}
+
+// findForkExposures lists the unpinned third-party actions and container
+// images of workflows triggered by pull requests. Whether forks can open
+// them depends on the repository being public, or on fork pull request
+// workflows being enabled for a private one, which the files do not show.
+func findForkExposures(actions WorkflowActions) []ForkExposure {
+	triggers := make(map[string][]string)
+	var exposures []ForkExposure
+	for _, action := range sortedActions(actions) {
+		if action.Pinned() || !(isThirdParty(action) || action.Docker) || isActionFile(action.WorkflowFile) {
+			continue
+		}
+		workflowTriggers, ok := triggers[action.WorkflowFile]
+		if !ok {
+			workflowTriggers = workflowForkTriggers(action.WorkflowFile)
+			triggers[action.WorkflowFile] = workflowTriggers
+		}
+		if len(workflowTriggers) == 0 {
+			continue
+		}
+		exposures = append(exposures, ForkExposure{
+			Workflow: action.WorkflowFile,
+			Line:     action.Line,
+			Repo:     action.Repo,
+			Ref:      action.CurrentRef,
+			Triggers: workflowTriggers,
+		})
+	}
+	return exposures
+}
+
+// printForkExposures warns about unpinned actions that pull requests from
+// forks can run
+func printForkExposures(exposures []ForkExposure) {
+	if len(exposures) == 0 || !ruleEnabled(ruleForkPR) {
+		return
+	}
+
+	fmt.Fprintf(console, "\n%s Unpinned third-party actions in workflows triggered by pull requests (%d) %s:\n", severityStatus(ruleForkPR), len(exposures), ruleTag(ruleForkPR))
+	for _, exposure := range exposures {
+		fmt.Fprintf(console, "  %s:%d %s (%s)\n", exposure.Workflow, exposure.Line, exposure.reference(), strings.Join(exposure.Triggers, ", "))
+	}
+	fmt.Fprintln(console, "  💡 Pull requests from forks of public repositories run these; pin them first")
+}
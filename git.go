@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// defaultBaseRef is the ref --changed-only compares against by default
+	defaultBaseRef = "origin/main"
+)
+
+// changedWorkflowFiles returns the workflow files, composite actions and
+// template sources that differ from the base ref of opts. Committed changes
+// since the merge base, uncommitted changes to tracked files, and untracked
+// files are all included. Deleted files are skipped since there is nothing
+// left to scan. Before the first commit, every staged file counts as
+// changed.
+func changedWorkflowFiles(opts ScanOptions) ([]string, error) {
+	baseRef := opts.BaseRef
+	if baseRef == "" || strings.HasPrefix(baseRef, "-") {
+		return nil, fmt.Errorf("invalid base ref %q", baseRef)
+	}
+
+	var committed, uncommitted []string
+	var err error
+	if _, headErr := gitLines("rev-parse", "--verify", "--quiet", "HEAD"); headErr != nil {
+		// An unborn HEAD has nothing to diff against
+		if uncommitted, err = gitLines("ls-files", "--cached"); err != nil {
+			return nil, err
+		}
+	} else {
+		// HEAD...HEAD is empty, so only the staged and worktree changes are left
+		if baseRef != "HEAD" {
+			if committed, err = gitLines("diff", "--name-only", "--relative", baseRef+"...HEAD"); err != nil {
+				return nil, err
+			}
+		}
+		if uncommitted, err = gitLines("diff", "--name-only", "--relative", "HEAD"); err != nil {
+			return nil, err
+		}
+	}
+	untrackedArgs := append([]string{"ls-files", "--others", "--exclude-standard", "--", workflowDir, "action.yml", "action.yaml"}, opts.ActionPaths...)
+	untracked, err := gitLines(append(untrackedArgs, opts.TemplatePaths...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, file := range append(append(committed, uncommitted...), untracked...) {
+		file = filepath.FromSlash(file)
+		if seen[file] || !isScannedFile(file, opts) {
+			continue
+		}
+		seen[file] = true
+
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// isWorkflowFile reports whether a path is a YAML file directly inside the workflow directory
+func isWorkflowFile(file string) bool {
+	if filepath.Dir(file) != filepath.FromSlash(workflowDir) {
+		return false
+	}
+	return strings.HasSuffix(file, ".yml") || strings.HasSuffix(file, ".yaml")
+}
+
+// isScannedFile reports whether a directory scan with opts would pick up file
+func isScannedFile(file string, opts ScanOptions) bool {
+	return isWorkflowFile(file) || isRootActionFile(file) || underActionPath(file, opts.ActionPaths) || underTemplatePath(file, opts.TemplatePaths)
+}
+
+// gitLines runs a git command and returns its non-empty output lines
+func gitLines(args ...string) ([]string, error) {
+	// #nosec G204 - arguments are passed directly to git, never through a shell
+	cmd := exec.Command("git", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to run git: %w", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
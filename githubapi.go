@@ -0,0 +1,699 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v74/github"
+)
+
+const (
+	// githubAPIVersionHeader selects the REST API version of a request
+	githubAPIVersionHeader = "X-GitHub-Api-Version"
+
+	// githubPageSize is the page size of list requests
+	githubPageSize = 100
+)
+
+// githubAPIVersion overrides the REST API version sent with every request,
+// e.g. for GitHub Enterprise Server releases that predate the default
+// version. It is set by --github-api-version.
+var githubAPIVersion string
+
+// registerGitHubAPIFlags adds the flags controlling GitHub API requests
+func registerGitHubAPIFlags(fs *flag.FlagSet) {
+	fs.StringVar(&githubAPIVersion, "github-api-version", "", "REST API `version` sent in the X-GitHub-Api-Version header (default: the client library's)")
+	fs.BoolVar(&batchGraphQL, "graphql", true, "Look up latest releases and refs in batched GraphQL queries, falling back to REST when they fail")
+	fs.BoolVar(&httpCache, "http-cache", true, "Keep API responses in the user cache directory and revalidate them with conditional requests, which do not count against the rate limit when unchanged")
+	fs.IntVar(&maxRetries, "max-retries", maxRetries, "Retry GitHub API requests failing with a server error, a dropped connection or a rate limit resetting within a minute up to this `number` of times")
+	fs.IntVar(&maxAPICalls, "max-api-calls", 0, "Maximum `number` of GitHub API requests per run, then exit with code 3 (0 = unlimited)")
+}
+
+// githubRelease is a release as returned by the GitHub API
+type githubRelease struct {
+	Release
+	Draft      bool
+	Prerelease bool
+	Assets     []githubAsset
+}
+
+// githubAsset is a file attached to a release
+type githubAsset struct {
+	Name        string
+	DownloadURL string
+}
+
+// githubTag is a tag with the commit it points at, peeled for annotated tags
+type githubTag struct {
+	Name string
+	SHA  string
+}
+
+// githubRef is the object a git ref points at. Type is "commit" or "tag"
+// for annotated tags.
+type githubRef struct {
+	SHA  string
+	Type string
+}
+
+// githubRepository is the metadata of a repository
+type githubRepository struct {
+	FullName    string
+	Description string
+	Archived    bool
+	// OwnerType is "Organization" or "User"
+	OwnerType string
+	Fork      bool
+	// Parent is the owner/repo a fork was created from
+	Parent string
+}
+
+// githubLicense is the license GitHub detected in a repository
+type githubLicense struct {
+	// SPDXID is the SPDX identifier, NOASSERTION for licenses GitHub does
+	// not recognize
+	SPDXID string
+	Name   string
+	// URL is the license file on GitHub
+	URL string
+}
+
+// githubSnapshotResult is the outcome of a dependency snapshot submission
+type githubSnapshotResult struct {
+	ID      int64  `json:"id"`
+	Result  string `json:"result"`
+	Message string `json:"message"`
+}
+
+// githubActionsPolicy is the allowed actions policy of an organization or
+// repository
+type githubActionsPolicy struct {
+	// Disabled is set when GitHub Actions is turned off
+	Disabled bool
+	// AllowedActions is "all", "local_only" or "selected"
+	AllowedActions     string
+	SHAPinningRequired bool
+	// GitHubOwnedAllowed, VerifiedAllowed and Patterns are only set for
+	// the "selected" policy
+	GitHubOwnedAllowed bool
+	VerifiedAllowed    bool
+	Patterns           []string
+}
+
+// githubRate is the quota of one rate limit resource
+type githubRate struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// githubRateLimits is the quota of the REST, GraphQL and code search APIs
+type githubRateLimits struct {
+	Core    githubRate  `json:"core"`
+	GraphQL *githubRate `json:"graphql,omitempty"`
+	Search  *githubRate `json:"search,omitempty"`
+}
+
+// githubEntry is a file listed by the git trees API
+type githubEntry struct {
+	Name string
+	Path string
+	Size int64
+}
+
+// githubCodeResult is a file found by code search
+type githubCodeResult struct {
+	Repo    string
+	Path    string
+	HTMLURL string
+}
+
+// githubAPI is the subset of the GitHub REST API the tool uses. Resolver
+// code only talks to this interface, so the client library can be upgraded
+// or calls moved to GraphQL in one place. Pages are numbered from 1; a next
+// page of 0 means the last page was returned.
+type githubAPI interface {
+	LatestRelease(ctx context.Context, owner, repo string) (*githubRelease, error)
+	ListReleases(ctx context.Context, owner, repo string, page int) ([]githubRelease, int, error)
+	ReleaseByTag(ctx context.Context, owner, repo, tag string) (*githubRelease, error)
+	ListTags(ctx context.Context, owner, repo string, page int) ([]githubTag, int, error)
+	GetRef(ctx context.Context, owner, repo, ref string) (*githubRef, error)
+	GetTagTarget(ctx context.Context, owner, repo, sha string) (string, error)
+	GetCommitSHA(ctx context.Context, owner, repo, ref string) (string, error)
+	Compare(ctx context.Context, owner, repo, base, head string) (*Comparison, error)
+	FileContents(ctx context.Context, owner, repo, path, ref string) ([]byte, error)
+	ListTree(ctx context.Context, owner, repo, ref string) ([]githubEntry, bool, error)
+	Repository(ctx context.Context, owner, repo string) (*githubRepository, error)
+	Readme(ctx context.Context, owner, repo string) ([]byte, error)
+	License(ctx context.Context, owner, repo string) (*githubLicense, error)
+	SubmitSnapshot(ctx context.Context, owner, repo string, snapshot *dependencySnapshot) (*githubSnapshotResult, error)
+	Advisories(ctx context.Context, repo, version string) ([]Advisory, error)
+	Attestations(ctx context.Context, owner, repo, digest string) ([]json.RawMessage, error)
+	SearchCode(ctx context.Context, query string, page int) ([]githubCodeResult, int, error)
+	OrgTwoFactorRequired(ctx context.Context, org string) (*bool, error)
+	OrgVerified(ctx context.Context, org string) (bool, error)
+	ActionsPolicy(ctx context.Context, owner, repo string) (*githubActionsPolicy, error)
+	GraphQL(ctx context.Context, query string, variables map[string]any, data any) error
+	RemainingRequests(ctx context.Context) (int, error)
+	RateLimits(ctx context.Context) (*githubRateLimits, error)
+	AuthenticatedUser(ctx context.Context) (string, error)
+}
+
+// goGitHubAPI implements githubAPI with go-github
+type goGitHubAPI struct {
+	client *github.Client
+}
+
+// newGoGitHubAPI returns the API client for httpClient. GITHUB_API_URL,
+// which runners on GitHub Enterprise Server set, selects the API host.
+func newGoGitHubAPI(httpClient *http.Client) (*goGitHubAPI, error) {
+	client := github.NewClient(httpClient)
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" && strings.TrimSuffix(apiURL, "/") != "https://api.github.com" {
+		enterprise, err := client.WithEnterpriseURLs(apiURL, apiURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GITHUB_API_URL %q: %w", apiURL, err)
+		}
+		client = enterprise
+	}
+	return &goGitHubAPI{client: client}, nil
+}
+
+// apiRelease is a release with the immutable flag, which go-github
+// does not know about yet
+type apiRelease struct {
+	github.RepositoryRelease
+	Immutable bool `json:"immutable"`
+}
+
+// convertRelease converts a go-github release
+func convertRelease(release *apiRelease) githubRelease {
+	r := githubRelease{
+		Release: Release{
+			Tag:       release.GetTagName(),
+			URL:       release.GetHTMLURL(),
+			Notes:     release.GetBody(),
+			Immutable: release.Immutable,
+		},
+		Draft:      release.GetDraft(),
+		Prerelease: release.GetPrerelease(),
+	}
+	if published := release.GetPublishedAt(); !published.IsZero() {
+		r.Date = published.Format(time.DateOnly)
+	}
+	for _, asset := range release.Assets {
+		r.Assets = append(r.Assets, githubAsset{Name: asset.GetName(), DownloadURL: asset.GetBrowserDownloadURL()})
+	}
+	return r
+}
+
+// LatestRelease implements githubAPI
+func (a *goGitHubAPI) LatestRelease(ctx context.Context, owner, repo string) (*githubRelease, error) {
+	return a.getRelease(ctx, fmt.Sprintf("repos/%s/%s/releases/latest", owner, repo))
+}
+
+// ReleaseByTag implements githubAPI
+func (a *goGitHubAPI) ReleaseByTag(ctx context.Context, owner, repo, tag string) (*githubRelease, error) {
+	return a.getRelease(ctx, fmt.Sprintf("repos/%s/%s/releases/tags/%s", owner, repo, url.PathEscape(tag)))
+}
+
+// getRelease fetches one release. The request is made directly so the
+// immutable flag is decoded.
+func (a *goGitHubAPI) getRelease(ctx context.Context, endpoint string) (*githubRelease, error) {
+	req, err := a.client.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	var release apiRelease
+	if _, err := a.client.Do(ctx, req, &release); err != nil {
+		return nil, err
+	}
+	r := convertRelease(&release)
+	return &r, nil
+}
+
+// ListReleases implements githubAPI
+func (a *goGitHubAPI) ListReleases(ctx context.Context, owner, repo string, page int) ([]githubRelease, int, error) {
+	req, err := a.client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s/releases?page=%d&per_page=%d", owner, repo, page, githubPageSize), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	var releases []*apiRelease
+	resp, err := a.client.Do(ctx, req, &releases)
+	if err != nil {
+		return nil, 0, err
+	}
+	converted := make([]githubRelease, 0, len(releases))
+	for _, release := range releases {
+		converted = append(converted, convertRelease(release))
+	}
+	return converted, resp.NextPage, nil
+}
+
+// ListTags implements githubAPI
+func (a *goGitHubAPI) ListTags(ctx context.Context, owner, repo string, page int) ([]githubTag, int, error) {
+	tags, resp, err := a.client.Repositories.ListTags(ctx, owner, repo, &github.ListOptions{Page: page, PerPage: githubPageSize})
+	if err != nil {
+		return nil, 0, err
+	}
+	converted := make([]githubTag, 0, len(tags))
+	for _, tag := range tags {
+		converted = append(converted, githubTag{Name: tag.GetName(), SHA: tag.GetCommit().GetSHA()})
+	}
+	return converted, resp.NextPage, nil
+}
+
+// GetRef implements githubAPI
+func (a *goGitHubAPI) GetRef(ctx context.Context, owner, repo, ref string) (*githubRef, error) {
+	gitRef, _, err := a.client.Git.GetRef(ctx, owner, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	if gitRef.Object == nil {
+		return nil, newResolveError(ErrorKindNotFound, "ref %s of %s/%s points at no object", ref, owner, repo)
+	}
+	return &githubRef{SHA: gitRef.Object.GetSHA(), Type: gitRef.Object.GetType()}, nil
+}
+
+// GetTagTarget implements githubAPI
+func (a *goGitHubAPI) GetTagTarget(ctx context.Context, owner, repo, sha string) (string, error) {
+	tag, _, err := a.client.Git.GetTag(ctx, owner, repo, sha)
+	if err != nil {
+		return "", err
+	}
+	if tag.Object == nil {
+		return "", newResolveError(ErrorKindNotFound, "tag %s of %s/%s points at no object", sha, owner, repo)
+	}
+	return tag.Object.GetSHA(), nil
+}
+
+// GetCommitSHA implements githubAPI
+func (a *goGitHubAPI) GetCommitSHA(ctx context.Context, owner, repo, ref string) (string, error) {
+	sha, _, err := a.client.Repositories.GetCommitSHA1(ctx, owner, repo, ref, "")
+	return sha, err
+}
+
+// Compare implements githubAPI
+func (a *goGitHubAPI) Compare(ctx context.Context, owner, repo, base, head string) (*Comparison, error) {
+	comparison, _, err := a.client.Repositories.CompareCommits(ctx, owner, repo, base, head, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Comparison{
+		Status:   comparison.GetStatus(),
+		AheadBy:  comparison.GetAheadBy(),
+		BehindBy: comparison.GetBehindBy(),
+	}
+	for _, commit := range comparison.Commits {
+		result.Commits = append(result.Commits, firstLine(commit.GetCommit().GetMessage()))
+	}
+	return result, nil
+}
+
+// FileContents implements githubAPI
+func (a *goGitHubAPI) FileContents(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	file, _, _, err := a.client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%s in %s/%s is not a file", path, owner, repo)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return []byte(content), nil
+}
+
+// ListTree implements githubAPI, listing every file of a repository at ref
+// in one request. The bool reports a listing GitHub truncated.
+func (a *goGitHubAPI) ListTree(ctx context.Context, owner, repo, ref string) ([]githubEntry, bool, error) {
+	tree, _, err := a.client.Git.GetTree(ctx, owner, repo, ref, true)
+	if err != nil {
+		return nil, false, err
+	}
+	listed := make([]githubEntry, 0, len(tree.Entries))
+	for _, entry := range tree.Entries {
+		if entry.GetType() != "blob" {
+			continue
+		}
+		listed = append(listed, githubEntry{Name: path.Base(entry.GetPath()), Path: entry.GetPath(), Size: int64(entry.GetSize())})
+	}
+	return listed, tree.GetTruncated(), nil
+}
+
+// Repository implements githubAPI, returning the current owner/repo of a
+// repository, which differs from the requested one after a rename or transfer
+func (a *goGitHubAPI) Repository(ctx context.Context, owner, repo string) (*githubRepository, error) {
+	repository, _, err := a.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	return &githubRepository{
+		FullName:    repository.GetFullName(),
+		Description: repository.GetDescription(),
+		Archived:    repository.GetArchived(),
+		OwnerType:   repository.GetOwner().GetType(),
+		Fork:        repository.GetFork(),
+		Parent:      repository.GetParent().GetFullName(),
+	}, nil
+}
+
+// Readme implements githubAPI. It returns the README of the default
+// branch, whatever its file name.
+func (a *goGitHubAPI) Readme(ctx context.Context, owner, repo string) ([]byte, error) {
+	file, _, err := a.client.Repositories.GetReadme(ctx, owner, repo, nil)
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the README of %s/%s: %w", owner, repo, err)
+	}
+	return []byte(content), nil
+}
+
+// License implements githubAPI
+func (a *goGitHubAPI) License(ctx context.Context, owner, repo string) (*githubLicense, error) {
+	license, _, err := a.client.Repositories.License(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	return &githubLicense{
+		SPDXID: license.GetLicense().GetSPDXID(),
+		Name:   license.GetLicense().GetName(),
+		URL:    license.GetHTMLURL(),
+	}, nil
+}
+
+// SubmitSnapshot implements githubAPI; go-github has no client for the
+// dependency submission endpoint
+func (a *goGitHubAPI) SubmitSnapshot(ctx context.Context, owner, repo string, snapshot *dependencySnapshot) (*githubSnapshotResult, error) {
+	req, err := a.client.NewRequest(http.MethodPost, fmt.Sprintf("repos/%s/%s/dependency-graph/snapshots", owner, repo), snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var result githubSnapshotResult
+	if _, err := a.client.Do(ctx, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Attestations implements githubAPI. It returns the Sigstore bundles
+// attesting the subject digest, e.g. sha1:<commit>; go-github has no client
+// for the endpoint.
+func (a *goGitHubAPI) Attestations(ctx context.Context, owner, repo, digest string) ([]json.RawMessage, error) {
+	req, err := a.client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s/attestations/%s?per_page=%d", owner, repo, url.PathEscape(digest), githubPageSize), nil)
+	if err != nil {
+		return nil, err
+	}
+	var listed struct {
+		Attestations []struct {
+			Bundle json.RawMessage `json:"bundle"`
+		} `json:"attestations"`
+	}
+	if _, err := a.client.Do(ctx, req, &listed); err != nil {
+		return nil, err
+	}
+	bundles := make([]json.RawMessage, 0, len(listed.Attestations))
+	for _, attestation := range listed.Attestations {
+		bundles = append(bundles, attestation.Bundle)
+	}
+	return bundles, nil
+}
+
+// githubAdvisory is a reviewed advisory of the global advisory database.
+// go-github has no client for it.
+type githubAdvisory struct {
+	GHSAID          string `json:"ghsa_id"`
+	CVEID           string `json:"cve_id"`
+	HTMLURL         string `json:"html_url"`
+	Summary         string `json:"summary"`
+	Severity        string `json:"severity"`
+	Vulnerabilities []struct {
+		Package struct {
+			Name string `json:"name"`
+		} `json:"package"`
+		FirstPatchedVersion string `json:"first_patched_version"`
+	} `json:"vulnerabilities"`
+}
+
+// Advisories implements githubAPI, listing the advisories of the actions
+// ecosystem that affect version of repo
+func (a *goGitHubAPI) Advisories(ctx context.Context, repo, version string) ([]Advisory, error) {
+	query := url.Values{}
+	query.Set("ecosystem", "actions")
+	query.Set("affects", repo+"@"+version)
+	query.Set("per_page", fmt.Sprint(githubPageSize))
+	req, err := a.client.NewRequest(http.MethodGet, "advisories?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var listed []githubAdvisory
+	if _, err := a.client.Do(ctx, req, &listed); err != nil {
+		return nil, err
+	}
+
+	advisories := make([]Advisory, 0, len(listed))
+	for _, advisory := range listed {
+		converted := Advisory{
+			ID:       advisory.GHSAID,
+			Summary:  advisory.Summary,
+			Severity: strings.ToLower(advisory.Severity),
+			URL:      advisory.HTMLURL,
+			Source:   advisorySourceGitHub,
+		}
+		if advisory.CVEID != "" {
+			converted.Aliases = []string{advisory.CVEID}
+		}
+		for _, vulnerability := range advisory.Vulnerabilities {
+			if strings.EqualFold(vulnerability.Package.Name, repo) {
+				converted.Patched = vulnerability.FirstPatchedVersion
+				break
+			}
+		}
+		advisories = append(advisories, converted)
+	}
+	return advisories, nil
+}
+
+// SearchCode implements githubAPI. Code search returns at most 1000 files
+// per query.
+func (a *goGitHubAPI) SearchCode(ctx context.Context, query string, page int) ([]githubCodeResult, int, error) {
+	result, resp, err := a.client.Search.Code(ctx, query, &github.SearchOptions{ListOptions: github.ListOptions{Page: page, PerPage: githubPageSize}})
+	if err != nil {
+		return nil, 0, err
+	}
+	found := make([]githubCodeResult, 0, len(result.CodeResults))
+	for _, file := range result.CodeResults {
+		found = append(found, githubCodeResult{Repo: file.GetRepository().GetFullName(), Path: file.GetPath(), HTMLURL: file.GetHTMLURL()})
+	}
+	return found, resp.NextPage, nil
+}
+
+// OrgTwoFactorRequired implements githubAPI. The setting is only returned to
+// owners of the organization; nil means it could not be read.
+func (a *goGitHubAPI) OrgTwoFactorRequired(ctx context.Context, org string) (*bool, error) {
+	organization, _, err := a.client.Organizations.Get(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	return organization.TwoFactorRequirementEnabled, nil
+}
+
+// OrgVerified implements githubAPI. Personal accounts are not found as
+// organizations.
+func (a *goGitHubAPI) OrgVerified(ctx context.Context, org string) (bool, error) {
+	organization, _, err := a.client.Organizations.Get(ctx, org)
+	if err != nil {
+		return false, err
+	}
+	return organization.GetIsVerified(), nil
+}
+
+// ActionsPolicy implements githubAPI, returning the policy of the
+// organization owner, or of the repository owner/repo when repo is set. The
+// requests are made directly since go-github does not know about the SHA
+// pinning requirement.
+func (a *goGitHubAPI) ActionsPolicy(ctx context.Context, owner, repo string) (*githubActionsPolicy, error) {
+	endpoint := fmt.Sprintf("orgs/%s/actions/permissions", owner)
+	if repo != "" {
+		endpoint = fmt.Sprintf("repos/%s/%s/actions/permissions", owner, repo)
+	}
+	req, err := a.client.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	var permissions struct {
+		EnabledRepositories string `json:"enabled_repositories"`
+		Enabled             *bool  `json:"enabled"`
+		AllowedActions      string `json:"allowed_actions"`
+		SHAPinningRequired  bool   `json:"sha_pinning_required"`
+	}
+	if _, err := a.client.Do(ctx, req, &permissions); err != nil {
+		return nil, err
+	}
+	policy := &githubActionsPolicy{
+		Disabled:           permissions.EnabledRepositories == "none" || (permissions.Enabled != nil && !*permissions.Enabled),
+		AllowedActions:     permissions.AllowedActions,
+		SHAPinningRequired: permissions.SHAPinningRequired,
+	}
+	if policy.Disabled || policy.AllowedActions != "selected" {
+		return policy, nil
+	}
+
+	req, err = a.client.NewRequest(http.MethodGet, endpoint+"/selected-actions", nil)
+	if err != nil {
+		return nil, err
+	}
+	var selected github.ActionsAllowed
+	if _, err := a.client.Do(ctx, req, &selected); err != nil {
+		return nil, err
+	}
+	policy.GitHubOwnedAllowed = selected.GetGithubOwnedAllowed()
+	policy.VerifiedAllowed = selected.GetVerifiedAllowed()
+	policy.Patterns = selected.PatternsAllowed
+	return policy, nil
+}
+
+// graphqlError is an error reported in the body of a GraphQL response
+type graphqlError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Path    []any  `json:"path"`
+}
+
+// GraphQL implements githubAPI, decoding the data of the response into
+// data. The endpoint is graphql on github.com and api/graphql on GitHub
+// Enterprise Server. Errors of individual fields are logged and leave them
+// null; a response without data fails.
+func (a *goGitHubAPI) GraphQL(ctx context.Context, query string, variables map[string]any, data any) error {
+	endpoint := "graphql"
+	if base := a.client.BaseURL; strings.HasSuffix(base.Path, "/api/v3/") {
+		endpoint = base.Scheme + "://" + base.Host + strings.TrimSuffix(base.Path, "v3/") + "graphql"
+	}
+	req, err := a.client.NewRequest(http.MethodPost, endpoint, map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphqlError  `json:"errors"`
+	}
+	if _, err := a.client.Do(ctx, req, &response); err != nil {
+		return err
+	}
+	for _, e := range response.Errors {
+		logger.Debug("GraphQL error", "type", e.Type, "message", e.Message, "path", e.Path)
+	}
+	if len(response.Data) == 0 || string(response.Data) == "null" {
+		if len(response.Errors) > 0 {
+			return fmt.Errorf("GraphQL query failed: %s", response.Errors[0].Message)
+		}
+		return errors.New("GraphQL response has no data")
+	}
+	return json.Unmarshal(response.Data, data)
+}
+
+// RemainingRequests implements githubAPI. The rate limit endpoint does not
+// count against the quota.
+func (a *goGitHubAPI) RemainingRequests(ctx context.Context) (int, error) {
+	limits, _, err := a.client.RateLimit.Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if limits.GetCore() == nil {
+		return 0, errors.New("rate limit response has no core quota")
+	}
+	return limits.GetCore().Remaining, nil
+}
+
+// RateLimits implements githubAPI. The rate limit endpoint does not count
+// against the quota.
+func (a *goGitHubAPI) RateLimits(ctx context.Context) (*githubRateLimits, error) {
+	limits, _, err := a.client.RateLimit.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if limits.GetCore() == nil {
+		return nil, errors.New("rate limit response has no core quota")
+	}
+	convert := func(rate *github.Rate) *githubRate {
+		if rate == nil {
+			return nil
+		}
+		return &githubRate{Limit: rate.Limit, Remaining: rate.Remaining, Reset: rate.Reset.Time}
+	}
+	return &githubRateLimits{Core: *convert(limits.GetCore()), GraphQL: convert(limits.GetGraphQL()), Search: convert(limits.GetSearch())}, nil
+}
+
+// AuthenticatedUser implements githubAPI, returning the login of the token's
+// user. Installation tokens belong to no user and fail.
+func (a *goGitHubAPI) AuthenticatedUser(ctx context.Context) (string, error) {
+	user, _, err := a.client.Users.Get(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	return user.GetLogin(), nil
+}
+
+// githubErrorKind classifies the errors returned by go-github
+func githubErrorKind(err error) (ErrorKind, bool) {
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) {
+		return ErrorKindRateLimited, true
+	}
+
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		switch respErr.Response.StatusCode {
+		case http.StatusNotFound, http.StatusGone:
+			return ErrorKindNotFound, true
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ErrorKindPermissionDenied, true
+		case http.StatusTooManyRequests:
+			return ErrorKindRateLimited, true
+		}
+	}
+	return "", false
+}
+
+// githubRetryAfter returns how long to wait before retrying a request that
+// hit a rate limit
+func githubRetryAfter(err error) (time.Duration, bool) {
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) && abuseErr.RetryAfter != nil {
+		return *abuseErr.RetryAfter, true
+	}
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return time.Until(rateLimitErr.Rate.Reset.Time), true
+	}
+	return 0, false
+}
+
+// apiVersionTransport sets the REST API version header chosen with
+// --github-api-version, replacing the default of the client library
+type apiVersionTransport struct {
+	base    http.RoundTripper
+	version string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t apiVersionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(githubAPIVersionHeader, t.version)
+	return t.base.RoundTrip(req)
+}
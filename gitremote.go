@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// lsRemoteTimeout bounds a single git ls-remote call
+	lsRemoteTimeout = 30 * time.Second
+
+	// peeledSuffix marks the commit an annotated tag points at in ls-remote output
+	peeledSuffix = "^{}"
+)
+
+var (
+	// repoNameRegex matches valid GitHub owner and repository names
+	repoNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+	// versionTagRegex matches release-like tags such as v1, v1.2 or 1.2.3
+	versionTagRegex = regexp.MustCompile(`^v?\d+(\.\d+)*$`)
+
+	// prereleaseTagRegex matches version tags of release candidates, betas
+	// and similar builds, such as v2.0.0-rc.1, v3.1beta2 or 1.4.0.dev1
+	prereleaseTagRegex = regexp.MustCompile(`(?i)^v?\d+(\.\d+)*(-|\.?(alpha|beta|rc|pre|preview|dev|draft|canary|nightly))`)
+)
+
+// isPrereleaseTag reports whether a tag names a pre-release. Releases are
+// not always flagged as pre-releases, so the tag name is checked as well.
+func isPrereleaseTag(tag string) bool {
+	return prereleaseTagRegex.MatchString(tag)
+}
+
+// remoteRefs holds the tags and branches of a repository as listed by git ls-remote
+type remoteRefs struct {
+	tags  map[string]string
+	heads map[string]string
+}
+
+// lsRemote lists the tags and branches of a public GitHub repository over the
+// git protocol, which does not count against the REST API quota
+func lsRemote(owner, repo string) (*remoteRefs, error) {
+	if !repoNameRegex.MatchString(owner) || !repoNameRegex.MatchString(repo) {
+		return nil, fmt.Errorf("invalid repository name %s/%s", owner, repo)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lsRemoteTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf("https://github.com/%s/%s.git", owner, repo)
+	// #nosec G204 - owner and repo are validated and no shell is involved
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--tags", "--heads", url)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-remote %s failed: %w", url, err)
+	}
+
+	refs := &remoteRefs{tags: make(map[string]string), heads: make(map[string]string)}
+	peeled := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		sha, name, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok || !shaRegex.MatchString(sha) {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(name, "refs/tags/") && strings.HasSuffix(name, peeledSuffix):
+			peeled[strings.TrimSuffix(strings.TrimPrefix(name, "refs/tags/"), peeledSuffix)] = sha
+		case strings.HasPrefix(name, "refs/tags/"):
+			refs.tags[strings.TrimPrefix(name, "refs/tags/")] = sha
+		case strings.HasPrefix(name, "refs/heads/"):
+			refs.heads[strings.TrimPrefix(name, "refs/heads/")] = sha
+		}
+	}
+
+	// Annotated tags resolve to the commit they point at
+	for tag, sha := range peeled {
+		refs.tags[tag] = sha
+	}
+	return refs, nil
+}
+
+// resolve returns the commit a tag or branch points at; tags win like in the API lookup
+func (r *remoteRefs) resolve(ref string) (string, bool) {
+	if sha, ok := r.tags[ref]; ok {
+		return sha, true
+	}
+	sha, ok := r.heads[ref]
+	return sha, ok
+}
+
+// expand returns the one tag or branch commit starting with prefix
+func (r *remoteRefs) expand(prefix string) (string, bool) {
+	found := ""
+	for _, refs := range []map[string]string{r.tags, r.heads} {
+		for _, sha := range refs {
+			if !strings.HasPrefix(sha, prefix) {
+				continue
+			}
+			if found != "" && found != sha {
+				return "", false
+			}
+			found = sha
+		}
+	}
+	return found, found != ""
+}
+
+// latestTag returns the highest version tag. With a filter, only matching
+// tags are considered; otherwise only release-like tags are. Pre-release
+// tags are skipped unless prereleases is set.
+func (r *remoteRefs) latestTag(filter *regexp.Regexp, prereleases bool) (string, bool) {
+	best := ""
+	for tag := range r.tags {
+		if filter != nil && !filter.MatchString(tag) {
+			continue
+		}
+		if filter == nil && !versionTagRegex.MatchString(tag) && !(prereleases && isPrereleaseTag(tag)) {
+			continue
+		}
+		if !prereleases && isPrereleaseTag(tag) {
+			continue
+		}
+		if best == "" || compareVersionTags(tag, best) > 0 {
+			best = tag
+		}
+	}
+	return best, best != ""
+}
+
+// compareVersionTags orders tags by their numeric components, so that v4.10
+// sorts after v4.2 and v4.2.2 after v4. A pre-release suffix sorts before
+// the release, so v2.0.0-rc.1 is lower than v2.0.0. Other non-numeric parts
+// compare as text.
+func compareVersionTags(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if c := compareVersionPart(pa[i], pb[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(pa) < len(pb):
+		return -1
+	case len(pa) > len(pb):
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareVersionPart compares one component of a version tag by its leading
+// number, then by its suffix; a component without a suffix, such as 0 in
+// v2.0.0, sorts after one with a suffix such as 0-rc
+func compareVersionPart(a, b string) int {
+	if a == b {
+		return 0
+	}
+	na, suffixA := splitVersionPart(a)
+	nb, suffixB := splitVersionPart(b)
+	if na < 0 || nb < 0 {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case na != nb:
+		if na < nb {
+			return -1
+		}
+		return 1
+	case suffixA == "":
+		return 1
+	case suffixB == "":
+		return -1
+	}
+	return strings.Compare(suffixA, suffixB)
+}
+
+// splitVersionPart splits a version component into its leading number and
+// the rest. The number is -1 when the component does not start with a digit.
+func splitVersionPart(part string) (int, string) {
+	end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' })
+	if end < 0 {
+		end = len(part)
+	}
+	n, err := strconv.Atoi(part[:end])
+	if err != nil {
+		return -1, part
+	}
+	return n, part[end:]
+}
+
+// gitForge resolves refs over the git protocol. It has no API quota but
+// also no release metadata, so the latest release is the highest version tag.
+type gitForge struct {
+	cache map[string]*remoteRefs
+}
+
+// newGitForge returns a forge backed by git ls-remote
+func newGitForge() *gitForge {
+	return &gitForge{cache: make(map[string]*remoteRefs)}
+}
+
+// refs returns the cached ls-remote listing of a repository
+func (g *gitForge) refs(owner, repo string) (*remoteRefs, error) {
+	key := owner + "/" + repo
+	if refs, ok := g.cache[key]; ok {
+		return refs, nil
+	}
+	refs, err := lsRemote(owner, repo)
+	if err != nil {
+		return nil, &ResolveError{Kind: ErrorKindNetwork, Err: err}
+	}
+	g.cache[key] = refs
+	return refs, nil
+}
+
+// Name implements Forge
+func (g *gitForge) Name() string {
+	return "git"
+}
+
+// LatestRelease implements Forge with the highest version tag
+func (g *gitForge) LatestRelease(owner, repo string, prereleases bool) (*Release, error) {
+	refs, err := g.refs(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	tag, ok := refs.latestTag(nil, prereleases)
+	if !ok {
+		return nil, newResolveError(ErrorKindNotFound, "no version tags found for %s/%s", owner, repo)
+	}
+	return &Release{Tag: tag}, nil
+}
+
+// LatestMatchingTag implements Forge
+func (g *gitForge) LatestMatchingTag(owner, repo string, filter *regexp.Regexp, prereleases bool) (string, *Release, error) {
+	refs, err := g.refs(owner, repo)
+	if err != nil {
+		return "", nil, err
+	}
+	tag, ok := refs.latestTag(filter, prereleases)
+	if !ok {
+		return "", nil, newResolveError(ErrorKindTagFormat, "no tag of %s/%s matches filter %q", owner, repo, filter.String())
+	}
+	return tag, nil, nil
+}
+
+// HighestTag implements Forge
+func (g *gitForge) HighestTag(owner, repo string, filter *regexp.Regexp) (string, error) {
+	refs, err := g.refs(owner, repo)
+	if err != nil {
+		return "", err
+	}
+	tag, ok := refs.latestTag(filter, true)
+	if !ok {
+		return "", newResolveError(ErrorKindNotFound, "no tag of %s/%s matches %q", owner, repo, filter.String())
+	}
+	return tag, nil
+}
+
+// ResolveRef implements Forge
+func (g *gitForge) ResolveRef(owner, repo, ref string) (string, error) {
+	refs, err := g.refs(owner, repo)
+	if err != nil {
+		return "", err
+	}
+	sha, ok := refs.resolve(ref)
+	if !ok {
+		return "", newResolveError(ErrorKindNotFound, "could not resolve ref %s for %s/%s", ref, owner, repo)
+	}
+	return sha, nil
+}
+
+// ExpandSHA implements Forge. ls-remote only lists the commits refs point
+// at, so abbreviated SHAs of other commits cannot be expanded.
+func (g *gitForge) ExpandSHA(owner, repo, prefix string) (string, error) {
+	refs, err := g.refs(owner, repo)
+	if err != nil {
+		return "", err
+	}
+	sha, ok := refs.expand(prefix)
+	if !ok {
+		return "", newResolveError(ErrorKindNotFound, "%s is not the commit of a tag or branch of %s/%s", prefix, owner, repo)
+	}
+	return sha, nil
+}
+
+// TagsAt implements Forge
+func (g *gitForge) TagsAt(owner, repo, sha string) ([]string, error) {
+	refs, err := g.refs(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for tag, tagSHA := range refs.tags {
+		if tagSHA == sha {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// Compare implements Forge; ls-remote does not transfer commit history
+func (g *gitForge) Compare(owner, repo, base, head string) (*Comparison, error) {
+	return nil, fmt.Errorf("comparing %s...%s in %s/%s is not supported over git ls-remote", base, head, owner, repo)
+}
+
+// FileContents implements Forge; ls-remote does not transfer file contents
+func (g *gitForge) FileContents(owner, repo, path, _ string) ([]byte, error) {
+	return nil, fmt.Errorf("reading %s from %s/%s is not supported over git ls-remote", path, owner, repo)
+}
+
+// LatestImmutableRelease implements Forge; git has no releases
+func (g *gitForge) LatestImmutableRelease(owner, repo string, _ bool) (*Release, error) {
+	return nil, newResolveError(ErrorKindNotFound, "releases of %s/%s are not visible over git ls-remote", owner, repo)
+}
+
+// ReleaseAt implements Forge; git has no releases
+func (g *gitForge) ReleaseAt(owner, repo, tag string) (*Release, error) {
+	return nil, newResolveError(ErrorKindNotFound, "release %s of %s/%s is not visible over git ls-remote", tag, owner, repo)
+}
+
+// CanonicalName implements Forge; git follows the redirects of moved
+// repositories without reporting them
+func (g *gitForge) CanonicalName(owner, repo string) (string, error) {
+	return owner + "/" + repo, nil
+}
@@ -1,10 +1,15 @@
 module github.com/greysquirr3l/github-ci-hash
 
-go 1.23
+go 1.23.0
 
 require (
-	github.com/google/go-github/v56 v56.0.0
+	github.com/google/go-github/v74 v74.0.0
 	golang.org/x/oauth2 v0.23.0
+	golang.org/x/term v0.25.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require github.com/google/go-querystring v1.1.0 // indirect
+require (
+	github.com/google/go-querystring v1.1.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+)
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// graphqlBatchSize is the number of repositories looked up per GraphQL query
+const graphqlBatchSize = 25
+
+// batchGraphQL enables the GraphQL batches looking up latest releases and
+// refs ahead of the REST calls. It is cleared by --graphql=false.
+var batchGraphQL = true
+
+// graphqlTarget is a repository whose lookups are batched
+type graphqlTarget struct {
+	owner, repo string
+	// latest is set when the latest release is looked up
+	latest bool
+	// refs are the tags and branches the workflows use
+	refs []string
+}
+
+// graphqlRelease is the latest release of a repository
+type graphqlRelease struct {
+	TagName     string     `json:"tagName"`
+	URL         string     `json:"url"`
+	Description string     `json:"description"`
+	PublishedAt *time.Time `json:"publishedAt"`
+	Immutable   bool       `json:"immutable"`
+	TagCommit   *struct {
+		OID string `json:"oid"`
+	} `json:"tagCommit"`
+}
+
+// graphqlRef is a git ref. Annotated tags point at a tag object, whose
+// target is the commit.
+type graphqlRef struct {
+	Target struct {
+		OID    string `json:"oid"`
+		Target *struct {
+			OID string `json:"oid"`
+		} `json:"target"`
+	} `json:"target"`
+}
+
+// commit returns the commit the ref points at, peeling annotated tags
+func (r *graphqlRef) commit() string {
+	if r.Target.Target != nil && r.Target.Target.OID != "" {
+		return r.Target.Target.OID
+	}
+	return r.Target.OID
+}
+
+// graphqlTargets returns the repositories of actions whose lookups can be
+// batched, in name order. Actions resolved by plugins, with a tag filter or
+// with pre-releases enabled are left to REST for their latest release, and
+// the CodeQL action, whose version tags are renamed, is left out entirely.
+func graphqlTargets(cfg *Config, actions WorkflowActions) []*graphqlTarget {
+	byRepo := make(map[string]*graphqlTarget)
+	seenRefs := make(map[string]bool)
+	for _, action := range sortedByRepo(actions) {
+		if action.Docker {
+			continue
+		}
+		owner, repo, _ := splitActionPath(action.Repo)
+		if owner == "" || repo == "" || cfg.resolver(owner+"/"+repo) != "" || (owner == "github" && repo == codeQLAction) {
+			continue
+		}
+		configKey := action.Repo
+		if group := builtinActionGroup(action.Repo); group != "" {
+			configKey = group
+		}
+
+		key := strings.ToLower(owner + "/" + repo)
+		target := byRepo[key]
+		if target == nil {
+			target = &graphqlTarget{owner: owner, repo: repo}
+			byRepo[key] = target
+		}
+		if cfg.actionConfig(configKey).tagFilter == nil && !cfg.includePrereleases(configKey) {
+			target.latest = true
+		}
+		if action.CurrentSHA == "" && action.CurrentRef != "" && !shortSHARegex.MatchString(action.CurrentRef) && !seenRefs[key+"@"+action.CurrentRef] {
+			seenRefs[key+"@"+action.CurrentRef] = true
+			target.refs = append(target.refs, action.CurrentRef)
+		}
+	}
+
+	targets := make([]*graphqlTarget, 0, len(byRepo))
+	for _, target := range byRepo {
+		if target.latest || len(target.refs) > 0 {
+			targets = append(targets, target)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return strings.ToLower(targets[i].owner+"/"+targets[i].repo) < strings.ToLower(targets[j].owner+"/"+targets[j].repo)
+	})
+	return targets
+}
+
+// graphqlQuery builds the query looking up targets, with one aliased
+// repository field per target and one tag and one branch field per ref
+func graphqlQuery(targets []*graphqlTarget) (string, map[string]any) {
+	var params, fields strings.Builder
+	variables := make(map[string]any)
+	for i, target := range targets {
+		n := strconv.Itoa(i)
+		fmt.Fprintf(&params, "$o%s: String!, $n%s: String!, ", n, n)
+		variables["o"+n] = target.owner
+		variables["n"+n] = target.repo
+		fmt.Fprintf(&fields, "  r%s: repository(owner: $o%s, name: $n%s) {\n    nameWithOwner\n", n, n, n)
+		if target.latest {
+			fields.WriteString("    latestRelease { tagName url description publishedAt immutable tagCommit { oid } }\n")
+		}
+		for j, ref := range target.refs {
+			m := n + "_" + strconv.Itoa(j)
+			fmt.Fprintf(&params, "$t%s: String!, $h%s: String!, ", m, m)
+			variables["t"+m] = "refs/tags/" + ref
+			variables["h"+m] = "refs/heads/" + ref
+			fmt.Fprintf(&fields, "    t%s: ref(qualifiedName: $t%s) { target { oid ... on Tag { target { oid } } } }\n", strconv.Itoa(j), m)
+			fmt.Fprintf(&fields, "    h%s: ref(qualifiedName: $h%s) { target { oid } }\n", strconv.Itoa(j), m)
+		}
+		fields.WriteString("  }\n")
+	}
+	return "query(" + strings.TrimSuffix(params.String(), ", ") + ") {\n" + fields.String() + "}", variables
+}
+
+// primeTarget records the lookups of target answered by its repository
+// field in memo. Renamed repositories are left to REST, which records the
+// move, and refs that resolve to neither a tag nor a branch are left to
+// REST, which reports why.
+func primeTarget(memo *memoForge, target *graphqlTarget, data json.RawMessage) int {
+	var repository struct {
+		NameWithOwner string          `json:"nameWithOwner"`
+		LatestRelease *graphqlRelease `json:"latestRelease"`
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &repository); err != nil || json.Unmarshal(data, &fields) != nil {
+		return 0
+	}
+	if !strings.EqualFold(repository.NameWithOwner, target.owner+"/"+target.repo) {
+		return 0
+	}
+
+	primed := 0
+	if release := repository.LatestRelease; target.latest && release != nil && release.TagName != "" && !isPrereleaseTag(release.TagName) {
+		latest := &Release{Tag: release.TagName, URL: release.URL, Notes: release.Description, Immutable: release.Immutable}
+		if release.PublishedAt != nil {
+			latest.Date = release.PublishedAt.Format(time.DateOnly)
+		}
+		memo.prime(memoKey("latest", target.owner, target.repo, "false"), latest)
+		primed++
+		if release.TagCommit != nil && release.TagCommit.OID != "" {
+			memo.prime(memoKey("ref", target.owner, target.repo, release.TagName), release.TagCommit.OID)
+		}
+	}
+	for j, ref := range target.refs {
+		// Like the REST lookup, a tag wins over a branch of the same name
+		for _, alias := range []string{"t", "h"} {
+			var gitRef *graphqlRef
+			if err := json.Unmarshal(fields[alias+strconv.Itoa(j)], &gitRef); err == nil && gitRef != nil && gitRef.commit() != "" {
+				memo.prime(memoKey("ref", target.owner, target.repo, ref), gitRef.commit())
+				primed++
+				break
+			}
+		}
+	}
+	return primed
+}
+
+// prefetchGraphQL looks up the latest releases and refs of the actions in
+// GraphQL queries of up to graphqlBatchSize repositories, so dozens of
+// actions cost a handful of requests instead of several REST calls each.
+// GraphQL needs a token; without one, or when a query fails, the lookups
+// are left to REST.
+func prefetchGraphQL(gc *GitHubClient, cfg *Config, memo *memoForge, actions WorkflowActions) {
+	if !batchGraphQL || gc.tokenKind == TokenNone {
+		return
+	}
+	targets := graphqlTargets(cfg, actions)
+	primed, queries := 0, 0
+	for start := 0; start < len(targets); start += graphqlBatchSize {
+		batch := targets[start:min(start+graphqlBatchSize, len(targets))]
+		query, variables := graphqlQuery(batch)
+		var data map[string]json.RawMessage
+		queries++
+		if err := gc.api.GraphQL(gc.ctx, query, variables, &data); err != nil {
+			logger.Info("GraphQL batch failed, resolving the remaining actions with REST", "error", err)
+			break
+		}
+		for i, target := range batch {
+			primed += primeTarget(memo, target, data["r"+strconv.Itoa(i)])
+		}
+	}
+	if queries > 0 {
+		logger.Info("looked up actions in GraphQL batches", "repositories", len(targets), "queries", queries, "lookups", primed)
+	}
+}
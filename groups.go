@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxChangelogLines limits how much of each release body is shown in prompts
+	maxChangelogLines = 10
+)
+
+// ActionGroup is a set of actions that form one logical dependency. Members
+// are checked, confirmed, and applied as a unit so that workflows never end up
+// with mismatched component versions.
+type ActionGroup struct {
+	Name    string
+	Members []*ActionInfo
+}
+
+// GroupReport is the machine readable view of an ActionGroup
+type GroupReport struct {
+	Name        string              `json:"name"`
+	Actions     []GroupActionReport `json:"actions"`
+	Files       []string            `json:"files"`
+	NeedsUpdate bool                `json:"needs_update"`
+	Mismatched  bool                `json:"mismatched"`
+	Blocked     bool                `json:"blocked"`
+}
+
+// GroupActionReport describes one distinct action within a group
+type GroupActionReport struct {
+	Repo         string `json:"repo"`
+	LatestTag    string `json:"latest_tag,omitempty"`
+	NeedsUpdate  bool   `json:"needs_update"`
+	ReleaseURL   string `json:"release_url,omitempty"`
+	ReleaseNotes string `json:"release_notes,omitempty"`
+}
+
+// builtinActionGroup returns the built-in group for an action, if any.
+// Sub-actions such as github/codeql-action/init and /analyze or
+// gradle/actions/setup-gradle are released with their repository, so the
+// sub-actions of one repository always move together.
+func builtinActionGroup(repo string) string {
+	if strings.HasPrefix(repo, dockerPrefix) || isReusableWorkflow(repo) {
+		return ""
+	}
+	owner, name, subpath := splitActionPath(repo)
+	if subpath == "" {
+		return ""
+	}
+	return owner + "/" + name
+}
+
+// collectGroups gathers the grouped actions across all workflows, sorted by name
+func collectGroups(actions WorkflowActions, cfg *Config) []*ActionGroup {
+	byName := make(map[string]*ActionGroup)
+
+	for workflow := range actions {
+		actionList := actions[workflow]
+		for i := range actionList {
+			name := cfg.actionGroup(actionList[i].Repo)
+			if name == "" {
+				continue
+			}
+			group, ok := byName[name]
+			if !ok {
+				group = &ActionGroup{Name: name}
+				byName[name] = group
+			}
+			group.Members = append(group.Members, &actionList[i])
+		}
+	}
+
+	groups := make([]*ActionGroup, 0, len(byName))
+	for _, group := range byName {
+		sort.Slice(group.Members, func(i, j int) bool {
+			a, b := group.Members[i], group.Members[j]
+			if a.WorkflowFile != b.WorkflowFile {
+				return a.WorkflowFile < b.WorkflowFile
+			}
+			return a.Line < b.Line
+		})
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups
+}
+
+// NeedsUpdate reports whether any member of the group needs an update
+func (g *ActionGroup) NeedsUpdate() bool {
+	for _, member := range g.Members {
+		if member.NeedsUpdate {
+			return true
+		}
+	}
+	return false
+}
+
+// Blocker returns the first member that failed to resolve. A group with a
+// failed member cannot be updated without risking mismatched versions.
+func (g *ActionGroup) Blocker() *ActionInfo {
+	for _, member := range g.Members {
+		if member.Error != nil {
+			return member
+		}
+	}
+	return nil
+}
+
+// Mismatched reports whether occurrences of the same action repository are
+// currently pinned to different commits
+func (g *ActionGroup) Mismatched() bool {
+	pinned := make(map[string]string)
+	for _, member := range g.Members {
+		repo := actionRepoRoot(member.Repo)
+		if sha, ok := pinned[repo]; ok && sha != member.CurrentSHA {
+			return true
+		}
+		pinned[repo] = member.CurrentSHA
+	}
+	return false
+}
+
+// Files returns the workflow files containing members of the group
+func (g *ActionGroup) Files() []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, member := range g.Members {
+		if !seen[member.WorkflowFile] {
+			seen[member.WorkflowFile] = true
+			files = append(files, member.WorkflowFile)
+		}
+	}
+	return files
+}
+
+// Actions returns one entry per distinct action path in the group
+func (g *ActionGroup) Actions() []GroupActionReport {
+	var reports []GroupActionReport
+	index := make(map[string]int)
+	for _, member := range g.Members {
+		if i, ok := index[member.Repo]; ok {
+			reports[i].NeedsUpdate = reports[i].NeedsUpdate || member.NeedsUpdate
+			continue
+		}
+		index[member.Repo] = len(reports)
+		reports = append(reports, GroupActionReport{
+			Repo:         member.Repo,
+			LatestTag:    member.LatestTag,
+			NeedsUpdate:  member.NeedsUpdate,
+			ReleaseURL:   member.ReleaseURL,
+			ReleaseNotes: member.ReleaseNotes,
+		})
+	}
+	return reports
+}
+
+// Report converts the group to its machine readable form
+func (g *ActionGroup) Report() GroupReport {
+	return GroupReport{
+		Name:        g.Name,
+		Actions:     g.Actions(),
+		Files:       g.Files(),
+		NeedsUpdate: g.NeedsUpdate(),
+		Mismatched:  g.Mismatched(),
+		Blocked:     g.Blocker() != nil,
+	}
+}
+
+// actionRepoRoot returns the owner/repo part of an action path
+func actionRepoRoot(repo string) string {
+	if parts := strings.SplitN(repo, "/", 3); len(parts) == 3 {
+		return parts[0] + "/" + parts[1]
+	}
+	return repo
+}
+
+// printGroups prints the status of each action group
+func printGroups(groups []*ActionGroup) {
+	if len(groups) == 0 {
+		return
+	}
+
+	fmt.Fprintln(console, "\n🔗 Groups (updated together):")
+	for _, group := range groups {
+		report := group.Report()
+		status := theme.OK + " Up to date"
+		switch {
+		case report.Blocked:
+			status = theme.Error + " Blocked: a member failed to resolve"
+		case report.NeedsUpdate:
+			status = theme.Update + " Update available"
+		}
+		fmt.Fprintf(console, "  %s (%d action(s) in %d file(s)): %s\n", group.Name, len(report.Actions), len(report.Files), status)
+		for _, action := range report.Actions {
+			if action.NeedsUpdate {
+				fmt.Fprintf(console, "     %s %s → %s\n", theme.Update, action.Repo, action.LatestTag)
+			}
+		}
+		if report.Mismatched {
+			fmt.Fprintf(console, "     %s Members are pinned to different versions\n", theme.Warning)
+		}
+	}
+}
+
+// printGroupChangelog prints the combined release notes for the distinct
+// repositories of a group that are about to be updated
+func printGroupChangelog(group *ActionGroup) {
+	seen := make(map[string]bool)
+	printedHeader := false
+
+	for _, member := range group.Members {
+		repo := actionRepoRoot(member.Repo)
+		if !member.NeedsUpdate || seen[repo] {
+			continue
+		}
+		seen[repo] = true
+
+		if member.ReleaseURL == "" && member.ReleaseNotes == "" {
+			continue
+		}
+		if !printedHeader {
+			fmt.Fprintln(console, "  📜 Changelog:")
+			printedHeader = true
+		}
+
+		fmt.Fprintf(console, "    %s %s", repo, member.LatestTag)
+		if member.ReleaseURL != "" {
+			fmt.Fprintf(console, " (%s)", member.ReleaseURL)
+		}
+		fmt.Fprintln(console)
+
+		lines := strings.Split(strings.TrimSpace(member.ReleaseNotes), "\n")
+		for i, line := range lines {
+			if i == maxChangelogLines {
+				fmt.Fprintf(console, "      … %d more line(s)\n", len(lines)-maxChangelogLines)
+				break
+			}
+			if line = strings.TrimSpace(line); line != "" {
+				fmt.Fprintf(console, "      %s\n", line)
+			}
+		}
+	}
+}
+
+// applyGroupUpdate writes the update for every member of a group. If any file
+// fails to update, all files touched by the group are restored from backup.
+func applyGroupUpdate(group *ActionGroup, backupFiles map[string]string) error {
+	byFile := make(map[string][]ActionInfo)
+	for _, member := range group.Members {
+		byFile[member.WorkflowFile] = append(byFile[member.WorkflowFile], *member)
+	}
+
+	var written []string
+	for _, file := range group.Files() {
+		if err := updateWorkflowFile(file, byFile[file]); err != nil {
+			for _, restore := range append(written, file) {
+				if backupFile, exists := backupFiles[restore]; exists {
+					if restoreErr := copyFile(backupFile, restore); restoreErr != nil {
+						fmt.Fprintf(console, "  %s Failed to restore backup of %s: %v\n", theme.Error, restore, restoreErr)
+					}
+				}
+			}
+			return fmt.Errorf("failed to update %s: %w", file, err)
+		}
+		written = append(written, file)
+	}
+
+	return nil
+}
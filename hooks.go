@@ -0,0 +1,405 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	// hookMarker identifies hook scripts written by install-hooks
+	hookMarker = "# Installed by github-ci-hash"
+
+	// hookBackupSuffix is appended to hooks that were replaced during install
+	hookBackupSuffix = ".github-ci-hash.bak"
+
+	// hookCommand is how hook manager stanzas invoke the tool
+	hookCommand = "github-ci-hash"
+
+	// hookBinaryVar is the shell variable the generated hooks store the tool's path in
+	hookBinaryVar = "GITHUB_CI_HASH"
+)
+
+// gitHook is a hook script managed by install-hooks and uninstall-hooks
+type gitHook struct {
+	name        string
+	description string
+	// args are the default arguments passed to the tool, see hook_args
+	args     string
+	template string
+}
+
+// hookTemplateData is passed to hook templates
+type hookTemplateData struct {
+	// Hook is the name of the hook being rendered, e.g. "pre-commit"
+	Hook string
+	// Marker must appear in the script for uninstall-hooks to recognize it;
+	// it is added after the shebang line when a template leaves it out
+	Marker string
+	// FindBinary is a shell snippet that sets $GITHUB_CI_HASH to the tool's
+	// path, or to an empty string when it cannot be found
+	FindBinary string
+	// Command invokes the located binary, e.g. {{.Command}} verify
+	Command string
+	// Args are the arguments from hook_args, or the hook's defaults
+	Args string
+}
+
+// managedHooks lists the hooks installed into the git hooks directory. Both
+// default to fast paths so that they are not bypassed: pre-commit verifies
+// only the changed workflows without network access, and pre-push checks for
+// updates at most once a week.
+var managedHooks = []gitHook{
+	{
+		name:        "pre-commit",
+		description: "Verifies changed workflows are pinned to SHAs",
+		args:        "verify --changed --offline",
+		template: `#!/bin/sh
+# Pre-commit hook for github-ci-hash
+{{.Marker}}
+set -e
+
+{{.FindBinary}}
+if [ -z "$GITHUB_CI_HASH" ]; then
+    echo "❌ github-ci-hash is not installed"
+    echo "   Install with: go install github.com/greysquirr3l/github-ci-hash@latest"
+    exit 1
+fi
+
+# Verify the GitHub Actions of changed workflows are pinned to SHAs
+if ! {{.Command}} {{.Args}}; then
+    echo "❌ Some GitHub Actions are not pinned to SHAs"
+    echo "   Run 'github-ci-hash update' to pin them"
+    exit 1
+fi
+`,
+	},
+	{
+		name:        "pre-push",
+		description: "Checks for GitHub Action updates at most once a week",
+		args:        "check --since 7d --quiet",
+		template: `#!/bin/sh
+# Pre-push hook for github-ci-hash
+{{.Marker}}
+
+{{.FindBinary}}
+if [ -z "$GITHUB_CI_HASH" ]; then
+    echo "⚠️  Warning: github-ci-hash is not installed, skipping update check"
+    exit 0
+fi
+
+# Check for GitHub Actions updates; never block the push
+if ! {{.Command}} {{.Args}}; then
+    echo "⚠️  Warning: Could not check for GitHub Action updates"
+    echo "   This might be due to API rate limits or network issues"
+fi
+exit 0
+`,
+	},
+}
+
+// hookArgs returns the arguments a hook passes to the tool, preferring
+// hook_args from the config
+func hookArgs(cfg *Config, hook gitHook) string {
+	if cfg != nil {
+		if args, ok := cfg.HookArgs[hook.name]; ok {
+			return args
+		}
+	}
+	return hook.args
+}
+
+// isManagedHookName reports whether name is one of the hooks install-hooks writes
+func isManagedHookName(name string) bool {
+	for _, hook := range managedHooks {
+		if hook.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote quotes a string for use as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// findBinarySnippet returns the shell snippet that locates the tool when a
+// hook runs: the binary that installed the hook, then PATH, then GOPATH/bin
+func findBinarySnippet() string {
+	candidates := []string{`"$(command -v github-ci-hash 2>/dev/null)"`, `"$(go env GOPATH 2>/dev/null)/bin/github-ci-hash"`}
+
+	// Binaries built by go run live in a temporary directory that will be gone
+	if exe, err := os.Executable(); err == nil && !strings.Contains(exe, "go-build") {
+		candidates = append([]string{shellQuote(exe)}, candidates...)
+	}
+
+	return `# Locate github-ci-hash: the installing binary, then PATH, then GOPATH/bin
+` + hookBinaryVar + `=""
+for candidate in ` + strings.Join(candidates, " ") + `; do
+    if [ -n "$candidate" ] && [ -x "$candidate" ]; then
+        ` + hookBinaryVar + `="$candidate"
+        break
+    fi
+done`
+}
+
+// renderHook expands a hook template with the hook's arguments, making sure
+// the result carries the marker that uninstall-hooks looks for
+func renderHook(name, text, args string) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template for %s hook: %w", name, err)
+	}
+
+	var b strings.Builder
+	data := hookTemplateData{
+		Hook:       name,
+		Marker:     hookMarker,
+		FindBinary: findBinarySnippet(),
+		Command:    `"$` + hookBinaryVar + `"`,
+		Args:       args,
+	}
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s hook: %w", name, err)
+	}
+
+	script := b.String()
+	if !strings.Contains(script, hookMarker) {
+		if first, rest, ok := strings.Cut(script, "\n"); ok && strings.HasPrefix(first, "#!") {
+			script = first + "\n" + hookMarker + "\n" + rest
+		} else {
+			script = "#!/bin/sh\n" + hookMarker + "\n" + script
+		}
+	}
+	return script, nil
+}
+
+// hookManager describes a hook manager that owns the repository's git hooks
+type hookManager struct {
+	name    string
+	config  string
+	stanza  string
+	removal string
+}
+
+// detectHookManager returns the hook manager configured in the repository, if
+// any. Its stanza runs the same commands as the hooks install-hooks writes.
+func detectHookManager(cfg *Config) *hookManager {
+	preCommit := hookCommand + " " + hookArgs(cfg, managedHooks[0])
+	prePush := hookCommand + " " + hookArgs(cfg, managedHooks[1])
+
+	for _, name := range []string{"lefthook.yml", "lefthook.yaml", ".lefthook.yml", ".lefthook.yaml"} {
+		if _, err := os.Stat(name); err == nil {
+			return &hookManager{
+				name:   "lefthook",
+				config: name,
+				stanza: `pre-commit:
+  commands:
+    github-ci-hash-verify:
+      glob: "` + workflowDir + `/*.{yml,yaml}"
+      run: ` + preCommit + `
+pre-push:
+  commands:
+    github-ci-hash-check:
+      run: ` + prePush + ` || true
+`,
+				removal: "Remove the github-ci-hash-verify and github-ci-hash-check commands from " + name + ", then run 'lefthook install'.",
+			}
+		}
+	}
+
+	if info, err := os.Stat(".husky"); err == nil && info.IsDir() {
+		return &hookManager{
+			name:   "husky",
+			config: ".husky",
+			stanza: `# .husky/pre-commit
+` + preCommit + `
+
+# .husky/pre-push
+` + prePush + ` || true
+`,
+			removal: "Remove the github-ci-hash lines from .husky/pre-commit and .husky/pre-push.",
+		}
+	}
+
+	return nil
+}
+
+// gitHooksDir returns the directory git runs hooks from, honoring core.hooksPath
+func gitHooksDir() (string, error) {
+	if _, err := gitLines("rev-parse", "--git-dir"); err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	lines, err := gitLines("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("git did not report a hooks directory")
+	}
+	return filepath.FromSlash(lines[0]), nil
+}
+
+// isManagedHook reports whether the hook at path was written by install-hooks
+func isManagedHook(path string) (bool, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(content), hookMarker), nil
+}
+
+// installHook writes a hook script, keeping a backup of any existing hook that
+// was not installed by this tool so that uninstall-hooks can restore it
+func installHook(hooksDir string, hook gitHook, script string) error {
+	hookPath := filepath.Join(hooksDir, hook.name)
+
+	managed, err := isManagedHook(hookPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read existing %s hook: %w", hook.name, err)
+	case !managed:
+		backupPath := hookPath + hookBackupSuffix
+		if _, err := os.Stat(backupPath); err == nil {
+			return fmt.Errorf("%s already exists; remove it or the existing %s hook first", backupPath, hook.name)
+		}
+		if err := os.Rename(hookPath, backupPath); err != nil {
+			return fmt.Errorf("failed to back up existing %s hook: %w", hook.name, err)
+		}
+		fmt.Fprintf(console, "💾 Existing %s hook moved to %s\n", hook.name, backupPath)
+	}
+
+	// #nosec G306 - Git hooks must be executable (0755) to function properly
+	if err := os.WriteFile(hookPath, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write %s hook: %w", hook.name, err)
+	}
+
+	fmt.Fprintf(console, "%s %s hook installed at %s\n", theme.OK, hook.name, hookPath)
+	return nil
+}
+
+// hookTemplate returns the template for a hook, preferring the one from the
+// config. The second result is false when the config disables the hook.
+func hookTemplate(cfg *Config, hook gitHook) (string, bool) {
+	if cfg != nil {
+		if custom, ok := cfg.Hooks[hook.name]; ok {
+			return custom, strings.TrimSpace(custom) != ""
+		}
+	}
+	return hook.template, true
+}
+
+// installPreCommitHooks installs pre-commit hooks for the repository. When a
+// hook manager owns the hooks, the integration stanza is printed instead.
+func installPreCommitHooks(cfg *Config) error {
+	fmt.Fprintln(console, "🔧 Installing pre-commit hooks...")
+
+	if manager := detectHookManager(cfg); manager != nil {
+		fmt.Fprintf(console, "\n🔗 Hooks are managed by %s (%s). Add the following to its configuration:\n\n", manager.name, manager.config)
+		fmt.Fprint(console, manager.stanza)
+		return nil
+	}
+
+	hooksDir, err := gitHooksDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(hooksDir, 0750); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	var active []gitHook
+	for _, hook := range managedHooks {
+		text, enabled := hookTemplate(cfg, hook)
+		if !enabled {
+			fmt.Fprintf(console, "%s %s hook disabled in config\n", theme.Skipped, hook.name)
+			if err := removeHook(hooksDir, hook); err != nil {
+				return err
+			}
+			continue
+		}
+		script, err := renderHook(hook.name, text, hookArgs(cfg, hook))
+		if err != nil {
+			return err
+		}
+		if err := installHook(hooksDir, hook, script); err != nil {
+			return err
+		}
+		if text != hook.template {
+			hook.description = "Custom script from config"
+		}
+		active = append(active, hook)
+	}
+
+	fmt.Fprintln(console, "\n🎉 Pre-commit hooks successfully installed!")
+	fmt.Fprintln(console, "\nThe following hooks are now active:")
+	for _, hook := range active {
+		fmt.Fprintf(console, "📋 %s: %s\n", hook.name, hook.description)
+	}
+	fmt.Fprintln(console, "\nTo bypass hooks (not recommended): git commit --no-verify")
+
+	return nil
+}
+
+// uninstallHooks removes the hooks written by install-hooks and restores any
+// hooks they replaced. Hooks written by anything else are left untouched.
+func uninstallHooks() error {
+	fmt.Fprintln(console, "🔧 Removing github-ci-hash hooks...")
+
+	if manager := detectHookManager(nil); manager != nil {
+		fmt.Fprintf(console, "\n🔗 Hooks are managed by %s. %s\n", manager.name, manager.removal)
+		return nil
+	}
+
+	hooksDir, err := gitHooksDir()
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range managedHooks {
+		if err := removeHook(hooksDir, hook); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeHook deletes a hook written by install-hooks and restores the hook it
+// replaced, if any. Hooks written by anything else are left untouched.
+func removeHook(hooksDir string, hook gitHook) error {
+	hookPath := filepath.Join(hooksDir, hook.name)
+
+	managed, err := isManagedHook(hookPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s hook: %w", hook.name, err)
+	}
+	if !managed {
+		fmt.Fprintf(console, "  %s Left %s untouched (not installed by github-ci-hash)\n", theme.Skipped, hookPath)
+		return nil
+	}
+
+	if err := os.Remove(hookPath); err != nil {
+		return fmt.Errorf("failed to remove %s hook: %w", hook.name, err)
+	}
+	fmt.Fprintf(console, "  %s Removed %s\n", theme.OK, hookPath)
+
+	backupPath := hookPath + hookBackupSuffix
+	if _, err := os.Stat(backupPath); err == nil {
+		if err := os.Rename(backupPath, hookPath); err != nil {
+			return fmt.Errorf("failed to restore previous %s hook: %w", hook.name, err)
+		}
+		fmt.Fprintf(console, "  %s Restored previous %s hook\n", theme.OK, hook.name)
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// maxCachedResponseSize is the largest response body kept in the HTTP cache
+const maxCachedResponseSize = 1 << 20
+
+// httpCache enables the conditional requests of etagTransport. It is
+// cleared by --http-cache=false.
+var httpCache = true
+
+// unchangedResponses counts the requests GitHub answered with 304 Not
+// Modified
+var unchangedResponses atomic.Int64
+
+// cachedResponse is a GitHub API response stored with its validators
+type cachedResponse struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// etagTransport stores GitHub API responses on disk with their ETag and
+// Last-Modified validators and sends them back as If-None-Match and
+// If-Modified-Since. GitHub answers an unchanged resource with 304 Not
+// Modified, which does not count against the rate limit, and the stored
+// response is returned in its place. Entries are keyed by the request,
+// including its token, so responses are never shared between identities.
+type etagTransport struct {
+	base http.RoundTripper
+	dir  string
+}
+
+// newETagTransport returns base with conditional requests, or base itself
+// when the cache is disabled or has no directory
+func newETagTransport(base http.RoundTripper) http.RoundTripper {
+	if !httpCache {
+		return base
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		logger.Debug("no cache directory, conditional requests disabled", "error", err)
+		return base
+	}
+	return etagTransport{base: base, dir: filepath.Join(dir, programName, "http")}
+}
+
+// cachePath returns the file holding the response to req
+func (t etagTransport) cachePath(req *http.Request) string {
+	sum := sha256.New()
+	for _, part := range []string{
+		req.URL.String(),
+		req.Header.Get("Accept"),
+		req.Header.Get(githubAPIVersionHeader),
+		req.Header.Get("Authorization"),
+	} {
+		sum.Write([]byte(part))
+		sum.Write([]byte{0})
+	}
+	return filepath.Join(t.dir, hex.EncodeToString(sum.Sum(nil))+".json")
+}
+
+// load returns the stored response at path, or nil
+func (t etagTransport) load(path string) *cachedResponse {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		logger.Debug("ignoring unreadable HTTP cache entry", "path", path, "error", err)
+		return nil
+	}
+	return &cached
+}
+
+// store writes a response to path, replacing the previous one atomically
+func (t etagTransport) store(path string, cached *cachedResponse) {
+	data, err := json.Marshal(cached)
+	if err == nil {
+		err = os.MkdirAll(t.dir, 0o700)
+	}
+	var tmp *os.File
+	if err == nil {
+		tmp, err = os.CreateTemp(t.dir, ".entry-*")
+	}
+	if err == nil {
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		logger.Debug("could not store HTTP cache entry", "url", cached.URL, "error", err)
+	}
+}
+
+// RoundTrip implements http.RoundTripper. Only GET requests are cached; the
+// rate limit endpoint is always fresh and does not count against the quota.
+func (t etagTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || strings.HasSuffix(req.URL.Path, "/rate_limit") {
+		return t.base.RoundTrip(req)
+	}
+
+	path := t.cachePath(req)
+	cached := t.load(path)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		_ = resp.Body.Close()
+		// The rate limit headers of the 304 are current, the rest are those
+		// of the stored response
+		header := cached.Header.Clone()
+		for name, values := range resp.Header {
+			header[name] = values
+		}
+		unchangedResponses.Add(1)
+		logger.Debug("HTTP cache hit", "url", req.URL.String())
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+			ContentLength: int64(len(cached.Body)),
+			Request:       resp.Request,
+		}, nil
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") || resp.ContentLength > maxCachedResponseSize {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedResponseSize+1))
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxCachedResponseSize {
+		// Too large to keep; hand back the response with the part read
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.store(path, &cachedResponse{URL: req.URL.String(), ETag: etag, LastModified: lastModified, Header: resp.Header, Body: body})
+	return resp, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// requiresImmutable reports whether require_immutable lists an action path.
+// Owners are case insensitive, so paths and patterns are compared in lower
+// case.
+func (c *Config) requiresImmutable(repo string) bool {
+	if c == nil {
+		return false
+	}
+	patterns := make([]string, len(c.RequireImmutable))
+	for i, pattern := range c.RequireImmutable {
+		patterns[i] = strings.ToLower(pattern)
+	}
+	return matchesActionGlobs(patterns, strings.ToLower(repo))
+}
+
+// immutableSetting returns the configuration key that makes updates of repo
+// prefer immutable releases, or "" when they do not
+func (c *Config) immutableSetting(repo string) string {
+	switch {
+	case c.requiresImmutable(repo):
+		return "require_immutable"
+	case c != nil && c.PreferImmutable:
+		return "prefer_immutable"
+	}
+	return ""
+}
+
+// newestImmutable returns the newest immutable release an update of action
+// may move to: it must pass the tag filter and update strategy and not be
+// older than the current version. It returns nil when there is none.
+func newestImmutable(forge Forge, action ActionInfo, owner, repo, configKey string, prereleases bool, cfg *Config) *Release {
+	release, err := forge.LatestImmutableRelease(owner, repo, prereleases)
+	if err != nil {
+		logger.Debug("no immutable release", "repo", action.Repo, "error", err)
+		return nil
+	}
+	if filter := cfg.actionConfig(configKey).tagFilter; filter != nil && !filter.MatchString(release.Tag) {
+		return nil
+	}
+	if filter := cfg.strategyFilter(action); filter != nil && !filter.MatchString(release.Tag) {
+		return nil
+	}
+	if current := currentVersion(action); current != "" && compareVersionTags(release.Tag, current) < 0 {
+		return nil
+	}
+	return release
+}
+
+// pinnedImmutability reports whether the release of the version an action
+// is pinned to is immutable, or nil when it cannot be told. Tags without a
+// release are mutable.
+func pinnedImmutability(forge Forge, action ActionInfo) (*bool, error) {
+	immutable := false
+	if action.LatestSHA != "" && action.CurrentSHA == action.LatestSHA {
+		immutable = action.LatestImmutable
+		return &immutable, nil
+	}
+	tag := currentVersion(action)
+	if tag == "" {
+		return nil, nil
+	}
+	owner, repo, _ := splitActionPath(action.Repo)
+	release, err := forge.ReleaseAt(owner, repo, tag)
+	switch {
+	case errorKindOf(err) == ErrorKindNotFound:
+	case err != nil:
+		return nil, err
+	default:
+		immutable = release.Immutable
+	}
+	return &immutable, nil
+}
+
+// annotateImmutability stores whether the pinned version of every action is
+// an immutable release, or only of the actions under require_immutable
+// unless all is set, and prints them per repository. It returns the number
+// of required actions that are not pinned to an immutable release.
+func annotateImmutability(forge Forge, cfg *Config, actions WorkflowActions, all bool) int {
+	fmt.Fprintln(console, "\n🔒 Checking for immutable releases...")
+
+	type lookup struct {
+		immutable *bool
+		err       error
+	}
+	looked := make(map[string]lookup)
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker || action.Error != nil {
+				continue
+			}
+			action.RequiresImmutable = cfg.requiresImmutable(action.Repo)
+			if !all && !action.RequiresImmutable {
+				continue
+			}
+			owner, repo, _ := splitActionPath(action.Repo)
+			key := strings.ToLower(owner+"/"+repo) + "@" + action.CurrentSHA + "@" + currentVersion(*action)
+			found, ok := looked[key]
+			if !ok {
+				found.immutable, found.err = pinnedImmutability(forge, *action)
+				looked[key] = found
+			}
+			action.Immutable = found.immutable
+			if found.err != nil {
+				logger.Info("failed to look up release", "workflow", workflow, "repo", action.Repo, "error", found.err)
+			}
+		}
+	}
+
+	mutable := 0
+	reported := make(map[string]bool)
+	for _, action := range sortedByRepo(actions) {
+		owner, repo, _ := splitActionPath(action.Repo)
+		key := strings.ToLower(owner+"/"+repo) + "@" + action.CurrentSHA + "@" + currentVersion(action)
+		found, ok := looked[key]
+		if !ok {
+			continue
+		}
+		if action.RequiresImmutable && (found.immutable == nil || !*found.immutable) {
+			mutable++
+		}
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+		label := action.Repo + "@" + shortPin(action.CurrentRef)
+		if version := currentVersion(action); version != "" && action.Pinned() {
+			label += " (" + version + ")"
+		}
+		switch {
+		case found.err != nil:
+			fmt.Fprintf(console, "  %s %s: could not look up the release: %v\n", theme.Warning, label, found.err)
+		case found.immutable == nil:
+			fmt.Fprintf(console, "  %s %s: no version to look up a release for\n", theme.Skipped, label)
+		case *found.immutable:
+			fmt.Fprintf(console, "  %s %s: immutable release\n", theme.OK, label)
+			continue
+		case action.RequiresImmutable:
+			fmt.Fprintf(console, "  %s %s: mutable release %s\n", severityStatus(ruleMutableRelease), label, ruleTag(ruleMutableRelease))
+		default:
+			fmt.Fprintf(console, "  %s %s: mutable release\n", theme.Skipped, label)
+		}
+		if action.LatestImmutable && action.CurrentSHA != action.LatestSHA {
+			fmt.Fprintf(console, "     💡 %s is an immutable release; update the pin\n", action.LatestTag)
+		}
+	}
+	if mutable == 0 && cfg != nil && len(cfg.RequireImmutable) > 0 {
+		fmt.Fprintf(console, "%s All actions under require_immutable are pinned to immutable releases\n", theme.OK)
+	}
+	return mutable
+}
+
+// verifyImmutableReleases requires every action under require_immutable to
+// be pinned to an immutable release
+func verifyImmutableReleases(forge Forge, cfg *Config, actions WorkflowActions) error {
+	mutable := annotateImmutability(forge, cfg, actions, false)
+	if mutable > 0 {
+		fmt.Fprintln(console, "     💡 Pin the actions to immutable releases, or ask their maintainers to enable immutable releases")
+		return fmt.Errorf("%d action(s) under require_immutable are not pinned to immutable releases", mutable)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import "fmt"
+
+// impostorKey identifies a pinned commit of a repository
+type impostorKey struct {
+	repo string
+	sha  string
+}
+
+// commitReachable reports whether sha is reachable from a tag or the default
+// branch of owner/repo. GitHub serves the commits of every fork through the parent
+// repository, so a SHA that resolves may still come from a fork; it is only
+// genuine when the repository's own tags or default branch contain it. The
+// tag in the pin comment is tried before the default branch, since release
+// commits often live on release branches.
+func commitReachable(forge Forge, owner, repo, sha, tag string) (bool, error) {
+	tags, err := forge.TagsAt(owner, repo, sha)
+	if err != nil {
+		return false, err
+	}
+	if len(tags) > 0 {
+		return true, nil
+	}
+
+	// HEAD names the default branch
+	bases := []string{"HEAD"}
+	if tag != "" {
+		bases = append([]string{tag}, bases...)
+	}
+	for _, base := range bases {
+		comparison, err := forge.Compare(owner, repo, base, sha)
+		if base == tag && errorKindOf(err) == ErrorKindNotFound {
+			// The tag was deleted or never existed
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		// sha is behind base when base contains it
+		if comparison.Status == "behind" || comparison.Status == "identical" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// verifyReachableCommits requires every commit SHA pin to be reachable from
+// the action repository itself, rejecting impostor commits that only exist
+// in a fork. Commits that cannot be checked fail too.
+func verifyReachableCommits(forge Forge, actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n🕵️  Verifying pinned commits belong to their repositories...")
+
+	checked := make(map[impostorKey]error)
+	failed := 0
+	for _, action := range sortedActions(actions) {
+		if action.Docker || !action.Pinned() {
+			continue
+		}
+		owner, repo, _ := splitActionPath(action.Repo)
+		key := impostorKey{repo: owner + "/" + repo, sha: action.CurrentRef}
+
+		err, ok := checked[key]
+		if !ok {
+			var reachable bool
+			reachable, err = commitReachable(forge, owner, repo, action.CurrentRef, commentTag(action.OriginalLine, action.Repo))
+			if err == nil && !reachable {
+				err = fmt.Errorf("%s is not reachable from the tags or default branch of %s, it may be an impostor commit from a fork", shortPin(action.CurrentRef), key.repo)
+			}
+			checked[key] = err
+		}
+		if err != nil {
+			fmt.Fprintf(console, "  %s %s:%d %s: %v %s\n", severityStatus(ruleImpostor), action.WorkflowFile, action.Line, action.Repo, err, ruleTag(ruleImpostor))
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d pinned commit(s) could not be verified as part of their repositories", failed)
+	}
+	fmt.Fprintf(console, "%s All pinned commits are reachable from their repositories\n", theme.OK)
+	return nil
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// scheduledWorkflowFile is the companion workflow written by init
+	scheduledWorkflowFile = workflowDir + "/github-ci-hash.yml"
+
+	// Pins used in the generated workflow; keep in sync with this repository's workflows
+	checkoutActionPin = "actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683 # v4.2.2"
+	setupGoActionPin  = "actions/setup-go@0a12ed9d6a96ab950c8f026ed9f722fe0da7ef32 # v5.0.2"
+
+	// Pins of the actions the companion workflow adds for SARIF upload and pull requests
+	uploadSARIFActionPin       = "github/codeql-action/upload-sarif@662472033e021d55d94146f66f6058822b0b39fd # v3.27.0"
+	createPullRequestActionPin = "peter-evans/create-pull-request@5e914681df9dc83aa4e4905692ca88beb2f9e91f # v7.0.5"
+
+	// Defaults of the companion workflow inputs
+	defaultWorkflowSchedule    = "0 0 * * 1"
+	defaultWorkflowBranch      = "github-ci-hash/update-actions"
+	defaultWorkflowTokenSecret = "GITHUB_CI_HASH_TOKEN"
+
+	// minGroupSuggestionSize is how many distinct actions an owner needs before a group is suggested
+	minGroupSuggestionSize = 2
+)
+
+var (
+	// pinCommentRegex extracts the comment following a uses: reference
+	pinCommentRegex = regexp.MustCompile(`#\s*(\S+)`)
+
+	// secretNameRegex matches valid GitHub Actions secret names
+	secretNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// pinConventions summarizes how the existing uses: references are written
+type pinConventions struct {
+	total   int
+	pinned  int
+	styles  map[string]int
+	byOwner map[string]map[string]bool
+}
+
+// detectPinConventions inspects scanned actions for pinning and comment styles
+func detectPinConventions(actions WorkflowActions) pinConventions {
+	conventions := pinConventions{
+		styles:  make(map[string]int),
+		byOwner: make(map[string]map[string]bool),
+	}
+
+	for _, actionList := range actions {
+		for _, action := range actionList {
+			conventions.total++
+
+			// Container images have no owner to group by
+			if !action.Docker {
+				owner, _, _ := strings.Cut(action.Repo, "/")
+				if conventions.byOwner[owner] == nil {
+					conventions.byOwner[owner] = make(map[string]bool)
+				}
+				conventions.byOwner[owner][actionRepoRoot(action.Repo)] = true
+			}
+
+			if !action.Pinned() {
+				continue
+			}
+			conventions.pinned++
+
+			style := "no comment"
+			if matches := pinCommentRegex.FindStringSubmatch(action.OriginalLine); matches != nil {
+				comment := matches[1]
+				switch {
+				case strings.HasPrefix(comment, "pin@"):
+					style = "# pin@vX.Y.Z"
+				case strings.HasPrefix(comment, "v"):
+					style = "# vX.Y.Z"
+				case comment[0] >= '0' && comment[0] <= '9':
+					style = "# X.Y.Z"
+				default:
+					style = "other comment"
+				}
+			}
+			conventions.styles[style]++
+		}
+	}
+
+	return conventions
+}
+
+// dominantStyle returns the most common comment style among pinned actions
+func (c pinConventions) dominantStyle() string {
+	best, bestCount := "", 0
+	styles := make([]string, 0, len(c.styles))
+	for style := range c.styles {
+		styles = append(styles, style)
+	}
+	sort.Strings(styles)
+	for _, style := range styles {
+		if c.styles[style] > bestCount {
+			best, bestCount = style, c.styles[style]
+		}
+	}
+	return best
+}
+
+// pinCommentSetting returns the pin_comment setting matching the dominant
+// comment style, or nil when the default fits
+func (c pinConventions) pinCommentSetting() *string {
+	var setting string
+	switch c.dominantStyle() {
+	case "# pin@vX.Y.Z":
+		setting = "pin@" + defaultPinComment
+	case "no comment":
+		setting = ""
+	default:
+		return nil
+	}
+	return &setting
+}
+
+// suggestedGroups proposes one group per owner that has several distinct actions
+func (c pinConventions) suggestedGroups() []GroupConfig {
+	owners := make([]string, 0, len(c.byOwner))
+	for owner, repos := range c.byOwner {
+		// CodeQL is already grouped automatically and actions/* are independent
+		if owner == "actions" || len(repos) < minGroupSuggestionSize {
+			continue
+		}
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	groups := make([]GroupConfig, 0, len(owners))
+	for _, owner := range owners {
+		groups = append(groups, GroupConfig{Name: owner, Actions: []string{owner + "/*"}})
+	}
+	return groups
+}
+
+// promptForInput asks for a line of text, returning def when the answer is empty
+func promptForInput(message, def string) string {
+	if def != "" {
+		fmt.Fprintf(console, "%s [%s]: ", message, def)
+	} else {
+		fmt.Fprintf(console, "%s: ", message)
+	}
+
+	response, err := stdinReader.ReadString('\n')
+	if err != nil && response == "" {
+		return def
+	}
+	if response = strings.TrimSpace(response); response == "" {
+		return def
+	}
+	return response
+}
+
+// splitList splits a comma separated answer into trimmed, non-empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// renderConfig serializes a configuration with a descriptive header
+func renderConfig(cfg *Config, header []string) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, line := range header {
+		buf.WriteString("# " + line + "\n")
+	}
+	if len(header) > 0 {
+		buf.WriteString("\n")
+	}
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	// An empty configuration encodes as "{}"; keep the file readable instead
+	return bytes.ReplaceAll(buf.Bytes(), []byte("{}\n"), nil), nil
+}
+
+// companionWorkflowTemplate is the companion workflow written by init. It uses
+// [[ ]] delimiters so that GitHub expressions can be written verbatim.
+const companionWorkflowTemplate = `name: GitHub CI Hash
+
+on:
+  schedule:
+    - cron: [[ quote .Schedule ]]
+  workflow_dispatch:
+
+permissions:
+  contents: read
+
+jobs:
+  check-actions:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: read
+      security-events: write
+    steps:
+      - name: Checkout code
+        uses: [[ .Checkout ]]
+
+      - name: Set up Go
+        uses: [[ .SetupGo ]]
+        with:
+          go-version: '1.23'
+
+      - name: Install github-ci-hash
+        run: go install github.com/greysquirr3l/github-ci-hash@[[ .Version ]]
+
+      - name: Check for action updates
+        run: github-ci-hash check --sarif github-ci-hash.sarif[[ .ConfigArgs ]]
+        env:
+          GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }}
+
+      - name: Upload SARIF results
+        if: always()
+        uses: [[ .UploadSARIF ]]
+        with:
+          sarif_file: github-ci-hash.sarif
+          category: github-ci-hash
+
+  update-actions:
+    needs: check-actions
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+      pull-requests: write
+    steps:
+      - name: Checkout code
+        uses: [[ .Checkout ]]
+
+      - name: Set up Go
+        uses: [[ .SetupGo ]]
+        with:
+          go-version: '1.23'
+
+      - name: Install github-ci-hash
+        run: go install github.com/greysquirr3l/github-ci-hash@[[ .Version ]]
+
+      - name: Pin actions to the latest releases
+        run: github-ci-hash update --yes --allow-breaking --pr-body "$RUNNER_TEMP/github-ci-hash-pr.md"[[ .ConfigArgs ]]
+        env:
+          GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }}
+
+      # Pushing changes to workflow files needs a token with the workflow scope
+      - name: Open a pull request
+        uses: [[ .CreatePullRequest ]]
+        with:
+          token: ${{ secrets.[[ .TokenSecret ]] }}
+          branch: [[ quote .Branch ]]
+          title: 'Update pinned GitHub Actions'
+          commit-message: 'Update pinned GitHub Actions'
+          body-path: ${{ runner.temp }}/github-ci-hash-pr.md
+          add-paths: |
+            .github/workflows/*.yml
+            .github/workflows/*.yaml
+          delete-branch: true
+[[- if .Labels ]]
+          labels: [[ quote .Labels ]]
+[[- end ]]
+[[- if .Reviewers ]]
+          reviewers: [[ quote .Reviewers ]]
+[[- end ]]
+`
+
+// companionWorkflowData holds the values substituted into the companion workflow
+type companionWorkflowData struct {
+	Schedule          string
+	Branch            string
+	Labels            string
+	Reviewers         string
+	TokenSecret       string
+	Version           string
+	ConfigArgs        string
+	Checkout          string
+	SetupGo           string
+	UploadSARIF       string
+	CreatePullRequest string
+}
+
+// yamlQuote returns s as a single-quoted YAML scalar
+func yamlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// scheduledWorkflow returns the content of the companion workflow that runs
+// weekly checks with the installed tool, uploads SARIF results and opens a
+// pull request with the updates. Inputs come from the workflow section of the
+// configuration; configPath is passed to the tool when it is not the default.
+func scheduledWorkflow(cfg *Config, configPath string) (string, error) {
+	version := "latest"
+	if Version != "dev" {
+		version = "v" + strings.TrimPrefix(Version, "v")
+	}
+
+	data := companionWorkflowData{
+		Schedule:          defaultWorkflowSchedule,
+		Branch:            defaultWorkflowBranch,
+		TokenSecret:       defaultWorkflowTokenSecret,
+		Version:           version,
+		Checkout:          checkoutActionPin,
+		SetupGo:           setupGoActionPin,
+		UploadSARIF:       uploadSARIFActionPin,
+		CreatePullRequest: createPullRequestActionPin,
+	}
+	if cfg != nil {
+		if cfg.Workflow.Schedule != "" {
+			data.Schedule = cfg.Workflow.Schedule
+		}
+		if cfg.Workflow.Branch != "" {
+			data.Branch = cfg.Workflow.Branch
+		}
+		if cfg.Workflow.TokenSecret != "" {
+			data.TokenSecret = cfg.Workflow.TokenSecret
+		}
+		data.Labels = strings.Join(cfg.Workflow.Labels, ",")
+		data.Reviewers = strings.Join(cfg.Workflow.Reviewers, ",")
+	}
+	if !secretNameRegex.MatchString(data.TokenSecret) {
+		return "", fmt.Errorf("invalid secret name %q in workflow.token_secret", data.TokenSecret)
+	}
+	if configPath != "" && configPath != defaultConfigFile {
+		data.ConfigArgs = " --config " + shellQuote(filepath.ToSlash(configPath))
+	}
+
+	tmpl, err := template.New("workflow").Delims("[[", "]]").Funcs(template.FuncMap{"quote": yamlQuote}).Parse(companionWorkflowTemplate)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// writeNewFile writes content to path, asking before replacing an existing file
+func writeNewFile(path string, content []byte, assumeYes bool) (bool, error) {
+	if _, err := os.Stat(path); err == nil {
+		if assumeYes || !promptForConfirmation(fmt.Sprintf("%s already exists. Overwrite?", path)) {
+			fmt.Fprintf(console, "  %s Kept existing %s\n", theme.Skipped, path)
+			return false, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return false, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// setupInit registers the flags of the init wizard and returns its runner
+func setupInit(fs *flag.FlagSet) func(args []string) {
+	assumeYes := fs.Bool("yes", false, "Accept all suggestions without prompting (never overwrites existing files)")
+
+	return func(_ []string) {
+		fmt.Fprintln(console, "🧙 Setting up github-ci-hash for this repository")
+
+		actions, _, err := scanWorkflows(ScanOptions{})
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		conventions := detectPinConventions(actions)
+		fmt.Fprintf(console, "\n🔍 Found %d action reference(s) in %d workflow file(s)\n", conventions.total, len(actions))
+		fmt.Fprintf(console, "🔒 %d of %d pinned to a commit SHA\n", conventions.pinned, conventions.total)
+
+		header := []string{"Configuration for github-ci-hash, generated by 'github-ci-hash init'"}
+		if style := conventions.dominantStyle(); style != "" {
+			fmt.Fprintf(console, "💬 Pin comment convention: %s (%d of %d pins)\n", style, conventions.styles[style], conventions.pinned)
+			header = append(header, fmt.Sprintf("Detected pin comment convention: %s (%d of %d pins)", style, conventions.styles[style], conventions.pinned))
+		}
+
+		cfg := &Config{PinComment: conventions.pinCommentSetting()}
+		if !*assumeYes {
+			fmt.Fprintln(console)
+			cfg.Exclude = splitList(promptForInput("Workflow files to exclude (comma separated globs)", ""))
+			if err := validateGlobs(cfg.Exclude); err != nil {
+				fmt.Fprintf(console, "Error: invalid exclude pattern: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		for _, group := range conventions.suggestedGroups() {
+			if *assumeYes || promptForConfirmation(fmt.Sprintf("Update all %s actions together as one group?", group.Actions[0])) {
+				cfg.Groups = append(cfg.Groups, group)
+			}
+		}
+
+		content, err := renderConfig(cfg, header)
+		if err != nil {
+			fmt.Fprintf(console, "Error rendering config: %v\n", err)
+			os.Exit(1)
+		}
+		written, err := writeNewFile(defaultConfigFile, content, *assumeYes)
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if written {
+			fmt.Fprintf(console, "%s Wrote %s\n", theme.OK, defaultConfigFile)
+		}
+
+		if *assumeYes || promptForConfirmation("\nInstall git hooks?") {
+			if err := installPreCommitHooks(cfg); err != nil {
+				fmt.Fprintf(console, "%s Could not install hooks: %v\n", theme.Warning, err)
+			}
+		}
+
+		if *assumeYes || promptForConfirmation("\nAdd a scheduled workflow that checks actions weekly?") {
+			content, err := scheduledWorkflow(cfg, defaultConfigFile)
+			if err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			written, err := writeNewFile(scheduledWorkflowFile, []byte(content), *assumeYes)
+			if err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if written {
+				fmt.Fprintf(console, "%s Wrote %s\n", theme.OK, scheduledWorkflowFile)
+			}
+		}
+
+		fmt.Fprintln(console, "\n🎉 Setup complete! Run 'github-ci-hash check' to see available updates.")
+	}
+}
+
+// setupInitWorkflow registers the flags of the init workflow command and returns its runner
+func setupInitWorkflow(fs *flag.FlagSet) func(args []string) {
+	configPath := fs.String("config", defaultConfigFile, "Path to the configuration `file`")
+	force := fs.Bool("force", false, "Replace an existing workflow without asking")
+	toStdout := fs.Bool("stdout", false, "Print the workflow instead of writing it")
+
+	return func(_ []string) {
+		cfg, err := loadConfig(*configPath, *configPath != defaultConfigFile)
+		if err != nil {
+			fmt.Fprintf(console, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		content, err := scheduledWorkflow(cfg, *configPath)
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *toStdout {
+			fmt.Fprint(os.Stdout, content)
+			return
+		}
+
+		if *force {
+			if err := os.MkdirAll(filepath.Dir(scheduledWorkflowFile), 0750); err == nil {
+				err = os.WriteFile(scheduledWorkflowFile, []byte(content), 0600)
+			}
+			if err != nil {
+				fmt.Fprintf(console, "Error: failed to write %s: %v\n", scheduledWorkflowFile, err)
+				os.Exit(1)
+			}
+		} else {
+			written, err := writeNewFile(scheduledWorkflowFile, []byte(content), false)
+			if err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if !written {
+				return
+			}
+		}
+
+		fmt.Fprintf(console, "%s Wrote %s\n", theme.OK, scheduledWorkflowFile)
+		tokenSecret := cfg.Workflow.TokenSecret
+		if tokenSecret == "" {
+			tokenSecret = defaultWorkflowTokenSecret
+		}
+		fmt.Fprintf(console, "   Add a %s secret with a token that has the workflow scope so that update pull requests can be opened.\n", tokenSecret)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envReferenceRegex matches ${NAME} and ${NAME:-default} references. A
+// leading $$ escapes the reference so that it is kept literally.
+var envReferenceRegex = regexp.MustCompile(`\$(\$)?\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolateConfigDocument replaces environment variable references in the
+// string values of a parsed configuration. Every reference to an unset
+// variable without a default is reported with its location.
+func interpolateConfigDocument(doc *yaml.Node) error {
+	var errs []error
+	for _, node := range doc.Content {
+		interpolateNode(node, "", &errs)
+	}
+	return errors.Join(errs...)
+}
+
+// interpolateNode walks a node, expanding references in scalar values
+func interpolateNode(node *yaml.Node, path string, errs *[]error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := node.Content[i].Value
+			if path != "" {
+				childPath = path + "." + childPath
+			}
+			interpolateNode(node.Content[i+1], childPath, errs)
+		}
+
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			interpolateNode(item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+
+	case yaml.ScalarNode:
+		if node.Tag != "!!str" {
+			return
+		}
+		node.Value = envReferenceRegex.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			match := envReferenceRegex.FindStringSubmatch(ref)
+			if match[1] != "" {
+				return ref[1:]
+			}
+
+			name, hasDefault, def := match[2], match[3] != "", match[4]
+			// As in the shell, an empty value counts as set but falls back to a default
+			if value, ok := os.LookupEnv(name); ok && (value != "" || !hasDefault) {
+				return value
+			}
+			if hasDefault {
+				return def
+			}
+			*errs = append(*errs, &SchemaError{
+				Path:    path,
+				Line:    node.Line,
+				Message: fmt.Sprintf("environment variable %s is not set (use ${%s:-default} to provide a fallback)", name, name),
+			})
+			return ref
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// workflowTemplatesDir holds organization workflow templates
+	workflowTemplatesDir = ".github/workflow-templates"
+
+	reasonSubdirectory = "in a subdirectory; GitHub only runs workflows directly in " + workflowDir
+	reasonTemplate     = "workflow template; not run directly"
+	reasonFiltered     = "excluded by include/exclude filters"
+	reasonNoActions    = "no uses: references found"
+	reasonLimits       = "exceeds limits: "
+)
+
+// SkippedFile records a YAML file that was found but contributed no actions,
+// so that nothing is silently ignored
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// isYAMLFile reports whether a file name has a YAML extension
+func isYAMLFile(name string) bool {
+	return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")
+}
+
+// findOutOfScopeFiles lists YAML files near the workflow directory that are
+// not loaded by GitHub as workflows: files in subdirectories of the workflow
+// directory and workflow templates
+func findOutOfScopeFiles() []SkippedFile {
+	var skipped []SkippedFile
+
+	collect := func(root, reason string, skipRoot bool) {
+		// Missing directories simply contribute nothing
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() || !isYAMLFile(d.Name()) {
+				return nil
+			}
+			if skipRoot && filepath.Dir(path) == filepath.Clean(root) {
+				return nil
+			}
+			skipped = append(skipped, SkippedFile{Path: path, Reason: reason})
+			return nil
+		})
+	}
+
+	collect(workflowDir, reasonSubdirectory, true)
+	if _, err := os.Stat(workflowTemplatesDir); err == nil {
+		collect(workflowTemplatesDir, reasonTemplate, false)
+	}
+
+	return skipped
+}
+
+// sortSkipped orders the inventory by path
+func sortSkipped(skipped []SkippedFile) {
+	sort.Slice(skipped, func(i, j int) bool {
+		return skipped[i].Path < skipped[j].Path
+	})
+}
+
+// printSkipped prints the inventory of files that contributed no actions
+func printSkipped(skipped []SkippedFile) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	fmt.Fprintf(console, "\n📋 Skipped files (%d):\n", len(skipped))
+	for _, file := range skipped {
+		fmt.Fprintf(console, "  %s: %s\n", file.Path, file.Reason)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// licenseNone stands for repositories without a license file
+	licenseNone = "NONE"
+
+	// licenseUnrecognized is the SPDX identifier GitHub reports for license
+	// files it does not recognize
+	licenseUnrecognized = "NOASSERTION"
+
+	// Output formats of the licenses command
+	licenseFormatTable = "table"
+	licenseFormatJSON  = "json"
+	licenseFormatCSV   = "csv"
+)
+
+// LicenseEntry is the license of an action repository
+type LicenseEntry struct {
+	Repo string `json:"repo"`
+	// License is the SPDX identifier, NONE without a license and
+	// NOASSERTION when GitHub does not recognize it
+	License string `json:"license,omitempty"`
+	Name    string `json:"name,omitempty"`
+	URL     string `json:"url,omitempty"`
+	// Workflows lists the files using an action of the repository
+	Workflows []string `json:"workflows"`
+	// Denied is set for licenses listed under deny_licenses
+	Denied bool   `json:"denied,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LicenseReport is the outcome of the licenses command
+type LicenseReport struct {
+	Run          RunMetadata    `json:"run"`
+	Repositories []LicenseEntry `json:"repositories"`
+	Denied       int            `json:"denied"`
+}
+
+// lookupLicense returns the license entry of owner/repo. Repositories
+// without a license file get NONE.
+func lookupLicense(gc *GitHubClient, owner, repo string) LicenseEntry {
+	entry := LicenseEntry{Repo: owner + "/" + repo}
+	license, err := gc.api.License(gc.ctx, owner, repo)
+	switch {
+	case errorKindOf(err) == ErrorKindNotFound:
+		entry.License = licenseNone
+	case err != nil:
+		entry.Error = err.Error()
+	default:
+		entry.License, entry.Name, entry.URL = license.SPDXID, license.Name, license.URL
+	}
+	return entry
+}
+
+// licenseReport looks up the license of every action repository once and
+// marks the licenses listed in deny, which are compared case-insensitively.
+// Container actions have no repository and are left out.
+func licenseReport(gc *GitHubClient, actions WorkflowActions, deny []string) LicenseReport {
+	report := LicenseReport{Repositories: []LicenseEntry{}}
+	index := make(map[string]int)
+	for _, action := range sortedByRepo(actions) {
+		if action.Docker {
+			continue
+		}
+		owner, repo, _ := splitActionPath(action.Repo)
+		key := strings.ToLower(owner + "/" + repo)
+		i, ok := index[key]
+		if !ok {
+			entry := lookupLicense(gc, owner, repo)
+			entry.Denied = entry.License != "" && slices.ContainsFunc(deny, func(id string) bool {
+				return strings.EqualFold(id, entry.License)
+			})
+			if entry.Denied {
+				report.Denied++
+			}
+			i = len(report.Repositories)
+			index[key] = i
+			report.Repositories = append(report.Repositories, entry)
+		}
+		if entry := &report.Repositories[i]; !slices.Contains(entry.Workflows, action.WorkflowFile) {
+			entry.Workflows = append(entry.Workflows, action.WorkflowFile)
+		}
+	}
+	for i := range report.Repositories {
+		slices.Sort(report.Repositories[i].Workflows)
+	}
+	slices.SortFunc(report.Repositories, func(a, b LicenseEntry) int {
+		return strings.Compare(strings.ToLower(a.Repo), strings.ToLower(b.Repo))
+	})
+	return report
+}
+
+// printLicenseTable prints the licenses aligned by repository
+func printLicenseTable(w io.Writer, report LicenseReport) {
+	width := 0
+	for _, entry := range report.Repositories {
+		width = max(width, len(entry.Repo))
+	}
+	fmt.Fprintf(w, "\n📜 Licenses of %d action repositories:\n", len(report.Repositories))
+	for _, entry := range report.Repositories {
+		switch {
+		case entry.Error != "":
+			fmt.Fprintf(w, "  %-*s %s could not look up the license: %s\n", width, entry.Repo, theme.Warning, entry.Error)
+		case entry.Denied:
+			fmt.Fprintf(w, "  %-*s %s %s is denied\n", width, entry.Repo, theme.Error, entry.License)
+		case entry.License == licenseNone:
+			fmt.Fprintf(w, "  %-*s %s no license\n", width, entry.Repo, theme.Warning)
+		case entry.License == licenseUnrecognized:
+			fmt.Fprintf(w, "  %-*s %s license not recognized, review %s\n", width, entry.Repo, theme.Warning, entry.URL)
+		default:
+			fmt.Fprintf(w, "  %-*s %s %s\n", width, entry.Repo, theme.OK, entry.License)
+		}
+	}
+}
+
+// writeLicenseCSV writes the licenses as CSV with a header row; the
+// workflows of a repository are separated by spaces
+func writeLicenseCSV(w io.Writer, report LicenseReport) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"repo", "license", "name", "url", "workflows", "denied", "error"})
+	for _, entry := range report.Repositories {
+		_ = writer.Write([]string{entry.Repo, entry.License, entry.Name, entry.URL,
+			strings.Join(entry.Workflows, " "), fmt.Sprint(entry.Denied), entry.Error})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// setupLicenses registers the flags of the licenses command and returns its
+// runner
+func setupLicenses(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	format := fs.String("format", licenseFormatTable, "Output `format`: table, json or csv")
+	var deny stringSliceFlag
+	fs.Var(&deny, "deny", "Fail for repositories under the license with this SPDX `id`, in addition to deny_licenses (repeatable)")
+
+	return func(args []string) {
+		switch *format {
+		case licenseFormatTable:
+		case licenseFormatJSON, licenseFormatCSV:
+			console = os.Stderr
+		default:
+			fmt.Fprintf(console, "Error: --format must be table, json or csv, got %q\n", *format)
+			os.Exit(2)
+		}
+
+		cfg, scanOpts := mustLoadScanConfig(opts)
+		gc := NewGitHubClient()
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintln(console, "📜 Looking up the licenses of action repositories...")
+		report := licenseReport(gc, actions, append(slices.Clone(cfg.DenyLicenses), deny...))
+		report.Run = runMetadata(cfg)
+
+		switch *format {
+		case licenseFormatJSON:
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(report)
+		case licenseFormatCSV:
+			err = writeLicenseCSV(os.Stdout, report)
+		default:
+			printLicenseTable(console, report)
+		}
+		if err != nil {
+			fmt.Fprintf(console, "Error writing the license report: %v\n", err)
+			os.Exit(1)
+		}
+
+		if report.Denied > 0 {
+			fmt.Fprintf(console, "\n%s %d action repositories use denied licenses\n", theme.Error, report.Denied)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultMaxFileSize is the largest workflow file parsed, in bytes
+	defaultMaxFileSize = 1 << 20
+
+	// defaultMaxLineLength is the longest line accepted in a workflow, in characters
+	defaultMaxLineLength = 16384
+
+	// defaultMaxActions is the largest number of uses: references accepted per file
+	defaultMaxActions = 500
+)
+
+// parseLimits bounds every parsed workflow. It is set from the limits
+// section of the configuration before scanning.
+var parseLimits LimitsConfig
+
+// effective returns the limits with defaults filled in
+func (l LimitsConfig) effective() LimitsConfig {
+	if l.MaxFileSize <= 0 {
+		l.MaxFileSize = defaultMaxFileSize
+	}
+	if l.MaxLineLength <= 0 {
+		l.MaxLineLength = defaultMaxLineLength
+	}
+	if l.MaxActions <= 0 {
+		l.MaxActions = defaultMaxActions
+	}
+	return l
+}
+
+// LimitError reports a workflow that exceeds one of the parse limits
+type LimitError struct {
+	File    string
+	Limit   string
+	Message string
+}
+
+// Error implements the error interface
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s", e.File, e.Reason())
+}
+
+// Reason describes the exceeded limit without the file name
+func (e *LimitError) Reason() string {
+	return fmt.Sprintf("%s (limits.%s)", e.Message, e.Limit)
+}
+
+// exceededLimits returns the skipped files that exceeded a parse limit. Their
+// actions are unknown, so verification cannot pass while they exist.
+func exceededLimits(skipped []SkippedFile) []SkippedFile {
+	var exceeded []SkippedFile
+	for _, file := range skipped {
+		if strings.HasPrefix(file.Reason, reasonLimits) {
+			exceeded = append(exceeded, file)
+		}
+	}
+	return exceeded
+}
+
+// checkFileSize rejects files larger than the size limit before they are read
+func checkFileSize(filename string) error {
+	info, err := os.Stat(filepath.Clean(filename))
+	if err != nil {
+		return nil
+	}
+	return checkSize(filename, info.Size())
+}
+
+// checkSize rejects content of size bytes when it exceeds the size limit
+func checkSize(filename string, size int64) error {
+	if limit := parseLimits.effective().MaxFileSize; size > limit {
+		return &LimitError{
+			File:    filename,
+			Limit:   "max_file_size",
+			Message: fmt.Sprintf("file is %d bytes, above the limit of %d", size, limit),
+		}
+	}
+	return nil
+}
+
+// checkContentLimits rejects content that is too large or has overly long lines
+func checkContentLimits(filename, content string) error {
+	if err := checkSize(filename, int64(len(content))); err != nil {
+		return err
+	}
+
+	limit := parseLimits.effective().MaxLineLength
+	for i, line := range strings.Split(content, "\n") {
+		// Byte length bounds the character count, so most lines skip the count
+		if len(line) <= limit {
+			continue
+		}
+		if length := utf8.RuneCountInString(line); length > limit {
+			return &LimitError{
+				File:    filename,
+				Limit:   "max_line_length",
+				Message: fmt.Sprintf("line %d is %d characters long, above the limit of %d", i+1, length, limit),
+			}
+		}
+	}
+	return nil
+}
+
+// checkActionCount rejects files holding more uses: references than allowed
+func checkActionCount(filename string, count int) error {
+	if limit := parseLimits.effective().MaxActions; count > limit {
+		return &LimitError{
+			File:    filename,
+			Limit:   "max_actions",
+			Message: fmt.Sprintf("file has %d uses: references, above the limit of %d", count, limit),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// privilegedTriggers run workflows for pull requests from forks with the
+// secrets and a write token of the base repository
+var privilegedTriggers = []string{"pull_request_target", "workflow_run"}
+
+var (
+	// expressionRegex matches a ${{ }} expression
+	expressionRegex = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+
+	// untrustedContextRegex matches the event fields anyone opening an issue,
+	// pull request or comment controls, after GitHub Security Lab's list
+	untrustedContextRegex = regexp.MustCompile(`(?i)\bgithub\.(head_ref\b|event\.(` +
+		`issue\.(title|body)|pull_request\.(title|body|head\.(ref|label|repo\.default_branch))|` +
+		`comment\.body|review\.body|review_comment\.body|discussion\.(title|body)|` +
+		`pages\b.*\.page_name|commits\b.*\.(message|author\.(email|name))|` +
+		`head_commit\.(message|author\.(email|name))|` +
+		`workflow_run\.(head_branch|head_commit\.(message|author\.(email|name))))\b)`)
+
+	// headRefRegex matches a checkout ref naming the head of a pull request
+	headRefRegex = regexp.MustCompile(`(?i)github\.event\.pull_request\.head\.(sha|ref)|github\.head_ref|` +
+		`github\.event\.workflow_run\.head_(sha|branch)|refs/pull/`)
+
+	// secretRegex matches a secret reference in an expression
+	secretRegex = regexp.MustCompile(`\bsecrets\.([A-Za-z_][A-Za-z0-9_-]*)|\bsecrets\[\s*['"]([^'"]+)['"]\s*\]`)
+)
+
+// LintReport is the JSON output of the lint command
+type LintReport struct {
+	Run      RunMetadata `json:"run"`
+	Files    []string    `json:"files"`
+	Findings []Finding   `json:"findings"`
+}
+
+// scalarLine returns the line of offset in the value of a scalar node.
+// Block scalars start on the line after their indicator.
+func scalarLine(node *yaml.Node, offset int) int {
+	line := node.Line + strings.Count(node.Value[:offset], "\n")
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		line++
+	}
+	return line
+}
+
+// walkScalars calls fn for every scalar below node, skipping mapping keys
+func walkScalars(node *yaml.Node, fn func(*yaml.Node)) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		fn(node)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkScalars(node.Content[i+1], fn)
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkScalars(child, fn)
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			walkScalars(node.Alias, fn)
+		}
+	}
+}
+
+// scalarValue returns the value of key in a mapping when it is a scalar
+func scalarValue(node *yaml.Node, key string) (*yaml.Node, string) {
+	_, value := mappingEntry(node, key)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return nil, ""
+	}
+	return value, value.Value
+}
+
+// isStepAction reports whether a uses: value runs the action owner/repo
+func isStepAction(uses, repo string) bool {
+	name, _, _ := strings.Cut(uses, "@")
+	return strings.EqualFold(name, repo)
+}
+
+// lintInjections reports untrusted event fields interpolated into a script
+func lintInjections(workflow string, script *yaml.Node, kind string) []Finding {
+	var findings []Finding
+	for _, match := range expressionRegex.FindAllStringSubmatchIndex(script.Value, -1) {
+		expression := script.Value[match[2]:match[3]]
+		field := untrustedContextRegex.FindString(expression)
+		if field == "" {
+			continue
+		}
+		findings = append(findings, newFinding(ruleInjection, workflow, scalarLine(script, match[0]),
+			fmt.Sprintf("%s is interpolated into a %s script; pass it through env: and quote the variable", field, kind)))
+	}
+	return findings
+}
+
+// lintSecrets reports the secrets a job of a privileged workflow reads,
+// except the GITHUB_TOKEN whose permissions the workflow declares
+func lintSecrets(workflow, job string, node *yaml.Node, triggers []string) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool)
+	if _, inherit := scalarValue(node, "secrets"); inherit == "inherit" {
+		keyNode, _ := mappingEntry(node, "secrets")
+		findings = append(findings, newFinding(ruleForkSecrets, workflow, keyNode.Line,
+			fmt.Sprintf("job %s passes every secret to a reusable workflow in a workflow triggered by %s", job, strings.Join(triggers, ", "))))
+	}
+	walkScalars(node, func(scalar *yaml.Node) {
+		for _, match := range secretRegex.FindAllStringSubmatchIndex(scalar.Value, -1) {
+			name := ""
+			for group := 2; group+1 < len(match); group += 2 {
+				if match[group] >= 0 {
+					name = scalar.Value[match[group]:match[group+1]]
+				}
+			}
+			if strings.EqualFold(name, "GITHUB_TOKEN") || seen[name] {
+				continue
+			}
+			seen[name] = true
+			findings = append(findings, newFinding(ruleForkSecrets, workflow, scalarLine(scalar, match[0]),
+				fmt.Sprintf("secret %s is exposed to job %s in a workflow triggered by %s", name, job, strings.Join(triggers, ", "))))
+		}
+	})
+	return findings
+}
+
+// lintJob reports the risky patterns of one job
+func lintJob(workflow, job string, node *yaml.Node, triggers []string) []Finding {
+	var findings []Finding
+	if len(triggers) > 0 {
+		findings = append(findings, lintSecrets(workflow, job, node, triggers)...)
+	}
+
+	_, steps := mappingEntry(node, "steps")
+	if steps == nil || steps.Kind != yaml.SequenceNode {
+		return findings
+	}
+	for _, step := range steps.Content {
+		if run, _ := scalarValue(step, "run"); run != nil {
+			findings = append(findings, lintInjections(workflow, run, "run:")...)
+		}
+		usesNode, uses := scalarValue(step, "uses")
+		if usesNode == nil {
+			continue
+		}
+		_, with := mappingEntry(step, "with")
+		if isStepAction(uses, "actions/github-script") {
+			if script, _ := scalarValue(with, "script"); script != nil {
+				findings = append(findings, lintInjections(workflow, script, "github-script")...)
+			}
+		}
+		if !isStepAction(uses, "actions/checkout") {
+			continue
+		}
+
+		if _, persist := scalarValue(with, "persist-credentials"); persist != "false" {
+			findings = append(findings, newFinding(rulePersistCreds, workflow, usesNode.Line,
+				"actions/checkout leaves the token in .git/config; set persist-credentials: false unless later steps push"))
+		}
+		if len(triggers) == 0 {
+			continue
+		}
+		for _, key := range []string{"ref", "repository"} {
+			if value, ref := scalarValue(with, key); value != nil && headRefRegex.MatchString(ref) {
+				findings = append(findings, newFinding(ruleCheckoutHead, workflow, value.Line,
+					fmt.Sprintf("job %s checks out the pull request head in a workflow triggered by %s, running its code with the secrets of the repository", job, strings.Join(triggers, ", "))))
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// lintWorkflow reports the risky patterns of a workflow file: pull request
+// heads checked out by privileged triggers, persisted checkout credentials,
+// untrusted event fields interpolated into scripts and secrets exposed to
+// pull requests from forks
+func lintWorkflow(workflow string) ([]Finding, error) {
+	content, err := os.ReadFile(filepath.Clean(workflow))
+	if err != nil {
+		return nil, err
+	}
+	body, _ := splitBOM(string(content))
+	docs, err := parseDocuments(body)
+	if err != nil || len(docs) == 0 || len(docs[0].Content) == 0 {
+		return nil, err
+	}
+
+	var triggers []string
+	for _, event := range triggerEvents(docs[0]) {
+		if slices.Contains(privilegedTriggers, event) {
+			triggers = append(triggers, event)
+		}
+	}
+
+	var findings []Finding
+	_, jobs := mappingEntry(docs[0].Content[0], "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		findings = append(findings, lintJob(workflow, jobs.Content[i].Value, jobs.Content[i+1], triggers)...)
+	}
+	return findings, nil
+}
+
+// lintFiles returns the workflow files of a scan, including those without
+// actions. Composite actions have no triggers or jobs.
+func lintFiles(actions WorkflowActions, skipped []SkippedFile) []string {
+	var files []string
+	for _, workflow := range sortedWorkflows(actions) {
+		if !isActionFile(workflow) {
+			files = append(files, workflow)
+		}
+	}
+	for _, file := range skipped {
+		if file.Reason == reasonNoActions && isYAMLFile(file.Path) && !isActionFile(file.Path) {
+			files = append(files, file.Path)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// lintFindings lints every file, in workflow and line order. Findings of
+// disabled rules are left out.
+func lintFindings(files []string) []Finding {
+	findings := []Finding{}
+	for _, file := range files {
+		found, err := lintWorkflow(file)
+		if err != nil {
+			fmt.Fprintf(console, "  %s Could not lint %s: %v\n", theme.Warning, file, err)
+			continue
+		}
+		for _, finding := range found {
+			if finding.Severity != SeverityOff {
+				findings = append(findings, finding)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Workflow != findings[j].Workflow {
+			return findings[i].Workflow < findings[j].Workflow
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// setupLint registers the flags of the lint command and returns its runner
+func setupLint(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	jsonOutput := fs.Bool("json", false, "Write the findings as JSON to stdout")
+	sarifPath := fs.String("sarif", "", "Also write the findings as SARIF for code scanning to `file`")
+
+	return func(args []string) {
+		if *jsonOutput {
+			console = os.Stderr
+		}
+		cfg, scanOpts := mustLoadScanConfig(opts)
+
+		fmt.Fprintln(console, "🔍 Linting workflow files...")
+		actions, skipped, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+		files := lintFiles(actions, skipped)
+		findings := lintFindings(files)
+
+		if *sarifPath != "" {
+			if err := writeSARIFFile(*sarifPath, findings, cfg); err != nil {
+				fmt.Fprintf(console, "Error writing SARIF report: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		failed := 0
+		for _, finding := range findings {
+			if ruleFails(finding.Rule) {
+				failed++
+			}
+		}
+
+		if *jsonOutput {
+			if files == nil {
+				files = []string{}
+			}
+			data, err := marshalIndented(LintReport{Run: runMetadata(cfg), Files: files, Findings: findings})
+			if err != nil {
+				fmt.Fprintf(console, "Error encoding the findings: %v\n", err)
+				os.Exit(1)
+			}
+			_, _ = os.Stdout.Write(data)
+		} else {
+			for _, finding := range findings {
+				fmt.Fprintf(console, "  %s %s:%d %s %s\n", severityStatus(finding.Rule), finding.Workflow, finding.Line, finding.Message, ruleTag(finding.Rule))
+			}
+			if len(findings) == 0 {
+				fmt.Fprintf(console, "%s No risky patterns found in %d workflow file(s)\n", theme.OK, len(files))
+			} else {
+				fmt.Fprintf(console, "\n📋 %d finding(s) in %d workflow file(s), %d failing\n", len(findings), len(files), failed)
+			}
+		}
+
+		if failed > 0 {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultLockFile records the commit every action is pinned to
+	defaultLockFile = "gha.lock"
+
+	// lockFormatVersion is the version of the lockfile format
+	lockFormatVersion = 1
+)
+
+// Lockfile is the committed state of the actions the workflows use, one
+// entry per action and version, so pins can be reviewed as a diff and
+// reproduced on other branches with sync
+type Lockfile struct {
+	Version int `json:"version"`
+	// Run records the binary, command and configuration that last wrote
+	// the lockfile
+	Run     *RunMetadata   `json:"run,omitempty"`
+	Actions []LockedAction `json:"actions"`
+}
+
+// LockedAction is the commit a version of an action is pinned to
+type LockedAction struct {
+	// Repo is the uses: path without the ref, e.g. github/codeql-action/init
+	Repo string `json:"repo"`
+	// Version is the tag of the pin comment or the ref as written, empty for
+	// pins without a comment
+	Version    string   `json:"version,omitempty"`
+	SHA        string   `json:"sha"`
+	ResolvedAt string   `json:"resolved_at"`
+	Workflows  []string `json:"workflows"`
+	// Provenance records why the commit was selected when it was resolved,
+	// absent when it was pinned by hand or not looked up
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// key identifies the entry of a version of an action
+func (l LockedAction) key() string {
+	return strings.ToLower(l.Repo) + "@" + l.Version
+}
+
+// label names the entry for the console
+func (l LockedAction) label() string {
+	if l.Version == "" {
+		return l.Repo
+	}
+	return l.Repo + "@" + l.Version
+}
+
+// lockVersion returns the version of a reference a lockfile records: the
+// version tag it is at, the ref of an unpinned reference, or the comment of
+// a pin
+func lockVersion(action ActionInfo) string {
+	if version := currentVersion(action); version != "" {
+		return version
+	}
+	if !action.Pinned() {
+		return action.CurrentRef
+	}
+	return commentTag(action.OriginalLine, action.Repo)
+}
+
+// readLockfile loads a lockfile
+func readLockfile(path string) (*Lockfile, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	if lock.Version != lockFormatVersion {
+		return nil, fmt.Errorf("unsupported lockfile version %d in %s", lock.Version, path)
+	}
+	return &lock, nil
+}
+
+// writeLockfile stores the lockfile in path
+func writeLockfile(path string, lock *Lockfile) error {
+	if err := checkWritePath(path); err != nil {
+		return err
+	}
+	data, err := marshalIndented(lock)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Clean(path), data, 0600)
+}
+
+// buildLockfile records the commit of every action and version. Entries
+// whose commit is unchanged keep the time they were resolved at and their
+// provenance from previous, so only real changes show up in diffs.
+// Container actions and references that could not be resolved are left out.
+func buildLockfile(actions WorkflowActions, previous *Lockfile, now time.Time) *Lockfile {
+	resolvedAt := make(map[string]string)
+	provenance := make(map[string]*Provenance)
+	if previous != nil {
+		for _, entry := range previous.Actions {
+			resolvedAt[entry.key()+"="+strings.ToLower(entry.SHA)] = entry.ResolvedAt
+			provenance[entry.key()+"="+strings.ToLower(entry.SHA)] = entry.Provenance
+		}
+	}
+
+	entries := make(map[string]*LockedAction)
+	for _, action := range sortedActions(actions) {
+		if action.Docker || action.CurrentSHA == "" {
+			continue
+		}
+		locked := LockedAction{Repo: action.Repo, Version: lockVersion(action), SHA: action.CurrentSHA}
+		// Workflows pinning one version to different commits keep an entry
+		// each, which sync --check reports
+		key := locked.key() + "=" + strings.ToLower(locked.SHA)
+		entry, ok := entries[key]
+		if !ok {
+			locked.ResolvedAt = resolvedAt[key]
+			if locked.ResolvedAt == "" {
+				locked.ResolvedAt = now.UTC().Format(time.RFC3339)
+			}
+			locked.Provenance = action.pinProvenance()
+			if locked.Provenance == nil {
+				locked.Provenance = provenance[key]
+			}
+			entry = &locked
+			entries[key] = entry
+		}
+		if !containsString(entry.Workflows, action.WorkflowFile) {
+			entry.Workflows = append(entry.Workflows, action.WorkflowFile)
+		}
+	}
+
+	lock := &Lockfile{Version: lockFormatVersion, Actions: make([]LockedAction, 0, len(entries))}
+	for _, entry := range entries {
+		sort.Strings(entry.Workflows)
+		lock.Actions = append(lock.Actions, *entry)
+	}
+	sort.Slice(lock.Actions, func(i, j int) bool {
+		a, b := lock.Actions[i], lock.Actions[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.SHA < b.SHA
+	})
+	return lock
+}
+
+// lockChanges lists how a lockfile differs from the previous one for the
+// console, one line per added, changed or removed entry
+func lockChanges(previous, lock *Lockfile) []string {
+	before := make(map[string]LockedAction)
+	if previous != nil {
+		for _, entry := range previous.Actions {
+			before[entry.key()] = entry
+		}
+	}
+	after := make(map[string]LockedAction)
+	for _, entry := range lock.Actions {
+		after[entry.key()] = entry
+	}
+
+	var changes []string
+	for _, entry := range lock.Actions {
+		old, ok := before[entry.key()]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+ %s %s", entry.label(), shortPin(entry.SHA)))
+		case !strings.EqualFold(old.SHA, entry.SHA):
+			changes = append(changes, fmt.Sprintf("~ %s %s → %s", entry.label(), shortPin(old.SHA), shortPin(entry.SHA)))
+		}
+	}
+	if previous != nil {
+		for _, entry := range previous.Actions {
+			if _, ok := after[entry.key()]; !ok {
+				changes = append(changes, fmt.Sprintf("- %s %s", entry.label(), shortPin(entry.SHA)))
+			}
+		}
+	}
+	return changes
+}
+
+// unexplained returns the actions of entries with no provenance in
+// previous, whose pins are looked up to record why they are what they are
+func unexplained(actions WorkflowActions, previous *Lockfile) WorkflowActions {
+	explained := make(map[string]bool)
+	if previous != nil {
+		for _, entry := range previous.Actions {
+			explained[entry.key()+"="+strings.ToLower(entry.SHA)] = entry.Provenance != nil
+		}
+	}
+	selected := make(WorkflowActions)
+	for _, action := range sortedActions(actions) {
+		key := strings.ToLower(action.Repo) + "@" + lockVersion(action) + "=" + strings.ToLower(action.CurrentSHA)
+		if !action.Docker && action.CurrentSHA != "" && !explained[key] {
+			selected[action.WorkflowFile] = append(selected[action.WorkflowFile], action)
+		}
+	}
+	return selected
+}
+
+// describeLockRun names the run that wrote a lockfile for the console
+func describeLockRun(run *RunMetadata) string {
+	return fmt.Sprintf("%s %s on %s with `%s`", run.Tool.Name, run.Tool.Version, run.StartedAt.Format(time.DateOnly), run.commandLine())
+}
+
+// lockTarget returns the entry a reference is synced to: the entry of its
+// version, or the only entry of its action, so a version bumped on another
+// branch is picked up
+func lockTarget(lock *Lockfile, action ActionInfo) (LockedAction, bool) {
+	key := strings.ToLower(action.Repo) + "@" + lockVersion(action)
+	var only []LockedAction
+	for _, entry := range lock.Actions {
+		if entry.key() == key {
+			return entry, true
+		}
+		if strings.EqualFold(entry.Repo, action.Repo) {
+			only = append(only, entry)
+		}
+	}
+	if len(only) == 1 {
+		return only[0], true
+	}
+	return LockedAction{}, false
+}
+
+// setupLock registers the flags of the lock command and returns its runner
+func setupLock(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	file := fs.String("file", defaultLockFile, "Lockfile `path`")
+	check := fs.Bool("check", false, "Fail when the lockfile is out of date instead of writing it")
+	explain := fs.Bool("provenance", true, "Look up why new and changed pins are what they are and record it; false needs the API only for unpinned references")
+
+	return func(_ []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+
+		// The lockfile covers every workflow, so named files would drop the others
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(nil, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+		previous, err := readLockfile(*file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Only references that are not pinned, and pins whose provenance is
+		// not recorded yet, need the API
+		var forge Forge
+		lookup := func() Forge {
+			if forge == nil {
+				forge = forgeFor(NewGitHubClient(), cfg, actions)
+			}
+			return forge
+		}
+		var unpinned bool
+		for _, action := range sortedActions(actions) {
+			unpinned = unpinned || (!action.Docker && !action.Pinned())
+		}
+		if unpinned {
+			fmt.Fprintln(console, "📌 Resolving unpinned references...")
+			resolveUnpinned(lookup(), actions)
+		}
+		if selected := unexplained(actions, previous); *explain && !*check && len(selected) > 0 {
+			fmt.Fprintln(console, "🧭 Looking up the provenance of new pins...")
+			checkForUpdates(lookup(), selected, cfg)
+			fmt.Fprintln(console)
+			for workflow, actionList := range selected {
+				for _, action := range actionList {
+					for i := range actions[workflow] {
+						if actions[workflow][i].Line == action.Line && actions[workflow][i].Column == action.Column {
+							actions[workflow][i].Provenance = action.Provenance
+							actions[workflow][i].NeedsUpdate = action.NeedsUpdate
+						}
+					}
+				}
+			}
+		}
+
+		lock := buildLockfile(actions, previous, time.Now())
+		run := runMetadata(cfg)
+		lock.Run = &run
+		changes := lockChanges(previous, lock)
+		for _, change := range changes {
+			fmt.Fprintf(console, "  %s\n", change)
+		}
+		explained := 0
+		if previous != nil {
+			explained = len(sortedActions(unexplained(actions, previous))) - len(sortedActions(unexplained(actions, lock)))
+		}
+
+		// Provenance recorded for existing entries is written, but does not
+		// make the lockfile out of date
+		switch {
+		case previous != nil && len(changes) == 0 && (explained == 0 || *check):
+			fmt.Fprintf(console, "%s %s is up to date with %d action(s)\n", theme.OK, *file, len(lock.Actions))
+		case *check:
+			fmt.Fprintf(console, "%s %s is out of date; run %s lock and commit it\n", theme.Error, *file, programName)
+			os.Exit(1)
+		default:
+			if err := writeLockfile(*file, lock); err != nil {
+				fmt.Fprintf(console, "Error writing %s: %v\n", *file, err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(console, "%s Wrote %s with %d action(s); commit it to review pin changes as diffs\n", theme.OK, *file, len(lock.Actions))
+		}
+	}
+}
+
+// setupSync registers the flags of the sync command and returns its runner
+func setupSync(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	file := fs.String("file", defaultLockFile, "Lockfile `path`")
+	check := fs.Bool("check", false, "Fail when a workflow differs from the lockfile instead of rewriting it")
+	fs.BoolVar(&autoApprove, "yes", false, "Apply without prompting")
+	requireSignature := fs.Bool("require-signature", false, "Fail when the lockfile has no Sigstore bundle, as when a signer is given with --key or --identity")
+	verifyOpts := registerLockVerifyFlags(fs)
+
+	return func(args []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+
+		lock, err := readLockfile(*file)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(console, "Error: %s does not exist; run %s lock to create it\n", *file, programName)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// A signed lockfile is only applied once its signature verifies, and
+		// a lockfile expected to be signed is not applied without a bundle
+		bundlePath := lockBundlePath(*file, *verifyOpts.bundle)
+		_, err = os.Stat(bundlePath)
+		switch {
+		case err == nil:
+			signer, err := verifyLockSignature(*file, verifyOpts)
+			if err != nil {
+				fmt.Fprintf(console, "%s Signature of %s does not verify: %v\n", theme.Error, *file, err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(console, "%s %s is signed by %s\n", theme.OK, *file, signer)
+		case !errors.Is(err, os.ErrNotExist):
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		case *requireSignature || verifyOpts.expectSignature():
+			fmt.Fprintf(console, "Error: %s is not signed; run %s lock sign to create %s\n", *file, programName, bundlePath)
+			os.Exit(1)
+		default:
+			fmt.Fprintf(console, "%s %s is not signed; pass --require-signature to refuse unsigned lockfiles\n", theme.Warning, *file)
+		}
+
+		if run := lock.Run; run != nil {
+			fmt.Fprintf(console, "📜 %s was written by %s\n", *file, describeLockRun(run))
+			if run.Config != nil && (cfg.source == nil || !strings.EqualFold(run.Config.SHA256, cfg.source.SHA256)) {
+				fmt.Fprintf(console, "%s %s was written with another configuration (%s, sha256 %s); its pins may follow other rules\n", theme.Warning, *file, run.Config.Path, shortPin(run.Config.SHA256))
+			}
+		}
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(console, "\n🔒 Comparing the workflows with %s...\n", *file)
+		explained := make(map[string]bool)
+		selected := make(WorkflowActions)
+		drift, missing := 0, 0
+		for _, action := range sortedActions(actions) {
+			if action.Docker {
+				continue
+			}
+			entry, ok := lockTarget(lock, action)
+			if !ok {
+				missing++
+				fmt.Fprintf(console, "  %s %s:%d %s is not in %s\n", theme.Warning, action.WorkflowFile, action.Line, action.reference(), *file)
+				continue
+			}
+			if strings.EqualFold(action.CurrentRef, entry.SHA) {
+				continue
+			}
+			drift++
+			why := ""
+			if entry.Provenance != nil {
+				why = " (" + entry.Provenance.String() + ")"
+			}
+			switch {
+			case *check:
+				fmt.Fprintf(console, "  %s %s:%d %s@%s, the lockfile pins %s%s\n", theme.Error, action.WorkflowFile, action.Line, action.Repo, shortPin(action.CurrentRef), entry.label()+" "+shortPin(entry.SHA), why)
+			case why != "" && !explained[entry.key()]:
+				explained[entry.key()] = true
+				fmt.Fprintf(console, "  📜 %s %s: %s\n", entry.label(), shortPin(entry.SHA), entry.Provenance)
+			}
+			action.LatestTag = entry.Version
+			if action.LatestTag == "" {
+				action.LatestTag = entry.SHA
+			}
+			action.LatestSHA = entry.SHA
+			selected[action.WorkflowFile] = append(selected[action.WorkflowFile], action)
+		}
+
+		switch {
+		case *check && drift+missing > 0:
+			fmt.Fprintf(console, "%s %d reference(s) differ from %s and %d are not in it\n", theme.Error, drift, *file, missing)
+			os.Exit(1)
+		case drift+missing == 0:
+			fmt.Fprintf(console, "%s Every reference matches %s\n", theme.OK, *file)
+			return
+		case drift > 0:
+			if err := alignActions(selected); err != nil {
+				fmt.Fprintf(console, "Error syncing workflows: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if missing > 0 {
+			fmt.Fprintf(console, "  💡 Run %s lock to add the %d reference(s) missing from %s\n", programName, missing, *file)
+		}
+	}
+}
@@ -0,0 +1,646 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultFulcioURL and defaultRekorURL are the public Sigstore instances
+	defaultFulcioURL = "https://fulcio.sigstore.dev"
+	defaultRekorURL  = "https://rekor.sigstore.dev"
+
+	// sigstoreTimeout bounds a single Fulcio, Rekor or OIDC request
+	sigstoreTimeout = 30 * time.Second
+
+	// maxSigstoreResponseSize bounds a Fulcio, Rekor or OIDC response
+	maxSigstoreResponseSize = 1 << 20
+
+	// sigstoreBundleMediaType is the Sigstore bundle version written
+	sigstoreBundleMediaType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+
+	// lockBundleSuffix names the bundle of a lockfile, e.g. gha.lock.sigstore.json
+	lockBundleSuffix = ".sigstore.json"
+)
+
+// Fulcio certificate extensions naming the OIDC issuer of the identity
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// lockBundle is the Sigstore bundle of a signed lockfile. Bundles signed
+// keyless carry the Fulcio certificate and the Rekor entry and can also be
+// checked with cosign verify-blob --bundle.
+type lockBundle struct {
+	MediaType            string                   `json:"mediaType"`
+	VerificationMaterial lockVerificationMaterial `json:"verificationMaterial"`
+	MessageSignature     lockMessageSignature     `json:"messageSignature"`
+}
+
+type lockVerificationMaterial struct {
+	Certificate *struct {
+		RawBytes string `json:"rawBytes"`
+	} `json:"certificate,omitempty"`
+	PublicKey *struct {
+		Hint string `json:"hint"`
+	} `json:"publicKey,omitempty"`
+	TlogEntries []json.RawMessage `json:"tlogEntries,omitempty"`
+}
+
+type lockMessageSignature struct {
+	MessageDigest struct {
+		Algorithm string `json:"algorithm"`
+		Digest    string `json:"digest"`
+	} `json:"messageDigest"`
+	Signature string `json:"signature"`
+}
+
+// lockBundlePath returns the bundle path of a lockfile unless one is given
+func lockBundlePath(lockPath, bundlePath string) string {
+	if bundlePath != "" {
+		return bundlePath
+	}
+	return lockPath + lockBundleSuffix
+}
+
+// keyHint identifies a public key by the digest of its DER encoding
+func keyHint(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(der)
+	return base64.StdEncoding.EncodeToString(digest[:]), nil
+}
+
+// readPrivateKey loads an unencrypted PEM private key
+func readPrivateKey(path string) (crypto.Signer, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM key", path)
+	}
+	var key any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s holds a %s, not an unencrypted private key; decrypt it with openssl pkcs8", path, strings.ToLower(block.Type))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key %s: %w", path, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key %T in %s", key, path)
+	}
+	return signer, nil
+}
+
+// readPublicKey loads a PEM public key
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s is not a PEM public key", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// signMessage signs a message the way verifyKeySignature checks it
+func signMessage(signer crypto.Signer, message []byte) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	hash := crypto.SHA256
+	if key, ok := signer.Public().(*ecdsa.PublicKey); ok && key.Curve.Params().BitSize > 256 {
+		hash = crypto.SHA384
+	}
+	h := hash.New()
+	h.Write(message)
+	return signer.Sign(rand.Reader, h.Sum(nil), hash)
+}
+
+// newLockBundle returns a bundle holding the signature of content
+func newLockBundle(content, signature []byte) *lockBundle {
+	digest := sha256.Sum256(content)
+	bundle := &lockBundle{MediaType: sigstoreBundleMediaType}
+	bundle.MessageSignature.MessageDigest.Algorithm = "SHA2_256"
+	bundle.MessageSignature.MessageDigest.Digest = base64.StdEncoding.EncodeToString(digest[:])
+	bundle.MessageSignature.Signature = base64.StdEncoding.EncodeToString(signature)
+	return bundle
+}
+
+// sigstoreClient obtains signing certificates from Fulcio and records
+// signatures in Rekor
+type sigstoreClient struct {
+	fulcioURL string
+	rekorURL  string
+	http      *http.Client
+}
+
+// newSigstoreClient returns a client for the Fulcio and Rekor instances
+func newSigstoreClient(fulcioURL, rekorURL string) *sigstoreClient {
+	return &sigstoreClient{
+		fulcioURL: strings.TrimSuffix(fulcioURL, "/"),
+		rekorURL:  strings.TrimSuffix(rekorURL, "/"),
+		http:      &http.Client{Transport: loggingTransport{}, Timeout: sigstoreTimeout},
+	}
+}
+
+// post sends a JSON request and decodes the JSON response into result
+func (c *sigstoreClient) post(service, url string, body, result any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return &ResolveError{Kind: ErrorKindNetwork, Err: fmt.Errorf("%s could not be reached: %w", service, err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSigstoreResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%s request failed with status %d: %s", service, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("invalid %s response: %w", service, err)
+	}
+	return nil
+}
+
+// identityToken returns the OIDC token a keyless signature is bound to:
+// SIGSTORE_ID_TOKEN, or the token of a GitHub Actions job with id-token:
+// write
+func (c *sigstoreClient) identityToken() (string, error) {
+	if token := os.Getenv("SIGSTORE_ID_TOKEN"); token != "" {
+		return token, nil
+	}
+	requestURL, requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return "", errors.New("no OIDC token for keyless signing; run in a GitHub Actions job with id-token: write, set SIGSTORE_ID_TOKEN, or sign with --key")
+	}
+	req, err := http.NewRequest(http.MethodGet, requestURL+"&audience=sigstore", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "bearer "+requestToken)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", &ResolveError{Kind: ErrorKindNetwork, Err: fmt.Errorf("the OIDC token endpoint could not be reached: %w", err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC token request failed with status %d", resp.StatusCode)
+	}
+	var result struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxSigstoreResponseSize)).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid OIDC token response: %w", err)
+	}
+	return result.Value, nil
+}
+
+// tokenSubject returns the claim Fulcio expects proof of possession for:
+// the email of email identities, the subject otherwise
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("the OIDC token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid OIDC token payload: %w", err)
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("invalid OIDC token claims: %w", err)
+	}
+	if claims.Email != "" {
+		return claims.Email, nil
+	}
+	return claims.Subject, nil
+}
+
+// signingCertificate requests a short-lived certificate for key bound to
+// the identity of token
+func (c *sigstoreClient) signingCertificate(token string, key *ecdsa.PrivateKey) (*x509.Certificate, error) {
+	subject, err := tokenSubject(token)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(subject))
+	proof, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	request := map[string]any{
+		"credentials": map[string]string{"oidcIdentityToken": token},
+		"publicKeyRequest": map[string]any{
+			"publicKey": map[string]string{
+				"algorithm": "ECDSA",
+				"content":   string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			},
+			"proofOfPossession": base64.StdEncoding.EncodeToString(proof),
+		},
+	}
+	type certificateChain struct {
+		Chain struct {
+			Certificates []string `json:"certificates"`
+		} `json:"chain"`
+	}
+	var response struct {
+		Embedded *certificateChain `json:"signedCertificateEmbeddedSct"`
+		Detached *certificateChain `json:"signedCertificateDetachedSct"`
+	}
+	if err := c.post("Fulcio", c.fulcioURL+"/api/v2/signingCert", request, &response); err != nil {
+		return nil, err
+	}
+	chain := response.Embedded
+	if chain == nil {
+		chain = response.Detached
+	}
+	if chain == nil || len(chain.Chain.Certificates) == 0 {
+		return nil, errors.New("Fulcio returned no certificate")
+	}
+	block, _ := pem.Decode([]byte(chain.Chain.Certificates[0]))
+	if block == nil {
+		return nil, errors.New("Fulcio returned an invalid certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// recordSignature adds the signature to the Rekor transparency log and
+// returns the entry in the form bundles hold it
+func (c *sigstoreClient) recordSignature(cert *x509.Certificate, content, signature []byte) (json.RawMessage, int64, error) {
+	digest := sha256.Sum256(content)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	request := map[string]any{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]any{
+			"signature": map[string]any{
+				"content":   base64.StdEncoding.EncodeToString(signature),
+				"publicKey": map[string]string{"content": base64.StdEncoding.EncodeToString(certPEM)},
+			},
+			"data": map[string]any{
+				"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(digest[:])},
+			},
+		},
+	}
+	var response map[string]struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+		Verification   struct {
+			SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+			InclusionProof       *struct {
+				Checkpoint string   `json:"checkpoint"`
+				Hashes     []string `json:"hashes"`
+				LogIndex   int64    `json:"logIndex"`
+				RootHash   string   `json:"rootHash"`
+				TreeSize   int64    `json:"treeSize"`
+			} `json:"inclusionProof"`
+		} `json:"verification"`
+	}
+	if err := c.post("Rekor", c.rekorURL+"/api/v1/log/entries", request, &response); err != nil {
+		return nil, 0, err
+	}
+	for _, entry := range response {
+		logID, err := hex.DecodeString(entry.LogID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid Rekor log ID: %w", err)
+		}
+		tlogEntry := map[string]any{
+			"logIndex":          strconv.FormatInt(entry.LogIndex, 10),
+			"logId":             map[string]string{"keyId": base64.StdEncoding.EncodeToString(logID)},
+			"kindVersion":       map[string]string{"kind": "hashedrekord", "version": "0.0.1"},
+			"integratedTime":    strconv.FormatInt(entry.IntegratedTime, 10),
+			"inclusionPromise":  map[string]string{"signedEntryTimestamp": entry.Verification.SignedEntryTimestamp},
+			"canonicalizedBody": entry.Body,
+		}
+		if proof := entry.Verification.InclusionProof; proof != nil {
+			tlogEntry["inclusionProof"] = map[string]any{
+				"logIndex":   strconv.FormatInt(proof.LogIndex, 10),
+				"rootHash":   proof.RootHash,
+				"treeSize":   strconv.FormatInt(proof.TreeSize, 10),
+				"hashes":     proof.Hashes,
+				"checkpoint": map[string]string{"envelope": proof.Checkpoint},
+			}
+		}
+		raw, err := json.Marshal(tlogEntry)
+		return raw, entry.LogIndex, err
+	}
+	return nil, 0, errors.New("Rekor returned no entry")
+}
+
+// signKeyless signs content with an ephemeral key certified by Fulcio for
+// the OIDC identity of the run, and records the signature in Rekor
+func signKeyless(client *sigstoreClient, content []byte) (*lockBundle, string, error) {
+	token, err := client.identityToken()
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	cert, err := client.signingCertificate(token, key)
+	if err != nil {
+		return nil, "", err
+	}
+	signature, err := signMessage(key, content)
+	if err != nil {
+		return nil, "", err
+	}
+	entry, logIndex, err := client.recordSignature(cert, content, signature)
+	if err != nil {
+		return nil, "", err
+	}
+
+	bundle := newLockBundle(content, signature)
+	bundle.VerificationMaterial.Certificate = &struct {
+		RawBytes string `json:"rawBytes"`
+	}{RawBytes: base64.StdEncoding.EncodeToString(cert.Raw)}
+	bundle.VerificationMaterial.TlogEntries = []json.RawMessage{entry}
+	return bundle, fmt.Sprintf("%s, transparency log index %d", certIdentity(cert), logIndex), nil
+}
+
+// signWithKey signs content with a local private key
+func signWithKey(path string, content []byte) (*lockBundle, string, error) {
+	signer, err := readPrivateKey(path)
+	if err != nil {
+		return nil, "", err
+	}
+	hint, err := keyHint(signer.Public())
+	if err != nil {
+		return nil, "", err
+	}
+	signature, err := signMessage(signer, content)
+	if err != nil {
+		return nil, "", err
+	}
+	bundle := newLockBundle(content, signature)
+	bundle.VerificationMaterial.PublicKey = &struct {
+		Hint string `json:"hint"`
+	}{Hint: hint}
+	return bundle, "key " + hint, nil
+}
+
+// certIdentity returns the workflow URI or email a Fulcio certificate was
+// issued to
+func certIdentity(cert *x509.Certificate) string {
+	if identity := workflowIdentity(cert); identity != "" {
+		return identity
+	}
+	if len(cert.EmailAddresses) > 0 {
+		return cert.EmailAddresses[0]
+	}
+	return ""
+}
+
+// certIssuer returns the OIDC issuer recorded in a Fulcio certificate
+func certIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// lockVerifyOptions are the flags checking a lockfile signature
+type lockVerifyOptions struct {
+	bundle      *string
+	key         *string
+	identity    *string
+	issuer      *string
+	trustedRoot *string
+}
+
+// registerLockVerifyFlags registers the flags checking a lockfile signature
+func registerLockVerifyFlags(fs *flag.FlagSet) *lockVerifyOptions {
+	return &lockVerifyOptions{
+		bundle:      fs.String("bundle", "", "Sigstore bundle `path`, by default the lockfile path with .sigstore.json appended"),
+		key:         fs.String("key", "", "Verify a signature made with the private key of this PEM public key `file`"),
+		identity:    fs.String("identity", "", "Require a keyless signature by an identity matching this `regexp`, e.g. a workflow URI"),
+		issuer:      fs.String("issuer", "", "Require a keyless signature by an identity of this OIDC issuer `url`"),
+		trustedRoot: fs.String("trusted-root", "", "Check keyless signatures against the Fulcio certificates and Rekor keys of this Sigstore trusted_root.json `file` instead of the embedded public-good root"),
+	}
+}
+
+// expectSignature reports whether the flags name a bundle or a signer, so a
+// lockfile without a bundle must not be trusted
+func (o *lockVerifyOptions) expectSignature() bool {
+	return *o.bundle != "" || *o.key != "" || *o.identity != "" || *o.issuer != "" || *o.trustedRoot != ""
+}
+
+// verifyLockSignature checks the bundle of a lockfile and returns the
+// signer. Keyless signatures must name the expected identity, their
+// certificate must chain to Fulcio and their entry must be recorded in
+// Rekor, as the public-good trusted root or --trusted-root has them.
+func verifyLockSignature(lockPath string, opts *lockVerifyOptions) (string, error) {
+	content, err := os.ReadFile(filepath.Clean(lockPath))
+	if err != nil {
+		return "", err
+	}
+	bundlePath := lockBundlePath(lockPath, *opts.bundle)
+	data, err := os.ReadFile(filepath.Clean(bundlePath))
+	if err != nil {
+		return "", err
+	}
+	var bundle lockBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return "", fmt.Errorf("invalid bundle %s: %w", bundlePath, err)
+	}
+
+	digest := sha256.Sum256(content)
+	if bundle.MessageSignature.MessageDigest.Digest != base64.StdEncoding.EncodeToString(digest[:]) {
+		return "", fmt.Errorf("%s was changed after it was signed", lockPath)
+	}
+	signature, err := base64.StdEncoding.DecodeString(bundle.MessageSignature.Signature)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	material := bundle.VerificationMaterial
+	switch {
+	case *opts.key != "":
+		publicKey, err := readPublicKey(*opts.key)
+		if err != nil {
+			return "", err
+		}
+		if err := verifyKeySignature(publicKey, content, signature); err != nil {
+			return "", err
+		}
+		hint, err := keyHint(publicKey)
+		return "key " + hint, err
+	case material.Certificate == nil:
+		return "", errors.New("the lockfile was signed with a key; pass its public key with --key")
+	case *opts.identity == "":
+		return "", errors.New("the lockfile was signed keyless; pass the expected signer with --identity")
+	}
+
+	identity, err := regexp.Compile(*opts.identity)
+	if err != nil {
+		return "", fmt.Errorf("invalid --identity: %w", err)
+	}
+	root, err := loadTrustedRoot(*opts.trustedRoot)
+	if err != nil {
+		return "", fmt.Errorf("keyless signatures cannot be verified: %w", err)
+	}
+	der, err := base64.StdEncoding.DecodeString(material.Certificate.RawBytes)
+	if err != nil {
+		return "", fmt.Errorf("invalid certificate encoding: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return "", fmt.Errorf("invalid certificate: %w", err)
+	}
+	if err := verifySignature(cert, content, signature); err != nil {
+		return "", err
+	}
+	signer := certIdentity(cert)
+	if !identity.MatchString(signer) {
+		return "", fmt.Errorf("signed by %q, which does not match --identity", signer)
+	}
+	if *opts.issuer != "" && certIssuer(cert) != *opts.issuer {
+		return "", fmt.Errorf("signed by an identity of %q, not %s", certIssuer(cert), *opts.issuer)
+	}
+	recorded, err := verifyTlogEntry(root, material.TlogEntries, cert, content, signature)
+	if err != nil {
+		return "", err
+	}
+	// Fulcio certificates expire minutes after signing, so the chain is
+	// checked at the time the log recorded the signature
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         root.roots,
+		Intermediates: root.intermediates,
+		CurrentTime:   recorded,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return "", fmt.Errorf("the signing certificate does not chain to the trusted root: %w", err)
+	}
+	return signer, nil
+}
+
+// setupLockSign registers the flags of the lock sign command and returns
+// its runner
+func setupLockSign(fs *flag.FlagSet) func(args []string) {
+	file := fs.String("file", defaultLockFile, "Lockfile `path`")
+	bundlePath := fs.String("bundle", "", "Write the Sigstore bundle to `path`, by default the lockfile path with .sigstore.json appended")
+	key := fs.String("key", "", "Sign with this unencrypted PEM private key `file` instead of keyless")
+	fulcioURL := fs.String("fulcio-url", defaultFulcioURL, "Fulcio `url` issuing keyless signing certificates")
+	rekorURL := fs.String("rekor-url", defaultRekorURL, "Rekor `url` recording keyless signatures")
+
+	return func(_ []string) {
+		content, err := os.ReadFile(filepath.Clean(*file))
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var bundle *lockBundle
+		var signer string
+		if *key != "" {
+			fmt.Fprintf(console, "🔏 Signing %s with %s...\n", *file, *key)
+			bundle, signer, err = signWithKey(*key, content)
+		} else {
+			fmt.Fprintf(console, "🔏 Signing %s keyless with Sigstore...\n", *file)
+			bundle, signer, err = signKeyless(newSigstoreClient(*fulcioURL, *rekorURL), content)
+		}
+		if err != nil {
+			fmt.Fprintf(console, "Error: failed to sign %s: %v\n", *file, err)
+			os.Exit(1)
+		}
+
+		path := lockBundlePath(*file, *bundlePath)
+		data, err := marshalIndented(bundle)
+		if err == nil {
+			err = checkWritePath(path)
+		}
+		if err == nil {
+			err = os.WriteFile(filepath.Clean(path), data, 0600)
+		}
+		if err != nil {
+			fmt.Fprintf(console, "Error writing %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "%s Signed %s as %s; commit %s next to it\n", theme.OK, *file, signer, path)
+	}
+}
+
+// setupLockVerify registers the flags of the lock verify command and
+// returns its runner
+func setupLockVerify(fs *flag.FlagSet) func(args []string) {
+	file := fs.String("file", defaultLockFile, "Lockfile `path`")
+	opts := registerLockVerifyFlags(fs)
+
+	return func(_ []string) {
+		signer, err := verifyLockSignature(*file, opts)
+		if err != nil {
+			fmt.Fprintf(console, "%s Signature of %s does not verify: %v\n", theme.Error, *file, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "%s %s is signed by %s\n", theme.OK, *file, signer)
+		// The signature covers the run block, so it names what wrote the pins
+		if lock, err := readLockfile(*file); err == nil && lock.Run != nil {
+			fmt.Fprintf(console, "  📜 Written by %s\n", describeLockRun(lock.Run))
+		}
+	}
+}
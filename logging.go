@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logger receives diagnostic messages. The human-friendly console output is
+// written separately; by default only warnings and errors are logged.
+var logger = slog.New(newConsoleHandler(os.Stderr, slog.LevelWarn))
+
+// logOptions holds the logging flags shared by every command
+type logOptions struct {
+	verbose bool
+	debug   bool
+	logFile string
+}
+
+// register adds the logging flags to a command's flag set
+func (opts *logOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&opts.verbose, "verbose", false, "Log informational messages to the console")
+	fs.BoolVar(&opts.debug, "debug", false, "Log debug messages, including GitHub API requests, to the console")
+	fs.StringVar(&opts.logFile, "log-file", "", "Also write log messages as JSON lines to `path`")
+}
+
+// level returns the console log level selected by the flags
+func (opts *logOptions) level() slog.Level {
+	switch {
+	case opts.debug:
+		return slog.LevelDebug
+	case opts.verbose:
+		return slog.LevelInfo
+	}
+	return slog.LevelWarn
+}
+
+// configureLogging installs the logger selected by the flags. The log file
+// records informational messages even without --verbose so that unattended
+// runs leave a complete trail; --debug adds debug messages to it as well.
+// The file stays open until the process exits.
+func configureLogging(opts *logOptions) error {
+	handlers := []slog.Handler{newConsoleHandler(os.Stderr, opts.level())}
+
+	if opts.logFile != "" {
+		file, err := os.OpenFile(filepath.Clean(opts.logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		fileLevel := slog.LevelInfo
+		if opts.debug {
+			fileLevel = slog.LevelDebug
+		}
+		handlers = append(handlers, slog.NewJSONHandler(file, &slog.HandlerOptions{Level: fileLevel}))
+	}
+
+	logger = slog.New(multiHandler(handlers))
+	return nil
+}
+
+// consoleHandler writes log records as single human readable lines
+type consoleHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+}
+
+// newConsoleHandler returns a handler writing records at or above level to w
+func newConsoleHandler(w io.Writer, level slog.Level) *consoleHandler {
+	return &consoleHandler{mu: &sync.Mutex{}, w: w, level: level}
+}
+
+// Enabled implements slog.Handler
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+// Handle implements slog.Handler
+func (h *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	switch {
+	case record.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case record.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	case record.Level < slog.LevelInfo:
+		b.WriteString("debug: ")
+	}
+	b.WriteString(record.Message)
+
+	writeAttr := func(attr slog.Attr) bool {
+		fmt.Fprintf(&b, " %s=%v", attr.Key, attr.Value)
+		return true
+	}
+	for _, attr := range h.attrs {
+		writeAttr(attr)
+	}
+	record.Attrs(writeAttr)
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// WithAttrs implements slog.Handler
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+// WithGroup implements slog.Handler; groups are not used by this tool
+func (h *consoleHandler) WithGroup(_ string) slog.Handler {
+	return h
+}
+
+// multiHandler passes each record to every handler that accepts its level
+type multiHandler []slog.Handler
+
+// Enabled implements slog.Handler
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements slog.Handler
+func (m multiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var firstErr error
+	for _, h := range m {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, record.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// WithAttrs implements slog.Handler
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+// WithGroup implements slog.Handler
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// loggingTransport logs every GitHub API and registry request at debug level
+type loggingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	duration := time.Since(start).Milliseconds()
+	if err != nil {
+		logger.Debug("HTTP request failed", "method", req.Method, "url", req.URL.String(), "duration_ms", duration, "error", err)
+		return nil, err
+	}
+	logger.Debug("HTTP request", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode,
+		"duration_ms", duration, "rate_remaining", resp.Header.Get("X-RateLimit-Remaining"))
+	return resp, nil
+}
@@ -4,30 +4,63 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
-	"github.com/google/go-github/v56/github"
 	"golang.org/x/oauth2"
 )
 
 const (
 	// codeQLAction is the GitHub CodeQL action repository name
 	codeQLAction = "codeql-action"
+
+	// workflowDir is the directory GitHub loads workflow files from
+	workflowDir = ".github/workflows"
+
+	// stdinWorkflowName labels the workflow read in --stdin mode
+	stdinWorkflowName = "<stdin>"
 )
 
 var (
+	// console is where human readable progress output is written. It is
+	// redirected to stderr when machine readable output goes to stdout.
+	console io.Writer = os.Stdout
+
+	// stdinReader is shared by all interactive prompts
+	stdinReader = bufio.NewReader(os.Stdin)
+
+	// autoApprove answers yes to every confirmation prompt (update --yes)
+	autoApprove bool
+
+	// allowBreaking lets --yes apply updates whose release notes mention
+	// breaking changes (update --allow-breaking)
+	allowBreaking bool
+
+	// followRenames rewrites uses: of renamed or transferred repositories
+	// to their new location without asking (update --follow-renames)
+	followRenames bool
+
 	// shaRegex is a compiled regex for matching 40-character SHA hashes
 	shaRegex = regexp.MustCompile(`^[a-f0-9]{40}$`)
 
+	// shortSHARegex matches abbreviated commit SHAs as printed by git log --oneline
+	shortSHARegex = regexp.MustCompile(`^[a-f0-9]{7,12}$`)
+
 	// Version information (set by build flags)
 	// Version is the current version of the application
 	Version = "dev"
@@ -46,45 +79,426 @@ type ActionInfo struct {
 	LatestSHA    string `json:"latest_sha"`
 	NeedsUpdate  bool   `json:"needs_update"`
 	Line         int    `json:"line"`
+	Column       int    `json:"column"`
 	OriginalLine string `json:"original_line"`
 	WorkflowFile string `json:"workflow_file"`
+	ReleaseURL   string `json:"release_url,omitempty"`
+	ReleaseNotes string `json:"-"`
+	ReleaseDate  string `json:"release_date,omitempty"`
+
+	// MovedTo is the action path at the new location of a renamed or
+	// transferred repository, which GitHub still serves under the old name.
+	// Rename is set when update rewrites the reference to it.
+	MovedTo string `json:"moved_to,omitempty"`
+	Rename  bool   `json:"-"`
+
+	// Job is the id of the workflow job the reference belongs to, empty in
+	// composite actions and template sources
+	Job string `json:"job,omitempty"`
+
+	// ReusableWorkflow is set for job-level calls such as
+	// org/repo/.github/workflows/build.yml@v1
+	ReusableWorkflow bool `json:"reusable_workflow,omitempty"`
+
+	// Docker is set for docker:// container actions. Repo holds the image
+	// name, CurrentRef its tag or digest, and ImageTag the tag a digest pin
+	// was made from.
+	Docker   bool   `json:"docker,omitempty"`
+	ImageTag string `json:"image_tag,omitempty"`
+
+	// Abbreviated is set when CurrentRef is a short commit SHA; CurrentSHA
+	// then holds the full SHA it expands to
+	Abbreviated bool `json:"abbreviated,omitempty"`
+
+	// DistMismatch lists the JavaScript entry points whose content at the
+	// tag in the pin comment differs from the pinned commit
+	DistMismatch []string `json:"dist_mismatch,omitempty"`
+
+	// Attestation is the verified attestation of the pinned commit, and
+	// AttestationError why its attestations do not verify; both are looked
+	// up with --attestations
+	Attestation      *Attestation `json:"attestation,omitempty"`
+	AttestationError string       `json:"attestation_error,omitempty"`
+
+	// Transitive lists the unpinned references in the steps of a pinned
+	// composite action, read with --deep
+	Transitive []TransitiveUse `json:"transitive,omitempty"`
+
+	// Runtime is the runs.using value of the action at the pinned commit,
+	// and LatestRuntime that of the latest release when it moves off a
+	// deprecated Node version; both are read with --runtimes
+	Runtime       string `json:"runtime,omitempty"`
+	LatestRuntime string `json:"latest_runtime,omitempty"`
+
+	// LatestImmutable is set when the release LatestTag names is immutable.
+	// Immutable records whether the release of the pinned version is, nil
+	// when unknown, and RequiresImmutable that require_immutable lists the
+	// action; both are looked up with --immutable or require_immutable.
+	LatestImmutable   bool  `json:"latest_immutable,omitempty"`
+	Immutable         *bool `json:"immutable,omitempty"`
+	RequiresImmutable bool  `json:"requires_immutable,omitempty"`
+
+	// TagMoved is set when a tag resolved to another commit than the pin
+	// database recorded for it; the action is not updated then
+	TagMoved *TagMove `json:"tag_moved,omitempty"`
+
+	// CoolingOff is the release held back by min_release_age; LatestTag
+	// and LatestSHA then stay at the current version
+	CoolingOff *PendingRelease `json:"cooling_off,omitempty"`
+
+	// Compromised is the denylist entry matching the version in use
+	Compromised *DenyEntry `json:"compromised,omitempty"`
+
+	// Untrusted is set when the action is outside the configured allowlist
+	Untrusted bool `json:"untrusted,omitempty"`
+
+	// Deprecation records why the action repository looks dead, looked up
+	// with --deprecated
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
+
+	// Publisher records whether the owner is a verified creator and the
+	// action is on the Marketplace, looked up with --publishers
+	Publisher *Publisher `json:"publisher,omitempty"`
+
+	// Ownership records whether the repository is owned by an organization
+	// and whether it is a fork, looked up with verify --require-org-owned
+	Ownership *Ownership `json:"ownership,omitempty"`
+
+	// Provenance records the rule that selected LatestTag
+	Provenance *Provenance `json:"provenance,omitempty"`
+
+	// BreakingChanges lists the lines of the latest release notes that
+	// mention breaking changes; such updates need review
+	BreakingChanges []string `json:"breaking_changes,omitempty"`
+
+	// Advisories lists the security advisories affecting the version in
+	// use, looked up by audit
+	Advisories []Advisory `json:"advisories,omitempty"`
+
+	// Scorecard is the OpenSSF Scorecard of the action repository, looked
+	// up with --scorecard
+	Scorecard *Scorecard `json:"scorecard,omitempty"`
+
+	Error *ResolveError `json:"error,omitempty"`
+}
+
+// Pinned reports whether the action is pinned to a commit SHA, or to an
+// image digest for container actions
+func (a ActionInfo) Pinned() bool {
+	if a.Docker {
+		return digestRegex.MatchString(a.CurrentRef)
+	}
+	return shaRegex.MatchString(a.CurrentRef)
+}
+
+// pinKind names what the action is pinned to once pinned
+func (a ActionInfo) pinKind() string {
+	if a.Docker {
+		return "a digest"
+	}
+	return "a commit SHA"
+}
+
+// reference spells the action as its uses: value does: image tags follow
+// the image name after a colon, commits and digests follow an @
+func (a ActionInfo) reference() string {
+	if a.Docker && !strings.HasPrefix(a.CurrentRef, "sha256:") {
+		return a.Repo + ":" + a.CurrentRef
+	}
+	return a.Repo + "@" + a.CurrentRef
+}
+
+// shortPin abbreviates a commit SHA or image digest for display
+func shortPin(pin string) string {
+	prefix := ""
+	if strings.HasPrefix(pin, "sha256:") {
+		prefix, pin = "sha256:", strings.TrimPrefix(pin, "sha256:")
+	}
+	if len(pin) > 8 {
+		pin = pin[:8]
+	}
+	return prefix + pin
 }
 
 // WorkflowActions represents all actions found in workflows
 type WorkflowActions map[string][]ActionInfo
 
+// sortedWorkflows returns the workflow files of actions in path order, so
+// output does not depend on map iteration
+func sortedWorkflows(actions WorkflowActions) []string {
+	workflows := make([]string, 0, len(actions))
+	for workflow := range actions {
+		workflows = append(workflows, workflow)
+	}
+	sort.Strings(workflows)
+	return workflows
+}
+
+// ScanOptions controls which workflow files are scanned
+type ScanOptions struct {
+	Include []string
+	Exclude []string
+
+	// ActionPaths are the directories searched for local composite actions
+	ActionPaths []string
+
+	// TemplatePaths are the files and directories holding workflow template sources
+	TemplatePaths []string
+
+	// ChangedOnly restricts scanning to workflow files changed relative to BaseRef
+	ChangedOnly bool
+	BaseRef     string
+}
+
+// shouldScan reports whether a workflow file passes the include/exclude filters.
+// relPath is the path relative to the workflow directory using forward slashes.
+func (o ScanOptions) shouldScan(relPath, fullPath string) bool {
+	if len(o.Include) > 0 && !matchesAnyGlob(o.Include, relPath, fullPath) {
+		return false
+	}
+	return !matchesAnyGlob(o.Exclude, relPath, fullPath)
+}
+
+// matchesAnyGlob reports whether any pattern matches the workflow path. Patterns
+// are matched against the path relative to the workflow directory, the full
+// path, and the bare file name so that both "release-*.yml" and
+// ".github/workflows/release-*.yml" work as expected.
+func matchesAnyGlob(patterns []string, relPath, fullPath string) bool {
+	fullPath = filepath.ToSlash(fullPath)
+	for _, pattern := range patterns {
+		for _, candidate := range []string{relPath, fullPath, path.Base(relPath)} {
+			if matched, err := path.Match(pattern, candidate); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateGlobs checks that all patterns are syntactically valid
+func validateGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// stringSliceFlag collects the values of a repeatable string flag
+type stringSliceFlag []string
+
+// String returns the collected values joined by commas
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set appends a value to the flag
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// commandOptions holds the flags shared by the scanning commands
+type commandOptions struct {
+	configPath         string
+	include            stringSliceFlag
+	exclude            stringSliceFlag
+	changedOnly        bool
+	changed            bool
+	baseRef            string
+	theme              string
+	events             string
+	gitFallback        bool
+	includePrereleases bool
+	strategy           string
+	minReleaseAge      string
+	plain              bool
+}
+
+// register adds the shared scanning flags to a command's flag set
+func (opts *commandOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&opts.configPath, "config", defaultConfigFile, "Path to the configuration `file`")
+	fs.Var(&opts.include, "include", "Only scan workflow files matching this `glob` (repeatable)")
+	fs.Var(&opts.exclude, "exclude", "Skip workflow files matching this `glob` (repeatable)")
+	fs.BoolVar(&opts.changedOnly, "changed-only", false, "Only scan workflow files changed relative to the base ref")
+	fs.StringVar(&opts.baseRef, "base", defaultBaseRef, "Base `ref` for --changed-only")
+	fs.BoolVar(&opts.changed, "changed", false, "Only scan workflow files with staged or uncommitted changes, e.g. in a pre-commit hook")
+	fs.StringVar(&opts.theme, "theme", "", "Output `theme`: "+strings.Join(themeNames(), ", "))
+	fs.BoolVar(&opts.plain, "plain", false, "Screen reader friendly output with words instead of emoji and no colors, the same as --theme plain")
+	fs.StringVar(&opts.events, "events", "", "Write progress events as JSON lines to this `file` (- for stderr)")
+	fs.BoolVar(&opts.gitFallback, "git-fallback", false, "Resolve actions with git ls-remote once the API rate limit is exhausted")
+	fs.BoolVar(&opts.includePrereleases, "include-prereleases", false, "Consider release candidates, betas and other pre-releases as the latest version")
+	fs.StringVar(&opts.strategy, "strategy", "", "Only propose updates within this semver `distance` of the current version: "+strings.Join(strategyNames, ", "))
+	fs.StringVar(&opts.minReleaseAge, "min-release-age", "", "Hold back releases younger than this `age`, e.g. 7d, as pending cool-off")
+	registerGitHubAPIFlags(fs)
+}
+
+// loadCommandConfig loads the configuration referenced by the command options
+func loadCommandConfig(opts *commandOptions) (*Config, error) {
+	explicit := opts.configPath != defaultConfigFile
+	return loadConfig(opts.configPath, explicit)
+}
+
+// mustLoadScanConfig loads the configuration and scan options for a command,
+// exiting the process on invalid input
+func mustLoadScanConfig(opts *commandOptions) (*Config, ScanOptions) {
+	cfg, err := loadCommandConfig(opts)
+	if err != nil {
+		fmt.Fprintf(console, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	logger.Debug("loaded configuration", "file", opts.configPath, "actions", len(cfg.Actions))
+
+	parseLimits = cfg.Limits
+	pinComment = mustPinCommentFormat(cfg.pinCommentFormat())
+	// Validated when the configuration was loaded
+	ruleSeverities, _ = resolveRuleSeverities(cfg.Rules)
+
+	// The flags can only enable the fallback and pre-releases
+	if opts.gitFallback {
+		cfg.GitFallback = true
+	}
+	if opts.includePrereleases {
+		cfg.IncludePrereleases = true
+	}
+	if opts.strategy != "" {
+		if _, err := parseUpdateStrategy(opts.strategy); err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.Strategy = opts.strategy
+	}
+	if opts.minReleaseAge != "" {
+		if _, err := parseAge(opts.minReleaseAge); err != nil {
+			fmt.Fprintf(console, "Error: invalid --min-release-age: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.MinReleaseAge = opts.minReleaseAge
+	}
+
+	// The flag wins over the config file
+	plainFlag := ""
+	if opts.plain {
+		plainFlag = plainThemeName
+	}
+	if err := selectTheme(plainFlag, opts.theme, cfg.Theme); err != nil {
+		fmt.Fprintf(console, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if plainOutput() {
+		console = plainWriter{w: console}
+	}
+
+	if opts.events != "" {
+		if err := openEventStream(opts.events); err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	scanOpts, err := opts.scanOptions(cfg)
+	if err != nil {
+		fmt.Fprintf(console, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	return cfg, scanOpts
+}
+
+// scanOptions merges command line filters with the configuration file.
+// Flags given on the command line take precedence over the config file.
+func (opts *commandOptions) scanOptions(cfg *Config) (ScanOptions, error) {
+	scanOpts := ScanOptions{
+		Include:       cfg.Include,
+		Exclude:       cfg.Exclude,
+		ActionPaths:   cfg.actionPaths(),
+		TemplatePaths: cfg.templatePaths(),
+		ChangedOnly:   opts.changedOnly,
+		BaseRef:       opts.baseRef,
+	}
+	// Staged and uncommitted changes are the changes relative to HEAD
+	if opts.changed {
+		scanOpts.ChangedOnly = true
+		scanOpts.BaseRef = "HEAD"
+	}
+	if len(opts.include) > 0 {
+		scanOpts.Include = opts.include
+	}
+	if len(opts.exclude) > 0 {
+		scanOpts.Exclude = opts.exclude
+	}
+
+	if err := validateGlobs(scanOpts.Include); err != nil {
+		return ScanOptions{}, fmt.Errorf("invalid --include pattern: %w", err)
+	}
+	if err := validateGlobs(scanOpts.Exclude); err != nil {
+		return ScanOptions{}, fmt.Errorf("invalid --exclude pattern: %w", err)
+	}
+
+	return scanOpts, nil
+}
+
 // GitHubClient wraps the GitHub API client with additional functionality
 type GitHubClient struct {
-	client *github.Client
-	ctx    context.Context
+	api githubAPI
+	ctx context.Context
+
+	// tokenKind determines the quota the requests are budgeted against
+	tokenKind TokenKind
+
+	// redirects records the repositories that were renamed or transferred
+	redirects *redirectLog
 }
 
 // NewGitHubClient creates a new GitHub client with optional authentication
 func NewGitHubClient() *GitHubClient {
 	ctx := context.Background()
-	var client *github.Client
+
+	var transport http.RoundTripper = newRetryTransport(newETagTransport(loggingTransport{}))
+	if githubAPIVersion != "" {
+		transport = apiVersionTransport{base: transport, version: githubAPIVersion}
+	}
+	if maxAPICalls > 0 {
+		transport = budgetTransport{base: transport}
+	}
 
 	// Try to use GitHub token from environment
-	if token, source := getGitHubToken(); token != "" {
+	httpClient := &http.Client{Transport: transport}
+	token, source := getGitHubToken()
+	kind := tokenKindOf(token)
+	if token != "" {
 		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-		tc := oauth2.NewClient(ctx, ts)
-		client = github.NewClient(tc)
+		httpClient = oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, httpClient), ts)
+		logger.Info("authenticated with GitHub", "source", source, "token", kind)
 
-		// Show green status indicator for authenticated access
-		fmt.Printf("🟢 GitHub API: \033[32mAuthenticated\033[0m via %s (higher rate limits available)\n", source)
+		// Show the authenticated status indicator
+		fmt.Fprintf(console, "%s GitHub API: %s via %s (%s, %d requests/hour)\n", theme.Authenticated, theme.Success("Authenticated"), source,
+			kind.Label(), kind.hourlyLimit())
 	} else {
-		client = github.NewClient(nil)
-		fmt.Printf("🟡 GitHub API: \033[33mUnauthenticated\033[0m (lower rate limits)\n")
-		fmt.Println("   Set GITHUB_TOKEN or GH_TOKEN environment variable, or authenticate with 'gh auth login'.")
+		logger.Info("no GitHub token found, using unauthenticated requests")
+		fmt.Fprintf(console, "%s GitHub API: %s (lower rate limits)\n", theme.Unauthenticated, theme.Attention("Unauthenticated"))
+		fmt.Fprintln(console, "   Set GITHUB_TOKEN or GH_TOKEN environment variable, or authenticate with 'gh auth login'.")
+	}
+
+	redirects := newRedirectLog()
+	httpClient.CheckRedirect = redirects.checkRedirect
+
+	api, err := newGoGitHubAPI(httpClient)
+	if err != nil {
+		fmt.Fprintf(console, "Error: %v\n", err)
+		os.Exit(1)
 	}
 
 	return &GitHubClient{
-		client: client,
-		ctx:    ctx,
+		api:       api,
+		ctx:       ctx,
+		tokenKind: kind,
+		redirects: redirects,
 	}
 }
 
-// getGitHubToken retrieves GitHub token from environment variables or gh CLI
+// getGitHubToken retrieves GitHub token from environment variables, gh CLI, or credential stores
 func getGitHubToken() (string, string) {
 	// Try environment variables first
 	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
@@ -99,6 +513,14 @@ func getGitHubToken() (string, string) {
 		return token, "gh CLI"
 	}
 
+	// Fall back to the OS credential store and git credential helpers
+	if token := getTokenFromOSStore(); token != "" {
+		return token, osCredentialSource
+	}
+	if token := getTokenFromGitCredential(); token != "" {
+		return token, "git credential helper"
+	}
+
 	return "", ""
 }
 
@@ -120,14 +542,79 @@ func getTokenFromGHCLI() string {
 }
 
 // GetLatestRelease fetches the latest release for a repository
-func (gc *GitHubClient) GetLatestRelease(owner, repo string) (*github.RepositoryRelease, error) {
-	release, _, err := gc.client.Repositories.GetLatestRelease(gc.ctx, owner, repo)
+func (gc *GitHubClient) GetLatestRelease(owner, repo string) (*githubRelease, error) {
+	release, err := gc.api.LatestRelease(gc.ctx, owner, repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest release for %s/%s: %w", owner, repo, err)
 	}
 	return release, nil
 }
 
+// GetNewestRelease returns the most recently created published release.
+// Pre-releases, whether flagged or named like one, are skipped unless
+// prereleases is set; drafts are never eligible.
+func (gc *GitHubClient) GetNewestRelease(owner, repo string, prereleases bool) (*githubRelease, error) {
+	for page := 1; page != 0; {
+		releases, next, err := gc.api.ListReleases(gc.ctx, owner, repo, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list releases for %s/%s: %w", owner, repo, err)
+		}
+		for i := range releases {
+			if eligibleRelease(&releases[i], prereleases) {
+				return &releases[i], nil
+			}
+		}
+		page = next
+	}
+	return nil, newResolveError(ErrorKindNotFound, "no eligible release of %s/%s", owner, repo)
+}
+
+// eligibleRelease reports whether a release can be selected as the latest version
+func eligibleRelease(release *githubRelease, prereleases bool) bool {
+	if release.Draft {
+		return false
+	}
+	return prereleases || (!release.Prerelease && !isPrereleaseTag(release.Tag))
+}
+
+// GetLatestMatchingTag returns the tag of the most recent published release whose
+// tag matches filter, along with the release. When no release matches, the
+// repository tags are searched instead so that tag-only projects can still be
+// tracked; the returned release is nil in that case.
+func (gc *GitHubClient) GetLatestMatchingTag(owner, repo string, filter *regexp.Regexp, prereleases bool) (string, *githubRelease, error) {
+	for page := 1; page != 0; {
+		releases, next, err := gc.api.ListReleases(gc.ctx, owner, repo, page)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to list releases for %s/%s: %w", owner, repo, err)
+		}
+		for i := range releases {
+			release := &releases[i]
+			if !eligibleRelease(release, prereleases) {
+				continue
+			}
+			if filter.MatchString(release.Tag) {
+				return release.Tag, release, nil
+			}
+		}
+		page = next
+	}
+
+	for page := 1; page != 0; {
+		tags, next, err := gc.api.ListTags(gc.ctx, owner, repo, page)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to list tags for %s/%s: %w", owner, repo, err)
+		}
+		for _, tag := range tags {
+			if filter.MatchString(tag.Name) && (prereleases || !isPrereleaseTag(tag.Name)) {
+				return tag.Name, nil, nil
+			}
+		}
+		page = next
+	}
+
+	return "", nil, newResolveError(ErrorKindTagFormat, "no release or tag of %s/%s matches filter %q", owner, repo, filter.String())
+}
+
 // ResolveSHA resolves a tag or branch to its commit SHA
 func (gc *GitHubClient) ResolveSHA(owner, repo, ref string) (string, error) {
 	// Special handling for CodeQL action bundle tags
@@ -136,78 +623,69 @@ func (gc *GitHubClient) ResolveSHA(owner, repo, ref string) (string, error) {
 	}
 
 	// Try to get tag first
-	gitRef, _, err := gc.client.Git.GetRef(gc.ctx, owner, repo, "tags/"+ref)
-	if err == nil && gitRef.Object != nil {
-		if gitRef.Object.GetType() == "tag" {
+	gitRef, err := gc.api.GetRef(gc.ctx, owner, repo, "tags/"+ref)
+	if err == nil {
+		if gitRef.Type == "tag" {
 			// Dereference annotated tag
-			tag, _, tagErr := gc.client.Git.GetTag(gc.ctx, owner, repo, gitRef.Object.GetSHA())
-			if tagErr == nil && tag.Object != nil {
-				return tag.Object.GetSHA(), nil
+			if sha, tagErr := gc.api.GetTagTarget(gc.ctx, owner, repo, gitRef.SHA); tagErr == nil {
+				return sha, nil
 			}
 		}
-		return gitRef.Object.GetSHA(), nil
+		return gitRef.SHA, nil
+	}
+	// A rate limit or network failure would fail the branch lookup too
+	if kind := errorKindOf(err); kind == ErrorKindRateLimited || kind == ErrorKindNetwork {
+		return "", &ResolveError{Kind: kind, Err: fmt.Errorf("could not resolve ref %s for %s/%s: %w", ref, owner, repo, err)}
 	}
 
 	// Try branch if tag fails
-	gitRef, _, err = gc.client.Git.GetRef(gc.ctx, owner, repo, "heads/"+ref)
-	if err == nil && gitRef.Object != nil {
-		return gitRef.Object.GetSHA(), nil
+	gitRef, err = gc.api.GetRef(gc.ctx, owner, repo, "heads/"+ref)
+	if err == nil {
+		return gitRef.SHA, nil
 	}
 
-	return "", fmt.Errorf("could not resolve ref %s for %s/%s", ref, owner, repo)
+	// Surface rate limit, permission and network failures as such rather
+	// than reporting the ref as missing
+	if kind := errorKindOf(err); kind != ErrorKindNotFound && kind != ErrorKindUnknown {
+		return "", &ResolveError{Kind: kind, Err: fmt.Errorf("could not resolve ref %s for %s/%s: %w", ref, owner, repo, err)}
+	}
+
+	return "", newResolveError(ErrorKindNotFound, "could not resolve ref %s for %s/%s", ref, owner, repo)
 }
 
 // parseWorkflowFile parses a workflow file and extracts GitHub Actions
 func parseWorkflowFile(filename string) ([]ActionInfo, error) {
+	if err := checkFileSize(filename); err != nil {
+		return nil, err
+	}
+
 	content, err := os.ReadFile(filepath.Clean(filename))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read workflow file %s: %w", filename, err)
 	}
 
-	var actions []ActionInfo
-	lines := strings.Split(string(content), "\n")
-
-	// Regex to match uses: statements
-	usesRegex := regexp.MustCompile(`^\s*uses:\s+([^@]+)@([a-f0-9]{40}|[^#\s]+)(?:\s*#\s*([^\s]+))?`)
-
-	for i, line := range lines {
-		matches := usesRegex.FindStringSubmatch(line)
-		if matches != nil {
-			repo := matches[1]
-			currentRef := matches[2]
-			// comment := "" // Available for future use
-			// if len(matches) > 3 {
-			// 	comment = matches[3]
-			// }
-
-			// Determine current SHA (if ref is already a SHA)
-			currentSHA := ""
-			if shaRegex.MatchString(currentRef) {
-				currentSHA = currentRef
-			}
-
-			actions = append(actions, ActionInfo{
-				Repo:         repo,
-				CurrentRef:   currentRef,
-				CurrentSHA:   currentSHA,
-				Line:         i + 1,
-				OriginalLine: line,
-				WorkflowFile: filename,
-			})
-		}
-	}
-
-	return actions, nil
+	return parseWorkflowContent(filename, string(content))
 }
 
-// scanWorkflows scans all workflow files and extracts GitHub Actions
-func scanWorkflows() (WorkflowActions, error) {
+// scanWorkflows scans all workflow files and local composite actions matching
+// the scan options and extracts GitHub Actions. YAML files that contribute no
+// actions are returned as an inventory of skipped files.
+func scanWorkflows(opts ScanOptions) (WorkflowActions, []SkippedFile, error) {
 	workflowActions := make(WorkflowActions)
 
-	workflowDir := ".github/workflows"
+	// Composite actions run uses: steps too, including the repository's own
+	// action when it is one
+	actionFiles := append(rootActionFiles(), findActionFiles(opts.ActionPaths)...)
+
+	// A repository holding only an action may have no workflows at all
 	entries, err := os.ReadDir(workflowDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read workflow directory: %w", err)
+	if err != nil && (!errors.Is(err, os.ErrNotExist) || len(actionFiles) == 0) {
+		return nil, nil, fmt.Errorf("failed to read workflow directory: %w", err)
+	}
+
+	skipped := findOutOfScopeFiles()
+	if err := checkSubdirectoryGlobs(opts, skipped); err != nil {
+		return nil, nil, err
 	}
 
 	for _, entry := range entries {
@@ -216,109 +694,512 @@ func scanWorkflows() (WorkflowActions, error) {
 		}
 
 		filename := entry.Name()
-		if !strings.HasSuffix(filename, ".yml") && !strings.HasSuffix(filename, ".yaml") {
+		if !isYAMLFile(filename) {
 			continue
 		}
 
 		fullPath := filepath.Join(workflowDir, filename)
-		actions, err := parseWorkflowFile(fullPath)
-		if err != nil {
-			fmt.Printf("Warning: Failed to parse %s: %v\n", fullPath, err)
+		skipped = scanFile(workflowActions, skipped, filename, fullPath, opts)
+	}
+
+	for _, actionFile := range actionFiles {
+		skipped = scanFile(workflowActions, skipped, filepath.ToSlash(actionFile), actionFile, opts)
+	}
+	for _, templateFile := range findTemplateFiles(opts.TemplatePaths) {
+		if _, ok := workflowActions[templateFile]; ok {
+			continue
+		}
+		skipped = scanFile(workflowActions, skipped, filepath.ToSlash(templateFile), templateFile, opts)
+	}
+
+	sortSkipped(skipped)
+	return workflowActions, skipped, nil
+}
+
+// checkSubdirectoryGlobs rejects include and exclude patterns that name files
+// in subdirectories of the workflow directory. Those are not scanned, so the
+// pattern would silently select nothing.
+func checkSubdirectoryGlobs(opts ScanOptions, outOfScope []SkippedFile) error {
+	for _, file := range outOfScope {
+		if file.Reason != reasonSubdirectory {
+			continue
+		}
+		fullPath := filepath.ToSlash(file.Path)
+		relPath := strings.TrimPrefix(fullPath, workflowDir+"/")
+		for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+			// Bare file name patterns also match workflows in the directory itself
+			if !strings.Contains(pattern, "/") {
+				continue
+			}
+			relMatch, _ := path.Match(pattern, relPath)
+			fullMatch, _ := path.Match(pattern, fullPath)
+			if relMatch || fullMatch {
+				return fmt.Errorf("pattern %q matches %s, but subdirectories of %s are not scanned because GitHub only runs workflows directly in it", pattern, fullPath, workflowDir)
+			}
+		}
+	}
+	return nil
+}
+
+// scanFile parses one file found by a directory scan into workflowActions,
+// recording it in the skipped inventory when it contributes no actions
+func scanFile(workflowActions WorkflowActions, skipped []SkippedFile, relPath, fullPath string, opts ScanOptions) []SkippedFile {
+	if !opts.shouldScan(relPath, fullPath) {
+		return append(skipped, SkippedFile{Path: fullPath, Reason: reasonFiltered})
+	}
+
+	actions, err := parseWorkflowFile(fullPath)
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		logger.Warn("skipped workflow exceeding limits", "file", fullPath, "reason", limitErr.Reason())
+		return append(skipped, SkippedFile{Path: fullPath, Reason: reasonLimits + limitErr.Reason()})
+	}
+	if err != nil {
+		logger.Warn("failed to parse workflow", "file", fullPath, "error", err)
+		return append(skipped, SkippedFile{Path: fullPath, Reason: fmt.Sprintf("parse error: %v", err)})
+	}
+
+	if len(actions) == 0 {
+		return append(skipped, SkippedFile{Path: fullPath, Reason: reasonNoActions})
+	}
+	workflowActions[fullPath] = actions
+	return skipped
+}
+
+// scanFiles parses explicitly named workflow files, applying the same
+// include/exclude filters as a directory scan
+func scanFiles(files []string, opts ScanOptions) (WorkflowActions, []SkippedFile, error) {
+	workflowActions := make(WorkflowActions)
+	var skipped []SkippedFile
+
+	for _, file := range files {
+		relPath := filepath.ToSlash(file)
+		if rel, err := filepath.Rel(workflowDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			relPath = filepath.ToSlash(rel)
+		}
+		if !opts.shouldScan(relPath, file) {
+			skipped = append(skipped, SkippedFile{Path: file, Reason: reasonFiltered})
 			continue
 		}
 
+		actions, err := parseWorkflowFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		if len(actions) > 0 {
-			workflowActions[fullPath] = actions
+			workflowActions[file] = actions
+		} else {
+			skipped = append(skipped, SkippedFile{Path: file, Reason: reasonNoActions})
 		}
 	}
 
-	return workflowActions, nil
+	sortSkipped(skipped)
+	return workflowActions, skipped, nil
 }
 
-// checkForUpdates checks if actions have newer versions available
-func checkForUpdates(gc *GitHubClient, actions WorkflowActions) {
-	fmt.Println("Checking for action updates...")
+// scanTargets scans the named files, or the whole workflow directory when none
+// are given. With ChangedOnly set, only changed files are scanned; named files
+// further narrow that set.
+func scanTargets(files []string, opts ScanOptions) (WorkflowActions, []SkippedFile, error) {
+	if opts.ChangedOnly {
+		changed, err := changedWorkflowFiles(opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list changed workflow files: %w", err)
+		}
+		if len(files) > 0 {
+			changed = intersectFiles(changed, files)
+		}
+		fmt.Fprintf(console, "📝 %d workflow file(s) changed relative to %s\n", len(changed), opts.BaseRef)
+		return scanFiles(changed, opts)
+	}
 
-	for workflow, actionList := range actions {
-		fmt.Printf("\n📁 %s:\n", workflow)
+	if len(files) > 0 {
+		return scanFiles(files, opts)
+	}
+	return scanWorkflows(opts)
+}
 
-		for i := range actionList {
-			action := &actionList[i]
+// intersectFiles returns the files in a that also appear in b, comparing cleaned paths
+func intersectFiles(a, b []string) []string {
+	wanted := make(map[string]bool, len(b))
+	for _, file := range b {
+		wanted[filepath.Clean(file)] = true
+	}
 
-			// Parse owner/repo from action repo
-			parts := strings.Split(action.Repo, "/")
-			if len(parts) < 2 {
-				fmt.Printf("  ⚠️  Invalid repo format: %s\n", action.Repo)
-				continue
+	var result []string
+	for _, file := range a {
+		if wanted[filepath.Clean(file)] {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// resolveUpdateTargets turns the arguments of update into files. Bare names
+// refer to workflows and composite actions are named by path, absolute paths
+// are made relative to the repository, and globs such as "deploy-*.yml" are
+// expanded with the same prefixing rule.
+func resolveUpdateTargets(args []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, target := range args {
+		if filepath.IsAbs(target) {
+			wd, err := os.Getwd()
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine repository root: %w", err)
+			}
+			rel, err := filepath.Rel(wd, target)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return nil, fmt.Errorf("%s is outside the repository", target)
 			}
+			target = filepath.ToSlash(rel)
+		}
+		if !strings.HasPrefix(target, workflowDir+"/") && !isActionFile(target) {
+			target = workflowDir + "/" + target
+		}
 
-			owner := parts[0]
-			repo := parts[1]
+		matches := []string{target}
+		if strings.ContainsAny(target, "*?[") {
+			var err error
+			matches, err = filepath.Glob(target)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", target, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", target)
+			}
+		}
+		for _, file := range matches {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
 
-			// For sub-actions (like github/codeql-action/upload-sarif), use the main repo
-			if len(parts) > 2 && owner == "github" && repo == codeQLAction {
-				// Keep the original repo path but fetch from main repo
-				repo = codeQLAction
+// filterJobs keeps the references in the workflow jobs matching one of the
+// patterns, which may be globs such as "deploy-*"
+func filterJobs(actions WorkflowActions, patterns []string) WorkflowActions {
+	filtered := make(WorkflowActions)
+	for workflow, actionList := range actions {
+		for _, action := range actionList {
+			for _, pattern := range patterns {
+				if matched, err := path.Match(pattern, action.Job); err == nil && matched && action.Job != "" {
+					filtered[workflow] = append(filtered[workflow], action)
+					break
+				}
 			}
+		}
+	}
+	return filtered
+}
 
-			fmt.Printf("  🔍 Checking %s...", action.Repo)
+// checkForUpdates checks if actions have newer versions available
+func checkForUpdates(forge Forge, actions WorkflowActions, cfg *Config) {
+	fmt.Fprintln(console, "Checking for action updates...")
 
-			// Get latest release
-			release, err := gc.GetLatestRelease(owner, repo)
-			if err != nil {
-				fmt.Printf(" ❌ Error: %v\n", err)
-				continue
-			}
+	// Container actions share one registry client so tokens are reused
+	var registry *registryClient
 
-			action.LatestTag = release.GetTagName()
+	for _, workflow := range sortedWorkflows(actions) {
+		actionList := actions[workflow]
+		fmt.Fprintf(console, "\n📁 %s:\n", workflow)
 
-			// Resolve SHA for latest tag
-			sha, err := gc.ResolveSHA(owner, repo, action.LatestTag)
-			if err != nil {
-				fmt.Printf(" ❌ Error resolving SHA: %v\n", err)
+		for i := range actionList {
+			action := &actionList[i]
+
+			if !action.Docker && budgetExhausted() {
+				action.Error = classifyError(errBudgetExhausted())
+				fmt.Fprintf(console, "  %s %s: skipped, API budget exhausted\n", theme.Skipped, action.Repo)
 				continue
 			}
 
-			action.LatestSHA = sha
-
-			// Check if update is needed
-			if action.CurrentSHA == "" {
-				// Current ref is not a SHA, resolve it
-				currentSHA, err := gc.ResolveSHA(owner, repo, action.CurrentRef)
-				if err != nil {
-					fmt.Printf(" ❌ Error resolving current SHA: %v\n", err)
-					continue
+			if action.Docker {
+				fmt.Fprintf(console, "  🔍 Checking image %s...", strings.TrimPrefix(action.Repo, dockerPrefix))
+				if registry == nil {
+					registry = newRegistryClient()
 				}
-				action.CurrentSHA = currentSHA
+				reportResolved(workflow, action, resolveImageAction(registry, action))
+				continue
+			}
+
+			// Sub-actions such as gradle/actions/setup-gradle are looked up in
+			// their repository; the path stays in the uses: value
+			owner, repo, _ := splitActionPath(action.Repo)
+			if owner == "" || repo == "" {
+				fmt.Fprintf(console, "  %s Invalid repo format: %s\n", theme.Warning, action.Repo)
+				continue
 			}
 
-			if action.CurrentSHA != action.LatestSHA {
-				action.NeedsUpdate = true
-				fmt.Printf(" 🔄 Update available: %s → %s\n", action.CurrentRef, action.LatestTag)
+			if action.ReusableWorkflow {
+				fmt.Fprintf(console, "  🔍 Checking reusable workflow %s...", action.Repo)
 			} else {
-				fmt.Printf(" ✅ Up to date (%s)\n", action.LatestTag)
+				fmt.Fprintf(console, "  🔍 Checking %s...", action.Repo)
 			}
+
+			reportResolved(workflow, action, resolveAction(forge, action, owner, repo, cfg))
 		}
 
 		// Update the slice in the map
 		actions[workflow] = actionList
 	}
+
+	if memo, ok := forge.(*memoForge); ok && memo.repeated() > 0 {
+		fmt.Fprintf(console, "\n♻️  %d repeated lookup(s) of actions used more than once were answered without an API request\n", memo.repeated())
+	}
+	if unchanged := unchangedResponses.Load(); unchanged > 0 {
+		fmt.Fprintf(console, "♻️  %d unchanged response(s) were revalidated without counting against the rate limit\n", unchanged)
+	}
+
+	if cfg != nil && cfg.PinDatabase != "" && ruleEnabled(ruleTagMoved) {
+		checkPinDatabase(cfg, actions)
+	}
 }
 
-// promptForConfirmation asks user for confirmation
-func promptForConfirmation(message string) bool {
-	fmt.Printf("%s (y/N): ", message)
+// reportResolved records and prints the outcome of resolving an action
+func reportResolved(workflow string, action *ActionInfo, err error) {
+	if err != nil {
+		action.Error = classifyError(err)
+		printResolveError(action.Error)
+		emitEvent(actionEvent(EventError, action))
+		logger.Info("failed to resolve action", "workflow", workflow, "repo", action.Repo, "kind", action.Error.Kind, "error", action.Error)
+		return
+	}
+	emitEvent(actionEvent(EventActionResolved, action))
+	logger.Debug("resolved action", "workflow", workflow, "repo", action.Repo, "current", action.CurrentRef,
+		"latest", action.LatestTag, "sha", action.LatestSHA, "needs_update", action.NeedsUpdate)
+
+	switch {
+	case action.NeedsUpdate:
+		fmt.Fprintf(console, " %s Update available: %s → %s\n", theme.Update, action.CurrentRef, action.LatestTag)
+	case action.CoolingOff != nil:
+		fmt.Fprintf(console, " ⏳ Pending cool-off: %s\n", describeCoolOff(action.CoolingOff))
+	default:
+		fmt.Fprintf(console, " %s Up to date (%s)\n", theme.OK, action.LatestTag)
+	}
+	if action.Abbreviated {
+		fmt.Fprintf(console, "     %s Abbreviated SHA %s is %s; run update --expand-short to pin the full SHA\n",
+			theme.Warning, action.CurrentRef, action.CurrentSHA)
+	}
+	if action.MovedTo != "" {
+		fmt.Fprintf(console, "     ↪️  Repository moved, the action is now %s\n", action.MovedTo)
+	}
+}
+
+// resolveAction looks up the latest version of an action and its current SHA
+func resolveAction(forge Forge, action *ActionInfo, owner, repo string, cfg *Config) error {
+	// Sub-actions share the settings of their repository so they cannot diverge
+	configKey := action.Repo
+	if group := builtinActionGroup(action.Repo); group != "" {
+		configKey = group
+	}
+
+	// Get latest release, honoring any configured tag filter
+	var release *Release
+	fromTags := false
+	prereleases := cfg.includePrereleases(configKey)
+	if filter := cfg.actionConfig(configKey).tagFilter; filter != nil {
+		tag, matched, err := forge.LatestMatchingTag(owner, repo, filter, prereleases)
+		if err != nil {
+			return err
+		}
+		action.LatestTag = tag
+		release = matched
+	} else {
+		latest, err := forge.LatestRelease(owner, repo, prereleases)
+		if errorKindOf(err) == ErrorKindNotFound {
+			// Projects that only push tags have no latest release
+			tag, tagErr := forge.HighestTag(owner, repo, cfg.tagFallbackFilter())
+			if tagErr != nil {
+				return fmt.Errorf("%w; %w", err, tagErr)
+			}
+			latest, err = &Release{Tag: tag}, nil
+			fromTags = true
+		}
+		if err != nil {
+			return err
+		}
+		action.LatestTag = latest.Tag
+		release = latest
+	}
+
+	action.Provenance = releaseProvenance(forge, configKey, action.LatestTag, cfg)
+	if fromTags {
+		action.Provenance.Reason = PinHighestTag
+	}
+
+	// A release beyond the allowed distance gives way to the newest tag
+	// within it, or to the current version when there is none
+	if filter := cfg.strategyFilter(*action); filter != nil && !filter.MatchString(action.LatestTag) {
+		tag, matched, err := forge.LatestMatchingTag(owner, repo, filter, prereleases)
+		switch {
+		case errorKindOf(err) == ErrorKindTagFormat:
+			tag, matched = currentVersion(*action), nil
+		case err != nil:
+			return err
+		}
+		action.LatestTag = tag
+		release = matched
+		action.Provenance = &Provenance{
+			Reason:     PinUpdateStrategy,
+			Tag:        tag,
+			Constraint: filter.String(),
+			Setting:    "strategy " + string(cfg.updateStrategy()),
+			Source:     forge.Name(),
+		}
+	}
+
+	// Immutable releases cannot be moved or swapped after they are pinned,
+	// so one is preferred over a newer mutable release when asked for
+	if setting := cfg.immutableSetting(configKey); setting != "" && (release == nil || !release.Immutable) {
+		if immutable := newestImmutable(forge, *action, owner, repo, configKey, prereleases, cfg); immutable != nil {
+			action.LatestTag = immutable.Tag
+			release = immutable
+			action.Provenance = &Provenance{Reason: PinImmutable, Tag: immutable.Tag, Setting: setting, Source: forge.Name()}
+		}
+	}
 
-	reader := bufio.NewReader(os.Stdin)
-	response, err := reader.ReadString('\n')
+	if release != nil {
+		action.LatestImmutable = release.Immutable
+		action.ReleaseURL = release.URL
+		action.ReleaseNotes = release.Notes
+		action.ReleaseDate = release.Date
+	}
+
+	// Resolve SHA for latest tag
+	sha, err := forge.ResolveRef(owner, repo, action.LatestTag)
 	if err != nil {
-		return false
+		return fmt.Errorf("failed to resolve SHA for %s: %w", action.LatestTag, err)
+	}
+
+	action.LatestSHA = sha
+
+	// Abbreviated SHAs name a commit, unless a tag or branch happens to be
+	// spelled like one
+	if action.CurrentSHA == "" && shortSHARegex.MatchString(action.CurrentRef) {
+		fullSHA, err := forge.ExpandSHA(owner, repo, action.CurrentRef)
+		switch {
+		case err == nil:
+			action.CurrentSHA = fullSHA
+			action.Abbreviated = true
+		case errorKindOf(err) != ErrorKindNotFound:
+			return fmt.Errorf("failed to expand %s: %w", action.CurrentRef, err)
+		}
 	}
 
-	response = strings.TrimSpace(strings.ToLower(response))
+	// Check if update is needed
+	if action.CurrentSHA == "" {
+		// Current ref is not a SHA, resolve it
+		currentSHA, err := forge.ResolveRef(owner, repo, action.CurrentRef)
+		if err != nil {
+			return fmt.Errorf("failed to resolve current SHA: %w", err)
+		}
+		action.CurrentSHA = currentSHA
+	}
+
+	action.NeedsUpdate = action.CurrentSHA != action.LatestSHA
+	if action.NeedsUpdate {
+		action.BreakingChanges = findBreakingMarkers(action.ReleaseNotes)
+	}
+	if minAge, setting := cfg.minReleaseAge(configKey); minAge > 0 {
+		holdForCoolOff(action, action.ReleaseDate, minAge, setting, forge.Name(), time.Now())
+	}
+	noteMove(forge, action, owner, repo)
+	return nil
+}
+
+// printResolveError prints a classified resolver error with its hint
+func printResolveError(err *ResolveError) {
+	fmt.Fprintf(console, " %s %s: %v\n", theme.Error, err.Label(), err)
+	if hint := err.Hint(); hint != "" {
+		fmt.Fprintf(console, "     💡 %s\n", hint)
+	}
+}
+
+// promptForConfirmation asks user for confirmation
+func promptForConfirmation(message string) bool {
+	fmt.Fprintf(console, "%s (y/N): ", message)
+	if autoApprove {
+		fmt.Fprintln(console, "y")
+		return true
+	}
+
+	response := readResponse()
 	return response == "y" || response == "yes"
 }
 
+// readResponse reads one line of user input, lowercased and trimmed
+func readResponse() string {
+	return strings.ToLower(readLine())
+}
+
+// readLine reads one line of user input, trimmed. A shared reader is used so
+// that buffered input is not lost between prompts.
+func readLine() string {
+	response, err := stdinReader.ReadString('\n')
+	if err != nil && response == "" {
+		return ""
+	}
+	return strings.TrimSpace(response)
+}
+
+// selectUpdates asks which of the pending updates in a workflow file to
+// apply: all of them, none, or an individual selection. When selecting, a
+// tag can be typed to pin that version instead of the latest release.
+// Nothing is written until every decision for the file has been made.
+func selectUpdates(forge Forge, workflow string, actionList []ActionInfo) []ActionInfo {
+	var pending []ActionInfo
+	for _, action := range actionList {
+		if action.NeedsUpdate {
+			pending = append(pending, action)
+		}
+	}
+
+	fmt.Fprintf(console, "Update %s? (y = all, s = select individually, N = skip): ", workflow)
+	if autoApprove {
+		fmt.Fprintln(console, "y")
+		var approved []ActionInfo
+		for _, action := range pending {
+			if !heldForReview(action, "  ") {
+				approved = append(approved, action)
+			}
+		}
+		return approved
+	}
+	switch readResponse() {
+	case "y", "yes":
+		return pending
+	case "s", "select":
+		var selected []ActionInfo
+		var registry *registryClient
+		for _, action := range pending {
+			fmt.Fprintf(console, "  Apply %s %s → %s (line %d)? (y/N, or a tag to pin instead): ", action.Repo, action.CurrentRef, action.LatestTag, action.Line)
+			switch answer := readLine(); strings.ToLower(answer) {
+			case "y", "yes":
+				selected = append(selected, action)
+			case "", "n", "no":
+				fmt.Fprintf(console, "  %s Held %s at %s\n", theme.Skipped, action.Repo, action.CurrentRef)
+			default:
+				if action.Docker && registry == nil {
+					registry = newRegistryClient()
+				}
+				if err := pinRequestedVersion(forge, registry, &action, answer); err != nil {
+					fmt.Fprintf(console, "  %s Held %s at %s: %v\n", theme.Error, action.Repo, action.CurrentRef, classifyError(err))
+					continue
+				}
+				fmt.Fprintf(console, "  %s %s → %s (%s)\n", theme.Update, action.Repo, action.LatestTag, shortPin(action.LatestSHA))
+				selected = append(selected, action)
+			}
+		}
+		return selected
+	default:
+		return nil
+	}
+}
+
 // updateWorkflowFile updates a workflow file with new action versions
 // This function is idempotent - it can be called multiple times safely
 // and will only make changes when actually needed
@@ -328,64 +1209,129 @@ func updateWorkflowFile(filename string, actions []ActionInfo) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	lines := strings.Split(string(content), "\n")
-
-	// Check if any updates are actually needed (idempotent check)
-	hasActualUpdates := false
-	for _, action := range actions {
-		if !action.NeedsUpdate {
-			continue
-		}
-
-		lineIndex := action.Line - 1
-		if lineIndex >= len(lines) {
-			continue
-		}
-
-		// Check if the line already has the target SHA
-		currentLine := lines[lineIndex]
-		expectedLine := regexp.MustCompile(`@[a-f0-9]{40}|@[^#\s]+`).ReplaceAllString(currentLine, fmt.Sprintf("@%s # %s", action.LatestSHA, action.LatestTag))
-		if currentLine != expectedLine {
-			hasActualUpdates = true
-			break
-		}
-	}
+	newContent, changed := applyActionUpdates(filename, string(content), actions)
 
 	// If no actual updates needed, return early (idempotent behavior)
-	if !hasActualUpdates {
-		fmt.Printf("  ✅ %s: Already up to date, no changes needed\n", filename)
+	if changed == 0 {
+		fmt.Fprintf(console, "  %s %s: Already up to date, no changes needed\n", theme.OK, filename)
 		return nil
 	}
 
-	// Sort actions by line number in reverse order to avoid line number shifting
-	sort.Slice(actions, func(i, j int) bool {
-		return actions[i].Line > actions[j].Line
-	})
+	// Write back to file
+	if err := checkWritePath(filename); err != nil {
+		emitEvent(Event{Type: EventError, Workflow: filename, Error: classifyError(err)})
+		return err
+	}
+	if err := os.WriteFile(filename, []byte(newContent), 0600); err != nil {
+		emitEvent(Event{Type: EventError, Workflow: filename, Error: classifyError(err)})
+		return err
+	}
+	emitEvent(Event{Type: EventFileUpdated, Workflow: filename, Changes: changed})
+	logger.Info("updated workflow", "file", filename, "changes", changed)
+	return nil
+}
+
+// applyActionUpdates rewrites the uses: references of actions that need an
+// update and returns the new content along with the number of references changed
+func applyActionUpdates(filename, content string, actions []ActionInfo) (string, int) {
+	// Splitting on \n keeps each line's \r and whether the file ends with a
+	// newline, and the byte order mark and --- separators are left in place,
+	// so only the rewritten references show up in diffs
+	content, bom := splitBOM(content)
+	lines := strings.Split(content, "\n")
+	changed := 0
+
+	// Every reference per line, so that a flow-style line holding several
+	// steps keeps a comment naming all of their tags
+	onLine := make(map[int][]ActionInfo)
+	if parsed, err := parseWorkflowContent(filename, content); err == nil {
+		for _, action := range parsed {
+			onLine[action.Line] = append(onLine[action.Line], action)
+		}
+	}
 
+	updates := make(map[int][]ActionInfo)
+	var lineNumbers []int
 	for _, action := range actions {
-		if !action.NeedsUpdate {
+		if !action.NeedsUpdate || action.Line-1 >= len(lines) {
 			continue
 		}
+		if _, ok := updates[action.Line]; !ok {
+			lineNumbers = append(lineNumbers, action.Line)
+		}
+		updates[action.Line] = append(updates[action.Line], action)
+	}
+	sort.Ints(lineNumbers)
+
+	marker := pinCommentMarker(content)
+	for _, lineNumber := range lineNumbers {
+		// Replace the references with updated SHAs and tag comment
+		oldLine, cr := splitCarriageReturn(lines[lineNumber-1])
+		newLine, rewritten := pinnedLineWithMarker(oldLine, updates[lineNumber], onLine[lineNumber], marker)
+		for _, action := range updates[lineNumber] {
+			if !containsAction(rewritten, action) {
+				fmt.Fprintf(console, "  %s Skipped line %d: %s could not be rewritten in place, e.g. in a block scalar\n", theme.Skipped, action.Line, action.reference())
+			}
+		}
 
-		lineIndex := action.Line - 1
-		if lineIndex >= len(lines) {
+		// Only update if actually different (idempotent check)
+		if oldLine == newLine {
 			continue
 		}
+		lines[lineNumber-1] = newLine + cr
+		for _, action := range rewritten {
+			changed++
+			// References pinned in place keep their ref, so show the commit
+			target := action.LatestTag
+			if target == action.CurrentRef {
+				target = shortPin(action.LatestSHA)
+			}
+			fmt.Fprintf(console, "  📝 Updated line %d: %s → %s\n", action.Line, action.CurrentRef, target)
+			if action.Rename {
+				fmt.Fprintf(console, "  📝 Moved line %d: %s → %s\n", action.Line, action.Repo, action.MovedTo)
+			}
+			emitEvent(actionEvent(EventUpdatePlanned, &action))
+		}
+	}
 
-		// Replace the line with updated SHA and tag comment
-		oldLine := lines[lineIndex]
-		newLine := regexp.MustCompile(`@[a-f0-9]{40}|@[^#\s]+`).ReplaceAllString(oldLine, fmt.Sprintf("@%s # %s", action.LatestSHA, action.LatestTag))
+	return bom + strings.Join(lines, "\n"), changed
+}
 
-		// Only update if actually different (additional idempotent check)
-		if oldLine != newLine {
-			lines[lineIndex] = newLine
-			fmt.Printf("  📝 Updated line %d: %s → %s\n", action.Line, action.CurrentRef, action.LatestTag)
+// containsAction reports whether actions holds the reference at the
+// position of action
+func containsAction(actions []ActionInfo, action ActionInfo) bool {
+	for _, other := range actions {
+		if other.Line == action.Line && other.Column == action.Column {
+			return true
 		}
 	}
+	return false
+}
 
-	// Write back to file
-	newContent := strings.Join(lines, "\n")
-	return os.WriteFile(filename, []byte(newContent), 0600)
+// createBackups copies each file to a .bak sibling. If any backup fails, the
+// backups created so far are removed.
+func createBackups(files []string) (map[string]string, error) {
+	backupFiles := make(map[string]string)
+	for _, workflow := range files {
+		// Create backup with deterministic name
+		backupFile := workflow + ".bak"
+		err := checkWritePath(workflow)
+		if err == nil {
+			err = copyFile(workflow, backupFile)
+		}
+		if err != nil {
+			// Clean up any backups we've already created
+			for _, existingBackup := range backupFiles {
+				if removeErr := os.Remove(existingBackup); removeErr != nil {
+					logger.Warn("failed to clean up backup", "file", existingBackup, "error", removeErr)
+				}
+			}
+			return nil, fmt.Errorf("failed to create backup for %s: %w", workflow, err)
+		}
+		backupFiles[workflow] = backupFile
+		fmt.Fprintf(console, "  💾 Created backup: %s\n", backupFile)
+	}
+	return backupFiles, nil
 }
 
 // updateActions updates the workflow files with new action versions
@@ -393,17 +1339,15 @@ func updateWorkflowFile(filename string, actions []ActionInfo) error {
 // - Creates backups before any modifications
 // - Rolls back changes if any operation fails
 // - Is idempotent and safe to retry
-func updateActions(actions WorkflowActions, targetWorkflow string) error {
-	fmt.Println("\n🚀 Updating workflow files...")
+//
+// It returns the updates that were written.
+func updateActions(forge Forge, actions WorkflowActions, cfg *Config) ([]ActionInfo, error) {
+	fmt.Fprintln(console, "\n🚀 Updating workflow files...")
 
 	// Collect files that need updates for atomic-like behavior
 	var filesToUpdate []string
-	for workflow, actionList := range actions {
-		// If specific workflow is targeted, skip others
-		if targetWorkflow != "" && workflow != targetWorkflow {
-			continue
-		}
-
+	for _, workflow := range sortedWorkflows(actions) {
+		actionList := actions[workflow]
 		// Check if any actions need updates
 		hasUpdates := false
 		for _, action := range actionList {
@@ -419,33 +1363,74 @@ func updateActions(actions WorkflowActions, targetWorkflow string) error {
 	}
 
 	if len(filesToUpdate) == 0 {
-		fmt.Println("  ✅ No updates needed for any workflow files")
-		return nil
+		fmt.Fprintf(console, "  %s No updates needed for any workflow files\n", theme.OK)
+		return nil, nil
 	}
 
 	// Create all backups first (atomic preparation)
-	backupFiles := make(map[string]string)
-	for _, workflow := range filesToUpdate {
-		// Create backup with deterministic name
-		backupFile := workflow + ".bak"
-		if err := copyFile(workflow, backupFile); err != nil {
-			// Clean up any backups we've already created
-			for _, existingBackup := range backupFiles {
-				if removeErr := os.Remove(existingBackup); removeErr != nil {
-					fmt.Printf("Warning: failed to clean up backup %s: %v\n", existingBackup, removeErr)
-				}
+	backupFiles, err := createBackups(filesToUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []ActionInfo
+
+	// Grouped actions are confirmed and applied as a unit across all files
+	for _, group := range collectGroups(actions, cfg) {
+		if !group.NeedsUpdate() {
+			continue
+		}
+
+		fmt.Fprintf(console, "\n🔗 Group %s:\n", group.Name)
+		for _, member := range group.Members {
+			if member.NeedsUpdate {
+				fmt.Fprintf(console, "  %s %s:%d %s: %s → %s\n", theme.Update, member.WorkflowFile, member.Line, member.Repo, member.CurrentRef, member.LatestTag)
 			}
-			return fmt.Errorf("failed to create backup for %s: %w", workflow, err)
 		}
-		backupFiles[workflow] = backupFile
-		fmt.Printf("  💾 Created backup: %s\n", backupFile)
+		printGroupChangelog(group)
+
+		if blocker := group.Blocker(); blocker != nil {
+			fmt.Fprintf(console, "  %s Skipped group: %s could not be resolved (%s)\n", theme.Skipped, blocker.Repo, blocker.Error.Label())
+			continue
+		}
+
+		// The group moves as a unit, so one member needing review holds it all
+		if slices.ContainsFunc(group.Members, func(member *ActionInfo) bool { return heldForReview(*member, "  ") }) {
+			continue
+		}
+
+		if !promptForConfirmation(fmt.Sprintf("Update group %s?", group.Name)) {
+			fmt.Fprintf(console, "  %s Skipped group %s\n", theme.Skipped, group.Name)
+			continue
+		}
+		for _, member := range group.Members {
+			if member.NeedsUpdate {
+				acceptMove(member, "  ")
+			}
+		}
+
+		if err := applyGroupUpdate(group, backupFiles); err != nil {
+			fmt.Fprintf(console, "  %s Failed to update group, all member files restored: %v\n", theme.Error, err)
+			continue
+		}
+
+		for _, member := range group.Members {
+			if member.NeedsUpdate {
+				applied = append(applied, *member)
+			}
+		}
+		fmt.Fprintf(console, "  %s Updated group %s\n", theme.OK, group.Name)
 	}
 
 	// Now process each workflow with atomic rollback capability
-	for workflow, actionList := range actions {
-		// If specific workflow is targeted, skip others
-		if targetWorkflow != "" && workflow != targetWorkflow {
-			continue
+	for _, workflow := range sortedWorkflows(actions) {
+		allActions := actions[workflow]
+		// Grouped actions were handled above
+		var actionList []ActionInfo
+		for _, action := range allActions {
+			if cfg.actionGroup(action.Repo) == "" {
+				actionList = append(actionList, action)
+			}
 		}
 
 		// Check if any actions need updates
@@ -458,55 +1443,70 @@ func updateActions(actions WorkflowActions, targetWorkflow string) error {
 		}
 
 		if !hasUpdates {
-			fmt.Printf("  ✅ %s: No updates needed\n", workflow)
+			fmt.Fprintf(console, "  %s %s: No updates needed\n", theme.OK, workflow)
 			continue
 		}
 
-		fmt.Printf("\n📁 %s:\n", workflow)
+		fmt.Fprintf(console, "\n📁 %s:\n", workflow)
 
 		// Show what will be updated
 		for _, action := range actionList {
 			if action.NeedsUpdate {
-				fmt.Printf("  🔄 %s: %s → %s (%s)\n", action.Repo, action.CurrentRef, action.LatestTag, action.LatestSHA[:8])
+				fmt.Fprintf(console, "  %s %s: %s → %s (%s)\n", theme.Update, action.Repo, action.CurrentRef, action.LatestTag, shortPin(action.LatestSHA))
+				if action.ReviewRequired() {
+					printBreakingChanges(action, "     ")
+				}
+				if action.MovedTo != "" {
+					fmt.Fprintf(console, "     ↪️  Moved to %s\n", action.MovedTo)
+				}
 			}
 		}
 
-		// Ask for confirmation
-		if !promptForConfirmation(fmt.Sprintf("Update %s?", workflow)) {
-			fmt.Printf("  ⏭️  Skipped %s\n", workflow)
+		// Ask which updates to apply
+		selected := selectUpdates(forge, workflow, actionList)
+		if len(selected) == 0 {
+			fmt.Fprintf(console, "  %s Skipped %s\n", theme.Skipped, workflow)
 			continue
 		}
+		for i := range selected {
+			acceptMove(&selected[i], "  ")
+		}
 
 		// Update the file (now with idempotent checks)
-		if err := updateWorkflowFile(workflow, actionList); err != nil {
-			fmt.Printf("  ❌ Failed to update: %v\n", err)
+		if err := updateWorkflowFile(workflow, selected); err != nil {
+			fmt.Fprintf(console, "  %s Failed to update: %v\n", theme.Error, err)
 
 			// Restore from backup on failure
 			if backupFile, exists := backupFiles[workflow]; exists {
 				if restoreErr := copyFile(backupFile, workflow); restoreErr != nil {
-					fmt.Printf("  ❌ Failed to restore backup: %v\n", restoreErr)
+					fmt.Fprintf(console, "  %s Failed to restore backup: %v\n", theme.Error, restoreErr)
 				} else {
-					fmt.Printf("  🔄 Restored from backup due to update failure\n")
+					fmt.Fprintf(console, "  %s Restored from backup due to update failure\n", theme.Update)
 				}
 			}
 			continue
 		}
 
-		fmt.Printf("  ✅ Updated %s\n", workflow)
+		applied = append(applied, selected...)
+		fmt.Fprintf(console, "  %s Updated %s\n", theme.OK, workflow)
 	}
 
-	return nil
+	return applied, nil
 }
 
-// copyFile copies a file
+// copyFile copies a file. The destination must stay inside the repository.
 func copyFile(src, dst string) error {
+	if err := checkWritePath(dst); err != nil {
+		return err
+	}
+
 	source, err := os.Open(filepath.Clean(src))
 	if err != nil {
 		return err
 	}
 	defer func() {
 		if closeErr := source.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close source file: %v\n", closeErr)
+			logger.Warn("failed to close source file", "file", src, "error", closeErr)
 		}
 	}()
 
@@ -516,7 +1516,7 @@ func copyFile(src, dst string) error {
 	}
 	defer func() {
 		if closeErr := destination.Close(); closeErr != nil {
-			fmt.Printf("Warning: failed to close destination file: %v\n", closeErr)
+			logger.Warn("failed to close destination file", "file", dst, "error", closeErr)
 		}
 	}()
 
@@ -525,259 +1525,671 @@ func copyFile(src, dst string) error {
 }
 
 // printSummary prints a summary of actions and their status
-func printSummary(actions WorkflowActions) {
-	fmt.Println("\n📊 Summary:")
-
-	totalActions := 0
-	upToDate := 0
-	needsUpdate := 0
-
-	for workflow, actionList := range actions {
-		fmt.Printf("\n📁 %s:\n", workflow)
+func printSummary(actions WorkflowActions, cfg *Config) {
+	fmt.Fprintln(console, "\n📊 Summary:")
+
+	for _, workflow := range sortedWorkflows(actions) {
+		actionList := actions[workflow]
+		fmt.Fprintf(console, "\n📁 %s:\n", workflow)
+
+		// Plain output lines the statuses up in one column
+		width := 0
+		if plainOutput() {
+			for _, action := range actionList {
+				width = max(width, len(action.Repo)+1)
+			}
+		}
 
 		for _, action := range actionList {
-			totalActions++
-			status := "✅ Up to date"
-			if action.NeedsUpdate {
-				needsUpdate++
-				status = "🔄 Update available"
-			} else {
-				upToDate++
+			label := action.Repo + ":"
+			switch {
+			case action.Error != nil:
+				fmt.Fprintf(console, "  %-*s %s %s\n", width, label, theme.Error, action.Error.Label())
+			case action.TagMoved != nil:
+				fmt.Fprintf(console, "  %-*s 🚨 Tag moved (%s): not updated %s\n", width, label, action.TagMoved.Tag, ruleTag(ruleTagMoved))
+			case action.ReviewRequired():
+				fmt.Fprintf(console, "  %-*s %s Review required (%s): release notes mention breaking changes\n", width, label, theme.Warning, action.LatestTag)
+			case action.NeedsUpdate:
+				fmt.Fprintf(console, "  %-*s %s Update available (%s)\n", width, label, theme.Update, action.LatestTag)
+			case action.CoolingOff != nil:
+				fmt.Fprintf(console, "  %-*s ⏳ Pending cool-off (%s)\n", width, label, describeCoolOff(action.CoolingOff))
+			default:
+				fmt.Fprintf(console, "  %-*s %s Up to date (%s)\n", width, label, theme.OK, action.LatestTag)
+			}
+			if commentMismatch(action) && ruleEnabled(ruleCommentMismatch) {
+				fmt.Fprintf(console, "     %s The pin comment says %s, which points at another commit %s\n", severityStatus(ruleCommentMismatch), action.LatestTag, ruleTag(ruleCommentMismatch))
+			}
+			if action.MovedTo != "" && ruleEnabled(ruleMoved) {
+				fmt.Fprintf(console, "     ↪️  Moved to %s %s\n", action.MovedTo, ruleTag(ruleMoved))
+			}
+			if action.Scorecard != nil {
+				fmt.Fprintf(console, "     📊 OpenSSF Scorecard %.1f/10\n", action.Scorecard.Score)
+			}
+			if action.Attestation != nil {
+				fmt.Fprintf(console, "     🔏 Attested: %s signed by %s\n", action.Attestation.describe(), action.Attestation.Signer)
+			}
+			if len(action.Transitive) > 0 {
+				fmt.Fprintf(console, "     🔗 Calls unpinned %s %s\n", describeTransitive(action.Transitive), ruleTag(ruleTransitive))
+			}
+			if deprecatedRuntime(action.Runtime) {
+				fmt.Fprintf(console, "     ⚙️  Runs on %s, which runners are removing %s\n", action.Runtime, ruleTag(ruleNodeRuntime))
+			}
+			if action.Deprecation != nil {
+				fmt.Fprintf(console, "     🪦 Unmaintained: %s %s\n", action.Deprecation.describe(), ruleTag(ruleDeprecated))
+			}
+			if action.Publisher != nil {
+				fmt.Fprintf(console, "     🏅 Publisher: %s\n", action.Publisher.describe())
+			}
+			switch {
+			case action.Immutable != nil && *action.Immutable:
+				fmt.Fprintln(console, "     🔒 Immutable release")
+			case action.RequiresImmutable:
+				fmt.Fprintf(console, "     🔓 Not an immutable release %s\n", ruleTag(ruleMutableRelease))
 			}
-
-			fmt.Printf("  %s: %s (%s)\n", action.Repo, status, action.LatestTag)
 		}
 	}
 
-	fmt.Printf("\n📈 Total: %d actions\n", totalActions)
-	fmt.Printf("✅ Up to date: %d\n", upToDate)
-	fmt.Printf("🔄 Need updates: %d\n", needsUpdate)
+	printGroups(collectGroups(actions, cfg))
+
+	summary := summarize(actions)
+	fmt.Fprintf(console, "\n📈 Total: %d actions\n", summary.Total)
+	fmt.Fprintf(console, "%s Up to date: %d\n", theme.OK, summary.UpToDate)
+	fmt.Fprintf(console, "%s Need updates: %d\n", theme.Update, summary.NeedsUpdate)
+	if summary.ReviewRequired > 0 {
+		fmt.Fprintf(console, "%s Review required: %d\n", theme.Warning, summary.ReviewRequired)
+	}
+	if summary.CoolingOff > 0 {
+		fmt.Fprintf(console, "⏳ Pending cool-off: %d\n", summary.CoolingOff)
+	}
+	if summary.Moved > 0 {
+		fmt.Fprintf(console, "↪️  Moved repositories: %d (update --follow-renames rewrites them)\n", summary.Moved)
+	}
+	if summary.Errors > 0 {
+		fmt.Fprintf(console, "%s Errors: %d\n", theme.Error, summary.Errors)
+		kinds := make([]string, 0, len(summary.ErrorsByKind))
+		for kind := range summary.ErrorsByKind {
+			kinds = append(kinds, string(kind))
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(console, "   %s: %d\n", kind, summary.ErrorsByKind[ErrorKind(kind)])
+		}
+	}
 }
 
 // verifyPinnedSHAs verifies that all actions are pinned to SHAs
-func verifyPinnedSHAs() error {
-	fmt.Println("\n🔒 Verifying all actions are pinned to SHAs...")
+func verifyPinnedSHAs(actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n🔒 Verifying all actions are pinned to SHAs...")
 
-	actions, err := scanWorkflows()
-	if err != nil {
-		return err
+	unpinned := findUnpinned(actions)
+
+	if len(unpinned) > 0 {
+		printUnpinned(unpinned)
+		return fmt.Errorf("found %d unpinned actions", len(unpinned))
 	}
 
-	unpinned := []string{}
+	fmt.Fprintf(console, "%s All actions are properly pinned to SHAs\n", theme.OK)
+	return nil
+}
 
-	for workflow, actionList := range actions {
+// printUnpinned lists the references that are not pinned to SHAs
+func printUnpinned(unpinned []ActionInfo) {
+	fmt.Fprintf(console, "%s The following actions are not pinned to SHAs %s:\n", severityStatus(ruleUnpinned), ruleTag(ruleUnpinned))
+	for _, action := range unpinned {
+		suffix := ""
+		switch {
+		case action.ReusableWorkflow:
+			suffix = " (reusable workflow)"
+		case action.Docker:
+			suffix = " (container image, pin to a sha256 digest)"
+		case shortSHARegex.MatchString(action.CurrentRef):
+			suffix = " (abbreviated SHA, run update --expand-short)"
+		}
+		fmt.Fprintf(console, "  %s:%d %s%s\n", action.WorkflowFile, action.Line, action.reference(), suffix)
+	}
+}
+
+// findUnpinned returns the actions that are not pinned to a full commit SHA,
+// ordered by workflow file and line
+func findUnpinned(actions WorkflowActions) []ActionInfo {
+	unpinned := []ActionInfo{}
+
+	for _, actionList := range actions {
 		for _, action := range actionList {
-			if !shaRegex.MatchString(action.CurrentRef) {
-				unpinned = append(unpinned, fmt.Sprintf("%s:%d %s@%s", workflow, action.Line, action.Repo, action.CurrentRef))
+			if !action.Pinned() {
+				unpinned = append(unpinned, action)
 			}
 		}
 	}
 
-	if len(unpinned) > 0 {
-		fmt.Println("❌ The following actions are not pinned to SHAs:")
-		for _, item := range unpinned {
-			fmt.Printf("  %s\n", item)
+	sort.Slice(unpinned, func(i, j int) bool {
+		if unpinned[i].WorkflowFile != unpinned[j].WorkflowFile {
+			return unpinned[i].WorkflowFile < unpinned[j].WorkflowFile
 		}
-		return fmt.Errorf("found %d unpinned actions", len(unpinned))
-	}
+		return unpinned[i].Line < unpinned[j].Line
+	})
 
-	fmt.Println("✅ All actions are properly pinned to SHAs")
-	return nil
+	return unpinned
 }
 
-// installPreCommitHooks installs pre-commit hooks for the repository
-func installPreCommitHooks() error {
-	fmt.Println("🔧 Installing pre-commit hooks...")
+// setupInstallHooks registers the flags of the install-hooks command and returns its runner
+func setupInstallHooks(fs *flag.FlagSet) func(args []string) {
+	configPath := fs.String("config", defaultConfigFile, "Path to the configuration `file`")
 
-	// Check if we're in a git repository
-	if _, err := os.Stat(".git"); os.IsNotExist(err) {
-		return fmt.Errorf("not in a git repository (no .git directory found)")
+	return func(_ []string) {
+		cfg, err := loadConfig(*configPath, *configPath != defaultConfigFile)
+		if err == nil {
+			err = installPreCommitHooks(cfg)
+		}
+		if err != nil {
+			fmt.Fprintf(console, "Failed to install hooks: %v\n", err)
+			os.Exit(1)
+		}
 	}
+}
 
-	// Create hooks directory if it doesn't exist
-	hooksDir := ".git/hooks"
-	if err := os.MkdirAll(hooksDir, 0750); err != nil {
-		return fmt.Errorf("failed to create hooks directory: %w", err)
+// setupUninstallHooks registers the flags of the uninstall-hooks command and returns its runner
+func setupUninstallHooks(_ *flag.FlagSet) func(args []string) {
+	return func(_ []string) {
+		if err := uninstallHooks(); err != nil {
+			fmt.Fprintf(console, "Failed to uninstall hooks: %v\n", err)
+			os.Exit(1)
+		}
 	}
+}
 
-	// Pre-commit hook script
-	preCommitHook := `#!/bin/sh
-# Pre-commit hook for github-ci-hash project
-set -e
-
-echo "🔍 Running pre-commit checks..."
-
-# Check if golangci-lint is available
-if ! command -v golangci-lint >/dev/null 2>&1; then
-    echo "❌ golangci-lint is not installed"
-    echo "   Install with: go install github.com/golangci/golangci-lint/cmd/golangci-lint@latest"
-    exit 1
-fi
+// setupVersion registers the flags of the version command and returns its runner
+func setupVersion(_ *flag.FlagSet) func(args []string) {
+	return func(_ []string) {
+		fmt.Fprintf(console, "GitHub CI Hash Updater\n")
+		fmt.Fprintf(console, "Version: %s\n", Version)
+		fmt.Fprintf(console, "Git Commit: %s\n", GitCommit)
+		fmt.Fprintf(console, "Build Time: %s\n", BuildTime)
+		fmt.Fprintf(console, "Go Version: %s\n", strings.TrimPrefix(runtime.Version(), "go"))
+	}
+}
 
-# Run linting
-echo "🔍 Running golangci-lint..."
-if ! golangci-lint run; then
-    echo "❌ Linting failed"
-    exit 1
-fi
+// setupCheck registers the flags of the check command and returns its runner
+func setupCheck(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	jsonOutput := fs.Bool("json", false, "Write machine readable results to stdout")
+	sarifPath := fs.String("sarif", "", "Also write the results as SARIF for code scanning to `file`")
+	verifyBundles := fs.Bool("verify-dist", false, "Compare the bundles of JavaScript actions at the pinned SHA and the commented tag")
+	since := fs.String("since", "", "Skip the check when the last one without errors ran less than this `age` ago, e.g. 7d or 12h")
+	quiet := fs.Bool("quiet", false, "Only print a grouped summary of updates and errors, e.g. in a pre-push hook")
+	scorecard := registerScorecardFlags(fs)
+	publishers := registerPublisherFlags(fs)
+	attestations := fs.Bool("attestations", false, "Verify the SLSA provenance or release attestations of the pinned commits through the attestations API")
+	deep := fs.Bool("deep", false, "Read the manifest of every pinned composite action and report the unpinned actions its steps call")
+	runtimes := fs.Bool("runtimes", false, "Read the manifest of every action at its pinned commit and flag actions running on deprecated Node versions")
+	deprecated := fs.Bool("deprecated", false, "Flag actions whose repository is archived or deprecated, or whose latest release is older than stale_after")
+	immutable := fs.Bool("immutable", false, "Show whether every action is pinned to an immutable release; actions under require_immutable are always checked")
+	var reporters stringSliceFlag
+	fs.Var(&reporters, "reporter", "Pipe the JSON results to the ci-hash-`name` plugin on PATH (repeatable)")
+
+	return func(args []string) {
+		if *jsonOutput {
+			console = os.Stderr
+		}
 
-# Run tests
-echo "🧪 Running tests..."
-if ! go test ./...; then
-    echo "❌ Tests failed"
-    exit 1
-fi
+		cfg, scanOpts := mustLoadScanConfig(opts)
 
-# Verify all GitHub Actions are pinned to SHAs
-echo "🔒 Verifying GitHub Actions are pinned to SHAs..."
-if ! go run . verify >/dev/null 2>&1; then
-    echo "❌ Some GitHub Actions are not pinned to SHAs"
-    echo "   Run 'go run . verify' to see details"
-    exit 1
-fi
+		// Fatal errors and the summary go to out even when progress is discarded
+		out := console
+		if *quiet {
+			console = io.Discard
+		}
 
-echo "✅ All pre-commit checks passed!"
-`
+		if *since != "" {
+			age, err := parseAge(*since)
+			if err != nil {
+				fmt.Fprintf(out, "Error: --since: %v\n", err)
+				os.Exit(2)
+			}
+			if last, ok := lastCheckTime(); ok && time.Since(last) < age {
+				fmt.Fprintf(out, "%s Last check ran %s ago, skipping until it is %s old\n",
+					theme.Skipped, time.Since(last).Round(time.Minute), *since)
+				return
+			}
+		}
 
-	// Write pre-commit hook
-	preCommitPath := filepath.Join(hooksDir, "pre-commit")
-	// #nosec G306 - Git hooks must be executable (0755) to function properly
-	if err := os.WriteFile(preCommitPath, []byte(preCommitHook), 0755); err != nil {
-		return fmt.Errorf("failed to write pre-commit hook: %w", err)
-	}
+		gc := NewGitHubClient()
 
-	fmt.Printf("✅ Pre-commit hook installed at %s\n", preCommitPath)
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, skipped, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(out, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+		expressions := collectExpressionUses(actions, skipped)
 
-	// Pre-push hook script
-	prePushHook := `#!/bin/sh
-# Pre-push hook for github-ci-hash project
-set -e
+		// Whether code is used can only be told from the whole repository
+		var unused []UnusedFile
+		if len(args) == 0 && !scanOpts.ChangedOnly {
+			unused = findUnusedFiles(scanOpts)
+		}
 
-echo "🚀 Running pre-push checks..."
+		if len(actions) == 0 && !*jsonOutput {
+			fmt.Fprintln(console, "No GitHub Actions found in workflow files")
+			printSkipped(skipped)
+			printExpressionUses(expressions)
+			printUnused(unused)
+			return
+		}
 
-# Check for GitHub Actions updates
-echo "🔍 Checking for GitHub Action updates..."
-if ! go run . check >/dev/null 2>&1; then
-    echo "⚠️  Warning: Could not check for GitHub Action updates"
-    echo "   This might be due to API rate limits or network issues"
-fi
+		forge := forgeFor(gc, cfg, actions)
+		checkForUpdates(forge, actions, cfg)
 
-echo "✅ Pre-push checks completed!"
-`
+		if *verifyBundles {
+			verifyDistBundles(forge, actions)
+		}
+		if *attestations {
+			checkAttestations(gc, actions)
+		}
+		// Mutable releases under require_immutable fail the check through
+		// their findings
+		if *immutable || (len(cfg.RequireImmutable) > 0 && ruleEnabled(ruleMutableRelease)) {
+			annotateImmutability(forge, cfg, actions, *immutable)
+		}
+		if *deep && ruleEnabled(ruleTransitive) {
+			scanTransitive(forge, actions)
+		}
+		if *runtimes && ruleEnabled(ruleNodeRuntime) {
+			checkRuntimes(forge, actions)
+		}
+		// Low scores fail the check through their findings
+		scorecard.scorecards(cfg, actions)
+		publishers.publishers(gc, forge, cfg, actions)
+		if *deprecated && ruleEnabled(ruleDeprecated) {
+			checkDeprecations(gc, cfg, actions)
+		}
 
-	// Write pre-push hook
-	prePushPath := filepath.Join(hooksDir, "pre-push")
-	// #nosec G306 - Git hooks must be executable (0755) to function properly
-	if err := os.WriteFile(prePushPath, []byte(prePushHook), 0755); err != nil {
-		return fmt.Errorf("failed to write pre-push hook: %w", err)
-	}
+		denylist, err := loadDenylist(cfg, false)
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		// Denylisted actions fail the check through their findings
+		_ = checkDenylist(denylist, actions)
+		if ruleEnabled(ruleUntrusted) {
+			markUntrusted(cfg, actions)
+		}
+		findings := collectFindings(actions, skipped, expressions, unused)
 
-	fmt.Printf("✅ Pre-push hook installed at %s\n", prePushPath)
+		if *sarifPath != "" {
+			if err := writeSARIFFile(*sarifPath, findings, cfg); err != nil {
+				fmt.Fprintf(out, "Error writing SARIF report: %v\n", err)
+				os.Exit(1)
+			}
+		}
 
-	fmt.Println("\n🎉 Pre-commit hooks successfully installed!")
-	fmt.Println("\nThe following hooks are now active:")
-	fmt.Println("📋 pre-commit: Runs linting, tests, and SHA verification")
-	fmt.Println("🚀 pre-push: Checks for GitHub Action updates")
-	fmt.Println("\nTo bypass hooks (not recommended): git commit --no-verify")
+		if *jsonOutput {
+			if err := writeJSONReport(os.Stdout, actions, skipped, expressions, unused, cfg); err != nil {
+				fmt.Fprintf(out, "Error writing JSON report: %v\n", err)
+				os.Exit(1)
+			}
+		} else if *quiet {
+			printGroupedSummary(out, actions)
+		} else {
+			printSummary(actions, cfg)
+			printForkExposures(findForkExposures(actions))
+			printPermissionIssues(findPermissionIssues(actions))
+			printVersionSkew(findVersionSkew(actions))
+			printSkipped(skipped)
+			printExpressionUses(expressions)
+			printUnused(unused)
+		}
 
-	return nil
-}
+		if len(reporters) > 0 {
+			var report bytes.Buffer
+			err := writeJSONReport(&report, actions, skipped, expressions, unused, cfg)
+			if err == nil {
+				err = runReporters(reporters, report.Bytes())
+			}
+			if err != nil {
+				fmt.Fprintf(out, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("GitHub CI Hash Updater")
-		fmt.Printf("Version: %s (commit: %s, built: %s)\n", Version, GitCommit, BuildTime)
-		fmt.Println("")
-		fmt.Println("Usage:")
-		fmt.Println("  github-ci-hash check                    - Check for updates without applying")
-		fmt.Println("  github-ci-hash update                   - Update all workflows (with confirmation)")
-		fmt.Println("  github-ci-hash update <workflow-file>   - Update specific workflow file")
-		fmt.Println("  github-ci-hash verify                   - Verify all actions are pinned to SHAs")
-		fmt.Println("  github-ci-hash install-hooks            - Install pre-commit hooks")
-		fmt.Println("  github-ci-hash version                  - Show version information")
-		fmt.Println("")
-		fmt.Println("Environment variables:")
-		fmt.Println("  GITHUB_TOKEN or GH_TOKEN - GitHub API token for higher rate limits")
-		fmt.Println("  (or authenticate with 'gh auth login' to use gh CLI token)")
-		os.Exit(1)
+		if summarize(actions).Errors == 0 {
+			recordCheck()
+		}
+		if failing := failingFindings(findings); len(failing) > 0 {
+			fmt.Fprintf(out, "\n%s %d finding(s) at error severity: %s\n", theme.Error, len(failing), findingRuleIDs(failing))
+			os.Exit(1)
+		}
+		exitIfBudgetExhausted(actions)
 	}
+}
 
-	command := os.Args[1]
+// setupUpdate registers the flags of the update command and returns its runner
+func setupUpdate(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	stdinMode := fs.Bool("stdin", false, "Read one workflow from stdin and write the pinned version to stdout")
+	tuiMode := fs.Bool("tui", false, "Review and apply updates in a full-screen terminal UI")
+	suggestPerms := fs.Bool("suggest-permissions", false, "Offer least-privilege permissions blocks for workflows without one")
+	expandShort := fs.Bool("expand-short", false, "Only expand abbreviated SHAs to full SHAs of the same commits")
+	fs.BoolVar(&autoApprove, "yes", false, "Apply every update without prompting, e.g. in scheduled workflows")
+	fs.BoolVar(&allowBreaking, "allow-breaking", false, "Let --yes apply updates whose release notes mention breaking changes")
+	fs.BoolVar(&followRenames, "follow-renames", false, "Rewrite uses: of renamed or transferred repositories to their new location")
+	prBodyPath := fs.String("pr-body", "", "Write a pull request description of the applied updates to `file`")
+	var to stringSliceFlag
+	fs.Var(&to, "to", "Pin an `action@tag` to that version instead of the latest release (repeatable)")
+	var jobs stringSliceFlag
+	fs.Var(&jobs, "job", "Only update the actions of workflow jobs matching this `id` or glob (repeatable)")
+
+	return func(args []string) {
+		if *stdinMode {
+			console = os.Stderr
+		}
 
-	switch command {
-	case "version":
-		fmt.Printf("GitHub CI Hash Updater\n")
-		fmt.Printf("Version: %s\n", Version)
-		fmt.Printf("Git Commit: %s\n", GitCommit)
-		fmt.Printf("Build Time: %s\n", BuildTime)
-		fmt.Printf("Go Version: %s\n", strings.TrimPrefix(runtime.Version(), "go"))
-		return
+		cfg, scanOpts := mustLoadScanConfig(opts)
+		versionTargets, err := parseVersionTargets(to)
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		if *tuiMode && plainOutput() {
+			fmt.Fprintln(console, "Error: --tui draws a full-screen interface and cannot be used with plain output")
+			os.Exit(2)
+		}
 
-	case "check":
 		gc := NewGitHubClient()
 
-		fmt.Println("🔍 Scanning workflow files...")
-		actions, err := scanWorkflows()
+		if *stdinMode {
+			if err := updateStdin(gc, cfg, os.Stdin, os.Stdout); err != nil {
+				fmt.Fprintf(console, "Error updating workflow: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		targets, err := resolveUpdateTargets(args)
 		if err != nil {
-			fmt.Printf("Error scanning workflows: %v\n", err)
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(2)
+		}
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, skipped, err := scanTargets(targets, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
 			os.Exit(1)
 		}
 
 		if len(actions) == 0 {
-			fmt.Println("No GitHub Actions found in workflow files")
+			fmt.Fprintln(console, "No GitHub Actions found in workflow files")
+			printSkipped(skipped)
 			return
 		}
+		if len(jobs) > 0 {
+			if actions = filterJobs(actions, jobs); len(actions) == 0 {
+				fmt.Fprintf(console, "No GitHub Actions found in jobs matching %s\n", jobs.String())
+				return
+			}
+		}
 
-		checkForUpdates(gc, actions)
-
-		printSummary(actions)
+		forge := forgeFor(gc, cfg, actions)
+		checkForUpdates(forge, actions, cfg)
 
-	case "update":
-		gc := NewGitHubClient()
+		if *expandShort {
+			if err := expandAbbreviatedSHAs(actions); err != nil {
+				fmt.Fprintf(console, "Error expanding SHAs: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		applyVersionTargets(forge, actions, versionTargets)
 
-		var targetWorkflow string
-		if len(os.Args) > 2 {
-			targetWorkflow = os.Args[2]
-			if !strings.HasPrefix(targetWorkflow, ".github/workflows/") {
-				targetWorkflow = ".github/workflows/" + targetWorkflow
+		if *tuiMode {
+			selected, err := runReviewTUI(actions, cfg)
+			if errors.Is(err, errReviewCancelled) {
+				fmt.Fprintf(console, "\n%s Review cancelled, no files were changed\n", theme.Skipped)
+				return
+			}
+			if err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
 			}
+			if len(selected) == 0 {
+				fmt.Fprintf(console, "\n%s No updates selected\n", theme.OK)
+				finishUpdate(actions, nil, cfg, *prBodyPath, *suggestPerms)
+				return
+			}
+			if err := applyReviewedUpdates(selected); err != nil {
+				fmt.Fprintf(console, "Error updating actions: %v\n", err)
+				os.Exit(1)
+			}
+			applied := make([]ActionInfo, 0, len(selected))
+			for _, action := range selected {
+				applied = append(applied, *action)
+			}
+			finishUpdate(actions, applied, cfg, *prBodyPath, *suggestPerms)
+			return
 		}
 
-		fmt.Println("🔍 Scanning workflow files...")
-		actions, err := scanWorkflows()
+		applied, err := updateActions(forge, actions, cfg)
 		if err != nil {
-			fmt.Printf("Error scanning workflows: %v\n", err)
+			fmt.Fprintf(console, "Error updating actions: %v\n", err)
 			os.Exit(1)
 		}
+		finishUpdate(actions, applied, cfg, *prBodyPath, *suggestPerms)
+	}
+}
 
-		if len(actions) == 0 {
-			fmt.Println("No GitHub Actions found in workflow files")
-			return
+// finishUpdate runs what follows applying the updates, whether they were
+// picked in the TUI or at the prompts: the pull request description, the
+// permission suggestions and the exit code of an exhausted API budget
+func finishUpdate(actions WorkflowActions, applied []ActionInfo, cfg *Config, prBodyPath string, suggestPerms bool) {
+	if prBodyPath != "" {
+		if err := writePRBodyFile(prBodyPath, applied, cfg); err != nil {
+			fmt.Fprintf(console, "Error writing pull request description: %v\n", err)
+			os.Exit(1)
 		}
+	}
 
-		checkForUpdates(gc, actions)
+	if suggestPerms {
+		suggestWorkflowPermissions(actions)
+	}
 
-		if err := updateActions(actions, targetWorkflow); err != nil {
-			fmt.Printf("Error updating actions: %v\n", err)
-			os.Exit(1)
-		}
+	fmt.Fprintf(console, "\n%s Update process completed!\n", theme.OK)
+	exitIfBudgetExhausted(actions)
+}
+
+// updateStdin pins the actions of a single workflow read from r and writes
+// the result to w without prompting for confirmation
+func updateStdin(gc *GitHubClient, cfg *Config, r io.Reader, w io.Writer) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read workflow from stdin: %w", err)
+	}
+
+	parsed, err := parseWorkflowContent(stdinWorkflowName, string(content))
+	if err != nil {
+		return err
+	}
+	actions := WorkflowActions{stdinWorkflowName: parsed}
+	checkForUpdates(forgeFor(gc, cfg, actions), actions, cfg)
 
-		fmt.Println("\n✅ Update process completed!")
+	newContent, _ := applyActionUpdates(stdinWorkflowName, string(content), actions[stdinWorkflowName])
+	_, err = io.WriteString(w, newContent)
+	return err
+}
 
-	case "verify":
-		if err := verifyPinnedSHAs(); err != nil {
-			fmt.Printf("Verification failed: %v\n", err)
-			os.Exit(1)
+// setupVerify registers the flags of the verify command and returns its runner
+func setupVerify(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	stdinMode := fs.Bool("stdin", false, "Verify a single workflow read from stdin")
+	requireTwoFactor := fs.Bool("require-2fa", false, "Require third-party actions to come from organizations that enforce two-factor authentication")
+	requireVerified := fs.Bool("require-verified", false, "Require third-party actions to come from verified creators unless they match allow_unverified")
+	requireOrgOwned := fs.Bool("require-org-owned", false, "Require actions to come from repositories owned by organizations that are not forks, unless they match allow_personal")
+	offline := fs.Bool("offline", false, "Fail instead of making network requests, e.g. in a pre-commit hook")
+	reachable := fs.Bool("reachable", false, "Require every pinned commit to be reachable from a branch or tag of its repository, rejecting impostor commits from forks")
+	comments := fs.Bool("comments", false, "Resolve the tag in the comment of every SHA pin and report pins whose tag points at another commit or no longer exists")
+	ratchet := fs.Bool("ratchet", false, "Only fail when there are more unpinned actions than the baseline, which is recorded on the first run")
+	baselinePath := fs.String("baseline", defaultBaselineFile, "Path to the baseline `file` of --ratchet")
+
+	return func(args []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+		if *offline && *requireTwoFactor {
+			fmt.Fprintln(console, "Error: --require-2fa looks publishers up through the API and cannot be used with --offline")
+			os.Exit(2)
+		}
+		if *offline && *requireVerified {
+			fmt.Fprintln(console, "Error: --require-verified looks publishers up through the API and cannot be used with --offline")
+			os.Exit(2)
+		}
+		if *offline && *requireOrgOwned {
+			fmt.Fprintln(console, "Error: --require-org-owned looks repositories up through the API and cannot be used with --offline")
+			os.Exit(2)
+		}
+		if *offline && len(cfg.RequireImmutable) > 0 {
+			fmt.Fprintf(console, "%s require_immutable looks releases up through the API and is not checked with --offline\n", theme.Skipped)
+		}
+		if *offline && *reachable {
+			fmt.Fprintln(console, "Error: --reachable compares commits through the API and cannot be used with --offline")
+			os.Exit(2)
+		}
+		if *offline && *comments {
+			fmt.Fprintln(console, "Error: --comments resolves tags through the API and cannot be used with --offline")
+			os.Exit(2)
+		}
+		if *ratchet && (*stdinMode || len(args) > 0) {
+			fmt.Fprintln(console, "Error: --ratchet counts the whole repository and cannot be used with --stdin or files")
+			os.Exit(2)
+		}
+
+		var actions WorkflowActions
+		var skipped []SkippedFile
+		var expressions []ExpressionUse
+		if *stdinMode {
+			content, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				fmt.Fprintf(console, "Error reading workflow from stdin: %v\n", err)
+				os.Exit(1)
+			}
+			parsed, err := parseWorkflowContent(stdinWorkflowName, string(content))
+			if err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			actions = WorkflowActions{stdinWorkflowName: parsed}
+			expressions = findExpressionUses(stdinWorkflowName, string(content))
+		} else {
+			var err error
+			actions, skipped, err = scanTargets(args, scanOpts)
+			if err != nil {
+				fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+				os.Exit(1)
+			}
+			expressions = collectExpressionUses(actions, skipped)
 		}
 
-	case "install-hooks":
-		if err := installPreCommitHooks(); err != nil {
-			fmt.Printf("Failed to install hooks: %v\n", err)
+		// Each check only fails the verification at error severity
+		var err error
+		switch {
+		case !ruleEnabled(ruleUnpinned):
+		case *ratchet:
+			err = enforce(ruleUnpinned, verifyRatchet(actions, *baselinePath, cfg))
+		default:
+			err = enforce(ruleUnpinned, verifyPinnedSHAs(actions))
+		}
+		denylist, denylistErr := loadDenylist(cfg, *offline)
+		if denylistErr == nil {
+			denylistErr = enforce(ruleDenylisted, checkDenylist(denylist, actions))
+		}
+		if err == nil {
+			err = denylistErr
+		}
+		var gc *GitHubClient
+		requireImmutable := !*offline && len(cfg.RequireImmutable) > 0 && ruleEnabled(ruleMutableRelease)
+		if *requireTwoFactor || *requireVerified || *requireOrgOwned || *reachable || *comments || requireImmutable {
+			gc = NewGitHubClient()
+		}
+		if allowlistErr := enforce(ruleUntrusted, checkAllowlist(cfg, actions)); err == nil {
+			err = allowlistErr
+		}
+		if policyErr := enforce(ruleCustomPolicy, checkPolicies(cfg, actions, skipped, expressions)); err == nil {
+			err = policyErr
+		}
+		if *requireTwoFactor {
+			if twoFactorErr := enforce(ruleTwoFactor, verifyPublisherTwoFactor(gc, cfg, actions)); err == nil {
+				err = twoFactorErr
+			}
+		}
+		if *requireVerified && ruleEnabled(ruleUnverified) {
+			if verifiedErr := enforce(ruleUnverified, verifyVerifiedPublishers(gc, cfg, actions)); err == nil {
+				err = verifiedErr
+			}
+		}
+		if *requireOrgOwned && ruleEnabled(rulePersonalOwner) {
+			if ownedErr := enforce(rulePersonalOwner, verifyOrgOwned(gc, cfg, actions)); err == nil {
+				err = ownedErr
+			}
+		}
+		if requireImmutable {
+			if immutableErr := enforce(ruleMutableRelease, verifyImmutableReleases(forgeFor(gc, cfg, actions), cfg, actions)); err == nil {
+				err = immutableErr
+			}
+		}
+		if *reachable {
+			if reachableErr := enforce(ruleImpostor, verifyReachableCommits(gc, actions)); err == nil {
+				err = reachableErr
+			}
+		}
+		if *comments && ruleEnabled(ruleCommentMismatch) {
+			if commentsErr := enforce(ruleCommentMismatch, verifyPinComments(forgeFor(gc, cfg, actions), actions)); err == nil {
+				err = commentsErr
+			}
+		}
+		exposures := findForkExposures(actions)
+		printForkExposures(exposures)
+		permissionIssues := findPermissionIssues(actions)
+		printPermissionIssues(permissionIssues)
+		printSkipped(skipped)
+		printExpressionUses(expressions)
+		if err == nil && len(exposures) > 0 && ruleFails(ruleForkPR) {
+			err = fmt.Errorf("%d unpinned third-party action(s) in workflows triggered by pull requests", len(exposures))
+		}
+		if writeAll := countPermissionIssues(permissionIssues, ruleWriteAll); err == nil && writeAll > 0 && ruleFails(ruleWriteAll) {
+			err = fmt.Errorf("%d workflow(s) or job(s) grant write-all permissions", writeAll)
+		}
+		if defaults := countPermissionIssues(permissionIssues, ruleDefaultPerms); err == nil && defaults > 0 && ruleFails(ruleDefaultPerms) {
+			err = fmt.Errorf("%d workflow(s) run jobs with the default token permissions", defaults)
+		}
+		if err == nil && len(expressions) > 0 && ruleFails(ruleExpression) {
+			err = fmt.Errorf("%d uses: value(s) built from expressions cannot be pinned", len(expressions))
+		}
+		if exceeded := exceededLimits(skipped); err == nil && len(exceeded) > 0 && ruleFails(ruleParseLimits) {
+			err = fmt.Errorf("%d file(s) exceed the parse limits and could not be verified", len(exceeded))
+		}
+		if err != nil {
+			fmt.Fprintf(console, "Verification failed: %v\n", err)
 			os.Exit(1)
 		}
+	}
+}
 
-	default:
-		fmt.Printf("Unknown command: %s\n", command)
+func main() {
+	_ = selectTheme()
+
+	if len(os.Args) < 2 {
+		printUsage(console)
+		os.Exit(1)
+	}
+
+	cmd, args := findCommand(os.Args[1:])
+	if cmd == nil {
+		if path, err := findPlugin(os.Args[1]); err == nil {
+			os.Exit(runPlugin(path, os.Args[2:]))
+		}
+		fmt.Fprintf(console, "Unknown command: %s\n", os.Args[1])
+		fmt.Fprintf(console, "Run '%s help' for the list of commands.\n", programName)
 		os.Exit(1)
 	}
+	cmd.run(args)
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// memoForge answers repeated lookups from memory, so an action used by
+// many workflows, such as actions/checkout at one version, is resolved with
+// one set of requests per run. Lookups are keyed by repository and
+// arguments; sub-actions share the lookups of their repository.
+type memoForge struct {
+	forge Forge
+
+	mu      sync.Mutex
+	entries map[string]memoEntry
+	// hits counts the lookups answered from memory
+	hits int
+}
+
+// memoEntry is the outcome of a lookup
+type memoEntry struct {
+	value any
+	err   error
+}
+
+// matchingTag is the outcome of LatestMatchingTag
+type matchingTag struct {
+	tag     string
+	release *Release
+}
+
+// newMemoForge returns forge with its lookups memoized for the run
+func newMemoForge(forge Forge) *memoForge {
+	return &memoForge{forge: forge, entries: make(map[string]memoEntry)}
+}
+
+// memoizable reports whether the outcome of a lookup holds for the rest of
+// the run. Rate limits, network errors and exhausted budgets are transient,
+// and a later call may succeed or use the git fallback.
+func memoizable(err error) bool {
+	if err == nil {
+		return true
+	}
+	switch errorKindOf(err) {
+	case ErrorKindNotFound, ErrorKindPermissionDenied, ErrorKindTagFormat:
+		return true
+	}
+	return false
+}
+
+// memoKey identifies a lookup. Owners and repositories are case insensitive.
+func memoKey(method, owner, repo string, args ...string) string {
+	return method + "\x00" + strings.ToLower(owner+"/"+repo) + "\x00" + strings.Join(args, "\x00")
+}
+
+// memoize returns the memoized outcome of the lookup key, calling lookup
+// the first time
+func memoize[T any](m *memoForge, key string, lookup func() (T, error)) (T, error) {
+	m.mu.Lock()
+	entry, ok := m.entries[key]
+	if ok {
+		m.hits++
+	}
+	m.mu.Unlock()
+	if ok {
+		value, _ := entry.value.(T)
+		return value, entry.err
+	}
+
+	value, err := lookup()
+	if memoizable(err) {
+		m.mu.Lock()
+		m.entries[key] = memoEntry{value: value, err: err}
+		m.mu.Unlock()
+	}
+	return value, err
+}
+
+// prime records the outcome of a lookup answered in bulk, such as by a
+// GraphQL batch, so the first call does not make a request either. Primed
+// lookups do not count as repeated.
+func (m *memoForge) prime(key string, value any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[key]; !ok {
+		m.entries[key] = memoEntry{value: value}
+	}
+}
+
+// cloneRelease copies a release so callers cannot change the memoized one
+func cloneRelease(release *Release) *Release {
+	if release == nil {
+		return nil
+	}
+	clone := *release
+	return &clone
+}
+
+// repeated returns the number of lookups answered from memory
+func (m *memoForge) repeated() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.hits
+}
+
+// Name implements Forge
+func (m *memoForge) Name() string {
+	return m.forge.Name()
+}
+
+// LatestRelease implements Forge
+func (m *memoForge) LatestRelease(owner, repo string, prereleases bool) (*Release, error) {
+	release, err := memoize(m, memoKey("latest", owner, repo, strconv.FormatBool(prereleases)), func() (*Release, error) {
+		return m.forge.LatestRelease(owner, repo, prereleases)
+	})
+	return cloneRelease(release), err
+}
+
+// LatestImmutableRelease implements Forge
+func (m *memoForge) LatestImmutableRelease(owner, repo string, prereleases bool) (*Release, error) {
+	release, err := memoize(m, memoKey("immutable", owner, repo, strconv.FormatBool(prereleases)), func() (*Release, error) {
+		return m.forge.LatestImmutableRelease(owner, repo, prereleases)
+	})
+	return cloneRelease(release), err
+}
+
+// ReleaseAt implements Forge
+func (m *memoForge) ReleaseAt(owner, repo, tag string) (*Release, error) {
+	release, err := memoize(m, memoKey("release", owner, repo, tag), func() (*Release, error) {
+		return m.forge.ReleaseAt(owner, repo, tag)
+	})
+	return cloneRelease(release), err
+}
+
+// LatestMatchingTag implements Forge
+func (m *memoForge) LatestMatchingTag(owner, repo string, filter *regexp.Regexp, prereleases bool) (string, *Release, error) {
+	match, err := memoize(m, memoKey("matching", owner, repo, filter.String(), strconv.FormatBool(prereleases)), func() (matchingTag, error) {
+		tag, release, err := m.forge.LatestMatchingTag(owner, repo, filter, prereleases)
+		return matchingTag{tag: tag, release: release}, err
+	})
+	return match.tag, cloneRelease(match.release), err
+}
+
+// HighestTag implements Forge
+func (m *memoForge) HighestTag(owner, repo string, filter *regexp.Regexp) (string, error) {
+	return memoize(m, memoKey("highest", owner, repo, filter.String()), func() (string, error) {
+		return m.forge.HighestTag(owner, repo, filter)
+	})
+}
+
+// ResolveRef implements Forge
+func (m *memoForge) ResolveRef(owner, repo, ref string) (string, error) {
+	return memoize(m, memoKey("ref", owner, repo, ref), func() (string, error) {
+		return m.forge.ResolveRef(owner, repo, ref)
+	})
+}
+
+// ExpandSHA implements Forge
+func (m *memoForge) ExpandSHA(owner, repo, prefix string) (string, error) {
+	return memoize(m, memoKey("expand", owner, repo, strings.ToLower(prefix)), func() (string, error) {
+		return m.forge.ExpandSHA(owner, repo, prefix)
+	})
+}
+
+// TagsAt implements Forge
+func (m *memoForge) TagsAt(owner, repo, sha string) ([]string, error) {
+	tags, err := memoize(m, memoKey("tags", owner, repo, strings.ToLower(sha)), func() ([]string, error) {
+		return m.forge.TagsAt(owner, repo, sha)
+	})
+	return append([]string(nil), tags...), err
+}
+
+// Compare implements Forge
+func (m *memoForge) Compare(owner, repo, base, head string) (*Comparison, error) {
+	return memoize(m, memoKey("compare", owner, repo, base, head), func() (*Comparison, error) {
+		return m.forge.Compare(owner, repo, base, head)
+	})
+}
+
+// FileContents implements Forge
+func (m *memoForge) FileContents(owner, repo, path, ref string) ([]byte, error) {
+	content, err := memoize(m, memoKey("file", owner, repo, path, ref), func() ([]byte, error) {
+		return m.forge.FileContents(owner, repo, path, ref)
+	})
+	return append([]byte(nil), content...), err
+}
+
+// CanonicalName implements Forge
+func (m *memoForge) CanonicalName(owner, repo string) (string, error) {
+	return memoize(m, memoKey("canonical", owner, repo), func() (string, error) {
+		return m.forge.CanonicalName(owner, repo)
+	})
+}
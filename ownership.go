@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// OwnerType is the kind of account owning an action repository
+type OwnerType string
+
+const (
+	// OwnerOrganization means the repository belongs to an organization
+	OwnerOrganization OwnerType = "organization"
+	// OwnerUser means the repository belongs to a personal account
+	OwnerUser OwnerType = "user"
+	// OwnerUnknown means the repository could not be looked up
+	OwnerUnknown OwnerType = "unknown"
+)
+
+// Ownership records who owns the repository of an action and whether it is
+// a fork of another repository
+type Ownership struct {
+	Owner string    `json:"owner"`
+	Type  OwnerType `json:"type"`
+	Fork  bool      `json:"fork,omitempty"`
+	// Parent is the owner/repo the fork was created from
+	Parent string `json:"parent,omitempty"`
+	// Allowed is set for actions matching allow_personal
+	Allowed bool `json:"allowed,omitempty"`
+}
+
+// blocked reports whether verify --require-org-owned rejects the action
+func (o *Ownership) blocked() bool {
+	return !o.Allowed && (o.Type != OwnerOrganization || o.Fork)
+}
+
+// describe summarizes the owner for the console
+func (o *Ownership) describe() string {
+	var description string
+	switch o.Type {
+	case OwnerOrganization:
+		description = "the organization " + o.Owner
+	case OwnerUser:
+		description = "the personal account " + o.Owner
+	default:
+		description = o.Owner + ", which could not be looked up"
+	}
+	switch {
+	case o.Fork && o.Parent != "":
+		description += ", a fork of " + o.Parent
+	case o.Fork:
+		description += ", a fork"
+	}
+	return description
+}
+
+// repositoryOwnership looks up the owner type and fork status of
+// owner/repo. First-party repositories are owned by GitHub's organizations
+// and need no request.
+func repositoryOwnership(gc *GitHubClient, owner, repo string) *Ownership {
+	if slices.Contains(firstPartyOwners, strings.ToLower(owner)) {
+		return &Ownership{Owner: owner, Type: OwnerOrganization}
+	}
+	repository, err := gc.api.Repository(gc.ctx, owner, repo)
+	if err != nil {
+		logger.Debug("could not look up repository", "repo", owner+"/"+repo, "error", err)
+		return &Ownership{Owner: owner, Type: OwnerUnknown}
+	}
+	ownership := &Ownership{Owner: owner, Type: OwnerUnknown, Fork: repository.Fork, Parent: repository.Parent}
+	switch repository.OwnerType {
+	case "Organization":
+		ownership.Type = OwnerOrganization
+	case "User":
+		ownership.Type = OwnerUser
+	}
+	return ownership
+}
+
+// allowsPersonal reports whether allow_personal accepts an action path.
+// Owners are case insensitive, so paths and patterns are compared in lower
+// case.
+func allowsPersonal(cfg *Config, repo string) bool {
+	patterns := make([]string, len(cfg.AllowPersonal))
+	for i, pattern := range cfg.AllowPersonal {
+		patterns[i] = strings.ToLower(pattern)
+	}
+	return matchesActionGlobs(patterns, strings.ToLower(repo))
+}
+
+// annotateOwnership stores the ownership of every action repository in the
+// actions, looking each repository up once
+func annotateOwnership(gc *GitHubClient, cfg *Config, actions WorkflowActions) {
+	lookups := make(map[string]*Ownership)
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker {
+				continue
+			}
+			owner, repo, _ := splitActionPath(action.Repo)
+			key := strings.ToLower(owner + "/" + repo)
+			ownership, ok := lookups[key]
+			if !ok {
+				ownership = repositoryOwnership(gc, owner, repo)
+				lookups[key] = ownership
+			}
+			annotated := *ownership
+			annotated.Allowed = allowsPersonal(cfg, action.Repo)
+			action.Ownership = &annotated
+		}
+	}
+}
+
+// verifyOrgOwned requires every action to come from a repository owned by
+// an organization that is not a fork, unless it matches allow_personal.
+// Repositories that could not be looked up fail too.
+func verifyOrgOwned(gc *GitHubClient, cfg *Config, actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n🏢 Verifying actions come from organization-owned repositories...")
+	annotateOwnership(gc, cfg, actions)
+
+	blocked := 0
+	reported := make(map[string]bool)
+	for _, action := range sortedByRepo(actions) {
+		ownership := action.Ownership
+		if ownership == nil {
+			continue
+		}
+		if ownership.blocked() {
+			blocked++
+		}
+		if reported[action.Repo] {
+			continue
+		}
+		reported[action.Repo] = true
+		switch {
+		case ownership.blocked():
+			fmt.Fprintf(console, "  %s %s: owned by %s %s\n", severityStatus(rulePersonalOwner), action.Repo, ownership.describe(), ruleTag(rulePersonalOwner))
+		case ownership.Type != OwnerOrganization || ownership.Fork:
+			fmt.Fprintf(console, "  %s %s: owned by %s, allowed by allow_personal\n", theme.Skipped, action.Repo, ownership.describe())
+		}
+	}
+
+	if blocked > 0 {
+		fmt.Fprintln(console, "     💡 Use the upstream or an organization-owned action, or list it under allow_personal once reviewed")
+		return fmt.Errorf("%d action(s) come from personal accounts or forks", blocked)
+	}
+	fmt.Fprintf(console, "%s All actions come from organization-owned repositories or are allowed\n", theme.OK)
+	return nil
+}
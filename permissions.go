@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	permissionRead  = "read"
+	permissionWrite = "write"
+)
+
+var (
+	// topLevelPermissionsRegex matches a workflow-level permissions key
+	topLevelPermissionsRegex = regexp.MustCompile(`(?m)^permissions\s*:`)
+
+	// topLevelJobsRegex matches the workflow-level jobs key
+	topLevelJobsRegex = regexp.MustCompile(`(?m)^jobs\s*:`)
+
+	// actionPermissions lists the GITHUB_TOKEN permissions needed by well-known
+	// actions, keyed by owner/repo. Actions not listed need only the baseline.
+	actionPermissions = map[string]map[string]string{
+		"actions/checkout":                      {"contents": permissionRead},
+		"actions/upload-artifact":               {},
+		"actions/download-artifact":             {"actions": permissionRead},
+		"actions/cache":                         {},
+		"actions/setup-go":                      {},
+		"actions/setup-node":                    {},
+		"actions/setup-python":                  {},
+		"actions/setup-java":                    {},
+		"actions/labeler":                       {"contents": permissionRead, "pull-requests": permissionWrite},
+		"actions/stale":                         {"issues": permissionWrite, "pull-requests": permissionWrite},
+		"actions/deploy-pages":                  {"pages": permissionWrite, "id-token": permissionWrite},
+		"actions/upload-pages-artifact":         {},
+		"actions/attest-build-provenance":       {"id-token": permissionWrite, "attestations": permissionWrite},
+		"github/codeql-action":                  {"security-events": permissionWrite, "actions": permissionRead, "contents": permissionRead},
+		"ossf/scorecard-action":                 {"security-events": permissionWrite, "id-token": permissionWrite},
+		"peter-evans/create-pull-request":       {"contents": permissionWrite, "pull-requests": permissionWrite},
+		"softprops/action-gh-release":           {"contents": permissionWrite},
+		"goreleaser/goreleaser-action":          {"contents": permissionWrite},
+		"docker/login-action":                   {"packages": permissionWrite},
+		"aws-actions/configure-aws-credentials": {"id-token": permissionWrite},
+		"google-github-actions/auth":            {"id-token": permissionWrite},
+		"azure/login":                           {"id-token": permissionWrite},
+		"codecov/codecov-action":                {},
+		"golangci/golangci-lint-action":         {"contents": permissionRead},
+		"step-security/harden-runner":           {},
+	}
+)
+
+// hasTopLevelPermissions reports whether a workflow declares workflow-level permissions
+func hasTopLevelPermissions(content string) bool {
+	content, _ = splitBOM(content)
+	return topLevelPermissionsRegex.MatchString(content)
+}
+
+// suggestPermissions derives a least-privilege permissions block from the
+// actions used by a workflow. The baseline is read access to contents; write
+// access is only suggested when a known action requires it.
+func suggestPermissions(actions []ActionInfo) map[string]string {
+	perms := map[string]string{"contents": permissionRead}
+
+	for _, action := range actions {
+		required, ok := actionPermissions[actionRepoRoot(action.Repo)]
+		if !ok {
+			continue
+		}
+		for scope, level := range required {
+			if perms[scope] != permissionWrite {
+				perms[scope] = level
+			}
+		}
+	}
+
+	return perms
+}
+
+// formatPermissionsBlock renders a workflow-level permissions block with sorted scopes
+func formatPermissionsBlock(perms map[string]string) string {
+	scopes := make([]string, 0, len(perms))
+	for scope := range perms {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	var b strings.Builder
+	b.WriteString("permissions:\n")
+	for _, scope := range scopes {
+		fmt.Fprintf(&b, "  %s: %s\n", scope, perms[scope])
+	}
+	return b.String()
+}
+
+// insertPermissionsBlock adds a permissions block directly before the jobs key
+func insertPermissionsBlock(content, block string) (string, error) {
+	content, bom := splitBOM(content)
+	loc := topLevelJobsRegex.FindStringIndex(content)
+	if loc == nil {
+		return "", fmt.Errorf("no top-level jobs: key found")
+	}
+	block = strings.ReplaceAll(block+"\n", "\n", lineEnding(content))
+	return bom + content[:loc[0]] + block + content[loc[0]:], nil
+}
+
+// suggestWorkflowPermissions offers to add a least-privilege permissions block
+// to each workflow that lacks one. Each block is confirmed and written as a
+// separate change from the pin updates so it can be reviewed on its own.
+func suggestWorkflowPermissions(actions WorkflowActions) {
+	workflows := make([]string, 0, len(actions))
+	for workflow := range actions {
+		// Composite actions run with the permissions of the calling workflow
+		if !isActionFile(workflow) && isYAMLFile(workflow) {
+			workflows = append(workflows, workflow)
+		}
+	}
+	sort.Strings(workflows)
+
+	for _, workflow := range workflows {
+		content, err := os.ReadFile(filepath.Clean(workflow))
+		if err != nil {
+			fmt.Fprintf(console, "  %s Could not read %s: %v\n", theme.Warning, workflow, err)
+			continue
+		}
+		if hasTopLevelPermissions(string(content)) {
+			continue
+		}
+
+		block := formatPermissionsBlock(suggestPermissions(actions[workflow]))
+		fmt.Fprintf(console, "\n🛡️  %s has no permissions: block. Suggested least-privilege block:\n", workflow)
+		for _, line := range strings.Split(strings.TrimSuffix(block, "\n"), "\n") {
+			fmt.Fprintf(console, "    %s\n", line)
+		}
+
+		if !promptForConfirmation(fmt.Sprintf("Add permissions block to %s?", workflow)) {
+			fmt.Fprintf(console, "  %s Skipped permissions for %s\n", theme.Skipped, workflow)
+			continue
+		}
+
+		newContent, err := insertPermissionsBlock(string(content), block)
+		if err != nil {
+			fmt.Fprintf(console, "  %s Could not add permissions to %s: %v\n", theme.Error, workflow, err)
+			continue
+		}
+		if err := checkWritePath(workflow); err != nil {
+			fmt.Fprintf(console, "  %s Could not add permissions to %s: %v\n", theme.Error, workflow, err)
+			continue
+		}
+		if err := os.WriteFile(workflow, []byte(newContent), 0600); err != nil {
+			fmt.Fprintf(console, "  %s Failed to write %s: %v\n", theme.Error, workflow, err)
+			continue
+		}
+		fmt.Fprintf(console, "  %s Added permissions block to %s\n", theme.OK, workflow)
+	}
+}
+
+// permissionsWriteAll grants every scope write access
+const permissionsWriteAll = "write-all"
+
+// PermissionIssue is a workflow or job whose GITHUB_TOKEN gets broader
+// permissions than its actions need
+type PermissionIssue struct {
+	Rule     string `json:"rule"`
+	Workflow string `json:"workflow"`
+	Line     int    `json:"line"`
+	// Job is empty for the workflow-level permissions
+	Job     string `json:"job,omitempty"`
+	Message string `json:"message"`
+	// Suggested is the least-privilege block derived from the actions
+	Suggested map[string]string `json:"suggested"`
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping node
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// isWriteAll reports whether a permissions value grants write-all
+func isWriteAll(perms *yaml.Node) bool {
+	return perms != nil && perms.Kind == yaml.ScalarNode && perms.Value == permissionsWriteAll
+}
+
+// workflowPermissionIssues reads the permissions of a workflow and its jobs.
+// Jobs without a block of their own in a workflow without one run with the
+// default token permissions of the repository, which are write access to
+// most scopes unless the repository or organization restricts them.
+func workflowPermissionIssues(workflow string, actions []ActionInfo) ([]PermissionIssue, error) {
+	content, err := os.ReadFile(filepath.Clean(workflow))
+	if err != nil {
+		return nil, err
+	}
+	body, _ := splitBOM(string(content))
+	docs, err := parseDocuments(body)
+	if err != nil || len(docs) == 0 || len(docs[0].Content) == 0 {
+		return nil, err
+	}
+	root := docs[0].Content[0]
+
+	jobActions := func(job string) []ActionInfo {
+		var used []ActionInfo
+		for _, action := range actions {
+			if action.Job == job {
+				used = append(used, action)
+			}
+		}
+		return used
+	}
+
+	var issues []PermissionIssue
+	_, topLevel := mappingEntry(root, "permissions")
+	if isWriteAll(topLevel) {
+		issues = append(issues, PermissionIssue{
+			Rule:      ruleWriteAll,
+			Workflow:  workflow,
+			Line:      topLevel.Line,
+			Message:   "the workflow grants write-all permissions",
+			Suggested: suggestPermissions(actions),
+		})
+	}
+
+	jobsKey, jobs := mappingEntry(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return issues, nil
+	}
+	var defaults []string
+	var defaultActions []ActionInfo
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		job := jobs.Content[i].Value
+		_, perms := mappingEntry(jobs.Content[i+1], "permissions")
+		switch {
+		case isWriteAll(perms):
+			issues = append(issues, PermissionIssue{
+				Rule:      ruleWriteAll,
+				Workflow:  workflow,
+				Line:      perms.Line,
+				Job:       job,
+				Message:   fmt.Sprintf("job %s grants write-all permissions", job),
+				Suggested: suggestPermissions(jobActions(job)),
+			})
+		case perms == nil && topLevel == nil:
+			defaults = append(defaults, job)
+			defaultActions = append(defaultActions, jobActions(job)...)
+		}
+	}
+	if len(defaults) > 0 {
+		issues = append(issues, PermissionIssue{
+			Rule:      ruleDefaultPerms,
+			Workflow:  workflow,
+			Line:      jobsKey.Line,
+			Message:   describeJobs(defaults) + " with the default token permissions",
+			Suggested: suggestPermissions(defaultActions),
+		})
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return issues, nil
+}
+
+// describeJobs names the jobs of a finding as the subject of "run"
+func describeJobs(jobs []string) string {
+	if len(jobs) == 1 {
+		return "job " + jobs[0] + " runs"
+	}
+	return "jobs " + strings.Join(jobs, ", ") + " run"
+}
+
+// findPermissionIssues lists the workflows and jobs that run with the
+// default token permissions or grant write-all. Composite actions run with
+// the permissions of the calling workflow and are skipped.
+func findPermissionIssues(actions WorkflowActions) []PermissionIssue {
+	var issues []PermissionIssue
+	for _, workflow := range sortedWorkflows(actions) {
+		if isActionFile(workflow) || !isYAMLFile(workflow) {
+			continue
+		}
+		found, err := workflowPermissionIssues(workflow, actions[workflow])
+		if err != nil {
+			logger.Debug("could not read workflow for permissions", "file", workflow, "error", err)
+			continue
+		}
+		issues = append(issues, found...)
+	}
+	return issues
+}
+
+// printPermissionIssues reports the workflow token permissions with the
+// least-privilege block to declare instead
+func printPermissionIssues(issues []PermissionIssue) {
+	var enabled []PermissionIssue
+	for _, issue := range issues {
+		if ruleEnabled(issue.Rule) {
+			enabled = append(enabled, issue)
+		}
+	}
+	if len(enabled) == 0 {
+		return
+	}
+
+	fmt.Fprintf(console, "\n🛡️  Workflows with broad token permissions (%d):\n", len(enabled))
+	for _, issue := range enabled {
+		fmt.Fprintf(console, "  %s %s:%d %s %s\n", severityStatus(issue.Rule), issue.Workflow, issue.Line, issue.Message, ruleTag(issue.Rule))
+		block := formatPermissionsBlock(issue.Suggested)
+		for _, line := range strings.Split(strings.TrimSuffix(block, "\n"), "\n") {
+			fmt.Fprintf(console, "      %s\n", line)
+		}
+	}
+	fmt.Fprintln(console, "  💡 Declare the suggested blocks; 'github-ci-hash update --suggest-permissions' adds the workflow-level ones")
+}
+
+// countPermissionIssues counts the issues of one rule
+func countPermissionIssues(issues []PermissionIssue, rule string) int {
+	count := 0
+	for _, issue := range issues {
+		if issue.Rule == rule {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// pinTarget points action at the commit or digest its current ref resolves
+// to, keeping the ref as its tag so the comment names what was written
+func pinTarget(forge Forge, registry *registryClient, action *ActionInfo) error {
+	if action.Docker {
+		return alignTarget(forge, registry, action, action.CurrentRef)
+	}
+
+	// Abbreviated SHAs already name a commit, unless a tag or branch happens
+	// to be spelled like one
+	if shortSHARegex.MatchString(action.CurrentRef) {
+		owner, repo, _ := splitActionPath(action.Repo)
+		fullSHA, err := forge.ExpandSHA(owner, repo, action.CurrentRef)
+		switch {
+		case err == nil:
+			action.CurrentSHA = fullSHA
+			action.Abbreviated = true
+			return nil
+		case errorKindOf(err) != ErrorKindNotFound:
+			return fmt.Errorf("failed to expand %s: %w", action.CurrentRef, err)
+		}
+	}
+	return alignTarget(forge, registry, action, action.CurrentRef)
+}
+
+// setupPin registers the flags of the pin command and returns its runner
+func setupPin(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	fs.BoolVar(&autoApprove, "yes", false, "Pin without prompting")
+
+	return func(args []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		unpinned := make(WorkflowActions)
+		for workflow, actionList := range actions {
+			for _, action := range actionList {
+				if !action.Pinned() {
+					unpinned[workflow] = append(unpinned[workflow], action)
+				}
+			}
+		}
+		if len(unpinned) == 0 {
+			fmt.Fprintf(console, "%s Every reference is pinned\n", theme.OK)
+			return
+		}
+
+		if err := pinActions(forgeFor(NewGitHubClient(), cfg, unpinned), unpinned); err != nil {
+			fmt.Fprintf(console, "Error pinning actions: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// pinActions resolves the ref each unpinned reference names, without
+// looking for newer versions, and after one confirmation rewrites it to that
+// commit or digest with the ref as its comment
+func pinActions(forge Forge, actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n📌 Resolving the refs as written...")
+
+	workflows := sortedWorkflows(actions)
+
+	// Container actions share one registry client so tokens are reused
+	var registry *registryClient
+
+	pinned := 0
+	var changed []string
+	for _, workflow := range workflows {
+		before := pinned
+		actionList := actions[workflow]
+		for i := range actionList {
+			action := &actionList[i]
+			if action.Docker && registry == nil {
+				registry = newRegistryClient()
+			}
+			err := pinTarget(forge, registry, action)
+			switch {
+			case err != nil:
+				action.Error = classifyError(err)
+				fmt.Fprintf(console, "  %s %s:%d %s: %v\n", theme.Error, workflow, action.Line, action.reference(), action.Error)
+			case action.Abbreviated:
+				fmt.Fprintf(console, "  %s %s:%d %s@%s: abbreviated SHA, run %s update --expand-short\n",
+					theme.Skipped, workflow, action.Line, action.Repo, action.CurrentRef, programName)
+			default:
+				action.NeedsUpdate = true
+				pinned++
+				fmt.Fprintf(console, "  %s %s:%d %s@%s → %s\n", theme.Update, workflow, action.Line,
+					strings.TrimPrefix(action.Repo, dockerPrefix), action.CurrentRef, shortPin(action.LatestSHA))
+			}
+		}
+		if pinned > before {
+			changed = append(changed, workflow)
+		}
+	}
+
+	if pinned == 0 {
+		fmt.Fprintf(console, "\n%s No references to pin\n", theme.OK)
+		return nil
+	}
+	if !promptForConfirmation(fmt.Sprintf("Pin %d reference(s) to their current commit?", pinned)) {
+		fmt.Fprintf(console, "%s No files were changed\n", theme.Skipped)
+		return nil
+	}
+
+	for _, workflow := range changed {
+		if err := updateWorkflowFile(workflow, actions[workflow]); err != nil {
+			return fmt.Errorf("failed to update %s: %w", workflow, err)
+		}
+	}
+	fmt.Fprintf(console, "\n%s Pinned %d reference(s); run %s check to look for newer versions\n", theme.OK, pinned, programName)
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	// defaultPinComment is the comment written after a pinned reference
+	defaultPinComment = "{{.Tag}}"
+
+	// pinCommentTagPlaceholder and pinCommentDatePlaceholder stand in for the
+	// fields when a comment template is turned into a pattern
+	pinCommentTagPlaceholder  = "\x00tag\x00"
+	pinCommentDatePlaceholder = "\x00date\x00"
+)
+
+// pinCommentData holds the fields available to pin_comment templates
+type pinCommentData struct {
+	// Tag is the release tag the reference is pinned to
+	Tag string
+	// Date is the publication date of the release as YYYY-MM-DD, empty when unknown
+	Date string
+}
+
+// pinCommentFormat renders the comment written after pinned references. A
+// nil template writes no comment.
+type pinCommentFormat struct {
+	tmpl    *template.Template
+	pattern *regexp.Regexp
+}
+
+// pinComment is the comment format used by updates. It is set from the
+// pin_comment setting of the configuration before updating.
+var pinComment = mustPinCommentFormat(defaultPinComment)
+
+// newPinCommentFormat parses a pin_comment template; an empty text disables comments
+func newPinCommentFormat(text string) (pinCommentFormat, error) {
+	if text == "" {
+		return pinCommentFormat{}, nil
+	}
+	tmpl, err := template.New("pin_comment").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return pinCommentFormat{}, err
+	}
+	f := pinCommentFormat{tmpl: tmpl}
+
+	// The pattern recovers the tag from comments written with this format,
+	// e.g. "pin@v4.2.1" or "v4.2.1 (2024-11-02)"
+	sample, err := f.render(pinCommentData{Tag: pinCommentTagPlaceholder, Date: pinCommentDatePlaceholder})
+	if err != nil {
+		return pinCommentFormat{}, err
+	}
+	if strings.ContainsAny(sample, "\r\n") {
+		return pinCommentFormat{}, fmt.Errorf("comment must fit on one line")
+	}
+	if !strings.Contains(sample, pinCommentTagPlaceholder) {
+		return pinCommentFormat{}, fmt.Errorf("comment must include {{.Tag}}")
+	}
+	expr := regexp.QuoteMeta(strings.TrimSpace(sample))
+	expr = strings.Replace(expr, pinCommentTagPlaceholder, `(?P<tag>\S+?)`, 1)
+	expr = strings.ReplaceAll(expr, pinCommentTagPlaceholder, `\S+?`)
+	expr = strings.ReplaceAll(expr, pinCommentDatePlaceholder, `.*?`)
+	// A note may follow the comment, as in "v4.2.1 # keep on v4"
+	f.pattern = regexp.MustCompile("^" + expr + `(?:\s|$)`)
+	return f, nil
+}
+
+// mustPinCommentFormat is newPinCommentFormat for built-in formats
+func mustPinCommentFormat(text string) pinCommentFormat {
+	f, err := newPinCommentFormat(text)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// pinCommentFormat returns the configured comment template
+func (c *Config) pinCommentFormat() string {
+	if c == nil || c.PinComment == nil {
+		return defaultPinComment
+	}
+	return *c.PinComment
+}
+
+// enabled reports whether comments are written
+func (f pinCommentFormat) enabled() bool {
+	return f.tmpl != nil
+}
+
+// render executes the template, trimming surrounding whitespace
+func (f pinCommentFormat) render(data pinCommentData) (string, error) {
+	var b strings.Builder
+	if err := f.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// format returns the comment text for an action pinned to its latest version
+func (f pinCommentFormat) format(action ActionInfo) string {
+	if !f.enabled() {
+		return ""
+	}
+	text, err := f.render(pinCommentData{Tag: action.LatestTag, Date: action.ReleaseDate})
+	if err != nil {
+		logger.Warn("could not render pin comment", "repo", action.Repo, "error", err)
+		return action.LatestTag
+	}
+	return text
+}
+
+// tag extracts the tag from a comment written with this format, falling back
+// to the first word for comments in other styles
+func (f pinCommentFormat) tag(text string) string {
+	text = strings.TrimSpace(text)
+	if f.pattern != nil {
+		if m := f.pattern.FindStringSubmatch(text); m != nil {
+			return m[f.pattern.SubexpIndex("tag")]
+		}
+	}
+	if fields := strings.Fields(text); len(fields) > 0 {
+		return strings.TrimPrefix(fields[0], "pin@")
+	}
+	return ""
+}
+
+// note returns the text of a comment besides its version tag, such as
+// "keep" in "# v3 # keep", so updates carry it over; a comment that does
+// not start with a version tag is kept whole
+func (f pinCommentFormat) note(comment string) string {
+	text := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(comment), "#!"))
+	isVersion := func(tag string) bool {
+		tag = strings.TrimPrefix(tag, "pin@")
+		return versionTagRegex.MatchString(tag) || isPrereleaseTag(tag)
+	}
+	end := 0
+	if f.pattern != nil {
+		if m := f.pattern.FindStringSubmatchIndex(text); m != nil {
+			if tag := f.pattern.SubexpIndex("tag"); isVersion(text[m[2*tag]:m[2*tag+1]]) {
+				end = m[1]
+			}
+		}
+	}
+	if fields := strings.Fields(text); end == 0 && len(fields) > 0 && isVersion(fields[0]) {
+		end = len(fields[0])
+	}
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(text[end:]), "#!"))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// plainThemeName is the theme selected by --plain
+	plainThemeName = "plain"
+)
+
+// ansiEscapeRegex matches ANSI control sequences such as colors
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// plainSymbolWords spells out the emoji that carry meaning in plain output
+var plainSymbolWords = map[rune]string{
+	'💡': "Hint: ",
+}
+
+// newPlainTheme builds the theme of --plain: statuses are spelled out as
+// words padded to one width, so columns line up and screen readers read
+// them as words
+func newPlainTheme() *Theme {
+	return &Theme{
+		Name:            plainThemeName,
+		OK:              "OK     ",
+		Update:          "UPDATE ",
+		Error:           "ERROR  ",
+		Warning:         "WARNING",
+		Skipped:         "SKIPPED",
+		Authenticated:   "OK     ",
+		Unauthenticated: "WARNING",
+	}
+}
+
+// plainWriter rewrites console output for screen readers and for pasting
+// into ticketing systems that strip formatting
+type plainWriter struct {
+	w io.Writer
+}
+
+// Write implements io.Writer
+func (p plainWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, plainText(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// plainText removes ANSI sequences and emoji from text, along with the
+// spacing that followed each emoji, spells out the emoji that carry meaning
+// and turns box drawing lines into hyphens
+func plainText(text string) string {
+	text = ansiEscapeRegex.ReplaceAllString(text, "")
+
+	var b strings.Builder
+	afterSymbol := false
+	for _, r := range text {
+		if word, ok := plainSymbolWords[r]; ok {
+			b.WriteString(word)
+			afterSymbol = true
+			continue
+		}
+		switch {
+		// Box drawing characters
+		case r >= '\u2500' && r <= '\u257F':
+			b.WriteRune('-')
+		case unicode.Is(unicode.So, r) || r == '\uFE0F' || r == '\u200D':
+			afterSymbol = true
+			continue
+		case afterSymbol && r == ' ':
+			continue
+		default:
+			b.WriteRune(r)
+		}
+		afterSymbol = false
+	}
+	return b.String()
+}
+
+// plainOutput reports whether the plain theme is active
+func plainOutput() bool {
+	return theme.Name == plainThemeName
+}
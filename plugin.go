@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// pluginPrefix starts the name of every plugin executable, so that
+	// ci-hash-lint on PATH runs as github-ci-hash lint
+	pluginPrefix = "ci-hash-"
+
+	// pluginProtocolVersion is sent with every resolver request and report
+	pluginProtocolVersion = 1
+
+	// resolverTimeout bounds a single resolver plugin call
+	resolverTimeout = 60 * time.Second
+)
+
+// pluginNameRegex matches the plugin names that are looked up on PATH
+var pluginNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// findPlugin returns the path of the ci-hash-<name> executable on PATH
+func findPlugin(name string) (string, error) {
+	if !pluginNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid plugin name %q", name)
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return "", fmt.Errorf("plugin %s%s not found on PATH", pluginPrefix, name)
+	}
+	return path, nil
+}
+
+// discoverPlugins returns the names of the plugins on PATH, sorted. A name
+// found in several directories is listed once; the first one on PATH runs.
+func discoverPlugins() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), pluginPrefix)
+			if !ok || entry.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if seen[name] || findCommandName(name) {
+				continue
+			}
+			if _, err := findPlugin(name); err == nil {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findCommandName reports whether name is a built-in command, which always
+// wins over a plugin of the same name
+func findCommandName(name string) bool {
+	cmd, _ := findCommand([]string{name})
+	return cmd != nil
+}
+
+// pluginEnv returns the environment plugins run with: the caller's, plus
+// the version and path of this binary so plugins can call back into it
+func pluginEnv() []string {
+	env := append(os.Environ(), "GITHUB_CI_HASH_VERSION="+Version)
+	if self, err := os.Executable(); err == nil {
+		env = append(env, "GITHUB_CI_HASH_BIN="+self)
+	}
+	return env
+}
+
+// runPlugin runs a plugin subcommand with the terminal attached and returns
+// its exit code
+func runPlugin(path string, args []string) int {
+	// #nosec G204 - the plugin was looked up on PATH by its validated name
+	cmd := exec.Command(path, args...)
+	cmd.Env = pluginEnv()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode()
+	case err != nil:
+		fmt.Fprintf(console, "Error running plugin %s: %v\n", filepath.Base(path), err)
+		return 1
+	}
+	return 0
+}
+
+// runReporters pipes the JSON report of a check run to the stdin of each
+// reporter plugin; their output goes to stdout
+func runReporters(names []string, report []byte) error {
+	for _, name := range names {
+		path, err := findPlugin(name)
+		if err != nil {
+			return err
+		}
+		// #nosec G204 - the plugin was looked up on PATH by its validated name
+		cmd := exec.Command(path)
+		cmd.Env = pluginEnv()
+		cmd.Stdin = bytes.NewReader(report)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("reporter %s failed: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// resolverRequest is the JSON a resolver plugin reads from stdin, one
+// request per run
+type resolverRequest struct {
+	Version     int    `json:"version"`
+	Method      string `json:"method"`
+	Owner       string `json:"owner"`
+	Repo        string `json:"repo"`
+	Ref         string `json:"ref,omitempty"`
+	Filter      string `json:"filter,omitempty"`
+	Prereleases bool   `json:"prereleases,omitempty"`
+}
+
+// resolverResponse is the JSON a resolver plugin writes to stdout. Only the
+// fields of the requested method are read.
+type resolverResponse struct {
+	Tag   string   `json:"tag,omitempty"`
+	URL   string   `json:"url,omitempty"`
+	Notes string   `json:"notes,omitempty"`
+	Date  string   `json:"date,omitempty"`
+	SHA   string   `json:"sha,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Error *struct {
+		Kind    ErrorKind `json:"kind"`
+		Message string    `json:"message"`
+	} `json:"error,omitempty"`
+
+	// Immutable reports a release locked against changes
+	Immutable bool `json:"immutable,omitempty"`
+}
+
+// pluginForge resolves actions with a ci-hash-<name> executable, for
+// internal mirrors and code hosts the tool does not know about
+type pluginForge struct {
+	name string
+}
+
+// call runs the resolver plugin with one request
+func (p *pluginForge) call(req resolverRequest) (*resolverResponse, error) {
+	path, err := findPlugin(p.name)
+	if err != nil {
+		return nil, err
+	}
+	req.Version = pluginProtocolVersion
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolverTimeout)
+	defer cancel()
+
+	// #nosec G204 - the plugin was looked up on PATH by its validated name
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = pluginEnv()
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("resolver %s failed on %s for %s/%s: %w", p.name, req.Method, req.Owner, req.Repo, err)
+	}
+
+	var resp resolverResponse
+	if err := json.Unmarshal(output, &resp); err != nil {
+		return nil, fmt.Errorf("resolver %s wrote invalid JSON: %w", p.name, err)
+	}
+	if resp.Error != nil {
+		kind := resp.Error.Kind
+		switch kind {
+		case ErrorKindRateLimited, ErrorKindNotFound, ErrorKindPermissionDenied, ErrorKindNetwork, ErrorKindTagFormat:
+		default:
+			kind = ErrorKindUnknown
+		}
+		return nil, newResolveError(kind, "%s", resp.Error.Message)
+	}
+	return &resp, nil
+}
+
+// Name implements Forge
+func (p *pluginForge) Name() string {
+	return pluginPrefix + p.name
+}
+
+// release returns the release described by a response
+func (r *resolverResponse) release() *Release {
+	return &Release{Tag: r.Tag, URL: r.URL, Notes: r.Notes, Date: r.Date, Immutable: r.Immutable}
+}
+
+// LatestRelease implements Forge
+func (p *pluginForge) LatestRelease(owner, repo string, prereleases bool) (*Release, error) {
+	resp, err := p.call(resolverRequest{Method: "latest_release", Owner: owner, Repo: repo, Prereleases: prereleases})
+	if err != nil {
+		return nil, err
+	}
+	return resp.release(), nil
+}
+
+// LatestMatchingTag implements Forge
+func (p *pluginForge) LatestMatchingTag(owner, repo string, filter *regexp.Regexp, prereleases bool) (string, *Release, error) {
+	resp, err := p.call(resolverRequest{Method: "latest_matching_tag", Owner: owner, Repo: repo, Filter: filter.String(), Prereleases: prereleases})
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.Tag, resp.release(), nil
+}
+
+// HighestTag implements Forge
+func (p *pluginForge) HighestTag(owner, repo string, filter *regexp.Regexp) (string, error) {
+	resp, err := p.call(resolverRequest{Method: "highest_tag", Owner: owner, Repo: repo, Filter: filter.String()})
+	if err != nil {
+		return "", err
+	}
+	return resp.Tag, nil
+}
+
+// ResolveRef implements Forge
+func (p *pluginForge) ResolveRef(owner, repo, ref string) (string, error) {
+	resp, err := p.call(resolverRequest{Method: "resolve_ref", Owner: owner, Repo: repo, Ref: ref})
+	if err != nil {
+		return "", err
+	}
+	if !shaRegex.MatchString(resp.SHA) {
+		return "", fmt.Errorf("resolver %s returned %q for %s, not a commit SHA", p.name, resp.SHA, ref)
+	}
+	return resp.SHA, nil
+}
+
+// ExpandSHA implements Forge
+func (p *pluginForge) ExpandSHA(owner, repo, prefix string) (string, error) {
+	resp, err := p.call(resolverRequest{Method: "expand_sha", Owner: owner, Repo: repo, Ref: prefix})
+	if err != nil {
+		return "", err
+	}
+	if !shaRegex.MatchString(resp.SHA) || !strings.HasPrefix(resp.SHA, prefix) {
+		return "", fmt.Errorf("resolver %s returned %q for %s, not a commit SHA starting with it", p.name, resp.SHA, prefix)
+	}
+	return resp.SHA, nil
+}
+
+// TagsAt implements Forge
+func (p *pluginForge) TagsAt(owner, repo, sha string) ([]string, error) {
+	resp, err := p.call(resolverRequest{Method: "tags_at", Owner: owner, Repo: repo, Ref: sha})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Tags, nil
+}
+
+// Compare implements Forge; the resolver protocol has no commit history
+func (p *pluginForge) Compare(owner, repo, base, head string) (*Comparison, error) {
+	return nil, fmt.Errorf("comparing %s...%s in %s/%s is not supported by resolver %s", base, head, owner, repo, p.name)
+}
+
+// FileContents implements Forge; the resolver protocol has no file contents
+func (p *pluginForge) FileContents(owner, repo, path, _ string) ([]byte, error) {
+	return nil, fmt.Errorf("reading %s from %s/%s is not supported by resolver %s", path, owner, repo, p.name)
+}
+
+// LatestImmutableRelease implements Forge; the resolver protocol only
+// reports immutability with the latest release
+func (p *pluginForge) LatestImmutableRelease(owner, repo string, prereleases bool) (*Release, error) {
+	release, err := p.LatestRelease(owner, repo, prereleases)
+	if err != nil {
+		return nil, err
+	}
+	if !release.Immutable {
+		return nil, newResolveError(ErrorKindNotFound, "no immutable release of %s/%s reported by resolver %s", owner, repo, p.name)
+	}
+	return release, nil
+}
+
+// ReleaseAt implements Forge; the resolver protocol has no release lookup
+func (p *pluginForge) ReleaseAt(owner, repo, tag string) (*Release, error) {
+	return nil, newResolveError(ErrorKindNotFound, "release %s of %s/%s is not supported by resolver %s", tag, owner, repo, p.name)
+}
+
+// CanonicalName implements Forge; repositories are resolved under the name
+// the workflow uses
+func (p *pluginForge) CanonicalName(owner, repo string) (string, error) {
+	return owner + "/" + repo, nil
+}
+
+// resolverForge sends the calls for repositories with a configured resolver
+// plugin to that plugin and every other call to the default forge
+type resolverForge struct {
+	cfg      *Config
+	fallback Forge
+	plugins  map[string]*pluginForge
+}
+
+// newResolverForge returns a forge that routes repositories to their
+// configured resolver plugins
+func newResolverForge(cfg *Config, fallback Forge) *resolverForge {
+	return &resolverForge{cfg: cfg, fallback: fallback, plugins: make(map[string]*pluginForge)}
+}
+
+// forge returns the forge resolving a repository
+func (r *resolverForge) forge(owner, repo string) Forge {
+	name := r.cfg.resolver(owner + "/" + repo)
+	if name == "" {
+		return r.fallback
+	}
+	if _, ok := r.plugins[name]; !ok {
+		r.plugins[name] = &pluginForge{name: name}
+	}
+	return r.plugins[name]
+}
+
+// Name implements Forge
+func (r *resolverForge) Name() string {
+	return r.fallback.Name()
+}
+
+// LatestRelease implements Forge
+func (r *resolverForge) LatestRelease(owner, repo string, prereleases bool) (*Release, error) {
+	return r.forge(owner, repo).LatestRelease(owner, repo, prereleases)
+}
+
+// LatestImmutableRelease implements Forge
+func (r *resolverForge) LatestImmutableRelease(owner, repo string, prereleases bool) (*Release, error) {
+	return r.forge(owner, repo).LatestImmutableRelease(owner, repo, prereleases)
+}
+
+// ReleaseAt implements Forge
+func (r *resolverForge) ReleaseAt(owner, repo, tag string) (*Release, error) {
+	return r.forge(owner, repo).ReleaseAt(owner, repo, tag)
+}
+
+// LatestMatchingTag implements Forge
+func (r *resolverForge) LatestMatchingTag(owner, repo string, filter *regexp.Regexp, prereleases bool) (string, *Release, error) {
+	return r.forge(owner, repo).LatestMatchingTag(owner, repo, filter, prereleases)
+}
+
+// HighestTag implements Forge
+func (r *resolverForge) HighestTag(owner, repo string, filter *regexp.Regexp) (string, error) {
+	return r.forge(owner, repo).HighestTag(owner, repo, filter)
+}
+
+// ResolveRef implements Forge
+func (r *resolverForge) ResolveRef(owner, repo, ref string) (string, error) {
+	return r.forge(owner, repo).ResolveRef(owner, repo, ref)
+}
+
+// ExpandSHA implements Forge
+func (r *resolverForge) ExpandSHA(owner, repo, prefix string) (string, error) {
+	return r.forge(owner, repo).ExpandSHA(owner, repo, prefix)
+}
+
+// TagsAt implements Forge
+func (r *resolverForge) TagsAt(owner, repo, sha string) ([]string, error) {
+	return r.forge(owner, repo).TagsAt(owner, repo, sha)
+}
+
+// Compare implements Forge
+func (r *resolverForge) Compare(owner, repo, base, head string) (*Comparison, error) {
+	return r.forge(owner, repo).Compare(owner, repo, base, head)
+}
+
+// FileContents implements Forge
+func (r *resolverForge) FileContents(owner, repo, path, ref string) ([]byte, error) {
+	return r.forge(owner, repo).FileContents(owner, repo, path, ref)
+}
+
+// CanonicalName implements Forge
+func (r *resolverForge) CanonicalName(owner, repo string) (string, error) {
+	return r.forge(owner, repo).CanonicalName(owner, repo)
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// policyInputVersion is the version of the document policies evaluate
+	policyInputVersion = 1
+
+	// Policy engines and the executables on PATH that evaluate them
+	policyEngineRego = "rego"
+	policyEngineCUE  = "cue"
+	opaExecutable    = "opa"
+	cueExecutable    = "cue"
+
+	// defaultRegoQuery is the rule Rego policies define their violations in
+	defaultRegoQuery = "data.github_ci_hash.deny"
+
+	// defaultCUEExpression is the field CUE policies list their violations in
+	defaultCUEExpression = "deny"
+
+	// policyTimeout bounds the evaluation of a single policy
+	policyTimeout = 60 * time.Second
+)
+
+// PolicyConfig is a user-supplied policy evaluated against the scanned
+// actions by verify, audit and policy eval
+type PolicyConfig struct {
+	// Path is the Rego (.rego) or CUE (.cue) policy file
+	Path string `yaml:"path"`
+	// Engine is rego or cue; defaults to the extension of Path
+	Engine string `yaml:"engine,omitempty"`
+	// Query is the Rego rule or CUE expression holding the violations,
+	// data.github_ci_hash.deny and deny by default
+	Query string `yaml:"query,omitempty"`
+}
+
+// PolicyInput is the document policies evaluate, as input in Rego and as
+// the input field in CUE
+type PolicyInput struct {
+	Version     int              `json:"version"`
+	Run         RunMetadata      `json:"run"`
+	Workflows   []PolicyWorkflow `json:"workflows"`
+	Actions     []ActionInfo     `json:"actions"`
+	Expressions []ExpressionUse  `json:"expressions"`
+	Skipped     []SkippedFile    `json:"skipped"`
+}
+
+// PolicyWorkflow is the metadata of a scanned workflow or composite action
+type PolicyWorkflow struct {
+	Path string `json:"path"`
+	// Composite is set for action.yml files
+	Composite bool `json:"composite,omitempty"`
+	// Events lists the events triggering the workflow
+	Events []string `json:"events"`
+	// Permissions is set when the workflow declares top-level permissions
+	Permissions bool `json:"permissions"`
+	Actions     int  `json:"actions"`
+}
+
+// engine returns the engine evaluating the policy
+func (p PolicyConfig) engine() string {
+	if p.Engine != "" {
+		return p.Engine
+	}
+	switch filepath.Ext(p.Path) {
+	case ".rego":
+		return policyEngineRego
+	case ".cue":
+		return policyEngineCUE
+	}
+	return ""
+}
+
+// validatePolicies checks that every policy names a file and an engine
+func validatePolicies(policies []PolicyConfig) error {
+	for i, policy := range policies {
+		if policy.Path == "" {
+			return fmt.Errorf("policy %d has no path", i+1)
+		}
+		switch policy.engine() {
+		case policyEngineRego, policyEngineCUE:
+		case "":
+			return fmt.Errorf("cannot tell the engine of %s from its extension; set engine to rego or cue", policy.Path)
+		default:
+			return fmt.Errorf("unknown engine %q for %s, expected rego or cue", policy.Engine, policy.Path)
+		}
+	}
+	return nil
+}
+
+// buildPolicyInput collects the scan results into the policy input
+func buildPolicyInput(cfg *Config, actions WorkflowActions, skipped []SkippedFile, expressions []ExpressionUse) PolicyInput {
+	input := PolicyInput{
+		Version:     policyInputVersion,
+		Run:         runMetadata(cfg),
+		Workflows:   []PolicyWorkflow{},
+		Actions:     sortedActions(actions),
+		Expressions: expressions,
+		Skipped:     skipped,
+	}
+	if input.Actions == nil {
+		input.Actions = []ActionInfo{}
+	}
+	if input.Expressions == nil {
+		input.Expressions = []ExpressionUse{}
+	}
+	if input.Skipped == nil {
+		input.Skipped = []SkippedFile{}
+	}
+	for _, workflow := range sortedWorkflows(actions) {
+		metadata := PolicyWorkflow{Path: workflow, Composite: isActionFile(workflow), Events: []string{}, Actions: len(actions[workflow])}
+		if content, err := os.ReadFile(filepath.Clean(workflow)); err == nil {
+			metadata.Permissions = hasTopLevelPermissions(string(content))
+			if events := workflowFileEvents(workflow); events != nil {
+				metadata.Events = events
+			}
+		}
+		input.Workflows = append(input.Workflows, metadata)
+	}
+	return input
+}
+
+// policyViolations decodes the violations a policy returned. Each is a
+// message or an object with msg, workflow, line, repo and ref fields.
+func policyViolations(values []json.RawMessage) ([]PolicyViolation, error) {
+	violations := make([]PolicyViolation, 0, len(values))
+	for _, value := range values {
+		var message string
+		if err := json.Unmarshal(value, &message); err == nil {
+			violations = append(violations, PolicyViolation{Message: message})
+			continue
+		}
+		var object struct {
+			PolicyViolation
+			Msg string `json:"msg"`
+		}
+		if err := json.Unmarshal(value, &object); err != nil {
+			return nil, fmt.Errorf("violation %s is neither a message nor an object", value)
+		}
+		if object.Message == "" {
+			object.Message = object.Msg
+		}
+		if object.Message == "" {
+			return nil, fmt.Errorf("violation %s has no msg", value)
+		}
+		violations = append(violations, object.PolicyViolation)
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Workflow != violations[j].Workflow {
+			return violations[i].Workflow < violations[j].Workflow
+		}
+		return violations[i].Line < violations[j].Line
+	})
+	return violations, nil
+}
+
+// runPolicyEngine runs an engine executable and returns its stdout
+func runPolicyEngine(executable string, stdin []byte, args ...string) ([]byte, error) {
+	path, err := exec.LookPath(executable)
+	if err != nil {
+		return nil, fmt.Errorf("%s not found on PATH", executable)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), policyTimeout)
+	defer cancel()
+
+	// #nosec G204 - the arguments are the configured policy and query
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s failed: %s", executable, message)
+		}
+		return nil, fmt.Errorf("%s failed: %w", executable, err)
+	}
+	return output, nil
+}
+
+// evalRego evaluates a Rego policy with opa eval
+func evalRego(policy PolicyConfig, input []byte) ([]json.RawMessage, error) {
+	query := policy.Query
+	if query == "" {
+		query = defaultRegoQuery
+	}
+	output, err := runPolicyEngine(opaExecutable, input, "eval", "--format", "json", "--stdin-input", "--data", policy.Path, query)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Result []struct {
+			Expressions []struct {
+				Value json.RawMessage `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("invalid opa output: %w", err)
+	}
+	// An undefined rule has no result, which means no violations
+	var values []json.RawMessage
+	for _, r := range result.Result {
+		for _, expression := range r.Expressions {
+			var set []json.RawMessage
+			if err := json.Unmarshal(expression.Value, &set); err != nil {
+				return nil, fmt.Errorf("%s must be a set or array of violations", query)
+			}
+			values = append(values, set...)
+		}
+	}
+	return values, nil
+}
+
+// evalCUE evaluates a CUE policy with cue export. The input is unified with
+// the policy as its input field.
+func evalCUE(policy PolicyConfig, input []byte) ([]json.RawMessage, error) {
+	expression := policy.Query
+	if expression == "" {
+		expression = defaultCUEExpression
+	}
+	file, err := os.CreateTemp("", "github-ci-hash-policy-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	document := append(append([]byte(`{"input":`), input...), '}')
+	if _, err := file.Write(document); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	output, err := runPolicyEngine(cueExecutable, nil, "export", "--out", "json", "-e", expression, policy.Path, file.Name())
+	if err != nil {
+		return nil, err
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(output, &values); err != nil {
+		return nil, fmt.Errorf("%s must be a list of violations", expression)
+	}
+	return values, nil
+}
+
+// evaluatePolicies evaluates every policy against the input and returns a
+// required policy rule per file. A policy that cannot be evaluated fails.
+func evaluatePolicies(policies []PolicyConfig, input PolicyInput) ([]PolicyResult, error) {
+	document, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]PolicyResult, 0, len(policies))
+	var errs []error
+	for _, policy := range policies {
+		var values []json.RawMessage
+		switch policy.engine() {
+		case policyEngineRego:
+			values, err = evalRego(policy, document)
+		case policyEngineCUE:
+			values, err = evalCUE(policy, document)
+		default:
+			err = fmt.Errorf("unknown engine %q", policy.Engine)
+		}
+		result := PolicyResult{Rule: "policy:" + policy.Path, Description: "Custom " + policy.engine() + " policy " + policy.Path, Required: true}
+		if err == nil {
+			result.Violations, err = policyViolations(values)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("policy %s: %w", policy.Path, err))
+			result.Violations = []PolicyViolation{{Message: "could not be evaluated: " + err.Error()}}
+		}
+		result.Passed = len(result.Violations) == 0
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// printPolicyResults lists the violations of every custom policy
+func printPolicyResults(results []PolicyResult) {
+	for _, result := range results {
+		if result.Passed {
+			fmt.Fprintf(console, "  %s %s\n", theme.OK, strings.TrimPrefix(result.Rule, "policy:"))
+			continue
+		}
+		fmt.Fprintf(console, "  %s %s (%d) %s:\n", severityStatus(ruleCustomPolicy), strings.TrimPrefix(result.Rule, "policy:"), len(result.Violations), ruleTag(ruleCustomPolicy))
+		for _, violation := range result.Violations {
+			printViolation(violation)
+		}
+	}
+}
+
+// checkPolicies evaluates the configured policies for verify and returns an
+// error when any reports a violation. Nothing is evaluated without policies
+// or when the rule is disabled.
+func checkPolicies(cfg *Config, actions WorkflowActions, skipped []SkippedFile, expressions []ExpressionUse) error {
+	if len(cfg.Policies) == 0 || !ruleEnabled(ruleCustomPolicy) {
+		return nil
+	}
+	fmt.Fprintln(console, "\n📜 Evaluating custom policies...")
+
+	results, _ := evaluatePolicies(cfg.Policies, buildPolicyInput(cfg, actions, skipped, expressions))
+	printPolicyResults(results)
+	violations := 0
+	for _, result := range results {
+		violations += len(result.Violations)
+	}
+	if violations > 0 {
+		return fmt.Errorf("%d custom policy violation(s)", violations)
+	}
+	return nil
+}
+
+// setupPolicyEval registers the flags of the policy eval command and returns
+// its runner
+func setupPolicyEval(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	inputOnly := fs.Bool("input", false, "Write the policy input document to stdout instead of evaluating, e.g. to write a policy against it")
+	resolve := fs.Bool("resolve", false, "Resolve the latest release of every action through the API first, so policies can check versions and release dates")
+	jsonOutput := fs.Bool("json", false, "Write the policy results as JSON to stdout")
+
+	return func(args []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+		policies := cfg.Policies
+		if len(args) > 0 {
+			policies = make([]PolicyConfig, 0, len(args))
+			for _, arg := range args {
+				policies = append(policies, PolicyConfig{Path: arg})
+			}
+		}
+		if err := validatePolicies(policies); err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		if len(policies) == 0 && !*inputOnly {
+			fmt.Fprintf(console, "Usage: %s policy eval [options] [policy files...]\n", programName)
+			fmt.Fprintln(console, "No policies are configured; list them under policies or pass policy files")
+			os.Exit(2)
+		}
+		if *jsonOutput || *inputOnly {
+			console = os.Stderr
+		}
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, skipped, err := scanTargets(nil, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+		if *resolve {
+			checkForUpdates(forgeFor(NewGitHubClient(), cfg, actions), actions, cfg)
+		}
+		input := buildPolicyInput(cfg, actions, skipped, collectExpressionUses(actions, skipped))
+
+		if *inputOnly {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(input); err != nil {
+				fmt.Fprintf(console, "Error writing policy input: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		fmt.Fprintln(console, "\n📜 Evaluating custom policies...")
+		results, err := evaluatePolicies(policies, input)
+		if *jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(results); err != nil {
+				fmt.Fprintf(console, "Error writing JSON report: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			printPolicyResults(results)
+		}
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, result := range results {
+			if !result.Passed {
+				os.Exit(1)
+			}
+		}
+		fmt.Fprintf(console, "%s All policies passed\n", theme.OK)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// PinReason names the rule that selected the version an action is pinned to
+type PinReason string
+
+const (
+	// PinLatestRelease means the latest published release was selected
+	PinLatestRelease PinReason = "latest_release"
+	// PinTagFilter means the newest tag matching the configured tag_filter was selected
+	PinTagFilter PinReason = "tag_filter"
+	// PinHighestTag means the highest version tag was selected on a forge
+	// without release metadata
+	PinHighestTag PinReason = "highest_tag"
+	// PinImageTag means the digest the image tag points at was selected
+	PinImageTag PinReason = "image_tag"
+	// PinDigest means a digest pin without a tag to follow was kept as written
+	PinDigest PinReason = "digest"
+	// PinUpdateStrategy means the latest release was beyond the configured
+	// update strategy and the newest tag within it was selected
+	PinUpdateStrategy PinReason = "update_strategy"
+	// PinRequestedVersion means the version was named with update --to or
+	// at the update prompt instead of the latest release
+	PinRequestedVersion PinReason = "requested_version"
+	// PinCoolOff means the latest release is younger than the configured
+	// minimum release age and the current version was kept
+	PinCoolOff PinReason = "cool_off"
+	// PinImmutable means the latest release is mutable and the newest
+	// immutable release was selected
+	PinImmutable PinReason = "immutable_release"
+)
+
+// Provenance records why a pin is what it is, so that later readers see
+// the intent instead of guessing it from tag comments
+type Provenance struct {
+	Reason PinReason `json:"reason"`
+	// Tag is the tag or release the rule selected
+	Tag string `json:"tag,omitempty"`
+	// Constraint is the tag_filter expression for PinTagFilter
+	Constraint string `json:"constraint,omitempty"`
+	// Setting is the configuration key holding the constraint
+	Setting string `json:"setting,omitempty"`
+	// Source is the forge or registry the version was looked up on
+	Source string `json:"source,omitempty"`
+	// Outdated is set when the pin is behind the version the rule selects
+	Outdated bool `json:"outdated,omitempty"`
+}
+
+// String describes the provenance in a sentence fragment
+func (p *Provenance) String() string {
+	var desc string
+	switch p.Reason {
+	case PinLatestRelease:
+		desc = "latest release " + p.Tag
+	case PinTagFilter:
+		desc = fmt.Sprintf("newest tag %s matching %s (%s)", p.Tag, p.Constraint, p.Setting)
+	case PinHighestTag:
+		desc = "highest version tag " + p.Tag
+	case PinImageTag:
+		desc = "digest of image tag " + p.Tag
+	case PinDigest:
+		desc = "digest kept as written, no tag to follow"
+	case PinUpdateStrategy:
+		desc = fmt.Sprintf("newest tag %s matching %s (%s)", p.Tag, p.Constraint, p.Setting)
+	case PinRequestedVersion:
+		desc = "requested version " + p.Tag
+	case PinImmutable:
+		desc = fmt.Sprintf("newest immutable release %s (%s)", p.Tag, p.Setting)
+	case PinCoolOff:
+		desc = fmt.Sprintf("current version, release %s is within its cool-off period (%s)", p.Tag, p.Setting)
+	default:
+		desc = string(p.Reason)
+	}
+	if p.Source != "" {
+		desc += " on " + p.Source
+	}
+	if p.Outdated {
+		desc = "behind the " + desc
+	}
+	return desc
+}
+
+// pinProvenance returns the provenance of the action's current pin, or nil
+// when the action was not resolved
+func (a ActionInfo) pinProvenance() *Provenance {
+	if a.Provenance == nil || a.Error != nil {
+		return nil
+	}
+	p := *a.Provenance
+	p.Outdated = a.NeedsUpdate
+	return &p
+}
+
+// releaseProvenance describes the release selected by resolveAction
+func releaseProvenance(forge Forge, configKey, tag string, cfg *Config) *Provenance {
+	p := &Provenance{Reason: PinLatestRelease, Tag: tag, Source: forge.Name()}
+	if filter := cfg.actionConfig(configKey).tagFilter; filter != nil {
+		p.Reason = PinTagFilter
+		p.Constraint = filter.String()
+		p.Setting = "actions." + configKey + ".tag_filter"
+	} else if p.Source == "git" {
+		// git has no releases, so gitForge selects the highest version tag
+		p.Reason = PinHighestTag
+	}
+	return p
+}
+
+// matchesActionPattern reports whether an action matches a why argument:
+// the exact repository, or a glob such as "actions/*"
+func matchesActionPattern(pattern string, action ActionInfo) bool {
+	if pattern == action.Repo || pattern == strings.TrimPrefix(action.Repo, dockerPrefix) {
+		return true
+	}
+	matched, err := path.Match(pattern, action.Repo)
+	return err == nil && matched
+}
+
+// setupWhy registers the flags of the why command and returns its runner
+func setupWhy(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+
+	return func(args []string) {
+		if len(args) == 0 {
+			fmt.Fprintf(console, "Usage: %s why [options] action...\n", programName)
+			os.Exit(2)
+		}
+
+		cfg, scanOpts := mustLoadScanConfig(opts)
+		actions, _, err := scanTargets(nil, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		selected := make(WorkflowActions)
+		for workflow, actionList := range actions {
+			for _, action := range actionList {
+				for _, pattern := range args {
+					if matchesActionPattern(pattern, action) {
+						selected[workflow] = append(selected[workflow], action)
+						break
+					}
+				}
+			}
+		}
+		if len(selected) == 0 {
+			fmt.Fprintf(console, "No workflow uses %s\n", strings.Join(args, ", "))
+			os.Exit(1)
+		}
+
+		checkForUpdates(forgeFor(NewGitHubClient(), cfg, selected), selected, cfg)
+		fmt.Fprintln(console)
+		printProvenance(selected)
+	}
+}
+
+// printProvenance explains every pin, grouped by action
+func printProvenance(actions WorkflowActions) {
+	repo := ""
+	for _, action := range sortedByRepo(actions) {
+		if action.Repo != repo {
+			repo = action.Repo
+			fmt.Fprintf(console, "📌 %s\n", repo)
+		}
+
+		pin := action.CurrentRef
+		if action.Pinned() {
+			pin = shortPin(action.CurrentRef)
+		}
+		fmt.Fprintf(console, "  %s:%d @%s\n", action.WorkflowFile, action.Line, pin)
+
+		p := action.pinProvenance()
+		switch {
+		case p == nil:
+			fmt.Fprintf(console, "     %s could not be resolved: %v\n", theme.Error, action.Error)
+		case !action.Pinned():
+			fmt.Fprintf(console, "     %s not pinned; the %s is %s\n", theme.Warning, action.Provenance, shortPin(action.LatestSHA))
+		case p.Outdated:
+			fmt.Fprintf(console, "     %s %s (%s)\n", theme.Update, p, shortPin(action.LatestSHA))
+		default:
+			fmt.Fprintf(console, "     %s %s\n", theme.OK, p)
+		}
+	}
+}
+
+// sortedByRepo returns all actions ordered by repository, workflow file and line
+func sortedByRepo(actions WorkflowActions) []ActionInfo {
+	sorted := sortedActions(actions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Repo < sorted[j].Repo
+	})
+	return sorted
+}
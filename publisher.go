@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TwoFactorStatus is whether the publisher of an action enforces two-factor
+// authentication for its members
+type TwoFactorStatus string
+
+const (
+	// TwoFactorEnforced means the organization requires 2FA of its members
+	TwoFactorEnforced TwoFactorStatus = "enforced"
+	// TwoFactorNotEnforced means the organization does not require 2FA
+	TwoFactorNotEnforced TwoFactorStatus = "not_enforced"
+	// TwoFactorUnknown means the setting could not be read
+	TwoFactorUnknown TwoFactorStatus = "unknown"
+	// TwoFactorPersonal means the action is published by a user account,
+	// which has no organization policy
+	TwoFactorPersonal TwoFactorStatus = "personal_account"
+)
+
+// firstPartyOwners publish the actions maintained by GitHub itself
+var firstPartyOwners = []string{"actions", "github"}
+
+// PublisherSignal records the 2FA policy of the owner of third-party actions
+type PublisherSignal struct {
+	Owner  string          `json:"owner"`
+	Status TwoFactorStatus `json:"status"`
+	// Source is "api" or "config" for owners listed in two_factor_orgs
+	Source string `json:"source,omitempty"`
+	// Repos lists the actions published by the owner
+	Repos []string `json:"repos"`
+}
+
+// isThirdParty reports whether an action is published outside GitHub
+func isThirdParty(action ActionInfo) bool {
+	owner, _, _ := strings.Cut(action.Repo, "/")
+	return !action.Docker && !slices.Contains(firstPartyOwners, owner)
+}
+
+// publisherTwoFactor looks up whether owner enforces 2FA. Organizations
+// listed in two_factor_orgs are trusted without a request.
+func publisherTwoFactor(gc *GitHubClient, cfg *Config, owner string) (TwoFactorStatus, string) {
+	for _, org := range cfg.TwoFactorOrgs {
+		if strings.EqualFold(org, owner) {
+			return TwoFactorEnforced, "config"
+		}
+	}
+
+	required, err := gc.api.OrgTwoFactorRequired(gc.ctx, owner)
+	switch {
+	case err != nil && errorKindOf(err) == ErrorKindNotFound:
+		return TwoFactorPersonal, "api"
+	case err != nil:
+		logger.Debug("could not read organization settings", "owner", owner, "error", err)
+		return TwoFactorUnknown, ""
+	case required == nil:
+		return TwoFactorUnknown, ""
+	case *required:
+		return TwoFactorEnforced, "api"
+	}
+	return TwoFactorNotEnforced, "api"
+}
+
+// collectPublisherSignals returns the 2FA signal of every third-party
+// publisher, ordered by owner
+func collectPublisherSignals(gc *GitHubClient, cfg *Config, actions WorkflowActions) []PublisherSignal {
+	repos := make(map[string][]string)
+	for _, action := range sortedByRepo(actions) {
+		if !isThirdParty(action) {
+			continue
+		}
+		owner, _, _ := strings.Cut(action.Repo, "/")
+		if !slices.Contains(repos[owner], action.Repo) {
+			repos[owner] = append(repos[owner], action.Repo)
+		}
+	}
+
+	signals := make([]PublisherSignal, 0, len(repos))
+	for owner, ownerRepos := range repos {
+		status, source := publisherTwoFactor(gc, cfg, owner)
+		signals = append(signals, PublisherSignal{Owner: owner, Status: status, Source: source, Repos: ownerRepos})
+	}
+	sort.Slice(signals, func(i, j int) bool {
+		return signals[i].Owner < signals[j].Owner
+	})
+	return signals
+}
+
+// verifyPublisherTwoFactor requires every third-party action to come from an
+// organization that enforces 2FA. Unknown settings fail, since a
+// high-assurance check cannot assume the best.
+func verifyPublisherTwoFactor(gc *GitHubClient, cfg *Config, actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n🔐 Verifying third-party publishers enforce two-factor authentication...")
+
+	failed := 0
+	for _, signal := range collectPublisherSignals(gc, cfg, actions) {
+		repos := strings.Join(signal.Repos, ", ")
+		switch signal.Status {
+		case TwoFactorEnforced:
+			fmt.Fprintf(console, "  %s %s enforces 2FA (%s)\n", theme.OK, signal.Owner, signal.Source)
+			continue
+		case TwoFactorNotEnforced:
+			fmt.Fprintf(console, "  %s %s does not enforce 2FA: %s %s\n", severityStatus(ruleTwoFactor), signal.Owner, repos, ruleTag(ruleTwoFactor))
+		case TwoFactorPersonal:
+			fmt.Fprintf(console, "  %s %s is a personal account without an organization 2FA policy: %s %s\n", severityStatus(ruleTwoFactor), signal.Owner, repos, ruleTag(ruleTwoFactor))
+		default:
+			fmt.Fprintf(console, "  %s %s 2FA setting is not readable with this token: %s %s\n", severityStatus(ruleTwoFactor), signal.Owner, repos, ruleTag(ruleTwoFactor))
+			fmt.Fprintf(console, "     💡 List the organization under two_factor_orgs once its policy is confirmed\n")
+		}
+		failed++
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d publisher(s) are not known to enforce two-factor authentication", failed)
+	}
+	fmt.Fprintf(console, "%s All third-party publishers enforce two-factor authentication\n", theme.OK)
+	return nil
+}
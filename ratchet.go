@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// defaultBaselineFile stores the violation count verify --ratchet allows
+	defaultBaselineFile = ".github-ci-hash-baseline.json"
+
+	// baselineFormatVersion is the version of the baseline file format
+	baselineFormatVersion = 1
+)
+
+// Baseline is the number of unpinned actions verify --ratchet tolerates. It
+// is committed to the repository so that legacy workflows can be enforced
+// right away and cleaned up over time.
+type Baseline struct {
+	Version    int         `json:"version"`
+	Violations int         `json:"violations"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	Run        RunMetadata `json:"run"`
+}
+
+// readBaseline loads a baseline file
+func readBaseline(path string) (*Baseline, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var baseline Baseline
+	if err := json.Unmarshal(content, &baseline); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s: %w", path, err)
+	}
+	if baseline.Version != baselineFormatVersion {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s", baseline.Version, path)
+	}
+	return &baseline, nil
+}
+
+// writeBaseline stores violations as the baseline in path
+func writeBaseline(path string, violations int, cfg *Config) error {
+	if err := checkWritePath(path); err != nil {
+		return err
+	}
+	data, err := marshalIndented(Baseline{
+		Version:    baselineFormatVersion,
+		Violations: violations,
+		UpdatedAt:  time.Now().UTC().Truncate(time.Second),
+		Run:        runMetadata(cfg),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Clean(path), data, 0600)
+}
+
+// verifyRatchet fails only when there are more unpinned actions than the
+// baseline allows. Without a baseline the current count becomes the
+// baseline, so enforcement starts without a first cleanup pass.
+func verifyRatchet(actions WorkflowActions, path string, cfg *Config) error {
+	fmt.Fprintln(console, "\n🔒 Verifying unpinned actions do not exceed the baseline...")
+
+	unpinned := findUnpinned(actions)
+	baseline, err := readBaseline(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := writeBaseline(path, len(unpinned), cfg); err != nil {
+			return fmt.Errorf("failed to write baseline: %w", err)
+		}
+		fmt.Fprintf(console, "📏 Recorded a baseline of %d unpinned actions in %s; commit it to enforce the ratchet\n", len(unpinned), path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(unpinned) > baseline.Violations:
+		printUnpinned(unpinned)
+		return fmt.Errorf("found %d unpinned actions, the baseline in %s allows %d", len(unpinned), path, baseline.Violations)
+	case len(unpinned) < baseline.Violations:
+		fmt.Fprintf(console, "%s %d unpinned actions, down from %d; run '%s baseline tighten' to lock in the progress\n",
+			theme.OK, len(unpinned), baseline.Violations, programName)
+	default:
+		fmt.Fprintf(console, "%s %d unpinned actions, at the baseline\n", theme.OK, len(unpinned))
+	}
+	return nil
+}
+
+// setupBaselineTighten registers the flags of the baseline tighten command
+// and returns its runner
+func setupBaselineTighten(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	baselinePath := fs.String("baseline", defaultBaselineFile, "Path to the baseline `file`")
+
+	return func(args []string) {
+		if len(args) > 0 {
+			fmt.Fprintf(console, "Usage: %s baseline tighten [options]\n", programName)
+			os.Exit(2)
+		}
+		cfg, scanOpts := mustLoadScanConfig(opts)
+
+		actions, _, err := scanWorkflows(scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+		current := len(findUnpinned(actions))
+
+		baseline, err := readBaseline(*baselinePath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		case current > baseline.Violations:
+			fmt.Fprintf(console, "Error: %d unpinned actions exceed the baseline of %d; pin the new ones before tightening\n", current, baseline.Violations)
+			os.Exit(1)
+		case current == baseline.Violations:
+			fmt.Fprintf(console, "%s The baseline is already at %d unpinned actions\n", theme.OK, current)
+			return
+		}
+
+		if err := writeBaseline(*baselinePath, current, cfg); err != nil {
+			fmt.Fprintf(console, "Error writing baseline: %v\n", err)
+			os.Exit(1)
+		}
+		if baseline == nil {
+			fmt.Fprintf(console, "📏 Recorded a baseline of %d unpinned actions in %s\n", current, *baselinePath)
+		} else {
+			fmt.Fprintf(console, "📏 Lowered the baseline in %s from %d to %d unpinned actions\n", *baselinePath, baseline.Violations, current)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// TokenKind classifies a GitHub token by the rate limit it is subject to
+type TokenKind string
+
+const (
+	// TokenNone means requests are unauthenticated
+	TokenNone TokenKind = "none"
+	// TokenClassicPAT is a classic personal access token (ghp_)
+	TokenClassicPAT TokenKind = "classic_pat"
+	// TokenFineGrainedPAT is a fine-grained personal access token (github_pat_)
+	TokenFineGrainedPAT TokenKind = "fine_grained_pat"
+	// TokenOAuth is an OAuth app or gh CLI token (gho_)
+	TokenOAuth TokenKind = "oauth"
+	// TokenUserToServer is a GitHub App token acting for a user (ghu_)
+	TokenUserToServer TokenKind = "user_to_server"
+	// TokenInstallation is a GitHub App installation token (ghs_), which
+	// includes the GITHUB_TOKEN of workflow runs
+	TokenInstallation TokenKind = "installation"
+	// TokenUnknown is a token without a recognized prefix
+	TokenUnknown TokenKind = "unknown"
+)
+
+const (
+	// unauthenticatedHourlyLimit is the REST quota of unauthenticated requests
+	unauthenticatedHourlyLimit = 60
+	// userHourlyLimit is the REST quota of personal and OAuth tokens
+	userHourlyLimit = 5000
+	// actionsHourlyLimit is the REST quota of the GITHUB_TOKEN in workflow runs
+	actionsHourlyLimit = 1000
+
+	// requestsPerAction estimates the REST calls resolving one action costs:
+	// the release lookup and the tag ref with its annotated tag object
+	requestsPerAction = 3
+	// requestsPerUnpinned estimates the extra calls resolving a tag pin costs
+	requestsPerUnpinned = 2
+)
+
+// tokenKindOf classifies a token by its prefix
+func tokenKindOf(token string) TokenKind {
+	switch {
+	case token == "":
+		return TokenNone
+	case strings.HasPrefix(token, "ghp_"):
+		return TokenClassicPAT
+	case strings.HasPrefix(token, "github_pat_"):
+		return TokenFineGrainedPAT
+	case strings.HasPrefix(token, "gho_"):
+		return TokenOAuth
+	case strings.HasPrefix(token, "ghu_"):
+		return TokenUserToServer
+	case strings.HasPrefix(token, "ghs_"):
+		return TokenInstallation
+	}
+	return TokenUnknown
+}
+
+// Label returns the token kind as shown in the authentication status
+func (k TokenKind) Label() string {
+	switch k {
+	case TokenClassicPAT:
+		return "classic personal access token"
+	case TokenFineGrainedPAT:
+		return "fine-grained personal access token"
+	case TokenOAuth:
+		return "OAuth token"
+	case TokenUserToServer:
+		return "GitHub App user token"
+	case TokenInstallation:
+		return "GitHub App installation token"
+	case TokenNone:
+		return "no token"
+	}
+	return "token"
+}
+
+// hourlyLimit returns the nominal REST quota of a token kind. Installation
+// tokens get at least 5,000 requests per hour, except the GITHUB_TOKEN of
+// workflow runs which gets 1,000 per repository.
+func (k TokenKind) hourlyLimit() int {
+	switch k {
+	case TokenNone:
+		return unauthenticatedHourlyLimit
+	case TokenInstallation:
+		if os.Getenv("GITHUB_ACTIONS") == "true" {
+			return actionsHourlyLimit
+		}
+	}
+	return userHourlyLimit
+}
+
+// estimateRequests returns the REST calls resolving the actions is expected
+// to cost. Lookups are memoized, so each repository and each unpinned ref
+// is only counted once however many workflows use it.
+func estimateRequests(actions WorkflowActions) int {
+	repositories := make(map[string]bool)
+	refs := make(map[string]bool)
+	for _, actionList := range actions {
+		for _, action := range actionList {
+			if action.Docker {
+				continue
+			}
+			owner, repo, _ := splitActionPath(action.Repo)
+			key := strings.ToLower(owner + "/" + repo)
+			repositories[key] = true
+			if action.CurrentSHA == "" {
+				refs[key+"@"+action.CurrentRef] = true
+			}
+		}
+	}
+	return len(repositories)*requestsPerAction + len(refs)*requestsPerUnpinned
+}
+
+// remainingRequests returns the REST requests left in the current window.
+// When the rate limit cannot be read the nominal limit of the token kind is
+// assumed.
+func (gc *GitHubClient) remainingRequests() int {
+	remaining, err := gc.api.RemainingRequests(gc.ctx)
+	if err != nil {
+		logger.Debug("rate limit unavailable, assuming the nominal quota", "token", gc.tokenKind, "error", err)
+		return gc.tokenKind.hourlyLimit()
+	}
+	return remaining
+}
+
+// budgetRequests compares the requests a run needs with the remaining quota
+// of the token and reports whether the run would exhaust it
+func budgetRequests(gc *GitHubClient, actions WorkflowActions) bool {
+	needed := estimateRequests(actions)
+	if needed == 0 {
+		return false
+	}
+	remaining := gc.remainingRequests()
+	logger.Info("planned API budget", "token", gc.tokenKind, "hourly_limit", gc.tokenKind.hourlyLimit(),
+		"remaining", remaining, "estimated", needed)
+	if needed <= remaining {
+		return false
+	}
+	fmt.Fprintf(console, "%s About %d API requests are needed but only %d remain for this %s\n",
+		theme.Warning, needed, remaining, gc.tokenKind.Label())
+	return true
+}
+
+// RateLimitReport is the outcome of rate-limit
+type RateLimitReport struct {
+	Run RunMetadata `json:"run"`
+	// Identity is the login of the token's user, empty for installation
+	// tokens and unauthenticated requests
+	Identity string            `json:"identity,omitempty"`
+	Token    TokenKind         `json:"token"`
+	Limits   *githubRateLimits `json:"limits"`
+	Estimate RequestEstimate   `json:"estimate"`
+}
+
+// RequestEstimate is the cost of a full check of the scanned workflows
+type RequestEstimate struct {
+	Actions      int `json:"actions"`
+	Repositories int `json:"repositories"`
+	// RESTRequests is the cost without GraphQL batches or cached responses
+	RESTRequests int `json:"rest_requests"`
+	// GraphQLQueries is the number of batches answering most lookups, 0
+	// when batching is off or there is no token
+	GraphQLQueries int `json:"graphql_queries"`
+}
+
+// estimateCheck returns the cost of a full check of actions
+func estimateCheck(gc *GitHubClient, cfg *Config, actions WorkflowActions) RequestEstimate {
+	estimate := RequestEstimate{RESTRequests: estimateRequests(actions)}
+	repositories := make(map[string]bool)
+	for _, action := range sortedActions(actions) {
+		if action.Docker {
+			continue
+		}
+		estimate.Actions++
+		owner, repo, _ := splitActionPath(action.Repo)
+		repositories[strings.ToLower(owner+"/"+repo)] = true
+	}
+	estimate.Repositories = len(repositories)
+	if batchGraphQL && gc.tokenKind != TokenNone {
+		targets := len(graphqlTargets(cfg, actions))
+		estimate.GraphQLQueries = (targets + graphqlBatchSize - 1) / graphqlBatchSize
+	}
+	return estimate
+}
+
+// describeRate formats a quota with the time it resets
+func describeRate(rate githubRate) string {
+	description := fmt.Sprintf("%d of %d left", rate.Remaining, rate.Limit)
+	if !rate.Reset.IsZero() {
+		description += fmt.Sprintf(", resets at %s (in %s)", rate.Reset.Local().Format(time.Kitchen), strings.TrimSuffix(max(time.Until(rate.Reset), 0).Round(time.Minute).String(), "0s"))
+	}
+	return description
+}
+
+// setupRateLimit registers the flags of the rate-limit command and returns
+// its runner
+func setupRateLimit(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	jsonOutput := fs.Bool("json", false, "Write the report as JSON to stdout")
+
+	return func(args []string) {
+		if *jsonOutput {
+			console = os.Stderr
+		}
+		cfg, scanOpts := mustLoadScanConfig(opts)
+		gc := NewGitHubClient()
+
+		report := RateLimitReport{Run: runMetadata(cfg), Token: gc.tokenKind}
+		if gc.tokenKind != TokenNone {
+			login, err := gc.api.AuthenticatedUser(gc.ctx)
+			switch {
+			case err == nil:
+				report.Identity = login
+			case gc.tokenKind != TokenInstallation:
+				logger.Debug("could not look up the authenticated user", "error", err)
+			}
+		}
+		limits, err := gc.api.RateLimits(gc.ctx)
+		if err != nil {
+			fmt.Fprintf(console, "Error: failed to read the rate limit: %v\n", err)
+			os.Exit(1)
+		}
+		report.Limits = limits
+
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+		report.Estimate = estimateCheck(gc, cfg, actions)
+
+		if *jsonOutput {
+			data, err := marshalIndented(report)
+			if err != nil {
+				fmt.Fprintf(console, "Error encoding the report: %v\n", err)
+				os.Exit(1)
+			}
+			_, _ = os.Stdout.Write(data)
+			return
+		}
+
+		switch {
+		case report.Identity != "":
+			fmt.Fprintf(console, "\n👤 Identity: %s\n", report.Identity)
+		case gc.tokenKind == TokenInstallation:
+			fmt.Fprintln(console, "\n👤 Identity: GitHub App installation, which belongs to no user")
+		case gc.tokenKind == TokenNone:
+			fmt.Fprintln(console, "\n👤 Identity: anonymous")
+		default:
+			fmt.Fprintln(console, "\n👤 Identity: unknown, the token cannot read its user")
+		}
+
+		fmt.Fprintln(console, "\n📊 Rate limits:")
+		fmt.Fprintf(console, "  REST     %s\n", describeRate(limits.Core))
+		if limits.GraphQL != nil {
+			fmt.Fprintf(console, "  GraphQL  %s\n", describeRate(*limits.GraphQL))
+		}
+		if limits.Search != nil {
+			fmt.Fprintf(console, "  Search   %s\n", describeRate(*limits.Search))
+		}
+
+		estimate := report.Estimate
+		fmt.Fprintf(console, "\n🧮 A full check of %d action(s) in %d repositories needs about %d REST request(s)\n",
+			estimate.Actions, estimate.Repositories, estimate.RESTRequests)
+		if estimate.GraphQLQueries > 0 {
+			fmt.Fprintf(console, "   %d GraphQL quer(ies) answer most of them, and unchanged cached responses do not count\n", estimate.GraphQLQueries)
+		}
+		if estimate.RESTRequests <= limits.Core.Remaining {
+			fmt.Fprintf(console, "%s The remaining quota covers a full check\n", theme.OK)
+			return
+		}
+		fmt.Fprintf(console, "%s The remaining quota does not cover a full check; it resolves with git ls-remote once the quota is exhausted\n", theme.Warning)
+		if gc.tokenKind == TokenNone {
+			fmt.Fprintln(console, "   💡 Set GITHUB_TOKEN or GH_TOKEN, or authenticate with 'gh auth login', for 5,000 requests per hour")
+		}
+	}
+}
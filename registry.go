@@ -0,0 +1,430 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// dockerPrefix marks a uses: value that runs a container image
+	dockerPrefix = "docker://"
+
+	// dockerHubHost is the registry of image names without a host
+	dockerHubHost = "docker.io"
+
+	// dockerHubAPIHost serves the registry API of Docker Hub
+	dockerHubAPIHost = "registry-1.docker.io"
+
+	// dockerHubConfigKey is the key of Docker Hub credentials in the Docker config
+	dockerHubConfigKey = "https://index.docker.io/v1/"
+
+	// ghcrHost is the GitHub container registry, which accepts GitHub tokens
+	ghcrHost = "ghcr.io"
+
+	// registryTimeout bounds a single registry request
+	registryTimeout = 30 * time.Second
+
+	// maxManifestSize bounds a manifest downloaded to compute its digest
+	maxManifestSize = 4 << 20
+)
+
+var (
+	// digestRegex matches a pinned image digest
+	digestRegex = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+	// manifestMediaTypes are accepted for manifests, indexes first so that
+	// multi-platform images resolve to the digest docker pull uses
+	manifestMediaTypes = []string{
+		"application/vnd.oci.image.index.v1+json",
+		"application/vnd.docker.distribution.manifest.list.v2+json",
+		"application/vnd.oci.image.manifest.v1+json",
+		"application/vnd.docker.distribution.manifest.v2+json",
+	}
+)
+
+// splitImage splits an image reference into its name, tag and digest
+func splitImage(image string) (name, tag, digest string) {
+	name, digest, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	return name, tag, digest
+}
+
+// dockerAction describes a docker:// uses: value on line. Digest pins keep
+// following the tag they were made from, taken from the reference itself
+// (image:tag@digest) or from the tag comment written by update.
+func dockerAction(value, line string) (ActionInfo, bool) {
+	name, tag, digest := splitImage(strings.TrimPrefix(value, dockerPrefix))
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return ActionInfo{}, false
+	}
+
+	action := ActionInfo{Repo: dockerPrefix + name, OriginalLine: line, Docker: true, ImageTag: tag}
+	switch {
+	case digest != "":
+		action.CurrentRef = digest
+		if digestRegex.MatchString(digest) {
+			action.CurrentSHA = digest
+		}
+		if tag == "" {
+			action.ImageTag = commentTag(line, action.Repo)
+		}
+	case tag != "":
+		action.CurrentRef = tag
+	default:
+		// An image without a tag runs latest
+		action.CurrentRef = "latest"
+		action.ImageTag = "latest"
+	}
+	return action, true
+}
+
+// commentTag returns the tag recorded for repo in the trailing comment of a
+// pinned line, or an empty string
+func commentTag(line, repo string) string {
+	_, comment := splitTrailingComment(line)
+	text := strings.TrimLeft(strings.TrimSpace(comment), "#!")
+	if tag, ok := parseTagList(text)[repo]; ok {
+		return tag
+	}
+	return pinComment.tag(text)
+}
+
+// parseImageName returns the registry host and repository of an image name,
+// applying the Docker Hub defaults (alpine is docker.io/library/alpine)
+func parseImageName(name string) (string, string) {
+	host := dockerHubHost
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		host, name = first, rest
+	}
+	if host == dockerHubHost && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	return host, name
+}
+
+// registryAPIHost returns the host serving the registry API of host
+func registryAPIHost(host string) string {
+	if host == dockerHubHost || host == "index.docker.io" {
+		return dockerHubAPIHost
+	}
+	return host
+}
+
+// registryCredential is a username and password for a registry
+type registryCredential struct {
+	username string
+	password string
+}
+
+// registryClient resolves image tags to digests over the OCI distribution
+// API. Tokens, credentials and digests are cached for the run.
+type registryClient struct {
+	http        *http.Client
+	tokens      map[string]string
+	credentials map[string]*registryCredential
+	digests     map[string]string
+}
+
+// newRegistryClient returns a client for Docker Hub, GHCR and other OCI registries
+func newRegistryClient() *registryClient {
+	return &registryClient{
+		http:        &http.Client{Transport: loggingTransport{}, Timeout: registryTimeout},
+		tokens:      make(map[string]string),
+		credentials: make(map[string]*registryCredential),
+		digests:     make(map[string]string),
+	}
+}
+
+// Digest returns the digest the tag of an image points at
+func (c *registryClient) Digest(image, tag string) (string, error) {
+	key := image + ":" + tag
+	if digest, ok := c.digests[key]; ok {
+		return digest, nil
+	}
+
+	host, repository := parseImageName(image)
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", registryAPIHost(host), repository, url.PathEscape(tag))
+
+	resp, err := c.get(http.MethodHead, manifestURL, host, repository)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+	digest := resp.Header.Get("Docker-Content-Digest")
+
+	// Some registries only send the digest with the manifest itself
+	if !digestRegex.MatchString(digest) {
+		resp, err = c.get(http.MethodGet, manifestURL, host, repository)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+		if err != nil {
+			return "", &ResolveError{Kind: ErrorKindNetwork, Err: fmt.Errorf("failed to read manifest of %s:%s: %w", image, tag, err)}
+		}
+		sum := sha256.Sum256(body)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	c.digests[key] = digest
+	return digest, nil
+}
+
+// get requests a manifest, authenticating as the registry's challenge asks
+func (c *registryClient) get(method, manifestURL, host, repository string) (*http.Response, error) {
+	tokenKey := host + "/" + repository
+	resp, err := c.do(method, manifestURL, host, c.tokens[tokenKey])
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+
+		scheme, params := parseChallenge(challenge)
+		token := ""
+		switch {
+		case strings.EqualFold(scheme, "bearer"):
+			token, err = c.token(params, host)
+			if err != nil {
+				return nil, err
+			}
+			c.tokens[tokenKey] = token
+		case strings.EqualFold(scheme, "basic") && c.credential(host) == nil:
+			return nil, newResolveError(ErrorKindPermissionDenied, "%s requires credentials; log in with docker login %s", host, host)
+		}
+
+		resp, err = c.do(method, manifestURL, host, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, registryStatusError(resp.StatusCode, host, repository)
+	}
+	return resp, nil
+}
+
+// do sends a manifest request with a bearer token, or with basic credentials
+// when there is no token
+func (c *registryClient) do(method, manifestURL, host, token string) (*http.Response, error) {
+	req, err := http.NewRequest(method, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if cred := c.credential(host); cred != nil {
+		req.SetBasicAuth(cred.username, cred.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &ResolveError{Kind: ErrorKindNetwork, Err: fmt.Errorf("registry %s could not be reached: %w", host, err)}
+	}
+	return resp, nil
+}
+
+// token fetches a bearer token from the realm named in a challenge
+func (c *registryClient) token(params map[string]string, host string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme != "https" {
+		return "", newResolveError(ErrorKindUnknown, "registry %s sent an invalid token realm %q", host, params["realm"])
+	}
+	query := realm.Query()
+	for _, name := range []string{"service", "scope"} {
+		if value := params[name]; value != "" {
+			query.Set(name, value)
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if cred := c.credential(host); cred != nil {
+		req.SetBasicAuth(cred.username, cred.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", &ResolveError{Kind: ErrorKindNetwork, Err: fmt.Errorf("token service of %s could not be reached: %w", host, err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", registryStatusError(resp.StatusCode, host, params["scope"])
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response from %s: %w", host, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// credential returns the cached credentials for a registry host
+func (c *registryClient) credential(host string) *registryCredential {
+	if cred, ok := c.credentials[host]; ok {
+		return cred
+	}
+	cred := registryCredentials(host)
+	c.credentials[host] = cred
+	return cred
+}
+
+// registryCredentials looks up credentials for a registry in the Docker
+// configuration written by docker login. GHCR falls back to the GitHub token.
+func registryCredentials(host string) *registryCredential {
+	key := host
+	if host == dockerHubHost {
+		key = dockerHubConfigKey
+	}
+	if cred := dockerConfigCredential(key); cred != nil {
+		return cred
+	}
+	if host == ghcrHost {
+		if token, _ := getGitHubToken(); token != "" {
+			return &registryCredential{username: "x-access-token", password: token}
+		}
+	}
+	return nil
+}
+
+// dockerConfigCredential reads the auths entry for key from
+// $DOCKER_CONFIG/config.json or ~/.docker/config.json. Credential helpers
+// are not consulted.
+func dockerConfigCredential(key string) *registryCredential {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	content, err := os.ReadFile(filepath.Join(filepath.Clean(dir), "config.json"))
+	if err != nil {
+		return nil
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		logger.Warn("ignoring invalid Docker config", "dir", dir, "error", err)
+		return nil
+	}
+
+	for _, candidate := range []string{key, "https://" + key} {
+		entry, ok := config.Auths[candidate]
+		if !ok {
+			continue
+		}
+		if entry.Username != "" && entry.Password != "" {
+			return &registryCredential{username: entry.Username, password: entry.Password}
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			continue
+		}
+		if username, password, ok := strings.Cut(string(decoded), ":"); ok {
+			return &registryCredential{username: username, password: password}
+		}
+	}
+	return nil
+}
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and
+// parameters, e.g. Bearer realm="https://auth.docker.io/token",service="..."
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		name, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				break
+			}
+			params[name] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			params[name] = strings.TrimSpace(value)
+		}
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return scheme, params
+}
+
+// registryStatusError classifies a failed registry response
+func registryStatusError(status int, host, resource string) error {
+	switch status {
+	case http.StatusNotFound:
+		return newResolveError(ErrorKindNotFound, "%s not found on %s", resource, host)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return newResolveError(ErrorKindPermissionDenied, "access to %s on %s denied; log in with docker login %s", resource, host, host)
+	case http.StatusTooManyRequests:
+		return newResolveError(ErrorKindRateLimited, "%s rate limited requests for %s", host, resource)
+	}
+	return newResolveError(ErrorKindUnknown, "%s answered %d for %s", host, status, resource)
+}
+
+// resolveImageAction looks up the digest the tag of a docker:// action points at
+func resolveImageAction(registry *registryClient, action *ActionInfo) error {
+	if action.ImageTag == "" {
+		// A bare digest pin records no tag to follow
+		action.LatestTag = action.CurrentRef
+		action.LatestSHA = action.CurrentSHA
+		action.NeedsUpdate = action.CurrentSHA == ""
+		action.Provenance = &Provenance{Reason: PinDigest}
+		if action.NeedsUpdate {
+			return newResolveError(ErrorKindTagFormat, "%s is not a valid digest", action.reference())
+		}
+		return nil
+	}
+
+	digest, err := registry.Digest(strings.TrimPrefix(action.Repo, dockerPrefix), action.ImageTag)
+	if err != nil {
+		return err
+	}
+	host, _ := parseImageName(strings.TrimPrefix(action.Repo, dockerPrefix))
+	action.LatestTag = action.ImageTag
+	action.LatestSHA = digest
+	action.Provenance = &Provenance{Reason: PinImageTag, Tag: action.ImageTag, Source: host}
+	action.NeedsUpdate = action.CurrentSHA != digest
+	return nil
+}
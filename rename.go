@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxRedirects matches the limit of the default HTTP client
+	maxRedirects = 10
+)
+
+// apiRepoPathRegex matches the repository a REST API path addresses, such as
+// /repos/actions/checkout/releases/latest, after any Enterprise prefix
+var apiRepoPathRegex = regexp.MustCompile(`/repos/([^/]+)/([^/]+)`)
+
+// redirectLog records the repositories whose API requests were redirected,
+// with their new name once it was looked up. GitHub answers requests for a
+// renamed or transferred repository with a redirect to its new location,
+// which the HTTP client follows, so versions still resolve under the old
+// name.
+type redirectLog struct {
+	mu    sync.Mutex
+	repos map[string]string
+}
+
+// newRedirectLog returns an empty redirect log
+func newRedirectLog() *redirectLog {
+	return &redirectLog{repos: make(map[string]string)}
+}
+
+// checkRedirect implements http.Client.CheckRedirect
+func (l *redirectLog) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	from := apiRepository(via[0].URL.Path)
+	if from == "" || strings.EqualFold(from, apiRepository(req.URL.Path)) {
+		return nil
+	}
+	logger.Debug("repository request redirected", "repo", from, "location", req.URL.Path)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.repos[strings.ToLower(from)]; !ok {
+		l.repos[strings.ToLower(from)] = ""
+	}
+	return nil
+}
+
+// lookup reports whether a request for owner/repo was redirected, and the
+// new name when it is known
+func (l *redirectLog) lookup(owner, repo string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	name, ok := l.repos[strings.ToLower(owner+"/"+repo)]
+	return name, ok
+}
+
+// setName records the new name of a redirected repository
+func (l *redirectLog) setName(owner, repo, name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.repos[strings.ToLower(owner+"/"+repo)] = name
+}
+
+// apiRepository returns the owner/repo a REST API path addresses, empty for
+// paths such as /repositories/<id> that address a repository by its id
+func apiRepository(urlPath string) string {
+	m := apiRepoPathRegex.FindStringSubmatch(urlPath)
+	if m == nil {
+		return ""
+	}
+	return m[1] + "/" + m[2]
+}
+
+// noteMove records in action.MovedTo where the repository of a resolved
+// action lives now. A failed lookup only loses the note.
+func noteMove(forge Forge, action *ActionInfo, owner, repo string) {
+	name, err := forge.CanonicalName(owner, repo)
+	if err != nil {
+		logger.Debug("could not look up the current repository name", "repo", owner+"/"+repo, "error", err)
+		return
+	}
+	if strings.EqualFold(name, owner+"/"+repo) {
+		return
+	}
+	_, _, subpath := splitActionPath(action.Repo)
+	action.MovedTo = path.Join(name, subpath)
+}
+
+// acceptMove decides whether the reference of a moved repository is
+// rewritten to its new location along with its update: always with
+// --follow-renames, after a prompt without --yes, and never otherwise
+func acceptMove(action *ActionInfo, indent string) {
+	switch {
+	case action.MovedTo == "":
+		return
+	case followRenames:
+		action.Rename = true
+	case autoApprove:
+		fmt.Fprintf(console, "%s%s Kept %s, which moved to %s; pass --follow-renames to rewrite it\n", indent, theme.Warning, action.Repo, action.MovedTo)
+	default:
+		action.Rename = promptForConfirmation(fmt.Sprintf("%s%s moved to %s. Rewrite uses: to the new location?", indent, action.Repo, action.MovedTo))
+	}
+}
+
+// usesPath returns the action path written into the uses: value
+func (a ActionInfo) usesPath() string {
+	if a.Rename {
+		return a.MovedTo
+	}
+	return a.Repo
+}
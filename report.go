@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Summary holds aggregate counts for a check run
+type Summary struct {
+	Total       int `json:"total"`
+	UpToDate    int `json:"up_to_date"`
+	NeedsUpdate int `json:"needs_update"`
+	// ReviewRequired counts the updates whose release notes mention
+	// breaking changes; they are included in NeedsUpdate
+	ReviewRequired int `json:"review_required"`
+	// CoolingOff counts the actions whose update is held back by
+	// min_release_age; they are not included in UpToDate
+	CoolingOff int `json:"cooling_off"`
+	// Moved counts the references to renamed or transferred repositories
+	Moved        int               `json:"moved"`
+	Errors       int               `json:"errors"`
+	ErrorsByKind map[ErrorKind]int `json:"errors_by_kind,omitempty"`
+}
+
+// Report is the machine readable result of a check run
+type Report struct {
+	// Run records the binary, flags and configuration of the scan
+	Run       RunMetadata     `json:"run"`
+	Workflows WorkflowActions `json:"workflows"`
+	Groups    []GroupReport   `json:"groups,omitempty"`
+	Skipped   []SkippedFile   `json:"skipped,omitempty"`
+	// Expressions lists uses: values that cannot be pinned statically
+	Expressions []ExpressionUse `json:"expressions,omitempty"`
+	// Unused lists composite actions and workflows that never run
+	Unused []UnusedFile `json:"unused,omitempty"`
+	// ForkExposures lists unpinned third-party actions in workflows
+	// triggered by pull requests
+	ForkExposures []ForkExposure `json:"fork_exposures,omitempty"`
+	// PermissionIssues lists workflows and jobs with broad token permissions
+	PermissionIssues []PermissionIssue `json:"permission_issues,omitempty"`
+	// VersionSkew lists actions pinned to different versions across workflows
+	VersionSkew []VersionSkew `json:"version_skew,omitempty"`
+	// Findings lists every finding with its rule ID and severity
+	Findings []Finding `json:"findings"`
+	Summary  Summary   `json:"summary"`
+}
+
+// summarize counts actions by status
+func summarize(actions WorkflowActions) Summary {
+	summary := Summary{}
+
+	for _, actionList := range actions {
+		for _, action := range actionList {
+			summary.Total++
+			if action.MovedTo != "" {
+				summary.Moved++
+			}
+			switch {
+			case action.Error != nil:
+				summary.Errors++
+				if summary.ErrorsByKind == nil {
+					summary.ErrorsByKind = make(map[ErrorKind]int)
+				}
+				summary.ErrorsByKind[action.Error.Kind]++
+			case action.NeedsUpdate:
+				summary.NeedsUpdate++
+				if action.ReviewRequired() {
+					summary.ReviewRequired++
+				}
+			case action.CoolingOff != nil:
+				summary.CoolingOff++
+			default:
+				summary.UpToDate++
+			}
+		}
+	}
+
+	return summary
+}
+
+// writeJSONReport writes the check results as indented JSON
+func writeJSONReport(w io.Writer, actions WorkflowActions, skipped []SkippedFile, expressions []ExpressionUse, unused []UnusedFile, cfg *Config) error {
+	if actions == nil {
+		actions = WorkflowActions{}
+	}
+
+	groups := collectGroups(actions, cfg)
+	groupReports := make([]GroupReport, 0, len(groups))
+	for _, group := range groups {
+		groupReports = append(groupReports, group.Report())
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Report{
+		Run:              runMetadata(cfg),
+		Workflows:        actions,
+		Groups:           groupReports,
+		Skipped:          skipped,
+		Expressions:      expressions,
+		Unused:           unused,
+		ForkExposures:    findForkExposures(actions),
+		PermissionIssues: findPermissionIssues(actions),
+		VersionSkew:      findVersionSkew(actions),
+		Findings:         collectFindings(actions, skipped, expressions, unused),
+		Summary:          summarize(actions),
+	})
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// retryBaseDelay is the backoff before the first retry of a server or
+	// network error; it doubles with every attempt
+	retryBaseDelay = time.Second
+	// maxRetryDelay caps the backoff between attempts
+	maxRetryDelay = 30 * time.Second
+	// maxRetryWait is the longest rate limit wait; longer ones fail the
+	// request, so the git fallback can take over
+	maxRetryWait = time.Minute
+	// secondaryRateLimitWait is the wait GitHub asks for after a secondary
+	// rate limit without a Retry-After header
+	secondaryRateLimitWait = time.Minute
+	// lowBudgetFraction is the share of the rate limit below which the
+	// remaining requests are reported
+	lowBudgetFraction = 0.1
+)
+
+// maxRetries is the number of times a failed GitHub API request is retried.
+// It is set by --max-retries.
+var maxRetries = 3
+
+// retryTransport retries GitHub API requests that hit a rate limit about to
+// reset, a server error or a transient network error. Rate limits are
+// waited out as GitHub asks, with Retry-After or the reset time; other
+// failures back off exponentially with jitter. It also warns once per rate
+// limit resource when the remaining requests run low.
+type retryTransport struct {
+	base http.RoundTripper
+
+	mu sync.Mutex
+	// warned records the rate limit resources already reported as low
+	warned map[string]bool
+}
+
+// newRetryTransport returns base with retries
+func newRetryTransport(base http.RoundTripper) *retryTransport {
+	return &retryTransport{base: base, warned: make(map[string]bool)}
+}
+
+// retryable reports whether req can be sent again. Reads are idempotent, and
+// so are GraphQL queries.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return strings.HasSuffix(req.URL.Path, "/graphql") && (req.Body == nil || req.GetBody != nil)
+	}
+	return false
+}
+
+// transientError reports whether a request failed on the way, such as with
+// a timeout or a dropped connection, and may succeed when sent again
+func transientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// rateLimitWait returns how long GitHub asks to wait before retrying a
+// rate limited response, and whether it is one. Secondary limits send
+// Retry-After, or only say so in the body; primary limits have no
+// requests remaining and reset at X-RateLimit-Reset.
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return max(time.Until(time.Unix(reset, 0)), 0) + time.Second, true
+	}
+
+	// Peek at the body, then put it back for go-github to decode
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err == nil && strings.Contains(strings.ToLower(string(body)), "secondary rate limit") {
+		return secondaryRateLimitWait, true
+	}
+	return 0, false
+}
+
+// backoff returns the delay before retry attempt, counted from 0: the base
+// delay doubled per attempt, capped, with up to half of it taken off at
+// random so parallel runs do not retry in step
+func backoff(attempt int) time.Duration {
+	delay := min(retryBaseDelay<<attempt, maxRetryDelay)
+	return delay - rand.N(delay/2+1)
+}
+
+// sleep waits for d unless ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// warnLowBudget reports, once per resource, that the rate limit is running
+// low, with the time it resets. The rate limit endpoint is left out, since
+// the budget of a run is checked with it.
+func (t *retryTransport) warnLowBudget(resp *http.Response) {
+	if resp.Request != nil && strings.HasSuffix(resp.Request.URL.Path, "/rate_limit") {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil || limit == 0 || float64(remaining) >= float64(limit)*lowBudgetFraction {
+		return
+	}
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.warned[resource] {
+		return
+	}
+	t.warned[resource] = true
+	resets := "soon"
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		resets = "at " + time.Unix(reset, 0).Format(time.Kitchen)
+	}
+	fmt.Fprintf(console, " %s only %d of %d %s API requests left, the limit resets %s...", theme.Warning, remaining, limit, resource, resets)
+	logger.Info("rate limit running low", "resource", resource, "remaining", remaining, "limit", limit)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	canRetry := maxRetries > 0 && retryable(req)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		last := !canRetry || attempt >= maxRetries
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if last || !transientError(err) {
+				return nil, err
+			}
+			wait = backoff(attempt)
+			logger.Info("retrying GitHub API request", "url", req.URL.String(), "attempt", attempt+1, "wait", wait, "error", err)
+		case resp.StatusCode >= http.StatusInternalServerError:
+			if last {
+				return resp, nil
+			}
+			wait = backoff(attempt)
+			logger.Info("retrying GitHub API request", "url", req.URL.String(), "attempt", attempt+1, "wait", wait, "status", resp.StatusCode)
+		default:
+			limited, isLimited := rateLimitWait(resp)
+			if !isLimited {
+				t.warnLowBudget(resp)
+				return resp, nil
+			}
+			if last || limited > maxRetryWait {
+				return resp, nil
+			}
+			wait = limited
+			fmt.Fprintf(console, " ⏳ GitHub API rate limit reached, retrying in %s...", wait.Round(time.Second))
+			logger.Info("waiting out GitHub API rate limit", "url", req.URL.String(), "attempt", attempt+1, "wait", wait)
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
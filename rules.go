@@ -0,0 +1,440 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severity is the level a finding is reported at; findings of rules set to
+// off are not reported
+type Severity string
+
+const (
+	// SeverityError fails the commands that enforce the rule
+	SeverityError Severity = "error"
+	// SeverityWarning is reported without failing
+	SeverityWarning Severity = "warning"
+	// SeverityNote is informational
+	SeverityNote Severity = "note"
+	// SeverityOff disables the rule
+	SeverityOff Severity = "off"
+)
+
+// severities lists the valid severities
+var severities = []Severity{SeverityError, SeverityWarning, SeverityNote, SeverityOff}
+
+// Rule IDs. They are stable: a rule keeps its ID when it is renamed, and
+// the IDs of removed rules are not reused.
+const (
+	ruleUnpinned        = "GCH001"
+	ruleCommentMismatch = "GCH002"
+	ruleOutdated        = "GCH003"
+	ruleUnresolved      = "GCH004"
+	ruleDistMismatch    = "GCH005"
+	ruleDenylisted      = "GCH006"
+	ruleExpression      = "GCH007"
+	ruleForkPR          = "GCH008"
+	ruleVersionSkew     = "GCH009"
+	ruleUnused          = "GCH010"
+	ruleMoved           = "GCH011"
+	ruleImpostor        = "GCH012"
+	ruleTwoFactor       = "GCH013"
+	ruleParseLimits     = "GCH014"
+	ruleAdvisory        = "GCH015"
+	ruleUntrusted       = "GCH016"
+	ruleCustomPolicy    = "GCH017"
+	ruleLowScorecard    = "GCH018"
+	ruleUnverified      = "GCH019"
+	ruleDeprecated      = "GCH020"
+	ruleNodeRuntime     = "GCH021"
+	ruleTransitive      = "GCH022"
+	ruleAttestation     = "GCH023"
+	ruleMutableRelease  = "GCH024"
+	ruleDefaultPerms    = "GCH025"
+	ruleWriteAll        = "GCH026"
+	ruleCheckoutHead    = "GCH027"
+	rulePersistCreds    = "GCH028"
+	ruleInjection       = "GCH029"
+	ruleForkSecrets     = "GCH030"
+	ruleTagMoved        = "GCH031"
+	ruleBlockedByPolicy = "GCH032"
+	rulePersonalOwner   = "GCH033"
+)
+
+// Rule is a kind of finding
+type Rule struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Summary  string   `json:"summary"`
+	Help     string   `json:"help"`
+	Severity Severity `json:"severity"`
+	// Check is set for the rules check fails on at error severity; verify
+	// and audit enforce the rules they evaluate
+	Check bool `json:"-"`
+}
+
+// findingRules lists every rule in ID order with its default severity
+var findingRules = []Rule{
+	{ID: ruleUnpinned, Name: "unpinned-action", Severity: SeverityError,
+		Summary: "Action is not pinned to a commit SHA",
+		Help:    "Tags and branches can be moved to point at different code. Run 'github-ci-hash pin' or 'github-ci-hash update' to pin the action to a full commit SHA."},
+	{ID: ruleCommentMismatch, Name: "comment-mismatch", Severity: SeverityWarning, Check: true,
+		Summary: "Pin comment names a release the pinned commit is not",
+		Help:    "The comment after the SHA names the latest release, but the release points at another commit. The comment is stale or the pin was edited by hand."},
+	{ID: ruleOutdated, Name: "outdated-action", Severity: SeverityWarning, Check: true,
+		Summary: "A newer release of the action is available",
+		Help:    "Run 'github-ci-hash update' to pin the action to the latest release."},
+	{ID: ruleUnresolved, Name: "unresolved-action", Severity: SeverityWarning, Check: true,
+		Summary: "Action reference could not be resolved",
+		Help:    "The latest release or the current ref of the action could not be looked up."},
+	{ID: ruleDistMismatch, Name: "dist-mismatch", Severity: SeverityError, Check: true,
+		Summary: "JavaScript bundle at the pinned commit differs from the commented tag",
+		Help:    "The tag was moved or its release rebuilt since the pin was written, which is how compromised actions have been distributed."},
+	{ID: ruleDenylisted, Name: "denylisted-action", Severity: SeverityError, Check: true,
+		Summary: "Action version is on the denylist of compromised actions",
+		Help:    "Pin the action to a safe commit and rotate the secrets its workflows could read."},
+	{ID: ruleExpression, Name: "expression-uses", Severity: SeverityWarning, Check: true,
+		Summary: "uses: value is built from an expression and cannot be pinned",
+		Help:    "The action is only known at run time. Spell out each variant with a pinned uses: value."},
+	{ID: ruleForkPR, Name: "fork-pull-request", Severity: SeverityWarning, Check: true,
+		Summary: "Unpinned third-party action in a workflow triggered by pull requests",
+		Help:    "Pull requests from forks of public repositories run these workflows; pin their actions first."},
+	{ID: ruleVersionSkew, Name: "version-skew", Severity: SeverityWarning, Check: true,
+		Summary: "Action is pinned to different versions across workflows",
+		Help:    "Run 'github-ci-hash align' to pin every occurrence to the same version."},
+	{ID: ruleUnused, Name: "unused-file", Severity: SeverityNote, Check: true,
+		Summary: "Composite action or reusable workflow is never used",
+		Help:    "Unused CI code still needs to be kept pinned; remove it."},
+	{ID: ruleMoved, Name: "moved-repository", Severity: SeverityNote, Check: true,
+		Summary: "Action repository was renamed or transferred",
+		Help:    "Run 'github-ci-hash update --follow-renames' to reference the new location."},
+	{ID: ruleImpostor, Name: "impostor-commit", Severity: SeverityError,
+		Summary: "Pinned commit is not reachable from the action repository",
+		Help:    "The commit may only exist in a fork. Pin a commit from a tag or the default branch of the repository."},
+	{ID: ruleTwoFactor, Name: "publisher-two-factor", Severity: SeverityError,
+		Summary: "Publisher is not known to enforce two-factor authentication",
+		Help:    "List organizations whose policy you confirmed under two_factor_orgs, or replace the action."},
+	{ID: ruleParseLimits, Name: "parse-limits", Severity: SeverityError,
+		Summary: "Workflow file exceeds the parse limits and was not scanned",
+		Help:    "Raise the limits in the configuration if the file is legitimate."},
+	{ID: ruleAdvisory, Name: "security-advisory", Severity: SeverityError,
+		Summary: "Action version has a published security advisory",
+		Help:    "Update the action to the version that fixes the advisory."},
+	{ID: ruleUntrusted, Name: "untrusted-owner", Severity: SeverityError,
+		Summary: "Action is outside the allowlist of trusted owners",
+		Help:    "Replace the action, or list it under allowlist.exceptions with a reason once its publisher is reviewed."},
+	{ID: ruleCustomPolicy, Name: "custom-policy", Severity: SeverityError,
+		Summary: "A policy configured under policies reports a violation",
+		Help:    "See the message of the policy; run 'github-ci-hash policy eval' to evaluate the policies alone."},
+	{ID: ruleLowScorecard, Name: "low-scorecard", Severity: SeverityError, Check: true,
+		Summary: "Action repository scores below min_scorecard in OpenSSF Scorecard",
+		Help:    "Review the failing Scorecard checks of the repository before trusting its releases, or replace the action."},
+	{ID: ruleUnverified, Name: "unverified-publisher", Severity: SeverityError,
+		Summary: "Third-party action is not published by a verified creator",
+		Help:    "Replace the action, or list it under allow_unverified once its publisher is reviewed."},
+	{ID: ruleDeprecated, Name: "deprecated-action", Severity: SeverityWarning, Check: true,
+		Summary: "Action repository is archived, deprecated or has no recent release",
+		Help:    "Dead actions get no security fixes. Replace the action, with its successor when one is named."},
+	{ID: ruleNodeRuntime, Name: "deprecated-runtime", Severity: SeverityWarning, Check: true,
+		Summary: "Action runs on a Node version GitHub runners are removing",
+		Help:    "The action stops working once runners drop the runtime. Update the pin to a release on a supported Node version, or replace the action."},
+	{ID: ruleTransitive, Name: "unpinned-transitive", Severity: SeverityWarning, Check: true,
+		Summary: "Pinned composite action calls actions that are not pinned",
+		Help:    "Pinning the composite action does not pin the actions its steps call. Ask its maintainers to pin them, or replace the action."},
+	{ID: ruleAttestation, Name: "invalid-attestation", Severity: SeverityError, Check: true,
+		Summary: "Pinned commit has attestations that do not verify",
+		Help:    "An attestation that is not signed by a workflow of the action repository, or whose signature does not match, may be forged. Check it with 'gh attestation verify' before trusting the commit."},
+	{ID: ruleMutableRelease, Name: "mutable-release", Severity: SeverityError, Check: true,
+		Summary: "Action under require_immutable is not pinned to an immutable release",
+		Help:    "The tag and assets of a mutable release can be replaced after the fact. Pin an immutable release, or ask the maintainers to enable immutable releases."},
+	{ID: ruleDefaultPerms, Name: "default-permissions", Severity: SeverityWarning, Check: true,
+		Summary: "Workflow jobs run with the default token permissions",
+		Help:    "Without a permissions block the GITHUB_TOKEN gets the repository default, often write access to most scopes. Declare the suggested least-privilege block."},
+	{ID: ruleWriteAll, Name: "write-all-permissions", Severity: SeverityError, Check: true,
+		Summary: "Workflow or job grants write-all token permissions",
+		Help:    "Every step, including third-party actions, can push code, publish packages and edit releases with the token. Replace write-all with the suggested least-privilege block."},
+	{ID: ruleCheckoutHead, Name: "untrusted-checkout", Severity: SeverityError,
+		Summary: "Privileged workflow checks out the head of a pull request",
+		Help:    "pull_request_target and workflow_run run with the secrets and a write token of the repository, so building the code of a fork there hands both to its author. Run untrusted code in a pull_request workflow instead."},
+	{ID: rulePersistCreds, Name: "persisted-credentials", Severity: SeverityWarning,
+		Summary: "actions/checkout keeps the token in the git configuration",
+		Help:    "Later steps, and artifacts uploading the workspace, can read the token from .git/config. Set persist-credentials: false unless a step pushes."},
+	{ID: ruleInjection, Name: "script-injection", Severity: SeverityError,
+		Summary: "Untrusted event field is interpolated into a script",
+		Help:    "Titles, bodies and branch names are chosen by whoever opens the issue or pull request and are pasted into the script before it runs. Pass the value through env: and reference the quoted variable."},
+	{ID: ruleForkSecrets, Name: "fork-secrets", Severity: SeverityWarning,
+		Summary: "Secret is exposed to a workflow run for pull requests from forks",
+		Help:    "Workflows triggered by pull_request_target and workflow_run get the secrets of the repository for pull requests from forks. Move the steps needing secrets to a workflow that does not touch the pull request's code."},
+	{ID: ruleTagMoved, Name: "moved-tag", Severity: SeverityError, Check: true,
+		Summary: "Release tag resolves to another commit than the pin database recorded",
+		Help:    "Release tags are not expected to move, and moving them is how compromised actions are distributed. Review the new commit, then remove the entry from pin_database to accept it."},
+	{ID: ruleBlockedByPolicy, Name: "blocked-by-policy", Severity: SeverityError,
+		Summary: "The allowed actions policy of the organization blocks the action",
+		Help:    "Runs using the action fail before their jobs start. Ask an organization owner to allow it, e.g. with a pattern from 'github-ci-hash allowed-actions', or replace the action."},
+	{ID: rulePersonalOwner, Name: "personal-or-fork", Severity: SeverityError,
+		Summary: "Action repository is owned by a personal account or is a fork",
+		Help:    "A single account can be compromised or deleted without an organization's controls, and forks drift from the code that was reviewed upstream. Use the upstream or an organization-owned action, or list it under allow_personal once reviewed."},
+}
+
+// ruleSeverities holds the severities configured under rules, keyed by ID.
+// It is set when the configuration is loaded.
+var ruleSeverities map[string]Severity
+
+// lookupRule finds a rule by its ID or name
+func lookupRule(key string) (Rule, bool) {
+	for _, rule := range findingRules {
+		if strings.EqualFold(rule.ID, key) || rule.Name == key {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// resolveRuleSeverities validates the rules section of the configuration
+// and returns the severities keyed by rule ID
+func resolveRuleSeverities(configured map[string]string) (map[string]Severity, error) {
+	resolved := make(map[string]Severity, len(configured))
+	keys := make([]string, 0, len(configured))
+	for key := range configured {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		rule, ok := lookupRule(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown rule %q", key)
+		}
+		severity := Severity(configured[key])
+		if !containsString(severityNames(), string(severity)) {
+			return nil, fmt.Errorf("invalid severity %q for %s (expected one of: %s)", severity, key, strings.Join(severityNames(), ", "))
+		}
+		if previous, ok := resolved[rule.ID]; ok && previous != severity {
+			return nil, fmt.Errorf("rule %s is configured twice", rule.ID)
+		}
+		resolved[rule.ID] = severity
+	}
+	return resolved, nil
+}
+
+// severityNames returns the valid severities as strings
+func severityNames() []string {
+	names := make([]string, 0, len(severities))
+	for _, severity := range severities {
+		names = append(names, string(severity))
+	}
+	return names
+}
+
+// severityOf returns the configured or default severity of a rule
+func severityOf(id string) Severity {
+	if severity, ok := ruleSeverities[id]; ok {
+		return severity
+	}
+	rule, _ := lookupRule(id)
+	return rule.Severity
+}
+
+// ruleEnabled reports whether the findings of a rule are reported
+func ruleEnabled(id string) bool {
+	return severityOf(id) != SeverityOff
+}
+
+// ruleFails reports whether the findings of a rule fail the command
+func ruleFails(id string) bool {
+	return severityOf(id) == SeverityError
+}
+
+// enforce returns err when the rule fails the command, so that findings of
+// rules lowered below error are reported without failing
+func enforce(id string, err error) error {
+	if err != nil && ruleFails(id) {
+		return err
+	}
+	return nil
+}
+
+// severityStatus returns the status symbol of the findings of a rule
+func severityStatus(id string) string {
+	if ruleFails(id) {
+		return theme.Error
+	}
+	return theme.Warning
+}
+
+// ruleTag labels text output with the ID of the rule that produced it
+func ruleTag(id string) string {
+	return "[" + id + "]"
+}
+
+// Finding is a problem reported by a rule
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Name     string   `json:"name"`
+	Severity Severity `json:"severity"`
+	Workflow string   `json:"workflow"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Repo     string   `json:"repo,omitempty"`
+	Ref      string   `json:"ref,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// newFinding builds a finding of rule id at a location
+func newFinding(id, workflow string, line int, message string) Finding {
+	rule, _ := lookupRule(id)
+	return Finding{Rule: id, Name: rule.Name, Severity: severityOf(id), Workflow: filepath.ToSlash(workflow), Line: line, Message: message}
+}
+
+// actionFinding builds a finding of rule id for an action
+func actionFinding(id string, action ActionInfo, message string) Finding {
+	finding := newFinding(id, action.WorkflowFile, action.Line, message)
+	finding.Column = action.Column
+	finding.Repo = action.Repo
+	finding.Ref = action.CurrentRef
+	return finding
+}
+
+// commentMismatch reports whether the pin comment of action names the
+// latest release while the pinned commit is not it. Only the latest release
+// is resolved, so comments naming older tags are not checked.
+func commentMismatch(action ActionInfo) bool {
+	if !action.Pinned() || action.Docker || action.LatestSHA == "" || action.Error != nil {
+		return false
+	}
+	return commentTag(action.OriginalLine, action.Repo) == action.LatestTag && action.CurrentSHA != action.LatestSHA
+}
+
+// collectFindings returns the findings of the rules check evaluates, in
+// workflow and line order. Findings of disabled rules are left out.
+func collectFindings(actions WorkflowActions, skipped []SkippedFile, expressions []ExpressionUse, unused []UnusedFile) []Finding {
+	findings := []Finding{}
+	for _, action := range sortedActions(actions) {
+		if !action.Pinned() {
+			findings = append(findings, actionFinding(ruleUnpinned, action, fmt.Sprintf("%s is not pinned to %s", action.reference(), action.pinKind())))
+		}
+		if commentMismatch(action) {
+			findings = append(findings, actionFinding(ruleCommentMismatch, action, fmt.Sprintf("%s is pinned with the comment %s, but %s points at %s", action.Repo, action.LatestTag, action.LatestTag, action.LatestSHA)))
+		}
+		switch {
+		case action.Error != nil:
+			findings = append(findings, actionFinding(ruleUnresolved, action, fmt.Sprintf("%s could not be resolved: %v", action.reference(), action.Error)))
+		case action.ReviewRequired():
+			findings = append(findings, actionFinding(ruleOutdated, action, fmt.Sprintf("%s can be updated to %s (%s); review required, its release notes mention breaking changes",
+				action.reference(), action.LatestTag, action.LatestSHA)))
+		case action.NeedsUpdate:
+			findings = append(findings, actionFinding(ruleOutdated, action, fmt.Sprintf("%s can be updated to %s (%s)", action.reference(), action.LatestTag, action.LatestSHA)))
+		}
+		if len(action.DistMismatch) > 0 {
+			findings = append(findings, actionFinding(ruleDistMismatch, action, fmt.Sprintf("%s differs from tag %s", strings.Join(action.DistMismatch, ", "), commentTag(action.OriginalLine, action.Repo))))
+		}
+		if action.Compromised != nil {
+			findings = append(findings, actionFinding(ruleDenylisted, action, action.Compromised.describe()))
+		}
+		if action.Untrusted {
+			findings = append(findings, actionFinding(ruleUntrusted, action, fmt.Sprintf("%s is not on the allowlist of trusted owners", action.Repo)))
+		}
+		if action.Scorecard != nil && action.Scorecard.BelowMinimum {
+			findings = append(findings, actionFinding(ruleLowScorecard, action, fmt.Sprintf("%s has an OpenSSF Scorecard score of %.1f", action.Repo, action.Scorecard.Score)))
+		}
+		if action.Publisher != nil && action.Publisher.blocked() {
+			findings = append(findings, actionFinding(ruleUnverified, action, fmt.Sprintf("%s is published by %s, a %s", action.Repo, action.Publisher.Owner, action.Publisher.describe())))
+		}
+		if action.Ownership != nil && action.Ownership.blocked() {
+			findings = append(findings, actionFinding(rulePersonalOwner, action, fmt.Sprintf("%s is owned by %s", action.Repo, action.Ownership.describe())))
+		}
+		if action.RequiresImmutable && (action.Immutable == nil || !*action.Immutable) {
+			findings = append(findings, actionFinding(ruleMutableRelease, action, fmt.Sprintf("%s is not pinned to an immutable release", action.reference())))
+		}
+		if action.TagMoved != nil {
+			findings = append(findings, actionFinding(ruleTagMoved, action, fmt.Sprintf("%s@%s resolves to %s, but the pin database recorded %s on %s",
+				action.Repo, action.TagMoved.Tag, action.TagMoved.Observed, action.TagMoved.Recorded, action.TagMoved.FirstSeen)))
+		}
+		if action.AttestationError != "" {
+			findings = append(findings, actionFinding(ruleAttestation, action, fmt.Sprintf("the attestation of %s does not verify: %s", action.reference(), action.AttestationError)))
+		}
+		for _, use := range action.Transitive {
+			findings = append(findings, actionFinding(ruleTransitive, action, fmt.Sprintf("%s calls %s at %s:%d, which is not pinned", action.Repo, use.Uses, use.File, use.Line)))
+		}
+		if deprecatedRuntime(action.Runtime) {
+			message := fmt.Sprintf("%s runs on %s, which GitHub runners are removing", action.reference(), action.Runtime)
+			if action.LatestRuntime != "" {
+				message += fmt.Sprintf("; %s runs on %s", action.LatestTag, action.LatestRuntime)
+			}
+			findings = append(findings, actionFinding(ruleNodeRuntime, action, message))
+		}
+		if action.Deprecation != nil {
+			findings = append(findings, actionFinding(ruleDeprecated, action, fmt.Sprintf("%s is %s", action.Repo, action.Deprecation.describe())))
+		}
+		if action.MovedTo != "" {
+			findings = append(findings, actionFinding(ruleMoved, action, fmt.Sprintf("%s moved to %s", action.Repo, action.MovedTo)))
+		}
+	}
+	for _, use := range expressions {
+		finding := newFinding(ruleExpression, use.File, use.Line, fmt.Sprintf("%s cannot be pinned", use.Value))
+		finding.Column = use.Column
+		findings = append(findings, finding)
+	}
+	for _, exposure := range findForkExposures(actions) {
+		finding := newFinding(ruleForkPR, exposure.Workflow, exposure.Line, fmt.Sprintf("%s is unpinned in a workflow triggered by %s", exposure.reference(), strings.Join(exposure.Triggers, ", ")))
+		finding.Repo, finding.Ref = exposure.Repo, exposure.Ref
+		findings = append(findings, finding)
+	}
+	for _, issue := range findPermissionIssues(actions) {
+		findings = append(findings, newFinding(issue.Rule, issue.Workflow, issue.Line, issue.Message))
+	}
+	for _, skew := range findVersionSkew(actions) {
+		for _, version := range skew.Versions[1:] {
+			for _, location := range version.Locations {
+				finding := newFinding(ruleVersionSkew, location.Workflow, location.Line, fmt.Sprintf("%s is pinned to %s here, most workflows pin %s", skew.Repo, version.Ref, skew.Versions[0].Ref))
+				finding.Repo, finding.Ref = skew.Repo, version.Ref
+				findings = append(findings, finding)
+			}
+		}
+	}
+	for _, file := range unused {
+		findings = append(findings, newFinding(ruleUnused, file.Path, 0, file.Reason))
+	}
+	for _, file := range exceededLimits(skipped) {
+		findings = append(findings, newFinding(ruleParseLimits, file.Path, 0, file.Reason))
+	}
+
+	enabled := findings[:0]
+	for _, finding := range findings {
+		if finding.Severity != SeverityOff {
+			enabled = append(enabled, finding)
+		}
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		if enabled[i].Workflow != enabled[j].Workflow {
+			return enabled[i].Workflow < enabled[j].Workflow
+		}
+		return enabled[i].Line < enabled[j].Line
+	})
+	return enabled
+}
+
+// failingFindings returns the findings check fails on
+func failingFindings(findings []Finding) []Finding {
+	var failing []Finding
+	for _, finding := range findings {
+		rule, _ := lookupRule(finding.Rule)
+		if rule.Check && finding.Severity == SeverityError {
+			failing = append(failing, finding)
+		}
+	}
+	return failing
+}
+
+// findingRuleIDs lists the distinct rules of findings, e.g. "GCH005, GCH006"
+func findingRuleIDs(findings []Finding) string {
+	var ids []string
+	for _, finding := range findings {
+		if !containsString(ids, finding.Rule) {
+			ids = append(ids, finding.Rule)
+		}
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ", ")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// RunMetadata records the binary and the conditions a report, lockfile or
+// pull request description was produced under, so the scan can be
+// reproduced
+type RunMetadata struct {
+	Tool      toolInfo  `json:"tool"`
+	StartedAt time.Time `json:"started_at"`
+	Command   string    `json:"command"`
+	// Flags lists the flags given on the command line, one entry per value
+	// of a repeatable flag
+	Flags []string `json:"flags"`
+	Args  []string `json:"args,omitempty"`
+	// Config identifies the configuration file by its SHA-256, absent when
+	// none was read
+	Config *fileDigest `json:"config,omitempty"`
+}
+
+// currentRun is the command being run, recorded when its flags are parsed
+var currentRun = RunMetadata{StartedAt: time.Now().UTC().Truncate(time.Second), Flags: []string{}}
+
+// currentTool identifies the running binary
+func currentTool() toolInfo {
+	return toolInfo{
+		Name:      programName,
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		GoVersion: strings.TrimPrefix(runtime.Version(), "go"),
+	}
+}
+
+// recordRun records the command and the flags set on its command line
+func recordRun(name string, fs *flag.FlagSet) {
+	currentRun.StartedAt = time.Now().UTC().Truncate(time.Second)
+	currentRun.Command = name
+	currentRun.Flags = []string{}
+	currentRun.Args = fs.Args()
+	fs.Visit(func(f *flag.Flag) {
+		currentRun.Flags = append(currentRun.Flags, flagArguments(f)...)
+	})
+}
+
+// flagArguments spells a set flag the way it was given, e.g. --yes or
+// --include=ci.yml for each value of a repeatable flag
+func flagArguments(f *flag.Flag) []string {
+	if values, ok := f.Value.(*stringSliceFlag); ok {
+		args := make([]string, 0, len(*values))
+		for _, value := range *values {
+			args = append(args, "--"+f.Name+"="+value)
+		}
+		return args
+	}
+	if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() && f.Value.String() == "true" {
+		return []string{"--" + f.Name}
+	}
+	return []string{"--" + f.Name + "=" + f.Value.String()}
+}
+
+// runMetadata returns the metadata of the current run with the
+// configuration it read
+func runMetadata(cfg *Config) RunMetadata {
+	run := currentRun
+	run.Tool = currentTool()
+	if cfg != nil {
+		run.Config = cfg.source
+	}
+	return run
+}
+
+// commandLine returns the command line of a run
+func (r RunMetadata) commandLine() string {
+	parts := append([]string{programName}, strings.Fields(r.Command)...)
+	parts = append(parts, r.Flags...)
+	return strings.Join(append(parts, r.Args...), " ")
+}
+
+// writeRunDetails appends the run metadata to a Markdown document
+func writeRunDetails(b *strings.Builder, run RunMetadata) {
+	b.WriteString("\n<details>\n<summary>Run details</summary>\n\n")
+	fmt.Fprintf(b, "- Tool: %s %s (commit %s, built %s, Go %s)\n", run.Tool.Name, run.Tool.Version, run.Tool.GitCommit, run.Tool.BuildTime, run.Tool.GoVersion)
+	fmt.Fprintf(b, "- Started: %s\n", run.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(b, "- Command: `%s`\n", run.commandLine())
+	if run.Config != nil {
+		fmt.Fprintf(b, "- Config: `%s` (sha256 %s)\n", run.Config.Path, run.Config.SHA256)
+	}
+	b.WriteString("\n</details>\n")
+}
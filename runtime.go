@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// deprecatedRuntimes are the runs.using values of JavaScript actions that
+// GitHub runners are removing
+var deprecatedRuntimes = []string{"node12", "node16"}
+
+// actionRuntime returns the runs.using value of the action manifest at ref
+func actionRuntime(forge Forge, action ActionInfo, ref string) (string, error) {
+	owner, repo, dir := splitActionPath(action.Repo)
+	manifest, err := fetchManifest(forge, owner, repo, dir, ref)
+	if err != nil {
+		return "", err
+	}
+	var m distManifest
+	if err := yaml.Unmarshal(manifest, &m); err != nil {
+		return "", fmt.Errorf("invalid action manifest: %w", err)
+	}
+	return strings.ToLower(m.Runs.Using), nil
+}
+
+// deprecatedRuntime reports whether an action runs on a Node version that
+// runners are removing
+func deprecatedRuntime(runtime string) bool {
+	return slices.Contains(deprecatedRuntimes, runtime)
+}
+
+// checkRuntimes reads the runtime of every action at its pinned commit and
+// reports the actions on deprecated Node versions, with the runtime of the
+// latest release when that one is newer. It returns the number of actions
+// on a deprecated runtime.
+func checkRuntimes(forge Forge, actions WorkflowActions) int {
+	fmt.Fprintln(console, "\n⚙️  Checking the Node runtimes of JavaScript actions...")
+
+	// The same pin is usually shared by many workflows
+	type lookup struct {
+		runtime string
+		err     error
+	}
+	runtimes := make(map[string]lookup)
+	runtimeAt := func(action ActionInfo, ref string) lookup {
+		key := action.Repo + "@" + ref
+		found, ok := runtimes[key]
+		if !ok {
+			found.runtime, found.err = actionRuntime(forge, action, ref)
+			runtimes[key] = found
+		}
+		return found
+	}
+
+	deprecated := 0
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker || action.ReusableWorkflow || action.Error != nil {
+				continue
+			}
+			ref := action.CurrentSHA
+			if ref == "" {
+				ref = action.CurrentRef
+			}
+			found := runtimeAt(*action, ref)
+			if found.err != nil {
+				fmt.Fprintf(console, "  %s %s:%d %s: could not read the action manifest: %v\n", theme.Warning, action.WorkflowFile, action.Line, action.Repo, found.err)
+				logger.Info("failed to read action runtime", "workflow", workflow, "repo", action.Repo, "error", found.err)
+				continue
+			}
+			action.Runtime = found.runtime
+			if !deprecatedRuntime(action.Runtime) {
+				continue
+			}
+			deprecated++
+			fmt.Fprintf(console, "  %s %s:%d %s@%s runs on %s, which runners are removing %s\n", severityStatus(ruleNodeRuntime), action.WorkflowFile, action.Line,
+				action.Repo, shortPin(action.CurrentRef), action.Runtime, ruleTag(ruleNodeRuntime))
+
+			if action.LatestSHA == "" || action.LatestSHA == action.CurrentSHA {
+				fmt.Fprintln(console, "     💡 This is the latest release; replace the action or ask its maintainers to upgrade")
+				continue
+			}
+			latest := runtimeAt(*action, action.LatestSHA)
+			switch {
+			case latest.err != nil:
+				logger.Info("failed to read latest action runtime", "repo", action.Repo, "error", latest.err)
+			case deprecatedRuntime(latest.runtime):
+				fmt.Fprintf(console, "     💡 %s still runs on %s; replace the action or ask its maintainers to upgrade\n", action.LatestTag, latest.runtime)
+			default:
+				action.LatestRuntime = latest.runtime
+				fmt.Fprintf(console, "     💡 %s runs on %s; update the pin\n", action.LatestTag, latest.runtime)
+			}
+		}
+	}
+
+	if deprecated == 0 {
+		fmt.Fprintf(console, "%s No action runs on a deprecated Node runtime\n", theme.OK)
+	}
+	return deprecated
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errOutsideRepository is returned when a write would leave the repository
+var errOutsideRepository = errors.New("refusing to write outside the repository")
+
+// isWithin reports whether path is root or lies below it. Both must be
+// absolute and clean.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// resolvePath resolves the symlinks of path. A file that does not exist yet
+// resolves through its parent directory; a dangling symlink is an error since
+// writing to it would create its target.
+func resolvePath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if info, lstatErr := os.Lstat(path); lstatErr == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return "", fmt.Errorf("%s is a symlink to a missing file", path)
+	}
+
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(path)), nil
+}
+
+// checkWritePath verifies that writing to path stays inside the repository,
+// the working directory the scan runs from: the path must not escape it with
+// .. segments, and neither the file nor its parent directories may be
+// symlinks resolving outside of it. The tool may run against untrusted
+// checkouts, where a workflow symlinked to e.g. ~/.bashrc must not be rewritten.
+func checkWritePath(path string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to determine repository root: %w", err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if !isWithin(wd, abs) {
+		return fmt.Errorf("%w: %s is not below %s", errOutsideRepository, path, wd)
+	}
+
+	root, err := filepath.EvalSymlinks(wd)
+	if err != nil {
+		return fmt.Errorf("failed to determine repository root: %w", err)
+	}
+	resolved, err := resolvePath(abs)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errOutsideRepository, err)
+	}
+	if !isWithin(root, resolved) {
+		return fmt.Errorf("%w: %s resolves to %s", errOutsideRepository, path, resolved)
+	}
+	return nil
+}
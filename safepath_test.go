@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckWritePath(t *testing.T) {
+	repo, outside := t.TempDir(), t.TempDir()
+	mustWrite := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("on: push\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustSymlink := func(target, link string) {
+		t.Helper()
+		if err := os.Symlink(target, filepath.Join(repo, link)); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+
+	mustWrite(filepath.Join(repo, ".github", "workflows", "ci.yml"))
+	mustWrite(filepath.Join(outside, "bashrc"))
+	mustSymlink(filepath.Join(outside, "bashrc"), filepath.Join(".github", "workflows", "evil.yml"))
+	mustSymlink("ci.yml", filepath.Join(".github", "workflows", "alias.yml"))
+	mustSymlink(filepath.Join(outside, "missing"), filepath.Join(".github", "workflows", "dangling.yml"))
+	mustSymlink(outside, "linked")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"workflow", ".github/workflows/ci.yml", false},
+		{"new file", ".github/workflows/new.yml", false},
+		{"absolute path inside", filepath.Join(repo, ".github", "workflows", "ci.yml"), false},
+		{"symlink inside", ".github/workflows/alias.yml", false},
+		{"parent segments", "../escape.yml", true},
+		{"parent segments back inside", ".github/../.github/workflows/ci.yml", false},
+		{"absolute path outside", filepath.Join(outside, "bashrc"), true},
+		{"symlink outside", ".github/workflows/evil.yml", true},
+		{"dangling symlink", ".github/workflows/dangling.yml", true},
+		{"symlinked directory", "linked/bashrc", true},
+		{"new file in symlinked directory", "linked/new.yml", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkWritePath(filepath.FromSlash(tt.path))
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, errOutsideRepository) {
+				t.Errorf("got %v, want %v", err, errOutsideRepository)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// sarifVersion and sarifSchema identify the SARIF format written by check --sarif
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifRule describes a kind of finding
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Help             sarifMessage `json:"help"`
+	DefaultConfig    sarifConfig  `json:"defaultConfiguration"`
+}
+
+// sarifConfig holds the default severity of a rule
+type sarifConfig struct {
+	Level string `json:"level"`
+}
+
+// sarifMessage is a plain text message
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifResult is a single finding
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+// sarifLocation points at a uses: reference in a workflow
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn,omitempty"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+// sarifRules describes the enabled rules with their configured severity
+func sarifRules() []sarifRule {
+	rules := []sarifRule{}
+	for _, rule := range findingRules {
+		if !ruleEnabled(rule.ID) {
+			continue
+		}
+		rules = append(rules, sarifRule{
+			ID:               rule.ID,
+			Name:             rule.Name,
+			ShortDescription: sarifMessage{Text: rule.Summary},
+			Help:             sarifMessage{Text: rule.Help},
+			DefaultConfig:    sarifConfig{Level: string(severityOf(rule.ID))},
+		})
+	}
+	return rules
+}
+
+// sarifResults converts findings to SARIF results. The severities error,
+// warning and note are SARIF levels as well.
+func sarifResults(findings []Finding) []sarifResult {
+	results := make([]sarifResult, 0, len(findings))
+	for _, finding := range findings {
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = finding.Workflow
+		location.PhysicalLocation.Region.StartLine = max(finding.Line, 1)
+		location.PhysicalLocation.Region.StartColumn = finding.Column
+		results = append(results, sarifResult{
+			RuleID:    finding.Rule,
+			Level:     string(finding.Severity),
+			Message:   sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{location},
+		})
+	}
+	return results
+}
+
+// writeSARIFReport writes the check findings as a SARIF log for code
+// scanning. The run metadata is kept in the property bag of the run.
+func writeSARIFReport(w io.Writer, findings []Finding, cfg *Config) error {
+	run := runMetadata(cfg)
+	log := map[string]any{
+		"version": sarifVersion,
+		"$schema": sarifSchema,
+		"runs": []any{
+			map[string]any{
+				"tool": map[string]any{
+					"driver": map[string]any{
+						"name":           programName,
+						"version":        Version,
+						"informationUri": "https://github.com/" + selfOwner + "/" + selfRepo,
+						"rules":          sarifRules(),
+					},
+				},
+				"invocations": []any{
+					map[string]any{
+						"commandLine":         run.commandLine(),
+						"startTimeUtc":        run.StartedAt.Format(time.RFC3339),
+						"executionSuccessful": true,
+					},
+				},
+				"properties": map[string]any{"run": run},
+				"results":    sarifResults(findings),
+			},
+		},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
+
+// writeSARIFFile writes the SARIF log of the check findings to path
+func writeSARIFFile(path string, findings []Finding, cfg *Config) error {
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := writeSARIFReport(file, findings, cfg); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
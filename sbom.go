@@ -0,0 +1,436 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// SBOM formats of the sbom command
+	sbomFormatCycloneDX = "cyclonedx"
+	sbomFormatSPDX      = "spdx"
+
+	cycloneDXSpecVersion = "1.5"
+	spdxVersion          = "SPDX-2.3"
+
+	// spdxNamespaceBase prefixes the unique namespace of an SPDX document
+	spdxNamespaceBase = "https://github.com/greysquirr3l/github-ci-hash/sbom/"
+)
+
+// sbomComponent is an action at one version, with the workflows using it
+type sbomComponent struct {
+	// Name is the action path, e.g. github/codeql-action/init, or the image
+	Name string
+	// Ref is the ref in the uses: reference and SHA the commit it
+	// resolves to, empty when unknown
+	Ref string
+	SHA string
+	// Version is the tag of the pin comment for pins, otherwise the ref
+	Version   string
+	Docker    bool
+	Workflows []string
+}
+
+// purlVersion percent-encodes a version for a package URL, which reserves
+// the colon of digests
+func purlVersion(version string) string {
+	return strings.ReplaceAll(url.PathEscape(version), ":", "%3A")
+}
+
+// purl returns the package URL of the component, pkg:github/owner/repo@sha
+// with the action directory as subpath, or pkg:docker for images
+func (c sbomComponent) purl() string {
+	version := c.SHA
+	if version == "" {
+		version = c.Ref
+	}
+	version = purlVersion(version)
+	if c.Docker {
+		name, qualifiers := c.Name, ""
+		if host, rest, ok := strings.Cut(name, "/"); ok && strings.ContainsAny(host, ".:") {
+			name, qualifiers = rest, "?repository_url="+url.QueryEscape(host)
+		}
+		return "pkg:docker/" + name + "@" + version + qualifiers
+	}
+	owner, repo, dir := splitActionPath(c.Name)
+	purl := "pkg:github/" + strings.ToLower(owner) + "/" + strings.ToLower(repo) + "@" + version
+	if dir != "" {
+		purl += "#" + dir
+	}
+	return purl
+}
+
+// sbomComponents lists the actions of the workflows once per version,
+// ordered by name and ref
+func sbomComponents(actions WorkflowActions) []sbomComponent {
+	index := make(map[string]int)
+	var components []sbomComponent
+	for _, action := range sortedByRepo(actions) {
+		key := action.Repo + "@" + action.CurrentRef
+		i, ok := index[key]
+		if !ok {
+			component := sbomComponent{Name: action.Repo, Ref: action.CurrentRef, Version: action.CurrentRef, Docker: action.Docker}
+			switch {
+			case action.Docker:
+				if action.ImageTag != "" {
+					component.Version = action.ImageTag
+				}
+			case action.Pinned():
+				component.SHA = action.CurrentRef
+				if tag := currentVersion(action); tag != "" {
+					component.Version = tag
+				}
+			default:
+				component.SHA = action.CurrentSHA
+			}
+			i = len(components)
+			index[key] = i
+			components = append(components, component)
+		}
+		if !slices.Contains(components[i].Workflows, action.WorkflowFile) {
+			components[i].Workflows = append(components[i].Workflows, action.WorkflowFile)
+		}
+	}
+	slices.SortFunc(components, func(a, b sbomComponent) int {
+		if a.Name != b.Name {
+			return strings.Compare(a.Name, b.Name)
+		}
+		return strings.Compare(a.Ref, b.Ref)
+	})
+	return components
+}
+
+// resolveUnpinned resolves the refs of unpinned actions to the commits they
+// point at, so their package URLs name a commit too. Failures leave the ref.
+func resolveUnpinned(forge Forge, actions WorkflowActions) {
+	resolved := make(map[string]string)
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker || action.Pinned() {
+				continue
+			}
+			owner, repo, _ := splitActionPath(action.Repo)
+			key := strings.ToLower(owner+"/"+repo) + "@" + action.CurrentRef
+			sha, ok := resolved[key]
+			if !ok {
+				var err error
+				if sha, err = forge.ResolveRef(owner, repo, action.CurrentRef); err != nil {
+					fmt.Fprintf(console, "  %s %s: %v\n", theme.Warning, action.reference(), err)
+				}
+				resolved[key] = sha
+			}
+			action.CurrentSHA = sha
+		}
+	}
+}
+
+// sbomSubject names the repository the SBOM describes, from
+// GITHUB_REPOSITORY in workflows or the working directory
+func sbomSubject() string {
+	if repo := os.Getenv("GITHUB_REPOSITORY"); repo != "" {
+		return repo
+	}
+	if dir, err := os.Getwd(); err == nil {
+		return filepath.Base(dir)
+	}
+	return "workflows"
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// cycloneDXBOM is a CycloneDX JSON document
+type cycloneDXBOM struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cycloneDXComponent `json:"components"`
+	} `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXComponent struct {
+	Type               string              `json:"type"`
+	BOMRef             string              `json:"bom-ref,omitempty"`
+	Name               string              `json:"name"`
+	Version            string              `json:"version,omitempty"`
+	PURL               string              `json:"purl,omitempty"`
+	ExternalReferences []cycloneDXExternal `json:"externalReferences,omitempty"`
+	Properties         []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXExternal struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// buildCycloneDX describes the workflows as file components depending on
+// the action components they use
+func buildCycloneDX(subject string, components []sbomComponent, now time.Time) cycloneDXBOM {
+	bom := cycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Components:   []cycloneDXComponent{},
+		Dependencies: []cycloneDXDependency{},
+	}
+	bom.Metadata.Timestamp = now.UTC().Format(time.RFC3339)
+	bom.Metadata.Tools.Components = []cycloneDXComponent{{Type: "application", Name: programName, Version: Version}}
+	bom.Metadata.Component = cycloneDXComponent{Type: "application", BOMRef: subject, Name: subject}
+
+	// Refs resolving to the same commit share one component
+	dependsOn := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, c := range components {
+		purl := c.purl()
+		for _, workflow := range c.Workflows {
+			if !slices.Contains(dependsOn[workflow], purl) {
+				dependsOn[workflow] = append(dependsOn[workflow], purl)
+			}
+		}
+		if seen[purl] {
+			continue
+		}
+		seen[purl] = true
+		component := cycloneDXComponent{Type: "library", BOMRef: purl, Name: c.Name, Version: c.Version, PURL: purl}
+		if c.Docker {
+			component.Type = "container"
+		} else {
+			owner, repo, _ := splitActionPath(c.Name)
+			component.ExternalReferences = []cycloneDXExternal{{Type: "vcs", URL: "https://github.com/" + owner + "/" + repo}}
+		}
+		component.Properties = []cycloneDXProperty{{Name: programName + ":ref", Value: c.Ref}}
+		bom.Components = append(bom.Components, component)
+	}
+
+	workflows := sortedKeys(dependsOn)
+	for _, workflow := range workflows {
+		bom.Components = append(bom.Components, cycloneDXComponent{Type: "file", BOMRef: workflow, Name: workflow})
+		bom.Dependencies = append(bom.Dependencies, cycloneDXDependency{Ref: workflow, DependsOn: dependsOn[workflow]})
+	}
+	bom.Dependencies = append(bom.Dependencies, cycloneDXDependency{Ref: subject, DependsOn: workflows})
+	return bom
+}
+
+// spdxDocument is an SPDX JSON document
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Files             []spdxFile         `json:"files"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxFile struct {
+	FileName  string         `json:"fileName"`
+	SPDXID    string         `json:"SPDXID"`
+	Checksums []spdxChecksum `json:"checksums"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxFileChecksums returns the SHA-1 and SHA-256 of a workflow file, which
+// SPDX requires for files; unreadable files get none
+func spdxFileChecksums(path string) []spdxChecksum {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []spdxChecksum{}
+	}
+	sum := sha1.Sum(data)
+	sum256 := sha256.Sum256(data)
+	return []spdxChecksum{
+		{Algorithm: "SHA1", ChecksumValue: hex.EncodeToString(sum[:])},
+		{Algorithm: "SHA256", ChecksumValue: hex.EncodeToString(sum256[:])},
+	}
+}
+
+// buildSPDX describes the workflows as files depending on the action
+// packages they use
+func buildSPDX(subject string, components []sbomComponent, now time.Time) spdxDocument {
+	doc := spdxDocument{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              subject,
+		DocumentNamespace: spdxNamespaceBase + url.PathEscape(subject) + "-" + newUUID(),
+		CreationInfo: spdxCreationInfo{
+			Created:  now.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + programName + "-" + Version},
+		},
+		Packages:      []spdxPackage{},
+		Files:         []spdxFile{},
+		Relationships: []spdxRelationship{},
+	}
+
+	fileIDs := make(map[string]string)
+	var workflows []string
+	for _, c := range components {
+		for _, workflow := range c.Workflows {
+			if _, ok := fileIDs[workflow]; !ok {
+				fileIDs[workflow] = ""
+				workflows = append(workflows, workflow)
+			}
+		}
+	}
+	slices.Sort(workflows)
+	for i, workflow := range workflows {
+		fileIDs[workflow] = fmt.Sprintf("SPDXRef-Workflow-%d", i+1)
+		doc.Files = append(doc.Files, spdxFile{FileName: "./" + filepath.ToSlash(workflow), SPDXID: fileIDs[workflow], Checksums: spdxFileChecksums(workflow)})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: doc.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: fileIDs[workflow]})
+	}
+
+	for i, c := range components {
+		pkg := spdxPackage{
+			Name:             c.Name,
+			SPDXID:           fmt.Sprintf("SPDXRef-Action-%d", i+1),
+			VersionInfo:      c.Version,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs:     []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: c.purl()}},
+		}
+		if !c.Docker && c.SHA != "" {
+			owner, repo, _ := splitActionPath(c.Name)
+			pkg.DownloadLocation = "git+https://github.com/" + owner + "/" + repo + "@" + c.SHA
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		for _, workflow := range c.Workflows {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{SPDXElementID: fileIDs[workflow], RelationshipType: "DEPENDS_ON", RelatedSPDXElement: pkg.SPDXID})
+		}
+	}
+	return doc
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// setupSBOM registers the flags of the sbom command and returns its runner
+func setupSBOM(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	format := fs.String("format", sbomFormatCycloneDX, "SBOM `format`: cyclonedx or spdx")
+	output := fs.String("output", "", "Write the SBOM to `file` instead of stdout")
+	resolve := fs.Bool("resolve", false, "Resolve the refs of unpinned actions to commit SHAs through the API")
+
+	return func(args []string) {
+		if *format != sbomFormatCycloneDX && *format != sbomFormatSPDX {
+			fmt.Fprintf(console, "Error: --format must be cyclonedx or spdx, got %q\n", *format)
+			os.Exit(2)
+		}
+		if *output == "" {
+			console = os.Stderr
+		}
+
+		cfg, scanOpts := mustLoadScanConfig(opts)
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+		if *resolve {
+			fmt.Fprintln(console, "📌 Resolving unpinned actions...")
+			resolveUnpinned(forgeFor(NewGitHubClient(), cfg, actions), actions)
+		}
+
+		components := sbomComponents(actions)
+		var document any
+		if *format == sbomFormatSPDX {
+			document = buildSPDX(sbomSubject(), components, time.Now())
+		} else {
+			document = buildCycloneDX(sbomSubject(), components, time.Now())
+		}
+		data, err := marshalIndented(document)
+		if err != nil {
+			fmt.Fprintf(console, "Error encoding the SBOM: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *output != "" {
+			if err := os.WriteFile(*output, data, 0o600); err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(console, "%s SBOM of %d actions written to %s\n", theme.OK, len(components), *output)
+			return
+		}
+		if _, err := os.Stdout.Write(data); err != nil {
+			fmt.Fprintf(console, "Error writing the SBOM: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSchemaJSON is the JSON Schema for the configuration file
+//
+//go:embed schema/config.schema.json
+var configSchemaJSON []byte
+
+// configSchema is the subset of JSON Schema used to validate the configuration
+type configSchema struct {
+	Type                 string                   `json:"type"`
+	Description          string                   `json:"description"`
+	Properties           map[string]*configSchema `json:"properties"`
+	AdditionalProperties *configSchema            `json:"additionalProperties"`
+	Items                *configSchema            `json:"items"`
+	Required             []string                 `json:"required"`
+	Enum                 []string                 `json:"enum"`
+	Format               string                   `json:"format"`
+	MinLength            int                      `json:"minLength"`
+	MinItems             int                      `json:"minItems"`
+	Minimum              *int64                   `json:"minimum"`
+
+	// forbidden is set for the boolean schema false
+	forbidden bool
+}
+
+// UnmarshalJSON accepts boolean schemas in addition to schema objects
+func (s *configSchema) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		*s = configSchema{forbidden: !allowed}
+		return nil
+	}
+
+	type plain configSchema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// SchemaError describes a configuration value that does not match the schema
+type SchemaError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// Error implements the error interface
+func (e *SchemaError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, path, e.Message)
+}
+
+// loadConfigSchema parses the embedded configuration schema
+func loadConfigSchema() (*configSchema, error) {
+	schema := &configSchema{}
+	if err := json.Unmarshal(configSchemaJSON, schema); err != nil {
+		return nil, fmt.Errorf("invalid embedded config schema: %w", err)
+	}
+	return schema, nil
+}
+
+// validateConfigDocument checks a parsed YAML document against the
+// configuration schema and returns every violation found
+func validateConfigDocument(doc *yaml.Node) error {
+	schema, err := loadConfigSchema()
+	if err != nil {
+		return err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+
+	var errs []error
+	validateNode(doc.Content[0], schema, "", &errs)
+	return errors.Join(errs...)
+}
+
+// validateNode checks a node against a schema, appending violations to errs
+func validateNode(node *yaml.Node, schema *configSchema, path string, errs *[]error) {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, &SchemaError{Path: path, Line: node.Line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if schema.forbidden {
+		fail("unknown property")
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			fail("expected a mapping, got %s", describeNode(node))
+			return
+		}
+		validateMapping(node, schema, path, errs)
+
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			fail("expected a list, got %s", describeNode(node))
+			return
+		}
+		if len(node.Content) < schema.MinItems {
+			fail("expected at least %d item(s)", schema.MinItems)
+		}
+		if schema.Items != nil {
+			for i, item := range node.Content {
+				validateNode(item, schema.Items, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+
+	case "string":
+		if node.Kind != yaml.ScalarNode || node.Tag == "!!null" {
+			fail("expected a string, got %s", describeNode(node))
+			return
+		}
+		if len(node.Value) < schema.MinLength {
+			fail("must not be empty")
+		}
+		if len(schema.Enum) > 0 && !containsString(schema.Enum, node.Value) {
+			fail("invalid value %q (expected one of: %s)", node.Value, strings.Join(schema.Enum, ", "))
+		}
+		if schema.Format == "regex" {
+			if _, err := regexp.Compile(node.Value); err != nil {
+				fail("invalid regular expression: %v", err)
+			}
+		}
+
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			fail("expected true or false, got %s", describeNode(node))
+		}
+
+	case "integer":
+		value, err := strconv.ParseInt(node.Value, 10, 64)
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" || err != nil {
+			fail("expected an integer, got %s", describeNode(node))
+			return
+		}
+		if schema.Minimum != nil && value < *schema.Minimum {
+			fail("must be at least %d", *schema.Minimum)
+		}
+	}
+}
+
+// validateMapping checks the keys and values of a mapping node
+func validateMapping(node *yaml.Node, schema *configSchema, path string, errs *[]error) {
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		seen[key.Value] = true
+
+		childPath := key.Value
+		if path != "" {
+			childPath = path + "." + key.Value
+		}
+
+		child, ok := schema.Properties[key.Value]
+		if !ok {
+			child = schema.AdditionalProperties
+		}
+		if child == nil {
+			continue
+		}
+		if child.forbidden {
+			*errs = append(*errs, &SchemaError{Path: childPath, Line: key.Line, Message: unknownPropertyMessage(schema)})
+			continue
+		}
+		validateNode(value, child, childPath, errs)
+	}
+
+	for _, name := range schema.Required {
+		if !seen[name] {
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+			*errs = append(*errs, &SchemaError{Path: childPath, Line: node.Line, Message: "missing required property"})
+		}
+	}
+}
+
+// unknownPropertyMessage lists the properties a mapping accepts
+func unknownPropertyMessage(schema *configSchema) string {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "unknown property (expected one of: " + strings.Join(names, ", ") + ")"
+}
+
+// describeNode names the kind of a YAML node for error messages
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!null":
+			return "null"
+		case "!!bool":
+			return "a boolean"
+		case "!!int", "!!float":
+			return "a number"
+		}
+		return "a string"
+	}
+	return "an unsupported value"
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultScorecardURL is the public OpenSSF Scorecard API
+	defaultScorecardURL = "https://api.securityscorecards.dev"
+
+	// scorecardTimeout bounds a single Scorecard request
+	scorecardTimeout = 30 * time.Second
+
+	// maxScorecardResponseSize bounds a Scorecard response
+	maxScorecardResponseSize = 4 << 20
+
+	// scorecardFailingScore is the score below which a check is reported
+	// as failing
+	scorecardFailingScore = 5
+)
+
+// Scorecard is the OpenSSF Scorecard result of an action repository
+type Scorecard struct {
+	Score float64 `json:"score"`
+	// Date is the day the repository was last scored
+	Date string `json:"date,omitempty"`
+	// Commit is the commit that was scored
+	Commit string `json:"commit,omitempty"`
+	URL    string `json:"url"`
+	// Failing lists the checks scoring below 5, lowest first
+	Failing []ScorecardCheck `json:"failing,omitempty"`
+	// BelowMinimum is set when the score is below min_scorecard
+	BelowMinimum bool `json:"below_minimum,omitempty"`
+}
+
+// ScorecardCheck is one check of a Scorecard result
+type ScorecardCheck struct {
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// scorecardClient queries the OpenSSF Scorecard API
+type scorecardClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// newScorecardClient returns a Scorecard client for the API at baseURL
+func newScorecardClient(baseURL string) *scorecardClient {
+	return &scorecardClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Transport: loggingTransport{}, Timeout: scorecardTimeout},
+	}
+}
+
+// fetch returns the Scorecard of a GitHub repository, or nil when the
+// repository has not been scored
+func (c *scorecardClient) fetch(owner, repo string) (*Scorecard, error) {
+	project := "github.com/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+	resp, err := c.http.Get(c.baseURL + "/projects/" + project)
+	if err != nil {
+		return nil, &ResolveError{Kind: ErrorKindNetwork, Err: fmt.Errorf("Scorecard API could not be reached: %w", err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("Scorecard request for %s/%s failed with status %d", owner, repo, resp.StatusCode)
+	}
+
+	var result struct {
+		Date string `json:"date"`
+		Repo struct {
+			Commit string `json:"commit"`
+		} `json:"repo"`
+		Score  float64 `json:"score"`
+		Checks []struct {
+			Name   string `json:"name"`
+			Score  int    `json:"score"`
+			Reason string `json:"reason"`
+		} `json:"checks"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxScorecardResponseSize)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid Scorecard response: %w", err)
+	}
+
+	scorecard := &Scorecard{
+		Score:  result.Score,
+		Date:   result.Date,
+		Commit: result.Repo.Commit,
+		URL:    "https://scorecard.dev/viewer/?uri=" + project,
+	}
+	for _, check := range result.Checks {
+		// Inconclusive checks score -1
+		if check.Score >= 0 && check.Score < scorecardFailingScore {
+			scorecard.Failing = append(scorecard.Failing, ScorecardCheck{Name: check.Name, Score: check.Score, Reason: check.Reason})
+		}
+	}
+	sort.SliceStable(scorecard.Failing, func(i, j int) bool { return scorecard.Failing[i].Score < scorecard.Failing[j].Score })
+	return scorecard, nil
+}
+
+// failingNames lists the failing checks with their scores
+func (s *Scorecard) failingNames() string {
+	names := make([]string, len(s.Failing))
+	for i, check := range s.Failing {
+		names[i] = fmt.Sprintf("%s %d", check.Name, check.Score)
+	}
+	return strings.Join(names, ", ")
+}
+
+// annotateScorecards looks up the Scorecard of the repository of every
+// action, stores it in the actions and prints the scores. It returns the
+// scorecard-minimum policy rule, which repositories scoring below minimum
+// fail; repositories that were not scored do not.
+func annotateScorecards(client *scorecardClient, actions WorkflowActions, minimum float64) PolicyResult {
+	fmt.Fprintln(console, "\n📊 Looking up OpenSSF Scorecard scores...")
+	result := PolicyResult{Rule: "scorecard-minimum", Description: fmt.Sprintf("Every action repository has an OpenSSF Scorecard score of at least %g", minimum), Required: true}
+
+	type lookup struct {
+		scorecard *Scorecard
+		err       error
+	}
+	scored := make(map[string]lookup)
+	var repos []string
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker {
+				continue
+			}
+			owner, repo, _ := splitActionPath(action.Repo)
+			key := strings.ToLower(owner + "/" + repo)
+			found, ok := scored[key]
+			if !ok {
+				found.scorecard, found.err = client.fetch(owner, repo)
+				if found.scorecard != nil && minimum > 0 && ruleEnabled(ruleLowScorecard) {
+					found.scorecard.BelowMinimum = found.scorecard.Score < minimum
+				}
+				scored[key] = found
+				repos = append(repos, owner+"/"+repo)
+			}
+			action.Scorecard = found.scorecard
+		}
+	}
+
+	sort.Strings(repos)
+	for _, repo := range repos {
+		found := scored[strings.ToLower(repo)]
+		switch {
+		case found.err != nil:
+			fmt.Fprintf(console, "  %s %s: could not look up the Scorecard: %v\n", theme.Warning, repo, found.err)
+		case found.scorecard == nil:
+			fmt.Fprintf(console, "  %s %s: not scored by OpenSSF Scorecard\n", theme.Skipped, repo)
+		default:
+			status, suffix := theme.OK, ""
+			if found.scorecard.BelowMinimum {
+				status, suffix = severityStatus(ruleLowScorecard), fmt.Sprintf(", below %g %s", minimum, ruleTag(ruleLowScorecard))
+			}
+			failing := ""
+			if len(found.scorecard.Failing) > 0 {
+				failing = ", failing " + found.scorecard.failingNames()
+			}
+			fmt.Fprintf(console, "  %s %s: %.1f/10%s%s\n", status, repo, found.scorecard.Score, failing, suffix)
+		}
+	}
+
+	for _, action := range sortedActions(actions) {
+		if action.Scorecard != nil && action.Scorecard.BelowMinimum {
+			result.Violations = append(result.Violations, violationFor(action, fmt.Sprintf("Scorecard score %.1f is below %g", action.Scorecard.Score, minimum)))
+		}
+	}
+	return result
+}
+
+// scorecardOptions holds the Scorecard flags of check and audit
+type scorecardOptions struct {
+	enabled bool
+	url     string
+	minimum float64
+}
+
+// registerScorecardFlags adds the Scorecard flags to a command's flag set
+func registerScorecardFlags(fs *flag.FlagSet) *scorecardOptions {
+	opts := &scorecardOptions{}
+	fs.BoolVar(&opts.enabled, "scorecard", false, "Show the OpenSSF Scorecard score and failing checks of every action repository")
+	fs.StringVar(&opts.url, "scorecard-url", defaultScorecardURL, "Scorecard API `url`")
+	fs.Float64Var(&opts.minimum, "min-scorecard", 0, "Fail when an action repository scores below this `score` out of 10, overriding min_scorecard; implies --scorecard")
+	return opts
+}
+
+// scorecards annotates the actions with their Scorecards when enabled by
+// the flags or a configured minimum. It returns the scorecard-minimum policy
+// rule, or nil when no minimum is set.
+func (o *scorecardOptions) scorecards(cfg *Config, actions WorkflowActions) *PolicyResult {
+	minimum := cfg.MinScorecard
+	if o.minimum > 0 {
+		minimum = o.minimum
+	}
+	if !o.enabled && minimum <= 0 {
+		return nil
+	}
+	result := annotateScorecards(newScorecardClient(o.url), actions, minimum)
+	if minimum <= 0 || !ruleEnabled(ruleLowScorecard) {
+		return nil
+	}
+	return &result
+}
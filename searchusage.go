@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// codeSearchInterval spaces code search requests to stay within the
+	// limit of 10 requests per minute
+	codeSearchInterval = 6 * time.Second
+
+	// maxCodeSearchRetries bounds the retries of a rate limited search
+	maxCodeSearchRetries = 3
+
+	// maxCodeSearchWait bounds a single wait for the search rate limit
+	maxCodeSearchWait = 2 * time.Minute
+)
+
+// UsageFinding is a reference to the searched action that needs remediation
+type UsageFinding struct {
+	Repository string `json:"repository"`
+	Workflow   string `json:"workflow"`
+	Line       int    `json:"line"`
+	Uses       string `json:"uses"`
+	Ref        string `json:"ref"`
+	// Version is the version of the reference, from the pin comment of
+	// commit pins, empty when unknown
+	Version string `json:"version,omitempty"`
+	// Reason is "unpinned" or "outdated"
+	Reason string `json:"reason"`
+	URL    string `json:"url,omitempty"`
+}
+
+// UsageReport is the outcome of search-usage
+type UsageReport struct {
+	Run    RunMetadata `json:"run"`
+	Action string      `json:"action"`
+	Org    string      `json:"org"`
+	Below  string      `json:"below,omitempty"`
+	// Files counts the files code search returned, Repositories the
+	// repositories they belong to
+	Files        int            `json:"files"`
+	Repositories int            `json:"repositories"`
+	References   int            `json:"references"`
+	Findings     []UsageFinding `json:"findings"`
+	// Unreadable lists the files whose content could not be fetched
+	Unreadable []string `json:"unreadable,omitempty"`
+}
+
+// codeSearcher paces code search requests, which have a much lower rate
+// limit than the rest of the API, and waits out the limit when it is hit
+type codeSearcher struct {
+	gc   *GitHubClient
+	last time.Time
+}
+
+// search returns a page of the files matching query
+func (s *codeSearcher) search(query string, page int) ([]githubCodeResult, int, error) {
+	for attempt := 0; ; attempt++ {
+		if wait := codeSearchInterval - time.Since(s.last); wait > 0 {
+			time.Sleep(wait)
+		}
+		s.last = time.Now()
+
+		results, next, err := s.gc.api.SearchCode(s.gc.ctx, query, page)
+		wait, limited := githubRetryAfter(err)
+		if !limited || attempt == maxCodeSearchRetries || wait > maxCodeSearchWait {
+			return results, next, err
+		}
+		wait = max(wait, codeSearchInterval)
+		fmt.Fprintf(console, "⏳ Code search rate limit reached, retrying in %s\n", wait.Round(time.Second))
+		time.Sleep(wait)
+	}
+}
+
+// usageQuery builds the code search query for the workflow files and
+// composite actions of org that mention repo
+func usageQuery(repo, org string) string {
+	return fmt.Sprintf("%q org:%s path:.github", repo, org)
+}
+
+// referencesAction reports whether a reference runs repo or one of its sub-actions
+func referencesAction(action ActionInfo, repo string) bool {
+	return strings.EqualFold(action.Repo, repo) || strings.HasPrefix(strings.ToLower(action.Repo), strings.ToLower(repo)+"/")
+}
+
+// usageFinding classifies a reference to the searched action, returning
+// false when it is pinned at or above below
+func usageFinding(action ActionInfo, below string) (UsageFinding, bool) {
+	finding := UsageFinding{
+		Workflow: action.WorkflowFile,
+		Line:     action.Line,
+		Uses:     action.Repo,
+		Ref:      action.CurrentRef,
+		Version:  currentVersion(action),
+	}
+	switch {
+	case !action.Pinned():
+		finding.Reason = "unpinned"
+	case below != "" && finding.Version != "" && compareVersionTags(finding.Version, below) < 0:
+		finding.Reason = "outdated"
+	default:
+		return finding, false
+	}
+	return finding, true
+}
+
+// searchUsage finds the references to repo in the workflows of org that are
+// unpinned or, when below is set, pinned to an older version
+func searchUsage(gc *GitHubClient, repo, org, below string) (*UsageReport, error) {
+	report := &UsageReport{Action: repo, Org: org, Below: below, Findings: []UsageFinding{}}
+	searcher := &codeSearcher{gc: gc}
+	query := usageQuery(repo, org)
+
+	var files []githubCodeResult
+	seen := make(map[string]bool)
+	for page := 1; page != 0; {
+		results, next, err := searcher.search(query, page)
+		if err != nil {
+			return nil, fmt.Errorf("code search for %s failed: %w", query, err)
+		}
+		for _, file := range results {
+			key := file.Repo + "/" + file.Path
+			if !seen[key] && isYAMLFile(file.Path) {
+				seen[key] = true
+				files = append(files, file)
+			}
+		}
+		page = next
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Repo != files[j].Repo {
+			return files[i].Repo < files[j].Repo
+		}
+		return files[i].Path < files[j].Path
+	})
+
+	repositories := make(map[string]bool)
+	for _, file := range files {
+		report.Files++
+		repositories[file.Repo] = true
+		fmt.Fprintf(console, "  📄 %s %s\n", file.Repo, file.Path)
+
+		owner, name, _ := strings.Cut(file.Repo, "/")
+		content, err := gc.api.FileContents(gc.ctx, owner, name, file.Path, "")
+		if err != nil {
+			logger.Debug("could not fetch search result", "repo", file.Repo, "path", file.Path, "error", err)
+			report.Unreadable = append(report.Unreadable, file.Repo+"/"+file.Path)
+			continue
+		}
+		actions, err := parseWorkflowContent(file.Path, string(content))
+		if err != nil {
+			logger.Debug("could not parse search result", "repo", file.Repo, "path", file.Path, "error", err)
+			report.Unreadable = append(report.Unreadable, file.Repo+"/"+file.Path)
+			continue
+		}
+		for _, action := range actions {
+			if action.Docker || !referencesAction(action, repo) {
+				continue
+			}
+			report.References++
+			finding, ok := usageFinding(action, below)
+			if !ok {
+				continue
+			}
+			finding.Repository = file.Repo
+			if file.HTMLURL != "" {
+				finding.URL = fmt.Sprintf("%s#L%d", file.HTMLURL, action.Line)
+			}
+			report.Findings = append(report.Findings, finding)
+		}
+	}
+	report.Repositories = len(repositories)
+	return report, nil
+}
+
+// printUsageReport lists the references that need remediation
+func printUsageReport(report *UsageReport) {
+	fmt.Fprintf(console, "\n📋 Usage of %s in %s: %d references in %d files across %d repositories\n",
+		report.Action, report.Org, report.References, report.Files, report.Repositories)
+	for _, finding := range report.Findings {
+		status, reason := theme.Error, "not pinned to a commit SHA"
+		if finding.Reason == "outdated" {
+			status, reason = theme.Update, finding.Version+" is older than "+report.Below
+		}
+		fmt.Fprintf(console, "  %s %s %s:%d %s@%s: %s\n", status, finding.Repository, finding.Workflow, finding.Line, finding.Uses, finding.Ref, reason)
+	}
+	for _, file := range report.Unreadable {
+		fmt.Fprintf(console, "  %s %s could not be read\n", theme.Warning, file)
+	}
+	switch {
+	case len(report.Findings) > 0:
+	case report.Below != "":
+		fmt.Fprintf(console, "%s Every reference is pinned to %s or later\n", theme.OK, report.Below)
+	default:
+		fmt.Fprintf(console, "%s Every reference is pinned\n", theme.OK)
+	}
+}
+
+// setupSearchUsage registers the flags of the search-usage command and
+// returns its runner
+func setupSearchUsage(fs *flag.FlagSet) func(args []string) {
+	org := fs.String("org", "", "Search the repositories of this `org` (required)")
+	below := fs.String("below", "", "Also report references pinned to a `version` older than this, e.g. v4")
+	jsonOutput := fs.Bool("json", false, "Write the findings as JSON to stdout")
+	registerGitHubAPIFlags(fs)
+
+	return func(args []string) {
+		if len(args) != 1 || *org == "" {
+			fmt.Fprintf(console, "Usage: %s search-usage --org <org> [options] owner/repo\n", programName)
+			os.Exit(2)
+		}
+		if *below != "" && !versionTagRegex.MatchString(*below) {
+			fmt.Fprintf(console, "Error: --below must be a version such as v4 or v4.1.7, got %q\n", *below)
+			os.Exit(2)
+		}
+		owner, repo, _ := splitActionPath(args[0])
+		if owner == "" || repo == "" {
+			fmt.Fprintf(console, "Error: %q is not an owner/repo action\n", args[0])
+			os.Exit(2)
+		}
+		if *jsonOutput {
+			console = os.Stderr
+		}
+
+		gc := NewGitHubClient()
+		fmt.Fprintf(console, "🔎 Searching %s for workflows using %s...\n", *org, args[0])
+		report, err := searchUsage(gc, args[0], *org, *below)
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		report.Run = runMetadata(nil)
+
+		if *jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				fmt.Fprintf(console, "Error writing JSON report: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		printUsageReport(report)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// selfOwner and selfRepo identify this tool's repository
+	selfOwner = "greysquirr3l"
+	selfRepo  = "github-ci-hash"
+
+	// checksumsAsset and signatureAsset are published with every release
+	checksumsAsset = "SHA256SUMS"
+	signatureAsset = "SHA256SUMS.sig"
+
+	// maxDownloadSize bounds the size of a downloaded release asset
+	maxDownloadSize = 100 << 20
+
+	// downloadTimeout bounds a single asset download
+	downloadTimeout = 5 * time.Minute
+)
+
+// ReleasePublicKey is the base64 encoded Ed25519 key that signs the release
+// checksums (set by build flags). Raw keys and DER encoded public keys are accepted.
+var ReleasePublicKey = ""
+
+// releaseAssetName returns the name of the release binary for this platform
+func releaseAssetName() string {
+	name := fmt.Sprintf("%s-%s-%s", selfRepo, runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// parseReleasePublicKey decodes the embedded release signing key
+func parseReleasePublicKey(encoded string) (ed25519.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid release public key: %w", err)
+	}
+	if len(data) == ed25519.PublicKeySize {
+		return ed25519.PublicKey(data), nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release public key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("release public key is not an Ed25519 key")
+	}
+	return edKey, nil
+}
+
+// checksumFor returns the expected SHA-256 of name from a SHA256SUMS file
+func checksumFor(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", checksumsAsset, name)
+}
+
+// findReleaseAsset returns the asset with the given name
+func findReleaseAsset(release *githubRelease, name string) (githubAsset, error) {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset, nil
+		}
+	}
+	return githubAsset{}, fmt.Errorf("release %s has no %s asset", release.Tag, name)
+}
+
+// downloadAsset fetches a release asset into memory
+func downloadAsset(ctx context.Context, asset githubAsset) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.DownloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", asset.Name, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", asset.Name, err)
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", asset.Name, maxDownloadSize)
+	}
+	return data, nil
+}
+
+// verifyReleaseBinary checks the signature of the checksums file and the
+// checksum of the downloaded binary. Without a public key only the checksum
+// is checked, which the caller must explicitly allow.
+func verifyReleaseBinary(binary, sums, signature []byte, name string, key ed25519.PublicKey) error {
+	if key != nil && !ed25519.Verify(key, sums, signature) {
+		return fmt.Errorf("signature verification of %s failed", checksumsAsset)
+	}
+
+	expected, err := checksumFor(sums, name)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(binary)
+	if actual := hex.EncodeToString(digest[:]); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+	}
+	return nil
+}
+
+// replaceExecutable atomically replaces the running executable with binary
+func replaceExecutable(binary []byte) (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the running executable: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", fmt.Errorf("failed to resolve the running executable: %w", err)
+	}
+
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(exe)+".new-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file next to %s: %w", exe, err)
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+
+	if _, err := tmp.Write(binary); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("failed to write new executable: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write new executable: %w", err)
+	}
+	// #nosec G302 - the replacement must be executable like the original
+	if err := os.Chmod(tmpPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to make new executable runnable: %w", err)
+	}
+
+	// Windows cannot replace a running executable, but it can rename it
+	if runtime.GOOS == "windows" {
+		oldPath := exe + ".old"
+		_ = os.Remove(oldPath)
+		if err := os.Rename(exe, oldPath); err != nil {
+			return "", fmt.Errorf("failed to move the running executable aside: %w", err)
+		}
+	}
+	if err := os.Rename(tmpPath, exe); err != nil {
+		return "", fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+	return exe, nil
+}
+
+// setupSelfUpdate registers the flags of the self-update command and returns its runner
+func setupSelfUpdate(fs *flag.FlagSet) func(args []string) {
+	checkOnly := fs.Bool("check", false, "Only report whether a newer release is available")
+	force := fs.Bool("force", false, "Reinstall even when the current version is the latest")
+	skipSignature := fs.Bool("skip-signature", false, "Allow updating with checksum verification only when no release key is embedded")
+	registerGitHubAPIFlags(fs)
+
+	return func(_ []string) {
+		gc := NewGitHubClient()
+
+		release, err := gc.GetLatestRelease(selfOwner, selfRepo)
+		if err != nil {
+			printResolveError(classifyError(err))
+			os.Exit(1)
+		}
+
+		latest := strings.TrimPrefix(release.Tag, "v")
+		current := strings.TrimPrefix(Version, "v")
+		fmt.Fprintf(console, "📦 Current version: %s, latest release: %s\n", Version, release.Tag)
+
+		if latest == current && !*force {
+			fmt.Fprintf(console, "%s Already up to date\n", theme.OK)
+			return
+		}
+		if *checkOnly {
+			fmt.Fprintf(console, "%s Update available: %s → %s\n", theme.Update, Version, release.Tag)
+			return
+		}
+
+		var key ed25519.PublicKey
+		if ReleasePublicKey != "" {
+			if key, err = parseReleasePublicKey(ReleasePublicKey); err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else if !*skipSignature {
+			fmt.Fprintln(console, "Error: this binary has no embedded release key, so signatures cannot be verified")
+			fmt.Fprintln(console, "   Re-run with --skip-signature to update with checksum verification only")
+			os.Exit(1)
+		}
+
+		name := releaseAssetName()
+		downloads := make(map[string][]byte)
+		wanted := []string{name, checksumsAsset}
+		if key != nil {
+			wanted = append(wanted, signatureAsset)
+		}
+		for _, assetName := range wanted {
+			asset, err := findReleaseAsset(release, assetName)
+			if err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(console, "⬇️  Downloading %s...\n", assetName)
+			if downloads[assetName], err = downloadAsset(gc.ctx, asset); err != nil {
+				fmt.Fprintf(console, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if err := verifyReleaseBinary(downloads[name], downloads[checksumsAsset], downloads[signatureAsset], name, key); err != nil {
+			fmt.Fprintf(console, "%s Verification failed: %v\n", theme.Error, err)
+			os.Exit(1)
+		}
+		if key != nil {
+			fmt.Fprintf(console, "%s Signature and checksum verified\n", theme.OK)
+		} else {
+			fmt.Fprintf(console, "%s Checksum verified (signature not checked)\n", theme.Warning)
+		}
+
+		exe, err := replaceExecutable(downloads[name])
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "%s Updated %s to %s\n", theme.OK, exe, release.Tag)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultServerAddr is the listen address used by the serve command
+	defaultServerAddr = ":8080"
+
+	// serverRequestTimeout bounds the API work done for a single request
+	serverRequestTimeout = 30 * time.Second
+
+	// mutableRefCacheTTL is how long results for branch or tag refs are cached.
+	// Results for full commit SHAs never change and are kept until evicted.
+	mutableRefCacheTTL = time.Minute
+
+	// maxVerifyCacheEntries bounds the memory used by the verification cache
+	maxVerifyCacheEntries = 1024
+)
+
+// UnpinnedReference describes an action reference that is not pinned to a SHA
+type UnpinnedReference struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Uses string `json:"uses"`
+}
+
+// VerifyResponse is returned by the /verify endpoint
+type VerifyResponse struct {
+	Repo     string              `json:"repo"`
+	Ref      string              `json:"ref"`
+	Pass     bool                `json:"pass"`
+	Unpinned []UnpinnedReference `json:"unpinned"`
+	// Skipped lists files exceeding the parse limits, and the repository
+	// when it could only be listed in part. They could not be verified, so
+	// the check does not pass.
+	Skipped []SkippedFile `json:"skipped,omitempty"`
+	Cached  bool          `json:"cached"`
+	Error   *ResolveError `json:"error,omitempty"`
+}
+
+// verifyCacheEntry is a cached verification result
+type verifyCacheEntry struct {
+	response VerifyResponse
+	expires  time.Time // zero for immutable refs
+}
+
+// verifyCache caches verification results keyed by repo@ref
+type verifyCache struct {
+	mu      sync.Mutex
+	entries map[string]verifyCacheEntry
+}
+
+// get returns a cached response if one is present and fresh
+func (c *verifyCache) get(key string) (VerifyResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return VerifyResponse{}, false
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return VerifyResponse{}, false
+	}
+	return entry.response, true
+}
+
+// put stores a response, evicting an arbitrary entry when the cache is full
+func (c *verifyCache) put(key string, response VerifyResponse, immutable bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxVerifyCacheEntries {
+		for existing := range c.entries {
+			delete(c.entries, existing)
+			break
+		}
+	}
+
+	entry := verifyCacheEntry{response: response}
+	if !immutable {
+		entry.expires = time.Now().Add(mutableRefCacheTTL)
+	}
+	c.entries[key] = entry
+}
+
+// verifyServer serves read-only verification results for merge queue gating
+type verifyServer struct {
+	gc    *GitHubClient
+	cache *verifyCache
+	// scan selects the files verified, as for a local scan
+	scan ScanOptions
+}
+
+// FetchWorkflowsAt downloads the files a local scan with opts would pick up
+// from a repository at ref, its workflows, composite actions and template
+// sources, and parses their actions. Files exceeding the parse limits are
+// returned as skipped, as is the repository when its tree is too large to
+// list in full.
+func (gc *GitHubClient) FetchWorkflowsAt(ctx context.Context, owner, repo, ref string, opts ScanOptions) (WorkflowActions, []SkippedFile, error) {
+	workflowActions := make(WorkflowActions)
+
+	entries, truncated, err := gc.api.ListTree(ctx, owner, repo, ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the files of %s/%s at %s: %w", owner, repo, ref, err)
+	}
+
+	var skipped []SkippedFile
+	if truncated {
+		skipped = append(skipped, SkippedFile{Path: owner + "/" + repo, Reason: "repository tree too large to list in full"})
+	}
+	var limitErr *LimitError
+	for _, entry := range entries {
+		file := filepath.FromSlash(entry.Path)
+		if !isYAMLFile(entry.Name) && !isTemplateFile(entry.Name) || !isScannedFile(file, opts) {
+			continue
+		}
+		// Like a local scan, workflow filters match paths below the workflow directory
+		relPath := entry.Path
+		if rel, ok := strings.CutPrefix(entry.Path, workflowDir+"/"); ok {
+			relPath = rel
+		}
+		if !opts.shouldScan(relPath, entry.Path) {
+			continue
+		}
+
+		// Oversized files are not downloaded at all
+		if err := checkSize(entry.Path, entry.Size); errors.As(err, &limitErr) {
+			skipped = append(skipped, SkippedFile{Path: entry.Path, Reason: reasonLimits + limitErr.Reason()})
+			continue
+		}
+
+		content, err := gc.fileContents(ctx, owner, repo, entry.Path, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		actions, err := parseWorkflowContent(entry.Path, string(content))
+		if errors.As(err, &limitErr) {
+			skipped = append(skipped, SkippedFile{Path: entry.Path, Reason: reasonLimits + limitErr.Reason()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(actions) > 0 {
+			workflowActions[entry.Path] = actions
+		}
+	}
+
+	return workflowActions, skipped, nil
+}
+
+// handleVerify implements GET /verify?repo=owner/name&ref=sha
+func (s *verifyServer) handleVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeServerError(w, http.StatusMethodNotAllowed, "only GET is supported")
+		return
+	}
+
+	repoParam := r.URL.Query().Get("repo")
+	ref := r.URL.Query().Get("ref")
+	owner, repo, ok := strings.Cut(repoParam, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		writeServerError(w, http.StatusBadRequest, "repo must be given as owner/name")
+		return
+	}
+	if ref == "" {
+		writeServerError(w, http.StatusBadRequest, "ref is required")
+		return
+	}
+
+	key := repoParam + "@" + ref
+	if response, ok := s.cache.get(key); ok {
+		response.Cached = true
+		writeServerJSON(w, http.StatusOK, response)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), serverRequestTimeout)
+	defer cancel()
+
+	response := VerifyResponse{Repo: repoParam, Ref: ref, Unpinned: []UnpinnedReference{}}
+
+	actions, skipped, err := s.gc.FetchWorkflowsAt(ctx, owner, repo, ref, s.scan)
+	if err != nil {
+		response.Error = classifyError(err)
+		fmt.Fprintf(console, "%s verify %s: %s: %v\n", theme.Error, key, response.Error.Label(), err)
+		writeServerJSON(w, http.StatusBadGateway, response)
+		return
+	}
+
+	for _, action := range findUnpinned(actions) {
+		response.Unpinned = append(response.Unpinned, UnpinnedReference{
+			File: action.WorkflowFile,
+			Line: action.Line,
+			Uses: action.reference(),
+		})
+	}
+	response.Skipped = skipped
+	response.Pass = len(response.Unpinned) == 0 && len(response.Skipped) == 0
+
+	s.cache.put(key, response, shaRegex.MatchString(ref))
+	fmt.Fprintf(console, "🔒 verify %s: pass=%t unpinned=%d skipped=%d\n", key, response.Pass, len(response.Unpinned), len(response.Skipped))
+	writeServerJSON(w, http.StatusOK, response)
+}
+
+// writeServerJSON writes a JSON response body
+func writeServerJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Warn("failed to write response", "error", err)
+	}
+}
+
+// writeServerError writes a JSON error response
+func writeServerError(w http.ResponseWriter, status int, message string) {
+	writeServerJSON(w, status, map[string]string{"error": message})
+}
+
+// setupServe registers the flags of the serve command and returns its runner
+func setupServe(fs *flag.FlagSet) func(args []string) {
+	addr := fs.String("addr", defaultServerAddr, "`Address` to listen on")
+	configPath := fs.String("config", defaultConfigFile, "Read parse limits and the files to verify from the configuration `file`")
+	registerGitHubAPIFlags(fs)
+
+	return func(_ []string) {
+		cfg, err := loadConfig(*configPath, *configPath != defaultConfigFile)
+		if err != nil {
+			fmt.Fprintf(console, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		parseLimits = cfg.Limits
+		scanOpts, err := (&commandOptions{}).scanOptions(cfg)
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		s := &verifyServer{
+			gc:    NewGitHubClient(),
+			cache: &verifyCache{entries: make(map[string]verifyCacheEntry)},
+			scan:  scanOpts,
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/verify", s.handleVerify)
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+			writeServerJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		})
+
+		server := &http.Server{
+			Addr:              *addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      serverRequestTimeout + 10*time.Second,
+		}
+
+		fmt.Fprintf(console, "🌐 Serving verification endpoint on %s (GET /verify?repo=owner/name&ref=sha)\n", *addr)
+		if err := server.ListenAndServe(); err != nil {
+			fmt.Fprintf(console, "Server error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// expandedLine rewrites the abbreviated SHAs of actions on line to the full
+// SHAs they resolved to. Unlike pinnedLine the pin comment is kept as is,
+// since the reference still names the same commit.
+func expandedLine(line string, actions []ActionInfo) string {
+	code, comment := splitTrailingComment(line)
+
+	ordered := append([]ActionInfo(nil), actions...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Column > ordered[j].Column
+	})
+	runes := []rune(code)
+	for _, action := range ordered {
+		action.LatestSHA = action.CurrentSHA
+		runes, _ = rewriteUsesValue(runes, action)
+	}
+	return string(runes) + comment
+}
+
+// applyExpansions expands the abbreviated SHAs of actions in content and
+// returns the new content along with the number of references changed
+func applyExpansions(content string, actions []ActionInfo) (string, int) {
+	content, bom := splitBOM(content)
+	lines := strings.Split(content, "\n")
+	onLine := make(map[int][]ActionInfo)
+	for _, action := range actions {
+		if action.Abbreviated && action.Error == nil && action.Line-1 < len(lines) {
+			onLine[action.Line] = append(onLine[action.Line], action)
+		}
+	}
+
+	changed := 0
+	for lineNumber, lineActions := range onLine {
+		oldLine, cr := splitCarriageReturn(lines[lineNumber-1])
+		if newLine := expandedLine(oldLine, lineActions); newLine != oldLine {
+			lines[lineNumber-1] = newLine + cr
+			changed += len(lineActions)
+		}
+	}
+	return bom + strings.Join(lines, "\n"), changed
+}
+
+// expandAbbreviatedSHAs offers to expand the abbreviated SHAs of every
+// workflow in place, without moving them to another version
+func expandAbbreviatedSHAs(actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n✂️  Expanding abbreviated SHAs...")
+
+	workflows := sortedWorkflows(actions)
+
+	expanded := 0
+	for _, workflow := range workflows {
+		var abbreviated []ActionInfo
+		for _, action := range actions[workflow] {
+			if action.Abbreviated && action.Error == nil {
+				abbreviated = append(abbreviated, action)
+			}
+		}
+		if len(abbreviated) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(console, "\n📁 %s:\n", workflow)
+		for _, action := range abbreviated {
+			fmt.Fprintf(console, "  %s %s:%d %s → %s\n", theme.Update, workflow, action.Line, action.reference(), action.CurrentSHA)
+		}
+		if !promptForConfirmation(fmt.Sprintf("Expand %d abbreviated SHA(s) in %s?", len(abbreviated), workflow)) {
+			fmt.Fprintf(console, "  %s Skipped %s\n", theme.Skipped, workflow)
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Clean(workflow))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", workflow, err)
+		}
+		newContent, changed := applyExpansions(string(content), abbreviated)
+		if changed == 0 {
+			continue
+		}
+		if err := checkWritePath(workflow); err != nil {
+			return err
+		}
+		if err := os.WriteFile(workflow, []byte(newContent), 0600); err != nil {
+			return err
+		}
+		emitEvent(Event{Type: EventFileUpdated, Workflow: workflow, Changes: changed})
+		logger.Info("expanded abbreviated SHAs", "file", workflow, "changes", changed)
+		expanded += changed
+	}
+
+	if expanded == 0 {
+		fmt.Fprintf(console, "  %s No abbreviated SHAs expanded\n", theme.OK)
+		return nil
+	}
+	fmt.Fprintf(console, "\n%s Expanded %d abbreviated SHA(s)\n", theme.OK, expanded)
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// UpdateStrategy limits how far an update may move from the current version
+type UpdateStrategy string
+
+const (
+	// StrategyPatch only proposes releases with the same major and minor version
+	StrategyPatch UpdateStrategy = "patch"
+	// StrategyMinor only proposes releases with the same major version
+	StrategyMinor UpdateStrategy = "minor"
+	// StrategyMajor proposes the latest release, the default
+	StrategyMajor UpdateStrategy = "major"
+)
+
+// strategyNames lists the accepted update strategies
+var strategyNames = []string{string(StrategyPatch), string(StrategyMinor), string(StrategyMajor)}
+
+// parseUpdateStrategy validates a --strategy or strategy: value; an empty
+// value is the major strategy
+func parseUpdateStrategy(value string) (UpdateStrategy, error) {
+	switch strategy := UpdateStrategy(value); strategy {
+	case "":
+		return StrategyMajor, nil
+	case StrategyPatch, StrategyMinor, StrategyMajor:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown update strategy %q, expected one of %s", value, strings.Join(strategyNames, ", "))
+}
+
+// currentVersion returns the version tag an action is at: the tag in the pin
+// comment of a pinned reference, or the ref itself. It is empty when neither
+// is a version tag.
+func currentVersion(action ActionInfo) string {
+	version := action.CurrentRef
+	if action.Pinned() {
+		version = commentTag(action.OriginalLine, action.Repo)
+	}
+	if !versionTagRegex.MatchString(version) {
+		return ""
+	}
+	return version
+}
+
+// strategyFilter returns the filter matching the tags an update of action
+// may move to, or nil when the strategy allows every release or the current
+// version is unknown. Versions with fewer components constrain only the
+// components they have, so v4 under the patch strategy stays within v4.
+func (c *Config) strategyFilter(action ActionInfo) *regexp.Regexp {
+	keep := 0
+	switch c.updateStrategy() {
+	case StrategyPatch:
+		keep = 2
+	case StrategyMinor:
+		keep = 1
+	}
+	version := currentVersion(action)
+	if keep == 0 || version == "" {
+		return nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) > keep {
+		parts = parts[:keep]
+	}
+	return regexp.MustCompile(`^v?` + strings.Join(parts, `\.`) + `(\.\d+)*$`)
+}
+
+// updateStrategy returns the configured strategy, major when unset
+func (c *Config) updateStrategy() UpdateStrategy {
+	if c == nil || c.Strategy == "" {
+		return StrategyMajor
+	}
+	return UpdateStrategy(c.Strategy)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// snapshotCorrelator groups the snapshots of this tool, so a new one
+	// replaces the previous one instead of adding to it
+	snapshotCorrelator = programName
+
+	// detectorURL identifies the tool in the dependency graph
+	detectorURL = "https://github.com/greysquirr3l/github-ci-hash"
+)
+
+// remoteRepoRegex extracts owner/repo from a GitHub remote URL in HTTPS or
+// SSH form
+var remoteRepoRegex = regexp.MustCompile(`github\.com[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// dependencySnapshot is the payload of the dependency submission API
+type dependencySnapshot struct {
+	Version  int                            `json:"version"`
+	SHA      string                         `json:"sha"`
+	Ref      string                         `json:"ref"`
+	Job      snapshotJob                    `json:"job"`
+	Detector snapshotDetector               `json:"detector"`
+	Scanned  string                         `json:"scanned"`
+	Manifest map[string]*dependencyManifest `json:"manifests"`
+}
+
+type snapshotJob struct {
+	Correlator string `json:"correlator"`
+	ID         string `json:"id"`
+	HTMLURL    string `json:"html_url,omitempty"`
+}
+
+type snapshotDetector struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+// dependencyManifest lists the actions of one workflow file
+type dependencyManifest struct {
+	Name string `json:"name"`
+	File struct {
+		SourceLocation string `json:"source_location"`
+	} `json:"file"`
+	Resolved map[string]resolvedDependency `json:"resolved"`
+}
+
+type resolvedDependency struct {
+	PackageURL   string `json:"package_url"`
+	Relationship string `json:"relationship"`
+	Scope        string `json:"scope"`
+}
+
+// dependencyPURL returns the package URL the dependency graph and
+// Dependabot use for an action: pkg:githubactions/owner/repo with the
+// version of the pin comment, so advisories match, or else the ref.
+// Container actions use their pkg:docker URL.
+func dependencyPURL(action ActionInfo) string {
+	if action.Docker {
+		component := sbomComponent{Name: action.Repo, Ref: action.CurrentRef, Docker: true}
+		return component.purl()
+	}
+	version := action.CurrentRef
+	if tag := currentVersion(action); tag != "" {
+		version = strings.TrimPrefix(tag, "v")
+	}
+	owner, repo, _ := splitActionPath(action.Repo)
+	return "pkg:githubactions/" + strings.ToLower(owner) + "/" + strings.ToLower(repo) + "@" + purlVersion(version)
+}
+
+// buildSnapshot describes every workflow file as a manifest whose direct
+// runtime dependencies are its actions
+func buildSnapshot(actions WorkflowActions, sha, ref string, now time.Time) *dependencySnapshot {
+	snapshot := &dependencySnapshot{
+		SHA: sha,
+		Ref: ref,
+		Job: snapshotJob{Correlator: snapshotCorrelator, ID: fmt.Sprint(now.Unix())},
+		Detector: snapshotDetector{
+			Name:    programName,
+			Version: Version,
+			URL:     detectorURL,
+		},
+		Scanned:  now.UTC().Format(time.RFC3339),
+		Manifest: make(map[string]*dependencyManifest),
+	}
+	if workflow := os.Getenv("GITHUB_WORKFLOW"); workflow != "" {
+		snapshot.Job.Correlator += "-" + workflow + "-" + os.Getenv("GITHUB_JOB")
+	}
+	if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" {
+		snapshot.Job.ID = runID
+		if server, repo := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"); server != "" && repo != "" {
+			snapshot.Job.HTMLURL = server + "/" + repo + "/actions/runs/" + runID
+		}
+	}
+
+	for _, workflow := range sortedWorkflows(actions) {
+		manifest := &dependencyManifest{Name: workflow, Resolved: make(map[string]resolvedDependency)}
+		manifest.File.SourceLocation = workflow
+		for _, action := range actions[workflow] {
+			purl := dependencyPURL(action)
+			manifest.Resolved[purl] = resolvedDependency{PackageURL: purl, Relationship: "direct", Scope: "runtime"}
+		}
+		snapshot.Manifest[workflow] = manifest
+	}
+	return snapshot
+}
+
+// originRepository returns the owner/repo of the origin remote, or "" when
+// it is not a GitHub repository
+func originRepository() string {
+	lines, err := gitLines("remote", "get-url", "origin")
+	if err != nil || len(lines) == 0 {
+		return ""
+	}
+	if m := remoteRepoRegex.FindStringSubmatch(lines[0]); m != nil {
+		return m[1] + "/" + m[2]
+	}
+	return ""
+}
+
+// snapshotTarget fills in the repository, commit and ref a snapshot is
+// submitted for from the workflow environment, or from git outside of one.
+// The repository stays empty when it cannot be told.
+func snapshotTarget(repo, sha, ref string) (string, string, string, error) {
+	if repo == "" {
+		repo = os.Getenv("GITHUB_REPOSITORY")
+	}
+	if repo == "" {
+		repo = originRepository()
+	}
+	if sha == "" {
+		sha = os.Getenv("GITHUB_SHA")
+	}
+	if sha == "" {
+		if lines, err := gitLines("rev-parse", "HEAD"); err == nil && len(lines) > 0 {
+			sha = lines[0]
+		}
+	}
+	if ref == "" {
+		ref = os.Getenv("GITHUB_REF")
+	}
+	if ref == "" {
+		if lines, err := gitLines("symbolic-ref", "HEAD"); err == nil && len(lines) > 0 {
+			ref = lines[0]
+		}
+	}
+
+	switch {
+	case !shaRegex.MatchString(sha):
+		return "", "", "", fmt.Errorf("the commit is unknown; set --sha")
+	case !strings.HasPrefix(ref, "refs/"):
+		return "", "", "", fmt.Errorf("the ref is unknown or not fully qualified; set --ref, e.g. refs/heads/main")
+	}
+	return repo, sha, ref, nil
+}
+
+// setupSubmitDeps registers the flags of the submit-deps command and
+// returns its runner
+func setupSubmitDeps(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	repoFlag := fs.String("repo", "", "Submit to this `owner/repo`, by default GITHUB_REPOSITORY or the origin remote")
+	shaFlag := fs.String("sha", "", "Commit `sha` of the snapshot, by default GITHUB_SHA or HEAD")
+	refFlag := fs.String("ref", "", "Fully qualified `ref` of the snapshot, by default GITHUB_REF or the current branch")
+	dryRun := fs.Bool("dry-run", false, "Write the snapshot to stdout instead of submitting it")
+
+	return func(args []string) {
+		if *dryRun {
+			console = os.Stderr
+		}
+		_, scanOpts := mustLoadScanConfig(opts)
+		repoName, sha, ref, err := snapshotTarget(*repoFlag, *shaFlag, *refFlag)
+		if err != nil {
+			fmt.Fprintf(console, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		owner, repo, ok := strings.Cut(repoName, "/")
+		switch {
+		case *dryRun:
+		case repoName == "":
+			fmt.Fprintln(console, "Error: the repository is unknown; set --repo")
+			os.Exit(2)
+		case !ok || owner == "" || repo == "" || strings.Contains(repo, "/"):
+			fmt.Fprintf(console, "Error: --repo must be owner/repo, got %q\n", repoName)
+			os.Exit(2)
+		}
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+		snapshot := buildSnapshot(actions, sha, ref, time.Now())
+
+		if *dryRun {
+			data, err := marshalIndented(snapshot)
+			if err != nil {
+				fmt.Fprintf(console, "Error encoding the snapshot: %v\n", err)
+				os.Exit(1)
+			}
+			_, _ = os.Stdout.Write(data)
+			return
+		}
+
+		gc := NewGitHubClient()
+		fmt.Fprintf(console, "📤 Submitting the actions of %d workflows to the dependency graph of %s...\n", len(snapshot.Manifest), repoName)
+		result, err := gc.api.SubmitSnapshot(gc.ctx, owner, repo, snapshot)
+		if err != nil {
+			fmt.Fprintf(console, "Error: failed to submit the dependency snapshot: %v\n", err)
+			if errorKindOf(err) == ErrorKindNotFound {
+				fmt.Fprintln(console, "     💡 The token needs contents: write on the repository, and the dependency graph must be enabled")
+			}
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "%s Snapshot %d submitted for %s at %s: %s\n", theme.OK, result.ID, ref, shortPin(sha), strings.ToLower(result.Result))
+		if result.Message != "" {
+			fmt.Fprintf(console, "     %s\n", result.Message)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VersionTarget pins the actions matching Pattern to Tag instead of their
+// latest release, e.g. when the latest release is broken
+type VersionTarget struct {
+	Pattern string
+	Tag     string
+}
+
+// parseVersionTargets parses update --to values of the form action@tag.
+// The action may be a glob such as actions/cache/*.
+func parseVersionTargets(values []string) ([]VersionTarget, error) {
+	targets := make([]VersionTarget, 0, len(values))
+	for _, value := range values {
+		at := strings.LastIndex(value, "@")
+		if at <= 0 || at == len(value)-1 {
+			return nil, fmt.Errorf("invalid --to %q, expected action@tag such as actions/checkout@v4.1.7", value)
+		}
+		targets = append(targets, VersionTarget{Pattern: value[:at], Tag: value[at+1:]})
+	}
+	return targets, nil
+}
+
+// targetFor returns the first target matching action
+func targetFor(targets []VersionTarget, action ActionInfo) (VersionTarget, bool) {
+	for _, target := range targets {
+		if matchesActionPattern(target.Pattern, action) || target.Pattern == skewKey(action) {
+			return target, true
+		}
+	}
+	return VersionTarget{}, false
+}
+
+// pinRequestedVersion points action at the commit or digest tag resolves
+// to in place of the latest release. The release details of the latest
+// release no longer apply and are dropped.
+func pinRequestedVersion(forge Forge, registry *registryClient, action *ActionInfo, tag string) error {
+	if err := alignTarget(forge, registry, action, tag); err != nil {
+		return err
+	}
+
+	source := forge.Name()
+	if action.Docker {
+		source, _ = parseImageName(strings.TrimPrefix(action.Repo, dockerPrefix))
+	} else if action.CurrentSHA == "" && action.Pinned() {
+		action.CurrentSHA = action.CurrentRef
+	}
+	action.Error = nil
+	action.ReleaseURL = ""
+	action.ReleaseNotes = ""
+	action.BreakingChanges = nil
+	action.Provenance = &Provenance{Reason: PinRequestedVersion, Tag: tag, Source: source}
+	action.NeedsUpdate = action.CurrentSHA != action.LatestSHA
+	return nil
+}
+
+// applyVersionTargets resolves the requested version of every action
+// matching an update --to target
+func applyVersionTargets(forge Forge, actions WorkflowActions, targets []VersionTarget) {
+	if len(targets) == 0 {
+		return
+	}
+	fmt.Fprintln(console, "\n🎯 Resolving requested versions...")
+
+	// Container actions share one registry client so tokens are reused
+	var registry *registryClient
+
+	used := make(map[VersionTarget]bool)
+	for _, workflow := range sortedWorkflows(actions) {
+		actionList := actions[workflow]
+		for i := range actionList {
+			action := &actionList[i]
+			target, ok := targetFor(targets, *action)
+			if !ok {
+				continue
+			}
+			used[target] = true
+			if action.Docker && registry == nil {
+				registry = newRegistryClient()
+			}
+			if err := pinRequestedVersion(forge, registry, action, target.Tag); err != nil {
+				action.Error = classifyError(err)
+				fmt.Fprintf(console, "  %s %s:%d %s@%s: %v\n", theme.Error, workflow, action.Line, action.Repo, target.Tag, action.Error)
+				continue
+			}
+			fmt.Fprintf(console, "  %s %s:%d %s: %s (%s)\n", theme.OK, workflow, action.Line, action.Repo, target.Tag, shortPin(action.LatestSHA))
+		}
+		actions[workflow] = actionList
+	}
+
+	for _, target := range targets {
+		if !used[target] {
+			fmt.Fprintf(console, "  %s No action matches --to %s@%s\n", theme.Warning, target.Pattern, target.Tag)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// templateMarkers open template expressions, statements or comments in
+	// Jinja, cookiecutter, Helm and Go templates, ytt and ERB sources
+	templateMarkers = []string{"{{", "{%", "{#", "#@", "<%"}
+
+	// templateExtensions are the file extensions scanned below template_paths
+	templateExtensions = []string{".yml", ".yaml", ".j2", ".jinja", ".jinja2", ".tpl", ".tmpl", ".gotmpl", ".jsonnet", ".libsonnet"}
+
+	// templateUsesRegex finds uses: keys in sources that are not valid YAML,
+	// including quoted keys as written in jsonnet
+	templateUsesRegex = regexp.MustCompile(`(?:^|[\s{,\[])["']?uses["']?[ \t]*:[ \t]*`)
+
+	// yttDirectiveRegex matches a ytt annotation line
+	yttDirectiveRegex = regexp.MustCompile(`(?m)^[ \t]*#@`)
+)
+
+// hasTemplateMarker reports whether s contains template syntax
+func hasTemplateMarker(s string) bool {
+	for _, marker := range templateMarkers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTemplateSource reports whether content that failed to parse as YAML is a
+// template, in which case its literal uses: references are still pinned.
+// Files with a template extension other than .yml and .yaml always are; YAML
+// files only when they hold template syntax besides the ${{ }} expressions
+// of ordinary workflows, so broken workflows still fail to parse.
+func isTemplateSource(filename, content string) bool {
+	if ext := filepath.Ext(filename); isTemplateFile(filename) && ext != ".yml" && ext != ".yaml" {
+		return true
+	}
+	return hasTemplateMarker(strings.ReplaceAll(content, "${{", ""))
+}
+
+// pinCommentMarker returns the comment marker used for tag comments. ytt
+// rejects plain YAML comments, so its sources get #! comments instead.
+func pinCommentMarker(content string) string {
+	if yttDirectiveRegex.MatchString(content) {
+		return "#!"
+	}
+	return "#"
+}
+
+// parseTemplateContent extracts the literal uses: references of a template
+// source line by line. References built from template expressions cannot be
+// pinned and are skipped.
+func parseTemplateContent(filename, content string) []ActionInfo {
+	var actions []ActionInfo
+	walkTemplateUses(content, func(value, line string, lineNo, column int) {
+		action, ok := usesAction(value, line)
+		if !ok {
+			return
+		}
+		action.Line = lineNo
+		action.Column = column
+		action.WorkflowFile = filename
+		actions = append(actions, action)
+	})
+	return actions
+}
+
+// walkTemplateUses calls fn with the value of every uses: key found on the
+// lines of a template source, along with the line and its 1-based position
+func walkTemplateUses(content string, fn func(value, line string, lineNo, column int)) {
+	for i, line := range strings.Split(content, "\n") {
+		line, _ = splitCarriageReturn(line)
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "{#") {
+			continue
+		}
+
+		for _, match := range templateUsesRegex.FindAllStringIndex(line, -1) {
+			runes := []rune(line)
+			start := utf8.RuneCountInString(line[:match[1]])
+			if start >= len(runes) {
+				continue
+			}
+			end := scalarEnd(runes, start)
+			if end <= start {
+				continue
+			}
+
+			fn(strings.Trim(string(runes[start:end]), `"'`), line, i+1, start+1)
+		}
+	}
+}
+
+// templatePaths returns the files and directories scanned as template sources
+func (c *Config) templatePaths() []string {
+	if c == nil {
+		return nil
+	}
+	return c.TemplatePaths
+}
+
+// isTemplateFile reports whether a file name has a template source extension
+func isTemplateFile(name string) bool {
+	for _, ext := range templateExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// findTemplateFiles returns the template sources below the given files and
+// directories, sorted by path. Missing paths contribute nothing.
+func findTemplateFiles(roots []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, root := range roots {
+		_ = filepath.WalkDir(filepath.Clean(root), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !isTemplateFile(d.Name()) || seen[path] {
+				return nil
+			}
+			seen[path] = true
+			files = append(files, path)
+			return nil
+		})
+	}
+	sort.Strings(files)
+	return files
+}
+
+// underTemplatePath reports whether a path is a template source below one of roots
+func underTemplatePath(file string, roots []string) bool {
+	if !isTemplateFile(file) {
+		return false
+	}
+	for _, root := range roots {
+		if rel, err := filepath.Rel(filepath.Clean(root), file); err == nil && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// defaultThemeName is the theme used when neither a flag nor the config selects one
+	defaultThemeName = "default"
+
+	// ANSI select graphic rendition codes used by the themes
+	sgrGreen         = "32"
+	sgrYellow        = "33"
+	sgrRed           = "31"
+	sgrBoldGreen     = "1;92"
+	sgrBoldYellow    = "1;93"
+	sgrBoldRed       = "1;91"
+	sgrSafeBlue      = "38;5;32"
+	sgrSafeOrange    = "38;5;214"
+	sgrSafeVermilion = "38;5;166"
+)
+
+// Theme controls the status glyphs and colors used in console output. Glyph
+// fields already include any color and padding, so they can be used directly
+// in format strings.
+type Theme struct {
+	Name string
+
+	OK              string
+	Update          string
+	Error           string
+	Warning         string
+	Skipped         string
+	Authenticated   string
+	Unauthenticated string
+
+	// SGR parameters for text colored by status; empty disables color
+	success   string
+	attention string
+	failure   string
+}
+
+// theme is the active output theme
+var theme = themes[defaultThemeName]
+
+// themes lists the built-in themes by name
+var themes = map[string]*Theme{
+	"default": {
+		Name:            "default",
+		OK:              "✅",
+		Update:          "🔄",
+		Error:           "❌",
+		Warning:         "⚠️ ",
+		Skipped:         "⏭️ ",
+		Authenticated:   "🟢",
+		Unauthenticated: "🟡",
+		success:         sgrGreen,
+		attention:       sgrYellow,
+		failure:         sgrRed,
+	},
+	"mono":             newShapeTheme("mono", "", "", ""),
+	"high-contrast":    newShapeTheme("high-contrast", sgrBoldGreen, sgrBoldYellow, sgrBoldRed),
+	"color-blind-safe": newShapeTheme("color-blind-safe", sgrSafeBlue, sgrSafeOrange, sgrSafeVermilion),
+	plainThemeName:     newPlainTheme(),
+}
+
+// newShapeTheme builds a theme whose statuses are told apart by glyph shape
+// first and color second, so that no information is carried by color alone
+func newShapeTheme(name, success, attention, failure string) *Theme {
+	t := &Theme{Name: name, success: success, attention: attention, failure: failure}
+	t.OK = t.Success("✔")
+	t.Update = t.Attention("↻")
+	t.Error = t.Failure("✖")
+	t.Warning = t.Attention("▲")
+	t.Skipped = "»"
+	t.Authenticated = t.Success("●")
+	t.Unauthenticated = t.Attention("○")
+	return t
+}
+
+// paint wraps text in an SGR sequence, leaving it unchanged when sgr is empty
+func paint(sgr, text string) string {
+	if sgr == "" {
+		return text
+	}
+	return "\033[" + sgr + "m" + text + "\033[0m"
+}
+
+// Success colors text that reports a good outcome
+func (t *Theme) Success(text string) string {
+	return paint(t.success, text)
+}
+
+// Attention colors text that needs the user's attention
+func (t *Theme) Attention(text string) string {
+	return paint(t.attention, text)
+}
+
+// Failure colors text that reports a failure
+func (t *Theme) Failure(text string) string {
+	return paint(t.failure, text)
+}
+
+// themeNames returns the names of the built-in themes, sorted
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupTheme returns the built-in theme with the given name
+func lookupTheme(name string) (*Theme, error) {
+	t, ok := themes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(themeNames(), ", "))
+	}
+	return t, nil
+}
+
+// selectTheme activates the first non-empty theme name. Without an explicit
+// choice the NO_COLOR convention selects the mono theme.
+func selectTheme(names ...string) error {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		t, err := lookupTheme(name)
+		if err != nil {
+			return err
+		}
+		theme = t
+		return nil
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		theme = themes["mono"]
+	} else {
+		theme = themes[defaultThemeName]
+	}
+	return nil
+}
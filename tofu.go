@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// pinDatabaseVersion is the version of the pin database format
+const pinDatabaseVersion = 1
+
+// floatingTagRegex matches major and minor tags such as v4 or v4.1, which
+// are moved to every new release on purpose and are not recorded
+var floatingTagRegex = regexp.MustCompile(`^v?\d+(\.\d+)?$`)
+
+// PinRecord is the commit a tag resolved to when it was first seen
+type PinRecord struct {
+	SHA       string `json:"sha"`
+	FirstSeen string `json:"first_seen"`
+}
+
+// PinDatabase records the tag to commit mappings accepted on first use,
+// keyed by lower-cased owner/repo and tag. It is committed to the
+// repository so that every branch and runner compares against the same
+// mappings.
+type PinDatabase struct {
+	Version int                             `json:"version"`
+	Pins    map[string]map[string]PinRecord `json:"pins"`
+}
+
+// TagMove is a tag that resolves to another commit than the pin database
+// recorded for it
+type TagMove struct {
+	Tag       string `json:"tag"`
+	Recorded  string `json:"recorded_sha"`
+	Observed  string `json:"observed_sha"`
+	FirstSeen string `json:"first_seen"`
+}
+
+// readPinDatabase loads a pin database; a missing file is an empty one
+func readPinDatabase(path string) (*PinDatabase, error) {
+	db := &PinDatabase{Version: pinDatabaseVersion, Pins: make(map[string]map[string]PinRecord)}
+	content, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, db); err != nil {
+		return nil, fmt.Errorf("invalid pin database %s: %w", path, err)
+	}
+	if db.Version != pinDatabaseVersion {
+		return nil, fmt.Errorf("unsupported pin database version %d in %s", db.Version, path)
+	}
+	if db.Pins == nil {
+		db.Pins = make(map[string]map[string]PinRecord)
+	}
+	return db, nil
+}
+
+// writePinDatabase stores the pin database in path
+func writePinDatabase(path string, db *PinDatabase) error {
+	if err := checkWritePath(path); err != nil {
+		return err
+	}
+	data, err := marshalIndented(db)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Clean(path), data, 0600)
+}
+
+// observe compares a tag resolution with the database. A tag seen for the
+// first time is recorded and reported as added; a different commit for a
+// recorded tag is returned as a move and left unrecorded.
+func (db *PinDatabase) observe(repo, tag, sha string, now time.Time) (move *TagMove, added bool) {
+	if tag == "" || sha == "" || floatingTagRegex.MatchString(tag) {
+		return nil, false
+	}
+	owner, name, _ := splitActionPath(repo)
+	key := strings.ToLower(owner + "/" + name)
+	tags := db.Pins[key]
+	if record, ok := tags[tag]; ok {
+		if strings.EqualFold(record.SHA, sha) {
+			return nil, false
+		}
+		return &TagMove{Tag: tag, Recorded: record.SHA, Observed: sha, FirstSeen: record.FirstSeen}, false
+	}
+	if tags == nil {
+		tags = make(map[string]PinRecord)
+		db.Pins[key] = tags
+	}
+	tags[tag] = PinRecord{SHA: sha, FirstSeen: now.UTC().Format(time.DateOnly)}
+	return nil, true
+}
+
+// checkPinDatabase compares the tags the actions resolved to with the pin
+// database and records the new ones. Actions whose tag moved are not
+// updated, since a moved release tag is how compromised actions are
+// distributed. It returns the number of actions whose tag moved.
+func checkPinDatabase(cfg *Config, actions WorkflowActions) int {
+	path := cfg.PinDatabase
+	fmt.Fprintf(console, "\n🗄️  Checking resolved tags against the pin database %s...\n", path)
+	db, err := readPinDatabase(path)
+	if err != nil {
+		fmt.Fprintf(console, "  %s Could not read the pin database: %v\n", theme.Warning, err)
+		return 0
+	}
+
+	now := time.Now()
+	moved, added := 0, 0
+	reported := make(map[string]bool)
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker || action.Error != nil {
+				continue
+			}
+			// Tags named by unpinned references are resolved as well
+			observations := [][2]string{{action.LatestTag, action.LatestSHA}}
+			if !action.Pinned() {
+				observations = append(observations, [2]string{action.CurrentRef, action.CurrentSHA})
+			}
+			for _, observed := range observations {
+				move, isNew := db.observe(action.Repo, observed[0], observed[1], now)
+				if isNew {
+					added++
+				}
+				if move == nil {
+					continue
+				}
+				action.TagMoved = move
+				action.NeedsUpdate = false
+				moved++
+				key := action.Repo + "@" + move.Tag
+				if reported[key] {
+					break
+				}
+				reported[key] = true
+				fmt.Fprintf(console, "  %s %s@%s now resolves to %s, but resolved to %s when first seen on %s %s\n", severityStatus(ruleTagMoved),
+					action.Repo, move.Tag, shortPin(move.Observed), shortPin(move.Recorded), move.FirstSeen, ruleTag(ruleTagMoved))
+				break
+			}
+		}
+	}
+
+	if moved > 0 {
+		fmt.Fprintln(console, "  🚨 Release tags are not expected to move; a moved tag is how compromised actions are distributed.")
+		fmt.Fprintf(console, "  💡 The pins were left as they are. Review the new commits, then remove their entries from %s to accept them\n", path)
+	} else {
+		fmt.Fprintf(console, "%s Every recorded tag resolves to the commit first seen\n", theme.OK)
+	}
+	if added > 0 {
+		if err := writePinDatabase(path, db); err != nil {
+			fmt.Fprintf(console, "  %s Could not write the pin database: %v\n", theme.Warning, err)
+		} else {
+			fmt.Fprintf(console, "📝 Recorded %d new tag(s) in %s; commit it so other runs trust the same commits\n", added, path)
+		}
+	}
+	return moved
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TransitiveUse is an unpinned uses: reference in the manifest of a
+// composite action a workflow runs
+type TransitiveUse struct {
+	// File is the manifest, e.g. owner/repo/dir/action.yml
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Uses string `json:"uses"`
+}
+
+// describeTransitive lists transitive references for the console
+func describeTransitive(uses []TransitiveUse) string {
+	values := make([]string, len(uses))
+	for i, use := range uses {
+		values[i] = use.Uses
+	}
+	return strings.Join(values, ", ")
+}
+
+// unpinnedTransitive fetches the manifest of a composite action at its
+// pinned commit and returns the uses: references of its steps that are not
+// pinned. Other actions have no steps and return nothing.
+func unpinnedTransitive(forge Forge, action ActionInfo) ([]TransitiveUse, error) {
+	owner, repo, dir := splitActionPath(action.Repo)
+	manifest, err := fetchManifest(forge, owner, repo, dir, action.CurrentSHA)
+	if err != nil {
+		return nil, err
+	}
+	var m distManifest
+	if err := yaml.Unmarshal(manifest, &m); err != nil {
+		return nil, fmt.Errorf("invalid action manifest: %w", err)
+	}
+	if !strings.EqualFold(m.Runs.Using, "composite") {
+		return nil, nil
+	}
+
+	file := path.Join(owner, repo, dir, "action.yml")
+	steps, err := parseWorkflowContent(file, string(manifest))
+	if err != nil {
+		return nil, err
+	}
+	var unpinned []TransitiveUse
+	for _, step := range steps {
+		if step.Pinned() {
+			continue
+		}
+		separator := "@"
+		if step.Docker {
+			separator = ":"
+		}
+		unpinned = append(unpinned, TransitiveUse{File: file, Line: step.Line, Uses: step.Repo + separator + step.CurrentRef})
+	}
+	return unpinned, nil
+}
+
+// scanTransitive looks one level into the composite actions pinned by the
+// workflows and stores the unpinned references of their steps in the
+// actions. Unpinned actions are reported on their own and not followed. It
+// returns the number of actions depending on unpinned references.
+func scanTransitive(forge Forge, actions WorkflowActions) int {
+	fmt.Fprintln(console, "\n🔗 Scanning composite actions for unpinned dependencies...")
+
+	// The same pin is usually shared by many workflows
+	type lookup struct {
+		uses []TransitiveUse
+		err  error
+	}
+	scanned := make(map[string]lookup)
+
+	exposed := 0
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker || action.ReusableWorkflow || !action.Pinned() {
+				continue
+			}
+			key := action.Repo + "@" + action.CurrentSHA
+			found, ok := scanned[key]
+			if !ok {
+				found.uses, found.err = unpinnedTransitive(forge, *action)
+				scanned[key] = found
+			}
+			switch {
+			case found.err != nil:
+				fmt.Fprintf(console, "  %s %s:%d %s: could not read the action manifest: %v\n", theme.Warning, action.WorkflowFile, action.Line, action.Repo, found.err)
+				logger.Info("failed to scan composite action", "workflow", workflow, "repo", action.Repo, "error", found.err)
+			case len(found.uses) > 0:
+				exposed++
+				action.Transitive = found.uses
+				fmt.Fprintf(console, "  %s %s:%d %s@%s calls unpinned actions %s\n", severityStatus(ruleTransitive), action.WorkflowFile, action.Line,
+					action.Repo, shortPin(action.CurrentRef), ruleTag(ruleTransitive))
+				for _, use := range found.uses {
+					fmt.Fprintf(console, "      %s:%d uses %s\n", use.File, use.Line, use.Uses)
+				}
+			}
+		}
+	}
+
+	if exposed == 0 {
+		fmt.Fprintf(console, "%s Pinned composite actions only call pinned actions\n", theme.OK)
+	}
+	return exposed
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// publicGoodTrustedRootJSON is the trusted root of the Sigstore public-good
+// instance, with the Fulcio certificates and Rekor keys, when the binary
+// was built
+//
+//go:embed sigstore/trusted_root.json
+var publicGoodTrustedRootJSON []byte
+
+// validityPeriod is the time a certificate authority or log key of a
+// trusted root was in use; End is unset while it still is
+type validityPeriod struct {
+	Start *time.Time `json:"start"`
+	End   *time.Time `json:"end"`
+}
+
+// covers reports whether t falls within the period
+func (p validityPeriod) covers(t time.Time) bool {
+	return (p.Start == nil || !t.Before(*p.Start)) && (p.End == nil || !t.After(*p.End))
+}
+
+// trustedLog is a transparency log key of a trusted root
+type trustedLog struct {
+	key      crypto.PublicKey
+	validFor validityPeriod
+}
+
+// trustedRoot holds what a keyless signature is checked against: the
+// Fulcio certificates its certificate chains to and the keys of the Rekor
+// logs its entry is recorded in, by log ID
+type trustedRoot struct {
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	logs          map[string]trustedLog
+}
+
+// loadTrustedRoot reads a Sigstore trusted_root.json, or the embedded root
+// of the public-good instance when path is empty
+func loadTrustedRoot(path string) (*trustedRoot, error) {
+	content, name := publicGoodTrustedRootJSON, "the embedded Sigstore trusted root"
+	if path != "" {
+		var err error
+		if content, err = os.ReadFile(filepath.Clean(path)); err != nil {
+			return nil, err
+		}
+		name = path
+	}
+
+	var document struct {
+		CertificateAuthorities []struct {
+			CertChain struct {
+				Certificates []struct {
+					RawBytes string `json:"rawBytes"`
+				} `json:"certificates"`
+			} `json:"certChain"`
+		} `json:"certificateAuthorities"`
+		Tlogs []struct {
+			PublicKey struct {
+				RawBytes string         `json:"rawBytes"`
+				ValidFor validityPeriod `json:"validFor"`
+			} `json:"publicKey"`
+			LogID struct {
+				KeyID string `json:"keyId"`
+			} `json:"logId"`
+		} `json:"tlogs"`
+	}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("invalid trusted root %s: %w", name, err)
+	}
+
+	root := &trustedRoot{roots: x509.NewCertPool(), intermediates: x509.NewCertPool(), logs: make(map[string]trustedLog)}
+	authorities := 0
+	for _, authority := range document.CertificateAuthorities {
+		for _, raw := range authority.CertChain.Certificates {
+			der, err := base64.StdEncoding.DecodeString(raw.RawBytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate in %s: %w", name, err)
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate in %s: %w", name, err)
+			}
+			if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+				root.roots.AddCert(cert)
+				authorities++
+			} else {
+				root.intermediates.AddCert(cert)
+			}
+		}
+	}
+	for _, tlog := range document.Tlogs {
+		der, err := base64.StdEncoding.DecodeString(tlog.PublicKey.RawBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transparency log key in %s: %w", name, err)
+		}
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transparency log key in %s: %w", name, err)
+		}
+		root.logs[tlog.LogID.KeyID] = trustedLog{key: key, validFor: tlog.PublicKey.ValidFor}
+	}
+
+	// Without both, a keyless signature cannot be told from a self-made one
+	if authorities == 0 {
+		return nil, fmt.Errorf("%s holds no Fulcio root certificate", name)
+	}
+	if len(root.logs) == 0 {
+		return nil, fmt.Errorf("%s holds no transparency log key", name)
+	}
+	return root, nil
+}
+
+// rekorEntry is a transparency log entry of a Sigstore bundle
+type rekorEntry struct {
+	LogIndex string `json:"logIndex"`
+	LogID    struct {
+		KeyID string `json:"keyId"`
+	} `json:"logId"`
+	IntegratedTime   string `json:"integratedTime"`
+	InclusionPromise *struct {
+		SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+	} `json:"inclusionPromise"`
+	InclusionProof *struct {
+		LogIndex   string   `json:"logIndex"`
+		RootHash   string   `json:"rootHash"`
+		TreeSize   string   `json:"treeSize"`
+		Hashes     []string `json:"hashes"`
+		Checkpoint struct {
+			Envelope string `json:"envelope"`
+		} `json:"checkpoint"`
+	} `json:"inclusionProof"`
+	CanonicalizedBody string `json:"canonicalizedBody"`
+}
+
+// checkBody checks that the hashedrekord body of the entry records this
+// signature of content by the certificate
+func (e *rekorEntry) checkBody(cert *x509.Certificate, content, signature []byte) error {
+	body, err := base64.StdEncoding.DecodeString(e.CanonicalizedBody)
+	if err != nil {
+		return fmt.Errorf("invalid transparency log entry body: %w", err)
+	}
+	var record struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Signature struct {
+				Content   string `json:"content"`
+				PublicKey struct {
+					Content string `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(body, &record); err != nil {
+		return fmt.Errorf("invalid transparency log entry body: %w", err)
+	}
+	if record.Kind != "hashedrekord" {
+		return fmt.Errorf("unsupported transparency log entry kind %q", record.Kind)
+	}
+
+	digest := sha256.Sum256(content)
+	if record.Spec.Data.Hash.Algorithm != "sha256" || !strings.EqualFold(record.Spec.Data.Hash.Value, hex.EncodeToString(digest[:])) {
+		return errors.New("the transparency log entry records another lockfile")
+	}
+	if record.Spec.Signature.Content != base64.StdEncoding.EncodeToString(signature) {
+		return errors.New("the transparency log entry records another signature")
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(record.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return fmt.Errorf("invalid transparency log entry certificate: %w", err)
+	}
+	if block, _ := pem.Decode(certPEM); block == nil || !bytes.Equal(block.Bytes, cert.Raw) {
+		return errors.New("the transparency log entry records another certificate")
+	}
+	return nil
+}
+
+// checkPromise checks the signed entry timestamp Rekor returned when it
+// accepted the entry, which vouches for the body, the log index and the
+// time the entry was integrated
+func (e *rekorEntry) checkPromise(key crypto.PublicKey, logID []byte) error {
+	if e.InclusionPromise == nil || e.InclusionPromise.SignedEntryTimestamp == "" {
+		return errors.New("the transparency log entry has no inclusion promise")
+	}
+	integratedTime, err := strconv.ParseInt(e.IntegratedTime, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid transparency log entry time: %w", err)
+	}
+	logIndex, err := strconv.ParseInt(e.LogIndex, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid transparency log index: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(e.InclusionPromise.SignedEntryTimestamp)
+	if err != nil {
+		return fmt.Errorf("invalid inclusion promise encoding: %w", err)
+	}
+	// The fields in key order, as Rekor canonicalizes them before signing
+	payload, err := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{e.CanonicalizedBody, integratedTime, hex.EncodeToString(logID), logIndex})
+	if err != nil {
+		return err
+	}
+	if err := verifyKeySignature(key, payload, signature); err != nil {
+		return fmt.Errorf("the inclusion promise is not signed by the transparency log: %w", err)
+	}
+	return nil
+}
+
+// merkleNode hashes two child nodes of a RFC 6962 Merkle tree
+func merkleNode(left, right []byte) []byte {
+	sum := sha256.Sum256(append(append([]byte{1}, left...), right...))
+	return sum[:]
+}
+
+// checkProof checks the inclusion proof of the entry against the tree root
+// it names, and that the checkpoint of that root is signed by the log
+func (e *rekorEntry) checkProof(key crypto.PublicKey) error {
+	proof := e.InclusionProof
+	index, err := strconv.ParseInt(proof.LogIndex, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid inclusion proof index: %w", err)
+	}
+	size, err := strconv.ParseInt(proof.TreeSize, 10, 64)
+	if err != nil || index < 0 || index >= size {
+		return fmt.Errorf("invalid inclusion proof tree size %q", proof.TreeSize)
+	}
+	body, err := base64.StdEncoding.DecodeString(e.CanonicalizedBody)
+	if err != nil {
+		return fmt.Errorf("invalid transparency log entry body: %w", err)
+	}
+	rootHash, err := hex.DecodeString(proof.RootHash)
+	if err != nil {
+		return fmt.Errorf("invalid inclusion proof root: %w", err)
+	}
+
+	leaf := sha256.Sum256(append([]byte{0}, body...))
+	hash, node, last := leaf[:], index, size-1
+	for _, encoded := range proof.Hashes {
+		sibling, err := hex.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid inclusion proof hash: %w", err)
+		}
+		if last == 0 {
+			return errors.New("the inclusion proof is longer than the tree is deep")
+		}
+		if node%2 == 1 || node == last {
+			hash = merkleNode(sibling, hash)
+			for node%2 == 0 && node != 0 {
+				node, last = node/2, last/2
+			}
+		} else {
+			hash = merkleNode(hash, sibling)
+		}
+		node, last = node/2, last/2
+	}
+	if last != 0 || !bytes.Equal(hash, rootHash) {
+		return errors.New("the inclusion proof does not lead to the tree root")
+	}
+
+	// A checkpoint is a signed note: the origin, tree size and base64 root
+	// hash, a blank line, then "— <name> <base64 key hint and signature>"
+	note, signatures, found := strings.Cut(proof.Checkpoint.Envelope, "\n\n")
+	if !found {
+		return errors.New("the inclusion proof has no signed checkpoint")
+	}
+	lines := strings.Split(note, "\n")
+	if len(lines) < 3 || lines[1] != proof.TreeSize || lines[2] != base64.StdEncoding.EncodeToString(rootHash) {
+		return errors.New("the checkpoint is of another tree than the inclusion proof")
+	}
+	for _, line := range strings.Split(signatures, "\n") {
+		fields := strings.Fields(strings.TrimPrefix(line, "— "))
+		if len(fields) != 2 {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(signature) <= 4 {
+			continue
+		}
+		if verifyKeySignature(key, []byte(note+"\n"), signature[4:]) == nil {
+			return nil
+		}
+	}
+	return errors.New("the checkpoint is not signed by the transparency log")
+}
+
+// verifyTlogEntry checks that a keyless signature is recorded in a
+// transparency log of the trusted root and returns the time it was
+// recorded, at which the short-lived certificate must have been valid
+func verifyTlogEntry(root *trustedRoot, entries []json.RawMessage, cert *x509.Certificate, content, signature []byte) (time.Time, error) {
+	if len(entries) == 0 {
+		return time.Time{}, errors.New("the bundle has no transparency log entry")
+	}
+	var failures []string
+	for _, raw := range entries {
+		var entry rekorEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			failures = append(failures, fmt.Sprintf("invalid transparency log entry: %v", err))
+			continue
+		}
+		integrated, err := entry.verify(root, cert, content, signature)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		return integrated, nil
+	}
+	return time.Time{}, errors.New(strings.Join(failures, "; "))
+}
+
+// verify checks one transparency log entry, see verifyTlogEntry
+func (e *rekorEntry) verify(root *trustedRoot, cert *x509.Certificate, content, signature []byte) (time.Time, error) {
+	if err := e.checkBody(cert, content, signature); err != nil {
+		return time.Time{}, err
+	}
+	log, ok := root.logs[e.LogID.KeyID]
+	if !ok {
+		return time.Time{}, errors.New("the transparency log entry is not from a log of the trusted root")
+	}
+	logID, err := base64.StdEncoding.DecodeString(e.LogID.KeyID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log ID: %w", err)
+	}
+	if err := e.checkPromise(log.key, logID); err != nil {
+		return time.Time{}, err
+	}
+	if e.InclusionProof != nil {
+		if err := e.checkProof(log.key); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	seconds, _ := strconv.ParseInt(e.IntegratedTime, 10, 64)
+	integrated := time.Unix(seconds, 0)
+	if !log.validFor.covers(integrated) {
+		return time.Time{}, errors.New("the transparency log key was not in use when the entry was recorded")
+	}
+	if integrated.Before(cert.NotBefore) || integrated.After(cert.NotAfter) {
+		return time.Time{}, errors.New("the signature was recorded outside the validity of its certificate")
+	}
+	return integrated, nil
+}
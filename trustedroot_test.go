@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// rfc6962Leaves are the leaves of the Merkle tree test vectors of the
+// Certificate Transparency reference implementation
+var rfc6962Leaves = []string{
+	"",
+	"00",
+	"10",
+	"2021",
+	"3031",
+	"40414243",
+	"5051525354555657",
+	"606162636465666768696a6b6c6d6e6f",
+}
+
+// rfc6962Proofs are inclusion proofs of rfc6962Leaves with the roots of the
+// trees they lead to
+var rfc6962Proofs = []struct {
+	index, size int
+	root        string
+	hashes      []string
+}{
+	{0, 8, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328", []string{
+		"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+		"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+		"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4",
+	}},
+	{5, 8, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328", []string{
+		"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+		"ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	}},
+	{2, 3, "aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77", []string{
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+	}},
+	{4, 5, "4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4", []string{
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	}},
+	{6, 7, "ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c", []string{
+		"0ebc5d3437fbe2db158b9f126a1d118e308181031d0a949f8dededebc558ef6a",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	}},
+}
+
+// newTestLogKey returns a transparency log signing key
+func newTestLogKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// signTest signs message with key as a transparency log does
+func signTest(t *testing.T, key *ecdsa.PrivateKey, message string) []byte {
+	t.Helper()
+	digest := sha256.Sum256([]byte(message))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signature
+}
+
+// signedCheckpoint returns a checkpoint of a tree signed by key, with the
+// key hint that precedes the signature in a signed note
+func signedCheckpoint(t *testing.T, key *ecdsa.PrivateKey, size int, root string) string {
+	t.Helper()
+	rootHash, err := hex.DecodeString(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := "rekor.test - 1193050959916656506\n" + strconv.Itoa(size) + "\n" + base64.StdEncoding.EncodeToString(rootHash) + "\n"
+	signature := append([]byte{0xc0, 0xd2, 0x3d, 0x6a}, signTest(t, key, note)...)
+	return note + "\n— rekor.test " + base64.StdEncoding.EncodeToString(signature) + "\n"
+}
+
+// proofEntry returns a transparency log entry of leaf with an inclusion proof
+func proofEntry(t *testing.T, leaf string, index, size int, root string, hashes []string, checkpoint string) *rekorEntry {
+	t.Helper()
+	body, err := hex.DecodeString(leaf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(map[string]any{
+		"canonicalizedBody": base64.StdEncoding.EncodeToString(body),
+		"inclusionProof": map[string]any{
+			"logIndex":   strconv.Itoa(index),
+			"rootHash":   root,
+			"treeSize":   strconv.Itoa(size),
+			"hashes":     hashes,
+			"checkpoint": map[string]string{"envelope": checkpoint},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry rekorEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		t.Fatal(err)
+	}
+	return &entry
+}
+
+func TestCheckProofVectors(t *testing.T) {
+	key := newTestLogKey(t)
+	for _, vector := range rfc6962Proofs {
+		t.Run(strconv.Itoa(vector.index)+"/"+strconv.Itoa(vector.size), func(t *testing.T) {
+			checkpoint := signedCheckpoint(t, key, vector.size, vector.root)
+			entry := proofEntry(t, rfc6962Leaves[vector.index], vector.index, vector.size, vector.root, vector.hashes, checkpoint)
+			if err := entry.checkProof(&key.PublicKey); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestCheckProofTampered(t *testing.T) {
+	key, otherKey := newTestLogKey(t), newTestLogKey(t)
+	vector := rfc6962Proofs[1]
+	flipped := append([]string(nil), vector.hashes...)
+	flipped[1] = "da" + flipped[1][2:]
+	otherRoot := rfc6962Proofs[3].root
+
+	tests := []struct {
+		name       string
+		leaf       string
+		index      int
+		size       int
+		root       string
+		hashes     []string
+		checkpoint string
+		wantErr    string
+	}{
+		{"other leaf", rfc6962Leaves[4], 5, 8, vector.root, vector.hashes, "", "does not lead to the tree root"},
+		{"other index", rfc6962Leaves[5], 4, 8, vector.root, vector.hashes, "", "does not lead to the tree root"},
+		{"other tree size", rfc6962Leaves[5], 5, 6, vector.root, vector.hashes, "", "longer than the tree is deep"},
+		// The proof of this leaf has the same shape in a tree of 7, so only
+		// the signed checkpoint binds the size
+		{"same shape tree size", rfc6962Leaves[5], 5, 7, vector.root, vector.hashes, signedCheckpoint(t, key, 8, vector.root), "checkpoint is of another tree"},
+		{"index beyond tree", rfc6962Leaves[5], 8, 8, vector.root, vector.hashes, "", "invalid inclusion proof tree size"},
+		{"flipped hash", rfc6962Leaves[5], 5, 8, vector.root, flipped, "", "does not lead to the tree root"},
+		{"missing hash", rfc6962Leaves[5], 5, 8, vector.root, vector.hashes[:2], "", "does not lead to the tree root"},
+		{"extra hash", rfc6962Leaves[5], 5, 8, vector.root, append(append([]string(nil), vector.hashes...), vector.hashes[0]), "", "longer than the tree is deep"},
+		{"other root", rfc6962Leaves[5], 5, 8, otherRoot, vector.hashes, signedCheckpoint(t, key, 8, otherRoot), "does not lead to the tree root"},
+		{"checkpoint of other tree", rfc6962Leaves[5], 5, 8, vector.root, vector.hashes, signedCheckpoint(t, key, 5, otherRoot), "checkpoint is of another tree"},
+		{"checkpoint of other size", rfc6962Leaves[5], 5, 8, vector.root, vector.hashes, signedCheckpoint(t, key, 9, vector.root), "checkpoint is of another tree"},
+		{"checkpoint by other key", rfc6962Leaves[5], 5, 8, vector.root, vector.hashes, signedCheckpoint(t, otherKey, 8, vector.root), "not signed by the transparency log"},
+		{"unsigned checkpoint", rfc6962Leaves[5], 5, 8, vector.root, vector.hashes, strings.SplitAfter(signedCheckpoint(t, key, 8, vector.root), "\n\n")[0], "not signed by the transparency log"},
+		{"no checkpoint", rfc6962Leaves[5], 5, 8, vector.root, vector.hashes, "", "no signed checkpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkpoint := tt.checkpoint
+			if checkpoint == "" && tt.wantErr != "no signed checkpoint" {
+				checkpoint = signedCheckpoint(t, key, tt.size, tt.root)
+			}
+			entry := proofEntry(t, tt.leaf, tt.index, tt.size, tt.root, tt.hashes, checkpoint)
+			err := entry.checkProof(&key.PublicKey)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPromise(t *testing.T) {
+	key, otherKey := newTestLogKey(t), newTestLogKey(t)
+	logID := sha256.Sum256([]byte("rekor.test"))
+	body := base64.StdEncoding.EncodeToString([]byte(`{"apiVersion":"0.0.1","kind":"hashedrekord"}`))
+
+	// The payload Rekor signs, spelled out to pin its canonical form
+	payload := `{"body":"` + body + `","integratedTime":1700000000,"logID":"` + hex.EncodeToString(logID[:]) + `","logIndex":42}`
+	promise := base64.StdEncoding.EncodeToString(signTest(t, key, payload))
+
+	entry := func(body, integratedTime, logIndex, promise string) *rekorEntry {
+		e := &rekorEntry{CanonicalizedBody: body, IntegratedTime: integratedTime, LogIndex: logIndex}
+		if promise != "" {
+			e.InclusionPromise = &struct {
+				SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+			}{promise}
+		}
+		return e
+	}
+	otherLogID := sha256.Sum256([]byte("other"))
+
+	tests := []struct {
+		name    string
+		entry   *rekorEntry
+		key     *ecdsa.PrivateKey
+		logID   []byte
+		wantErr string
+	}{
+		{"valid", entry(body, "1700000000", "42", promise), key, logID[:], ""},
+		{"other body", entry(base64.StdEncoding.EncodeToString([]byte("{}")), "1700000000", "42", promise), key, logID[:], "not signed by the transparency log"},
+		{"other time", entry(body, "1700000001", "42", promise), key, logID[:], "not signed by the transparency log"},
+		{"other index", entry(body, "1700000000", "43", promise), key, logID[:], "not signed by the transparency log"},
+		{"other log", entry(body, "1700000000", "42", promise), key, otherLogID[:], "not signed by the transparency log"},
+		{"other key", entry(body, "1700000000", "42", promise), otherKey, logID[:], "not signed by the transparency log"},
+		{"no promise", entry(body, "1700000000", "42", ""), key, logID[:], "no inclusion promise"},
+		{"invalid time", entry(body, "soon", "42", promise), key, logID[:], "invalid transparency log entry time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.entry.checkPromise(&tt.key.PublicKey, tt.logID)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	// ANSI sequences used by the review screen
+	ansiAltScreenOn  = "\033[?1049h"
+	ansiAltScreenOff = "\033[?1049l"
+	ansiHideCursor   = "\033[?25l"
+	ansiShowCursor   = "\033[?25h"
+	ansiClearScreen  = "\033[H\033[2J"
+	ansiReverse      = "\033[7m"
+	ansiDim          = "\033[2m"
+	ansiReset        = "\033[0m"
+
+	// tuiChromeLines is the number of screen lines used by the title, preview and key help
+	tuiChromeLines = 9
+)
+
+// errReviewCancelled is returned when the user quits the review screen
+var errReviewCancelled = errors.New("review cancelled")
+
+// reviewItem is a pending update shown in the review screen
+type reviewItem struct {
+	action   *ActionInfo
+	group    string
+	blocked  bool
+	selected bool
+}
+
+// reviewRow is one line of the list: either a workflow header or an item
+type reviewRow struct {
+	header string
+	item   int
+}
+
+// reviewModel holds the state of the review screen
+type reviewModel struct {
+	items  []*reviewItem
+	rows   []reviewRow
+	cursor int // index into items
+	offset int // first visible row
+	height int
+	width  int
+}
+
+// newReviewModel builds the list of pending updates grouped by workflow
+func newReviewModel(actions WorkflowActions, cfg *Config) *reviewModel {
+	blockedGroups := make(map[string]bool)
+	for _, group := range collectGroups(actions, cfg) {
+		if group.Blocker() != nil {
+			blockedGroups[group.Name] = true
+		}
+	}
+
+	workflows := sortedWorkflows(actions)
+
+	m := &reviewModel{}
+	for _, workflow := range workflows {
+		actionList := actions[workflow]
+		header := false
+		for i := range actionList {
+			if !actionList[i].NeedsUpdate {
+				continue
+			}
+			if !header {
+				m.rows = append(m.rows, reviewRow{header: workflow, item: -1})
+				header = true
+			}
+			group := cfg.actionGroup(actionList[i].Repo)
+			m.rows = append(m.rows, reviewRow{item: len(m.items)})
+			m.items = append(m.items, &reviewItem{
+				action:  &actionList[i],
+				group:   group,
+				blocked: blockedGroups[group],
+			})
+		}
+	}
+
+	return m
+}
+
+// toggle flips the selection of the current item and, for grouped actions,
+// of every other member of its group so that groups move together
+func (m *reviewModel) toggle() {
+	if len(m.items) == 0 {
+		return
+	}
+	current := m.items[m.cursor]
+	if current.blocked {
+		return
+	}
+	selected := !current.selected
+	for _, item := range m.items {
+		if item == current || (current.group != "" && item.group == current.group) {
+			item.selected = selected
+		}
+	}
+}
+
+// selectAll sets the selection of every item that can be applied
+func (m *reviewModel) selectAll(selected bool) {
+	for _, item := range m.items {
+		if !item.blocked {
+			item.selected = selected
+		}
+	}
+}
+
+// move moves the cursor by delta items and scrolls it into view
+func (m *reviewModel) move(delta int) {
+	if len(m.items) == 0 {
+		return
+	}
+	m.cursor = min(max(m.cursor+delta, 0), len(m.items)-1)
+
+	visible := max(m.height-tuiChromeLines, 1)
+	row := m.cursorRow()
+	if row-1 < m.offset {
+		// Keep the workflow header above the first item visible
+		m.offset = max(row-1, 0)
+	}
+	if row >= m.offset+visible {
+		m.offset = row - visible + 1
+	}
+}
+
+// cursorRow returns the row index of the current item
+func (m *reviewModel) cursorRow() int {
+	for i, row := range m.rows {
+		if row.item == m.cursor {
+			return i
+		}
+	}
+	return 0
+}
+
+// selectedActions returns the actions the user chose to apply
+func (m *reviewModel) selectedActions() []*ActionInfo {
+	var selected []*ActionInfo
+	for _, item := range m.items {
+		if item.selected {
+			selected = append(selected, item.action)
+		}
+	}
+	return selected
+}
+
+// render draws the full screen
+func (m *reviewModel) render() string {
+	var b strings.Builder
+
+	count := len(m.selectedActions())
+	fmt.Fprintf(&b, "GitHub CI Hash Updater — review updates (%d of %d selected)\n", count, len(m.items))
+	b.WriteString(strings.Repeat("─", m.width) + "\n")
+
+	visible := max(m.height-tuiChromeLines, 1)
+	end := min(m.offset+visible, len(m.rows))
+	for _, row := range m.rows[m.offset:end] {
+		if row.item < 0 {
+			fmt.Fprintf(&b, "📁 %s\n", row.header)
+			continue
+		}
+
+		item := m.items[row.item]
+		mark := "[ ]"
+		if item.selected {
+			mark = "[x]"
+		}
+		line := fmt.Sprintf("  %s %s: %s → %s", mark, item.action.Repo, item.action.CurrentRef, item.action.LatestTag)
+		if item.group != "" {
+			line += "  🔗 " + item.group
+		}
+		if item.action.ReviewRequired() {
+			line += "  " + theme.Warning + " review required"
+		}
+		if item.blocked {
+			line = ansiDim + line + " (blocked: a group member failed to resolve)" + ansiReset
+		}
+		if row.item == m.cursor {
+			line = ansiReverse + line + ansiReset
+		}
+		b.WriteString(line + "\n")
+	}
+	for i := end - m.offset; i < visible; i++ {
+		b.WriteString("\n")
+	}
+
+	b.WriteString(strings.Repeat("─", m.width) + "\n")
+	if len(m.items) > 0 {
+		action := m.items[m.cursor].action
+		fmt.Fprintf(&b, "%s:%d\n", action.WorkflowFile, action.Line)
+		b.WriteString(theme.Failure("- "+strings.TrimSpace(action.OriginalLine)) + "\n")
+		b.WriteString(theme.Success("+ "+strings.TrimSpace(pinnedLine(action.OriginalLine, []ActionInfo{*action}, nil))) + "\n")
+	} else {
+		b.WriteString("No updates available\n\n\n")
+	}
+	b.WriteString(strings.Repeat("─", m.width) + "\n")
+	b.WriteString("↑/↓ or j/k move · space toggle · a all · n none · enter apply · q quit")
+
+	// Raw mode needs explicit carriage returns
+	return ansiClearScreen + strings.ReplaceAll(b.String(), "\n", "\r\n")
+}
+
+// runReviewTUI shows a full-screen list of pending updates and returns the
+// actions the user selected for a batch apply
+func runReviewTUI(actions WorkflowActions, cfg *Config) ([]*ActionInfo, error) {
+	// #nosec G115 - file descriptors always fit in an int
+	stdinFd, stdoutFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(stdinFd) || !term.IsTerminal(stdoutFd) {
+		return nil, fmt.Errorf("the review screen requires an interactive terminal")
+	}
+
+	m := newReviewModel(actions, cfg)
+	if len(m.items) == 0 {
+		return nil, nil
+	}
+
+	state, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enter raw terminal mode: %w", err)
+	}
+	defer func() {
+		fmt.Fprint(os.Stdout, ansiShowCursor+ansiAltScreenOff)
+		if restoreErr := term.Restore(stdinFd, state); restoreErr != nil {
+			logger.Warn("failed to restore terminal", "error", restoreErr)
+		}
+	}()
+	fmt.Fprint(os.Stdout, ansiAltScreenOn+ansiHideCursor)
+
+	buf := make([]byte, 8)
+	for {
+		m.width, m.height, err = term.GetSize(stdoutFd)
+		if err != nil {
+			m.width, m.height = 80, 24
+		}
+		m.move(0)
+		fmt.Fprint(os.Stdout, m.render())
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key: %w", err)
+		}
+
+		switch key := string(buf[:n]); key {
+		case "q", "\x03", "\x1b":
+			return nil, errReviewCancelled
+		case "\x1b[A", "k":
+			m.move(-1)
+		case "\x1b[B", "j":
+			m.move(1)
+		case "\x1b[5~":
+			m.move(-(m.height - tuiChromeLines))
+		case "\x1b[6~":
+			m.move(m.height - tuiChromeLines)
+		case "g":
+			m.move(-len(m.items))
+		case "G":
+			m.move(len(m.items))
+		case " ":
+			m.toggle()
+		case "a":
+			m.selectAll(true)
+		case "n":
+			m.selectAll(false)
+		case "\r", "\n":
+			return m.selectedActions(), nil
+		}
+	}
+}
+
+// applyReviewedUpdates writes the selected updates as one batch. If any file
+// fails to update, every file in the batch is restored from its backup.
+func applyReviewedUpdates(selected []*ActionInfo) error {
+	byFile := make(map[string][]ActionInfo)
+	var files []string
+	for _, action := range selected {
+		if _, ok := byFile[action.WorkflowFile]; !ok {
+			files = append(files, action.WorkflowFile)
+		}
+		byFile[action.WorkflowFile] = append(byFile[action.WorkflowFile], *action)
+	}
+	sort.Strings(files)
+
+	fmt.Fprintln(console, "\n🚀 Applying selected updates...")
+
+	backupFiles, err := createBackups(files)
+	if err != nil {
+		return err
+	}
+
+	for i, file := range files {
+		fmt.Fprintf(console, "\n📁 %s:\n", file)
+		if err := updateWorkflowFile(file, byFile[file]); err != nil {
+			for _, restore := range files[:i+1] {
+				if restoreErr := copyFile(backupFiles[restore], restore); restoreErr != nil {
+					fmt.Fprintf(console, "  %s Failed to restore backup of %s: %v\n", theme.Error, restore, restoreErr)
+				}
+			}
+			return fmt.Errorf("failed to update %s, batch rolled back: %w", file, err)
+		}
+		fmt.Fprintf(console, "  %s Updated %s\n", theme.OK, file)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// unpinnedLine rewrites the SHA pins of actions on line to the tags in their
+// LatestTag, the reverse of pinnedLine. The tag comment is dropped once it no
+// longer describes a pinned reference: when every reference on the line is
+// unpinned and the comment named their tags, or stale versions of them,
+// instead of being a note of its own. A note following the tag is kept.
+func unpinnedLine(line string, actions []ActionInfo, onLine int) string {
+	code, comment := splitTrailingComment(line)
+
+	ordered := append([]ActionInfo(nil), actions...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Column > ordered[j].Column
+	})
+	runes := []rune(code)
+	fromComment := true
+	for _, action := range ordered {
+		if tag := commentTag(line, action.Repo); tag != action.LatestTag && !versionTagRegex.MatchString(tag) {
+			fromComment = false
+		}
+		action.LatestSHA = action.LatestTag
+		// A comment stays with a reference that could not be rewritten
+		var ok bool
+		if runes, ok = rewriteUsesValue(runes, action); !ok {
+			fromComment = false
+		}
+	}
+	if fromComment && len(actions) == onLine {
+		code := strings.TrimRight(string(runes), " \t")
+		if note := pinComment.note(comment); note != "" && onLine == 1 {
+			marker := "#"
+			if strings.HasPrefix(strings.TrimSpace(comment), "#!") {
+				marker = "#!"
+			}
+			return code + " " + marker + " " + note
+		}
+		return code
+	}
+	return string(runes) + comment
+}
+
+// applyUnpins rewrites the SHA pins of actions with a LatestTag to that tag
+// and returns the new content along with the number of references changed
+func applyUnpins(content string, actions []ActionInfo) (string, int) {
+	content, bom := splitBOM(content)
+	lines := strings.Split(content, "\n")
+	references := make(map[int]int)
+	onLine := make(map[int][]ActionInfo)
+	for _, action := range actions {
+		references[action.Line]++
+		if action.LatestTag != "" && action.Line-1 < len(lines) {
+			onLine[action.Line] = append(onLine[action.Line], action)
+		}
+	}
+
+	changed := 0
+	for lineNumber, lineActions := range onLine {
+		oldLine, cr := splitCarriageReturn(lines[lineNumber-1])
+		if newLine := unpinnedLine(oldLine, lineActions, references[lineNumber]); newLine != oldLine {
+			lines[lineNumber-1] = newLine + cr
+			changed += len(lineActions)
+		}
+	}
+	return bom + strings.Join(lines, "\n"), changed
+}
+
+// setupUnpin registers the flags of the unpin command and returns its runner
+func setupUnpin(fs *flag.FlagSet) func(args []string) {
+	opts := &commandOptions{}
+	opts.register(fs)
+	fs.BoolVar(&autoApprove, "yes", false, "Rewrite the pins without prompting")
+
+	return func(args []string) {
+		cfg, scanOpts := mustLoadScanConfig(opts)
+
+		fmt.Fprintln(console, "🔍 Scanning workflow files...")
+		actions, _, err := scanTargets(args, scanOpts)
+		if err != nil {
+			fmt.Fprintf(console, "Error scanning workflows: %v\n", err)
+			os.Exit(1)
+		}
+
+		// The forge is only needed for pins without a version comment
+		var resolver *tagResolver
+		lookup := func() *tagResolver {
+			if resolver == nil {
+				resolver = newTagResolver(forgeFor(NewGitHubClient(), cfg, actions))
+			}
+			return resolver
+		}
+		if err := unpinActions(lookup, actions); err != nil {
+			fmt.Fprintf(console, "Error unpinning actions: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// unpinActions finds the tag of every SHA pin, from a version in its comment
+// that still resolves to the pinned commit or by looking up the tags pointing
+// at the commit, and after one confirmation rewrites the pins to those tags.
+// Image digests are left alone.
+func unpinActions(lookup func() *tagResolver, actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n📍 Finding the tags of SHA pins...")
+
+	workflows := sortedWorkflows(actions)
+
+	unpinned := 0
+	var changed []string
+	for _, workflow := range workflows {
+		before := unpinned
+		actionList := actions[workflow]
+		for i := range actionList {
+			action := &actionList[i]
+			if action.Docker || !action.Pinned() {
+				continue
+			}
+			// Comments that are notes rather than versions, stale or naming a
+			// moved tag fall back to the lookup
+			tag := commentTag(action.OriginalLine, action.Repo)
+			current := false
+			if versionTagRegex.MatchString(tag) {
+				var err error
+				if current, err = lookup().resolvesTo(*action, tag); err != nil {
+					action.Error = classifyError(err)
+					fmt.Fprintf(console, "  %s %s:%d %s: %v\n", theme.Error, workflow, action.Line, action.Repo, action.Error)
+					continue
+				}
+				if !current {
+					logger.Debug("pin comment does not name the pinned commit", "repo", action.Repo, "tag", tag, "sha", action.CurrentRef)
+				}
+			}
+			if !current {
+				var err error
+				if tag, err = lookup().tagFor(*action); err != nil {
+					action.Error = classifyError(err)
+					fmt.Fprintf(console, "  %s %s:%d %s: %v\n", theme.Error, workflow, action.Line, action.Repo, action.Error)
+					continue
+				}
+			}
+			if tag == "" {
+				fmt.Fprintf(console, "  %s %s:%d %s@%s: no tag points at this commit\n", theme.Skipped, workflow, action.Line, action.Repo, shortPin(action.CurrentRef))
+				continue
+			}
+			action.LatestTag = tag
+			unpinned++
+			fmt.Fprintf(console, "  %s %s:%d %s@%s → %s\n", theme.Update, workflow, action.Line, action.Repo, shortPin(action.CurrentRef), tag)
+		}
+		if unpinned > before {
+			changed = append(changed, workflow)
+		}
+	}
+
+	if unpinned == 0 {
+		fmt.Fprintf(console, "\n%s No SHA pins to convert\n", theme.OK)
+		return nil
+	}
+	fmt.Fprintf(console, "\n%s Tags can be moved, so unpinned workflows are no longer reproducible\n", theme.Warning)
+	if !promptForConfirmation(fmt.Sprintf("Replace %d SHA pin(s) with their tags?", unpinned)) {
+		fmt.Fprintf(console, "%s No files were changed\n", theme.Skipped)
+		return nil
+	}
+
+	for _, workflow := range changed {
+		content, err := os.ReadFile(filepath.Clean(workflow))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", workflow, err)
+		}
+		newContent, count := applyUnpins(string(content), actions[workflow])
+		if count == 0 {
+			continue
+		}
+		if err := checkWritePath(workflow); err != nil {
+			return err
+		}
+		if err := os.WriteFile(workflow, []byte(newContent), 0600); err != nil {
+			return err
+		}
+		emitEvent(Event{Type: EventFileUpdated, Workflow: workflow, Changes: count})
+		logger.Info("unpinned actions", "file", workflow, "changes", count)
+	}
+	fmt.Fprintf(console, "\n%s Unpinned %d reference(s)\n", theme.OK, unpinned)
+	return nil
+}
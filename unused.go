@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	reasonUnreferenced = "composite action not used by any workflow or action in this repository"
+	reasonNoTriggers   = "no on: triggers; GitHub never runs this workflow"
+	reasonBadTriggers  = "no valid on: triggers; unknown event(s) "
+)
+
+// workflowEvents are the events a workflow can be triggered by
+var workflowEvents = map[string]bool{
+	"branch_protection_rule": true, "check_run": true, "check_suite": true, "create": true,
+	"delete": true, "deployment": true, "deployment_status": true, "discussion": true,
+	"discussion_comment": true, "fork": true, "gollum": true, "image_version": true,
+	"issue_comment": true, "issues": true, "label": true, "merge_group": true,
+	"milestone": true, "page_build": true, "project": true, "project_card": true,
+	"project_column": true, "public": true, "pull_request": true, "pull_request_review": true,
+	"pull_request_review_comment": true, "pull_request_target": true, "push": true,
+	"registry_package": true, "release": true, "repository_dispatch": true, "schedule": true,
+	"status": true, "watch": true, "workflow_call": true, "workflow_dispatch": true,
+	"workflow_run": true,
+}
+
+// UnusedFile records CI code that never runs: a local composite action no
+// workflow or action uses, or a workflow without a valid trigger
+type UnusedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// findLocalUses returns the directories named by the local uses: references
+// of content, such as .github/actions/setup for ./.github/actions/setup.
+// GitHub resolves them against the repository root.
+func findLocalUses(content string) []string {
+	var dirs []string
+	record := func(value string) {
+		if isLocalUses(value) {
+			dirs = append(dirs, path.Clean(value))
+		}
+	}
+
+	content, _ = splitBOM(content)
+	docs, err := parseDocuments(content)
+	if err != nil {
+		walkTemplateUses(content, func(value, _ string, _, _ int) {
+			record(value)
+		})
+		return dirs
+	}
+	for _, doc := range docs {
+		walkUses(doc, func(node *yaml.Node) {
+			record(node.Value)
+		})
+	}
+	return dirs
+}
+
+// triggerEvents returns the event names under the on: key of a workflow
+// document, in any of its scalar, sequence and mapping forms
+func triggerEvents(doc *yaml.Node) []string {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := doc.Content[0]
+
+	var on *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "on" {
+			on = root.Content[i+1]
+		}
+	}
+	if on == nil {
+		return nil
+	}
+
+	var events []string
+	switch on.Kind {
+	case yaml.ScalarNode:
+		if on.Tag != "!!null" && on.Value != "" {
+			events = append(events, on.Value)
+		}
+	case yaml.SequenceNode:
+		for _, event := range on.Content {
+			events = append(events, event.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(on.Content); i += 2 {
+			events = append(events, on.Content[i].Value)
+		}
+	}
+	return events
+}
+
+// triggerProblem explains why a workflow document is never triggered, or
+// returns "" when its on: key names at least one known event
+func triggerProblem(doc *yaml.Node) string {
+	events := triggerEvents(doc)
+	if len(events) == 0 {
+		return reasonNoTriggers
+	}
+
+	var unknown []string
+	for _, event := range events {
+		if workflowEvents[event] {
+			return ""
+		}
+		unknown = append(unknown, event)
+	}
+	return reasonBadTriggers + strings.Join(unknown, ", ")
+}
+
+// findUnusedFiles lists the composite actions in the action paths that no
+// workflow, action or template source uses, and the workflows that are never
+// triggered. The repository's own action is published rather than used
+// locally, and files that do not parse are already in the skipped inventory.
+func findUnusedFiles(opts ScanOptions) []UnusedFile {
+	var unused []UnusedFile
+	var workflows []string
+	if entries, err := os.ReadDir(workflowDir); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && isYAMLFile(entry.Name()) {
+				workflows = append(workflows, filepath.Join(workflowDir, entry.Name()))
+			}
+		}
+	}
+	actionFiles := findActionFiles(opts.ActionPaths)
+
+	used := make(map[string]bool)
+	sources := append(append(append([]string(nil), workflows...), actionFiles...), findTemplateFiles(opts.TemplatePaths)...)
+	for i, file := range append(sources, rootActionFiles()...) {
+		content, err := os.ReadFile(filepath.Clean(file))
+		if err != nil {
+			logger.Debug("could not read file for local uses", "file", file, "error", err)
+			continue
+		}
+		for _, dir := range findLocalUses(string(content)) {
+			used[dir] = true
+		}
+
+		if i >= len(workflows) {
+			continue
+		}
+		body, _ := splitBOM(string(content))
+		if docs, err := parseDocuments(body); err == nil && len(docs) > 0 {
+			if reason := triggerProblem(docs[0]); reason != "" {
+				unused = append(unused, UnusedFile{Path: file, Reason: reason})
+			}
+		}
+	}
+
+	for _, file := range actionFiles {
+		if dir := filepath.ToSlash(filepath.Dir(file)); !used[dir] {
+			unused = append(unused, UnusedFile{Path: file, Reason: reasonUnreferenced})
+		}
+	}
+
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].Path < unused[j].Path
+	})
+	return unused
+}
+
+// printUnused lists the CI code that never runs, so it can be pruned
+func printUnused(unused []UnusedFile) {
+	if len(unused) == 0 || !ruleEnabled(ruleUnused) {
+		return
+	}
+
+	fmt.Fprintf(console, "\n🧹 Unused CI code (%d) %s:\n", len(unused), ruleTag(ruleUnused))
+	for _, file := range unused {
+		fmt.Fprintf(console, "  %s: %s\n", file.Path, file.Reason)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultMarketplaceURL hosts the GitHub Marketplace listings
+	defaultMarketplaceURL = "https://github.com"
+
+	// marketplaceTimeout bounds a single Marketplace request
+	marketplaceTimeout = 30 * time.Second
+
+	// maxMarketplacePageSize bounds a Marketplace listing page
+	maxMarketplacePageSize = 4 << 20
+)
+
+// PublisherStatus is whether the owner of an action is a verified creator
+type PublisherStatus string
+
+const (
+	// PublisherVerified means GitHub verified the organization, or the
+	// action is first-party
+	PublisherVerified PublisherStatus = "verified"
+	// PublisherUnverified means the organization is not verified
+	PublisherUnverified PublisherStatus = "unverified"
+	// PublisherPersonal means the action is published by a user account,
+	// which cannot be verified
+	PublisherPersonal PublisherStatus = "personal_account"
+	// PublisherUnknown means the owner could not be looked up
+	PublisherUnknown PublisherStatus = "unknown"
+)
+
+// Publisher records who publishes an action and whether it is listed on
+// GitHub Marketplace
+type Publisher struct {
+	Owner  string          `json:"owner"`
+	Status PublisherStatus `json:"status"`
+	// Marketplace is the URL of the Marketplace listing, empty when the
+	// action is not listed or the listing was not looked up
+	Marketplace string `json:"marketplace,omitempty"`
+	// Allowed is set for unverified publishers of actions matching
+	// allow_unverified
+	Allowed bool `json:"allowed,omitempty"`
+}
+
+// blocked reports whether verify --require-verified rejects the action
+func (p *Publisher) blocked() bool {
+	return p.Status != PublisherVerified && !p.Allowed
+}
+
+// describe summarizes the publisher and listing for the console
+func (p *Publisher) describe() string {
+	var description string
+	switch p.Status {
+	case PublisherVerified:
+		description = "verified creator"
+	case PublisherUnverified:
+		description = "unverified organization"
+	case PublisherPersonal:
+		description = "personal account"
+	default:
+		description = "publisher could not be looked up"
+	}
+	if p.Marketplace != "" {
+		description += ", on the Marketplace"
+	}
+	return description
+}
+
+// marketplaceClient looks up Marketplace listings. GitHub has no API for
+// them, so the public listing pages are read.
+type marketplaceClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// newMarketplaceClient returns a Marketplace client for the site at baseURL
+func newMarketplaceClient(baseURL string) *marketplaceClient {
+	return &marketplaceClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Transport: loggingTransport{}, Timeout: marketplaceTimeout},
+	}
+}
+
+// nonSlugRegex matches the characters Marketplace slugs replace with dashes
+var nonSlugRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// marketplaceSlug returns the listing slug of an action name, e.g.
+// "setup-node-js-environment" for "Setup Node.js environment"
+func marketplaceSlug(name string) string {
+	return strings.Trim(nonSlugRegex.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// listing returns the URL of the Marketplace listing of the action named
+// name in owner/repo, or "" when it is not listed. Listings are found by
+// name, so one that links to another repository does not count.
+func (c *marketplaceClient) listing(owner, repo, name string) (string, error) {
+	slug := marketplaceSlug(name)
+	if slug == "" {
+		return "", nil
+	}
+	listingURL := c.baseURL + "/marketplace/actions/" + slug
+	resp, err := c.http.Get(listingURL)
+	if err != nil {
+		return "", &ResolveError{Kind: ErrorKindNetwork, Err: fmt.Errorf("Marketplace could not be reached: %w", err)}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		return "", fmt.Errorf("Marketplace request for %s failed with status %d", slug, resp.StatusCode)
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxMarketplacePageSize))
+	if err != nil {
+		return "", fmt.Errorf("reading Marketplace listing %s: %w", slug, err)
+	}
+	if !strings.Contains(strings.ToLower(string(page)), strings.ToLower("github.com/"+owner+"/"+repo+`"`)) {
+		return "", nil
+	}
+	return listingURL, nil
+}
+
+// actionName reads the name of the action at the root of owner/repo
+func actionName(forge Forge, owner, repo, ref string) (string, error) {
+	manifest, err := fetchManifest(forge, owner, repo, "", ref)
+	if err != nil {
+		return "", err
+	}
+	var m distManifest
+	if err := yaml.Unmarshal(manifest, &m); err != nil {
+		return "", fmt.Errorf("invalid action manifest: %w", err)
+	}
+	return m.Name, nil
+}
+
+// publisherStatus looks up whether owner is a verified creator. First-party
+// owners are verified without a request.
+func publisherStatus(gc *GitHubClient, owner string) PublisherStatus {
+	if slices.Contains(firstPartyOwners, owner) {
+		return PublisherVerified
+	}
+	verified, err := gc.api.OrgVerified(gc.ctx, owner)
+	switch {
+	case err != nil && errorKindOf(err) == ErrorKindNotFound:
+		return PublisherPersonal
+	case err != nil:
+		logger.Debug("could not look up organization", "owner", owner, "error", err)
+		return PublisherUnknown
+	case verified:
+		return PublisherVerified
+	}
+	return PublisherUnverified
+}
+
+// allowsUnverified reports whether allow_unverified accepts an action path.
+// Owners are case insensitive, so paths and patterns are compared in lower
+// case.
+func allowsUnverified(cfg *Config, repo string) bool {
+	patterns := make([]string, len(cfg.AllowUnverified))
+	for i, pattern := range cfg.AllowUnverified {
+		patterns[i] = strings.ToLower(pattern)
+	}
+	return matchesActionGlobs(patterns, strings.ToLower(repo))
+}
+
+// annotatePublishers stores the publisher of every action in the actions,
+// and looks up Marketplace listings of root actions unless marketplace is
+// nil. Each owner and repository is looked up once.
+func annotatePublishers(gc *GitHubClient, forge Forge, marketplace *marketplaceClient, cfg *Config, actions WorkflowActions) {
+	statuses := make(map[string]PublisherStatus)
+	listings := make(map[string]string)
+	for _, workflow := range sortedWorkflows(actions) {
+		for i := range actions[workflow] {
+			action := &actions[workflow][i]
+			if action.Docker {
+				continue
+			}
+			owner, repo, dir := splitActionPath(action.Repo)
+			status, ok := statuses[strings.ToLower(owner)]
+			if !ok {
+				status = publisherStatus(gc, owner)
+				statuses[strings.ToLower(owner)] = status
+			}
+			action.Publisher = &Publisher{Owner: owner, Status: status}
+			if status != PublisherVerified {
+				action.Publisher.Allowed = allowsUnverified(cfg, action.Repo)
+			}
+
+			// Only actions at the root of a repository can be listed
+			if marketplace == nil || dir != "" || action.ReusableWorkflow {
+				continue
+			}
+			key := strings.ToLower(owner + "/" + repo)
+			listing, ok := listings[key]
+			if !ok {
+				ref := action.CurrentSHA
+				if ref == "" {
+					ref = action.CurrentRef
+				}
+				name, err := actionName(forge, owner, repo, ref)
+				if err == nil {
+					listing, err = marketplace.listing(owner, repo, name)
+				}
+				if err != nil {
+					logger.Debug("could not look up Marketplace listing", "repo", key, "error", err)
+				}
+				listings[key] = listing
+			}
+			action.Publisher.Marketplace = listing
+		}
+	}
+}
+
+// reportPublishers prints the publishers of the actions, or only the
+// unverified ones when verifying, and returns the number of actions verify
+// --require-verified rejects
+func reportPublishers(actions WorkflowActions, verifying bool) int {
+	blocked := 0
+	reported := make(map[string]bool)
+	for _, action := range sortedByRepo(actions) {
+		publisher := action.Publisher
+		if publisher == nil {
+			continue
+		}
+		if publisher.blocked() {
+			blocked++
+		}
+		if reported[action.Repo] || (verifying && publisher.Status == PublisherVerified) {
+			continue
+		}
+		reported[action.Repo] = true
+		switch {
+		case publisher.Status == PublisherVerified:
+			fmt.Fprintf(console, "  %s %s: %s\n", theme.OK, action.Repo, publisher.describe())
+		case publisher.Allowed:
+			fmt.Fprintf(console, "  %s %s: %s, allowed by allow_unverified\n", theme.Skipped, action.Repo, publisher.describe())
+		case verifying:
+			fmt.Fprintf(console, "  %s %s: %s %s\n", severityStatus(ruleUnverified), action.Repo, publisher.describe(), ruleTag(ruleUnverified))
+		default:
+			fmt.Fprintf(console, "  %s %s: %s %s\n", theme.Warning, action.Repo, publisher.describe(), ruleTag(ruleUnverified))
+		}
+		if publisher.Marketplace != "" {
+			fmt.Fprintf(console, "      %s\n", publisher.Marketplace)
+		}
+	}
+	return blocked
+}
+
+// publisherOptions holds the publisher flags of check
+type publisherOptions struct {
+	enabled        bool
+	marketplaceURL string
+}
+
+// registerPublisherFlags adds the publisher flags to a command's flag set
+func registerPublisherFlags(fs *flag.FlagSet) *publisherOptions {
+	opts := &publisherOptions{}
+	fs.BoolVar(&opts.enabled, "publishers", false, "Show whether action owners are verified creators and actions are listed on GitHub Marketplace")
+	fs.StringVar(&opts.marketplaceURL, "marketplace-url", defaultMarketplaceURL, "Marketplace site `url`")
+	return opts
+}
+
+// publishers annotates and prints the publishers of the actions when
+// enabled. Marketplace listings are only looked up on github.com.
+func (o *publisherOptions) publishers(gc *GitHubClient, forge Forge, cfg *Config, actions WorkflowActions) {
+	if !o.enabled {
+		return
+	}
+	fmt.Fprintln(console, "\n🏅 Looking up action publishers...")
+	var marketplace *marketplaceClient
+	if apiURL := os.Getenv("GITHUB_API_URL"); forge.Name() == "github" && (apiURL == "" || strings.TrimSuffix(apiURL, "/") == "https://api.github.com" || o.marketplaceURL != defaultMarketplaceURL) {
+		marketplace = newMarketplaceClient(o.marketplaceURL)
+	}
+	annotatePublishers(gc, forge, marketplace, cfg, actions)
+	reportPublishers(actions, false)
+}
+
+// verifyVerifiedPublishers requires every third-party action to come from
+// a verified creator unless it matches allow_unverified. Owners that could
+// not be looked up fail too.
+func verifyVerifiedPublishers(gc *GitHubClient, cfg *Config, actions WorkflowActions) error {
+	fmt.Fprintln(console, "\n🏅 Verifying third-party actions come from verified creators...")
+	annotatePublishers(gc, gc, nil, cfg, actions)
+	blocked := reportPublishers(actions, true)
+	if blocked > 0 {
+		fmt.Fprintln(console, "     💡 Replace the actions, or list them under allow_unverified once reviewed")
+		return fmt.Errorf("%d action(s) are not published by verified creators", blocked)
+	}
+	fmt.Fprintf(console, "%s All third-party actions come from verified creators or are allowed\n", theme.OK)
+	return nil
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// utf8BOM is the byte order mark some Windows editors write at the start of files
+const utf8BOM = "\ufeff"
+
+// splitBOM separates a leading byte order mark from content. The parser does
+// not count it in columns, so it is removed before parsing or rewriting and
+// written back unchanged.
+func splitBOM(content string) (string, string) {
+	if body, ok := strings.CutPrefix(content, utf8BOM); ok {
+		return body, utf8BOM
+	}
+	return content, ""
+}
+
+// parseDocuments parses every document of a YAML stream, so references
+// after a --- separator are found as well. Line numbers count from the start
+// of the stream.
+func parseDocuments(content string) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+}
+
+// parseWorkflowContent extracts GitHub Actions from workflow content by
+// walking its YAML documents, so uses: keys are found regardless of quoting,
+// flow style or indentation. Template sources that are not valid YAML are
+// scanned line by line instead. filename labels the returned actions and
+// identifies jsonnet sources.
+func parseWorkflowContent(filename, content string) ([]ActionInfo, error) {
+	if err := checkContentLimits(filename, content); err != nil {
+		return nil, err
+	}
+	content, _ = splitBOM(content)
+
+	docs, err := parseDocuments(content)
+	if err != nil {
+		if !isTemplateSource(filename, content) {
+			return nil, fmt.Errorf("invalid YAML in %s: %w", filename, err)
+		}
+		actions := parseTemplateContent(filename, content)
+		if err := checkActionCount(filename, len(actions)); err != nil {
+			return nil, err
+		}
+		return actions, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	var actions []ActionInfo
+	for _, doc := range docs {
+		jobs := usesJobs(doc)
+		walkUses(doc, func(node *yaml.Node) {
+			originalLine := ""
+			if node.Line-1 < len(lines) {
+				originalLine, _ = splitCarriageReturn(lines[node.Line-1])
+			}
+
+			// Literal block scalars keep their final line break
+			action, ok := usesAction(strings.TrimSpace(node.Value), originalLine)
+			if !ok {
+				return
+			}
+			action.Line = node.Line
+			action.Column = node.Column
+			action.WorkflowFile = filename
+			action.Job = jobs[node]
+			actions = append(actions, action)
+		})
+	}
+	// Aliases and flow mappings can yield nodes out of document order
+	sort.SliceStable(actions, func(i, j int) bool {
+		if actions[i].Line != actions[j].Line {
+			return actions[i].Line < actions[j].Line
+		}
+		return actions[i].Column < actions[j].Column
+	})
+
+	if err := checkActionCount(filename, len(actions)); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// usesAction describes the reference named by a uses: value on line. It
+// reports false for local actions and values that cannot be pinned.
+func usesAction(value, line string) (ActionInfo, bool) {
+	// References built from template or workflow expressions cannot be
+	// pinned; see findExpressionUses
+	if hasTemplateMarker(value) || isExpression(value) {
+		return ActionInfo{}, false
+	}
+	if strings.HasPrefix(value, dockerPrefix) {
+		return dockerAction(value, line)
+	}
+
+	repo, ref, ok := strings.Cut(value, "@")
+	if !ok || repo == "" || ref == "" || isLocalUses(repo) || strings.ContainsAny(repo, " \t") {
+		return ActionInfo{}, false
+	}
+
+	// Determine current SHA (if ref is already a SHA)
+	currentSHA := ""
+	if shaRegex.MatchString(ref) {
+		currentSHA = ref
+	}
+
+	return ActionInfo{
+		Repo:             repo,
+		CurrentRef:       ref,
+		CurrentSHA:       currentSHA,
+		OriginalLine:     line,
+		ReusableWorkflow: isReusableWorkflow(repo),
+	}, true
+}
+
+// usesJobs maps the uses: values of a workflow document to the id of the
+// job under jobs: they belong to
+func usesJobs(doc *yaml.Node) map[*yaml.Node]string {
+	jobs := make(map[*yaml.Node]string)
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return jobs
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "jobs" || root.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		jobMap := root.Content[i+1]
+		for j := 0; j+1 < len(jobMap.Content); j += 2 {
+			id := jobMap.Content[j].Value
+			walkUses(jobMap.Content[j+1], func(node *yaml.Node) {
+				jobs[node] = id
+			})
+		}
+	}
+	return jobs
+}
+
+// walkUses calls fn for the scalar value of every uses: key in the document.
+// Aliases and merge keys are not followed, so a step or value defined with an
+// anchor and reused through *aliases is reported once, where it is defined,
+// and updating it there updates every reuse.
+func walkUses(node *yaml.Node, fn func(*yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			walkUses(child, fn)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "uses" && value.Kind == yaml.ScalarNode {
+				fn(value)
+				continue
+			}
+			walkUses(value, fn)
+		}
+	}
+}
+
+// isLocalUses reports whether a uses: value refers to a local action rather
+// than a repository
+func isLocalUses(repo string) bool {
+	return strings.HasPrefix(repo, "./") || strings.HasPrefix(repo, "../")
+}
+
+// isReusableWorkflow reports whether a uses: value calls a reusable workflow
+// in another repository. Its ref is resolved against the owning owner/repo.
+func isReusableWorkflow(repo string) bool {
+	parts := strings.SplitN(repo, "/", 3)
+	return len(parts) == 3 && strings.HasPrefix(parts[2], ".github/workflows/")
+}
+
+// pinnedLine rewrites the uses: values of updates, which all sit on line, to
+// their latest SHA and sets a tag comment in the pin_comment format. Only the scalars and the tag in the
+// trailing comment are touched; other comment text, quoting, anchors, flow
+// collections and the rest of the line are preserved. onLine lists every reference on the line: when a
+// flow-style line holds several, the comment names the tag of each of them.
+func pinnedLine(line string, updates []ActionInfo, onLine []ActionInfo) string {
+	pinned, _ := pinnedLineWithMarker(line, updates, onLine, "#")
+	return pinned
+}
+
+// pinnedLineWithMarker is pinnedLine with a custom comment marker, such as
+// the #! comments of ytt templates. It also returns the updates whose value
+// was found and rewritten; the comment only changes for those, and the line
+// is returned as is when there are none.
+func pinnedLineWithMarker(line string, updates []ActionInfo, onLine []ActionInfo, marker string) (string, []ActionInfo) {
+	code, comment := splitTrailingComment(line)
+
+	// Rewrite from right to left so that earlier columns stay valid
+	ordered := append([]ActionInfo(nil), updates...)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Column > ordered[j].Column
+	})
+	runes := []rune(code)
+	var rewritten []ActionInfo
+	for _, action := range ordered {
+		var ok bool
+		if runes, ok = rewriteUsesValue(runes, action); ok {
+			rewritten = append([]ActionInfo{action}, rewritten...)
+		}
+	}
+	if len(rewritten) == 0 {
+		return line, nil
+	}
+	updates = rewritten
+	code = strings.TrimRight(string(runes), " \t")
+
+	if len(onLine) <= 1 {
+		// The version tag comment is replaced, any other comment text kept
+		var parts []string
+		if pinComment.enabled() {
+			parts = append(parts, pinComment.format(updates[0]))
+		}
+		if note := pinComment.note(comment); note != "" {
+			parts = append(parts, note)
+		}
+		if len(parts) == 0 {
+			return code, updates
+		}
+		return code + " " + marker + " " + strings.Join(parts, " "+marker+" "), updates
+	}
+	if !pinComment.enabled() {
+		return code, updates
+	}
+
+	tags := parseTagList(strings.TrimPrefix(comment, marker))
+	names := make(map[string]string)
+	for _, action := range updates {
+		tags[action.Repo] = action.LatestTag
+		names[action.Repo] = action.usesPath()
+	}
+	var parts []string
+	for _, action := range onLine {
+		if tag, ok := tags[action.Repo]; ok {
+			name := action.Repo
+			if updated, ok := names[action.Repo]; ok {
+				name = updated
+			}
+			parts = append(parts, name+" "+tag)
+		}
+	}
+	return code + " " + marker + " " + strings.Join(parts, ", "), updates
+}
+
+// parseTagList reads a "# owner/repo tag, owner/repo tag" comment written for
+// lines holding several references
+func parseTagList(comment string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(comment), "#")), ",") {
+		if fields := strings.Fields(part); len(fields) == 2 {
+			tags[fields[0]] = fields[1]
+		}
+	}
+	return tags
+}
+
+// lineEnding returns the line terminator a file predominantly uses, so
+// inserted lines match the rest of Windows-originated files
+func lineEnding(content string) string {
+	crlf := strings.Count(content, "\r\n")
+	if crlf > 0 && crlf >= strings.Count(content, "\n")-crlf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// splitCarriageReturn separates the \r left at the end of a line when CRLF
+// content is split on \n, so it can be restored after the line is rewritten
+func splitCarriageReturn(line string) (string, string) {
+	if body, ok := strings.CutSuffix(line, "\r"); ok {
+		return body, "\r"
+	}
+	return line, ""
+}
+
+// splitTrailingComment splits a line into its content and a trailing comment,
+// ignoring # characters inside quoted scalars
+func splitTrailingComment(line string) (string, string) {
+	runes := []rune(line)
+	var quote rune
+	for i, r := range runes {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || runes[i-1] == ' ' || runes[i-1] == '\t'):
+			return string(runes[:i]), string(runes[i:])
+		}
+	}
+	return line, ""
+}
+
+// rewriteUsesValue replaces the uses: value of action, which starts at
+// action.Column, with the latest SHA. The runes are returned unchanged, and
+// the bool false, when the value is not found at that position, e.g. in a
+// block scalar.
+func rewriteUsesValue(runes []rune, action ActionInfo) ([]rune, bool) {
+	start := action.Column - 1
+	if start < 0 || start >= len(runes) {
+		return runes, false
+	}
+
+	// The node position of an anchored or tagged scalar is its first
+	// property, so skip anchors and tags to reach the value itself
+	for start < len(runes) && (runes[start] == '&' || runes[start] == '!') {
+		for start < len(runes) && runes[start] != ' ' {
+			start++
+		}
+		for start < len(runes) && runes[start] == ' ' {
+			start++
+		}
+	}
+	if start >= len(runes) {
+		return runes, false
+	}
+
+	end := scalarEnd(runes, start)
+	if end <= start {
+		return runes, false
+	}
+
+	raw := string(runes[start:end])
+	unquoted := strings.Trim(raw, `"'`)
+	if !strings.HasPrefix(unquoted, action.Repo+"@") && !(action.Docker && strings.HasPrefix(unquoted, action.Repo)) {
+		return runes, false
+	}
+	value := action.usesPath() + "@" + action.LatestSHA
+	if action.Docker && !pinComment.enabled() && action.LatestTag != "" {
+		// Without a comment the tag is kept in the reference so it can be followed
+		value = action.Repo + ":" + action.LatestTag + "@" + action.LatestSHA
+	}
+	switch raw[0] {
+	case '"', '\'':
+		value = raw[:1] + value + raw[:1]
+	}
+
+	rewritten := append([]rune{}, runes[:start]...)
+	rewritten = append(rewritten, []rune(value)...)
+	return append(rewritten, runes[end:]...), true
+}
+
+// scalarEnd returns the index just past the scalar token starting at start,
+// or -1 when the token does not end on this line
+func scalarEnd(runes []rune, start int) int {
+	switch quote := runes[start]; quote {
+	case '"':
+		for i := start + 1; i < len(runes); i++ {
+			switch runes[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+		return -1
+	case '\'':
+		for i := start + 1; i < len(runes); i++ {
+			if runes[i] != '\'' {
+				continue
+			}
+			if i+1 < len(runes) && runes[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+		return -1
+	}
+
+	// Plain scalars end at a comment or, in flow style, at a flow indicator
+	end := start
+	for end < len(runes) {
+		r := runes[end]
+		if r == ',' || r == '}' || r == ']' || r == '\t' ||
+			(r == ' ' && end+1 < len(runes) && runes[end+1] == '#') {
+			break
+		}
+		end++
+	}
+	for end > start && runes[end-1] == ' ' {
+		end--
+	}
+	return end
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPinSHA = "0123456789abcdef0123456789abcdef01234567"
+
+// readFixture reads a workflow fixture below testdata/workflows
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", "workflows", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// describeActions lists actions as "line:column repo@ref"
+func describeActions(actions []ActionInfo) []string {
+	described := []string{}
+	for _, action := range actions {
+		described = append(described, fmt.Sprintf("%d:%d %s@%s", action.Line, action.Column, action.Repo, action.CurrentRef))
+	}
+	return described
+}
+
+func TestParseWorkflowContent(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []string
+	}{
+		{"bom.yml", []string{"7:15 actions/checkout@v3"}},
+		{"crlf.yml", []string{"7:15 actions/checkout@v3", "9:15 actions/setup-go@v4"}},
+		{"multidoc.yml", []string{"8:15 actions/checkout@v3", "17:15 actions/cache@v3"}},
+		{"flow.yml", []string{"6:20 actions/checkout@v3", "6:49 actions/setup-go@v4", "10:16 actions/cache@v3"}},
+		{"blockscalar.yml", []string{"7:15 actions/folded@v1", "9:15 actions/literal@v1", "11:15 actions/checkout@v3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			actions, err := parseWorkflowContent(tt.fixture, readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeActions(actions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWorkflowContentInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "broken workflow with expressions",
+			filename: "ci.yml",
+			content:  "jobs:\n  a:\n    runs-on: ${{ matrix.os }}\n    steps:\n      - uses: actions/checkout@v3\n       bad: [\n",
+			wantErr:  true,
+		},
+		{
+			name:     "jinja template",
+			filename: "ci.yml",
+			content:  "jobs:\n{% for os in oses %}\n  {{ os }}:\n    steps:\n      - uses: actions/checkout@v3\n{% endfor %}\n",
+			want:     []string{"5:15 actions/checkout@v3"},
+		},
+		{
+			name:     "jsonnet source",
+			filename: "ci.jsonnet",
+			content:  "{\n  steps: [{ 'uses': 'actions/checkout@v3' }],\n}\n",
+			want:     []string{"2:21 actions/checkout@v3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := parseWorkflowContent(tt.filename, tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsed %q, want an error", describeActions(actions))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeActions(actions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyActionUpdates(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		wantChanged int
+	}{
+		{"bom.yml", 1},
+		{"crlf.yml", 2},
+		{"multidoc.yml", 2},
+		{"flow.yml", 3},
+		// Only the plain scalar can be rewritten in place
+		{"blockscalar.yml", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			content := readFixture(t, tt.fixture)
+			actions, err := parseWorkflowContent(tt.fixture, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range actions {
+				actions[i].NeedsUpdate = true
+				actions[i].LatestTag = "v9.9.9"
+				actions[i].LatestSHA = testPinSHA
+			}
+
+			got, changed := applyActionUpdates(tt.fixture, content, actions)
+			if changed != tt.wantChanged {
+				t.Errorf("changed %d references, want %d", changed, tt.wantChanged)
+			}
+			want := readFixture(t, strings.TrimSuffix(tt.fixture, ".yml")+".pinned.yml")
+			if got != want {
+				t.Errorf("got\n%q\nwant\n%q", got, want)
+			}
+
+			// Pinning the result again changes nothing
+			repinned, err := parseWorkflowContent(tt.fixture, got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range repinned {
+				repinned[i].NeedsUpdate = repinned[i].CurrentRef != testPinSHA
+				repinned[i].LatestTag = "v9.9.9"
+				repinned[i].LatestSHA = testPinSHA
+			}
+			if again, _ := applyActionUpdates(tt.fixture, got, repinned); again != got {
+				t.Errorf("second run changed the content to\n%q", again)
+			}
+		})
+	}
+}